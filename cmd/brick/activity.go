@@ -0,0 +1,244 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/events"
+)
+
+// activityMonitorAlertName is used in place of the alert/search name for
+// events generated by the activity monitor.
+const activityMonitorAlertName string = "Activity monitor"
+
+// activityAnySender is the key used to track alert payloads received from
+// any sender.
+const activityAnySender string = ""
+
+// activitySenderStatus reflects when an alert payload was last received
+// from a sender and whether the sender is considered quiet.
+type activitySenderStatus struct {
+	Sender   string `json:"sender"`
+	LastSeen string `json:"last_seen"`
+	Quiet    bool   `json:"quiet"`
+}
+
+// activityTracker records when valid alert payloads are received, both
+// overall and for each of the specified senders. If no alert payloads are
+// received within the window a warning notification is sent; a recovery
+// notification is sent once alert payloads are received again.
+type activityTracker struct {
+	tenant          string
+	window          time.Duration
+	notifyWorkQueue chan<- events.Record
+
+	mutex    *sync.Mutex
+	lastSeen map[string]time.Time
+	quiet    map[string]bool
+}
+
+// newActivityTracker constructs a new activityTracker for the provided
+// tenant, window and sender IP Addresses. The application start time is used
+// as the initial last seen time so that a warning is not sent until the
+// window has elapsed.
+func newActivityTracker(
+	tenant string,
+	window time.Duration,
+	senders []string,
+	notifyWorkQueue chan<- events.Record,
+) *activityTracker {
+
+	now := time.Now()
+
+	at := activityTracker{
+		tenant:          tenant,
+		window:          window,
+		notifyWorkQueue: notifyWorkQueue,
+		mutex:           &sync.Mutex{},
+		lastSeen:        map[string]time.Time{activityAnySender: now},
+		quiet:           make(map[string]bool),
+	}
+
+	for _, sender := range senders {
+		at.lastSeen[sender] = now
+	}
+
+	return &at
+}
+
+// enabled indicates whether activity monitoring has been configured.
+func (at *activityTracker) enabled() bool {
+	return at.window > 0
+}
+
+// seen records that a valid alert payload was received from the provided
+// sender (as reported by events.GetIP). A recovery notification is sent if
+// the sender (or all senders) had been quiet.
+func (at *activityTracker) seen(payloadSenderIP string) {
+
+	if !at.enabled() {
+		return
+	}
+
+	at.mutex.Lock()
+	defer at.mutex.Unlock()
+
+	now := time.Now()
+	sender := events.SenderIP(payloadSenderIP)
+
+	for _, key := range []string{activityAnySender, sender} {
+
+		// only the specified senders are tracked individually
+		if _, tracked := at.lastSeen[key]; !tracked {
+			continue
+		}
+
+		if at.quiet[key] {
+			at.quiet[key] = false
+			at.notify(
+				key,
+				events.ActionSuccessActivityResumed,
+				fmt.Sprintf(
+					"Valid alert payloads received again from %s after %s without activity",
+					activitySenderLabel(key),
+					now.Sub(at.lastSeen[key]).Round(time.Second),
+				),
+			)
+		}
+
+		at.lastSeen[key] = now
+	}
+}
+
+// check sends a warning notification for each tracked sender (or all
+// senders) from which no alert payloads have been received within the
+// window. Only one warning is sent for each quiet period.
+func (at *activityTracker) check(now time.Time) {
+
+	at.mutex.Lock()
+	defer at.mutex.Unlock()
+
+	for key, lastSeen := range at.lastSeen {
+
+		if at.quiet[key] || now.Sub(lastSeen) < at.window {
+			continue
+		}
+
+		at.quiet[key] = true
+		at.notify(
+			key,
+			events.ActionFailureNoActivity,
+			fmt.Sprintf(
+				"No valid alert payloads received from %s since %s; expected at least one every %s",
+				activitySenderLabel(key),
+				lastSeen.Format(time.RFC3339),
+				at.window,
+			),
+		)
+	}
+}
+
+// status returns the current activity status for all senders and each of
+// the specified senders, sorted by sender.
+func (at *activityTracker) status() []activitySenderStatus {
+
+	at.mutex.Lock()
+	defer at.mutex.Unlock()
+
+	statuses := make([]activitySenderStatus, 0, len(at.lastSeen))
+	for key, lastSeen := range at.lastSeen {
+		statuses = append(statuses, activitySenderStatus{
+			Sender:   activitySenderLabel(key),
+			LastSeen: lastSeen.Format(time.RFC3339),
+			Quiet:    at.quiet[key],
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Sender < statuses[j].Sender
+	})
+
+	return statuses
+}
+
+// notify logs the provided message and sends it as a notification; the
+// caller is expected to hold the mutex.
+func (at *activityTracker) notify(sender string, action string, msg string) {
+
+	switch action {
+	case events.ActionFailureNoActivity:
+		log.Warn(msg)
+	default:
+		log.Info(msg)
+	}
+
+	alert := events.SplunkAlertEvent{
+		PayloadSenderIP: sender,
+		ArrivalTime:     time.Now().Format(time.RFC3339),
+		LocalTime:       time.Now().Format("2006-01-02 15:04:05"),
+		AlertName:       activityMonitorAlertName,
+		Tenant:          at.tenant,
+	}
+
+	record := events.NewRecord(alert, nil, msg, action, nil)
+
+	go func() {
+		at.notifyWorkQueue <- record
+	}()
+}
+
+// monitor periodically checks for quiet senders until the provided context
+// is cancelled. This is intended to be run as a goroutine.
+func (at *activityTracker) monitor(ctx context.Context, interval time.Duration) {
+
+	if !at.enabled() {
+		log.Debug("activityTracker: activity monitoring not enabled")
+		return
+	}
+
+	log.Debugf("activityTracker: checking for alert payloads every %s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("activityTracker: context cancelled; stopping")
+			return
+		case now := <-ticker.C:
+			at.check(now)
+		}
+	}
+}
+
+// activitySenderLabel returns a description of the sender used in log
+// messages, notifications and health status.
+func activitySenderLabel(sender string) string {
+	if sender == activityAnySender {
+		return "any sender"
+	}
+
+	return sender
+}
@@ -0,0 +1,541 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/atc0005/brick/config"
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/internal/caller"
+)
+
+// Values used to build Adaptive Card messages accepted by Teams Workflows
+// (Power Automate) webhooks.
+// https://learn.microsoft.com/en-us/microsoftteams/platform/task-modules-and-cards/cards/cards-reference#adaptive-card
+const (
+	adaptiveCardContentType string = "application/vnd.microsoft.card.adaptive"
+	adaptiveCardSchema      string = "http://adaptivecards.io/schemas/adaptive-card.json"
+	adaptiveCardVersion     string = "1.4"
+)
+
+// adaptiveCardFact is a title/value pair displayed by a FactSet element.
+type adaptiveCardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// adaptiveCardElement is an Adaptive Card element. Only the fields used by
+// the TextBlock, Container and FactSet elements are provided.
+type adaptiveCardElement struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Size      string                `json:"size,omitempty"`
+	Weight    string                `json:"weight,omitempty"`
+	Color     string                `json:"color,omitempty"`
+	IsSubtle  bool                  `json:"isSubtle,omitempty"`
+	Wrap      bool                  `json:"wrap,omitempty"`
+	Separator bool                  `json:"separator,omitempty"`
+	Spacing   string                `json:"spacing,omitempty"`
+	Items     []adaptiveCardElement `json:"items,omitempty"`
+	Facts     []adaptiveCardFact    `json:"facts,omitempty"`
+}
+
+// adaptiveCardAction is an Action.OpenUrl action displayed as a button at
+// the bottom of an Adaptive Card.
+type adaptiveCardAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	Style string `json:"style,omitempty"`
+}
+
+// adaptiveCardMSTeams provides the Microsoft Teams specific settings of an
+// Adaptive Card.
+type adaptiveCardMSTeams struct {
+	Width string `json:"width"`
+}
+
+// adaptiveCard is the content of an Adaptive Card attachment.
+type adaptiveCard struct {
+	Schema  string                `json:"$schema"`
+	Type    string                `json:"type"`
+	Version string                `json:"version"`
+	Body    []adaptiveCardElement `json:"body"`
+	Actions []adaptiveCardAction  `json:"actions,omitempty"`
+	MSTeams adaptiveCardMSTeams   `json:"msteams"`
+}
+
+// adaptiveCardAttachment wraps an Adaptive Card for submission as a message
+// attachment.
+type adaptiveCardAttachment struct {
+	ContentType string       `json:"contentType"`
+	ContentURL  *string      `json:"contentUrl"`
+	Content     adaptiveCard `json:"content"`
+}
+
+// adaptiveCardMessage is the payload submitted to a Teams Workflows webhook.
+type adaptiveCardMessage struct {
+	Type        string                   `json:"type"`
+	Attachments []adaptiveCardAttachment `json:"attachments"`
+}
+
+// adaptiveCardSection returns a Container element with the provided title,
+// optional text and optional facts. The container is separated from the
+// preceding element in the same way as the sections of MessageCard messages.
+func adaptiveCardSection(title string, text string, facts []adaptiveCardFact) adaptiveCardElement {
+
+	section := adaptiveCardElement{
+		Type:      "Container",
+		Separator: true,
+		Spacing:   "Medium",
+		Items: []adaptiveCardElement{
+			{
+				Type:   "TextBlock",
+				Text:   title,
+				Size:   "Medium",
+				Weight: "Bolder",
+				Wrap:   true,
+			},
+		},
+	}
+
+	if text != "" {
+		section.Items = append(section.Items, adaptiveCardElement{
+			Type: "TextBlock",
+			Text: text,
+			Wrap: true,
+		})
+	}
+
+	if len(facts) > 0 {
+		section.Items = append(section.Items, adaptiveCardElement{
+			Type:  "FactSet",
+			Facts: facts,
+		})
+	}
+
+	return section
+}
+
+// adaptiveCardOpenURL returns an Action.OpenUrl action with the provided
+// title, URL and style.
+func adaptiveCardOpenURL(title string, actionURL string, style string) adaptiveCardAction {
+	return adaptiveCardAction{
+		Type:  "Action.OpenUrl",
+		Title: title,
+		URL:   actionURL,
+		Style: style,
+	}
+}
+
+// newTeamsUnblockURLTemplate parses the provided template for the URL opened
+// by the Unblock user action of Adaptive Card messages. A nil template is
+// returned if one is not specified. The template is rendered using a sample
+// alert to confirm that it produces an absolute http or https URL.
+func newTeamsUnblockURLTemplate(templateText string) (*template.Template, error) {
+
+	if templateText == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("teamsUnblockURL").Option("missingkey=error").Parse(templateText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Microsoft Teams unblock URL template: %w", err)
+	}
+
+	sample := events.SplunkAlertEvent{
+		Username: "sample-user",
+		UserIP:   "192.0.2.1",
+	}
+	if _, err := renderTeamsUnblockURL(tmpl, sample); err != nil {
+		return nil, fmt.Errorf("invalid Microsoft Teams unblock URL template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// renderTeamsUnblockURL renders the URL opened by the Unblock user action
+// for the provided alert using the provided template. An error is returned
+// if the rendered value is not an absolute http or https URL.
+func renderTeamsUnblockURL(tmpl *template.Template, alert events.SplunkAlertEvent) (string, error) {
+
+	var renderedTmpl bytes.Buffer
+	if err := tmpl.Execute(&renderedTmpl, alert); err != nil {
+		return "", fmt.Errorf("error rendering unblock URL template: %w", err)
+	}
+
+	unblockURL := strings.TrimSpace(renderedTmpl.String())
+	u, err := url.Parse(unblockURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("unblock URL template rendered %q; expected http or https URL", unblockURL)
+	}
+
+	return unblockURL, nil
+}
+
+// createTeamsAdaptiveCard receives an event Record and generates an Adaptive
+// Card message which is used to generate a Microsoft Teams message via a
+// Workflows webhook. The sections mirror those of MessageCard messages. The
+// View in Splunk, Approve/Reject and Unblock user actions are added when the
+// alert provides a results link, is pending approval or an unblock URL
+// template is provided, respectively.
+func createTeamsAdaptiveCard(record events.Record, unblockURL *template.Template) adaptiveCardMessage {
+
+	myFuncName := caller.GetFuncName()
+
+	log.Debugf("%s: alert received: %#v", myFuncName, record)
+
+	// getMsgTitle() and getMsgSummaryText() are used for all messages
+	titleBlock := adaptiveCardElement{
+		Type:   "TextBlock",
+		Text:   getMsgTitle(config.MyAppName+": ", record),
+		Size:   "Large",
+		Weight: "Bolder",
+		Wrap:   true,
+	}
+
+	if record.HighPriority() {
+		titleBlock.Color = "Attention"
+	}
+
+	card := adaptiveCard{
+		Schema:  adaptiveCardSchema,
+		Type:    "AdaptiveCard",
+		Version: adaptiveCardVersion,
+		Body: []adaptiveCardElement{
+			titleBlock,
+			{
+				Type: "TextBlock",
+				Text: getMsgSummaryText(record),
+				Wrap: true,
+			},
+		},
+		MSTeams: adaptiveCardMSTeams{Width: "Full"},
+	}
+
+	/*
+		Errors Section
+	*/
+
+	switch {
+	case record.Error != nil:
+		card.Body = append(card.Body, adaptiveCardSection(
+			"Disable User Request Errors",
+			"",
+			[]adaptiveCardFact{{Title: "Error", Value: record.Error.Error()}},
+		))
+	default:
+		card.Body = append(card.Body, adaptiveCardSection("Disable User Request Errors", "None", nil))
+	}
+
+	// If Session Termination is enabled, create Termination Results section
+	if record.SessionTerminationResults != nil {
+
+		resultsText := getTerminationResultsList(record.SessionTerminationResults)
+		if record.Verification != nil {
+			resultsText = fmt.Sprintf("Verification: %s\n\n%s", record.Verification, resultsText)
+		}
+
+		if scope := record.TerminationScope(); scope != "" {
+			resultsText = fmt.Sprintf("Scope: %s\n\n%s", scope, resultsText)
+		}
+
+		card.Body = append(card.Body, adaptiveCardSection("Session Termination Results", resultsText, nil))
+	}
+
+	// If any hooks were run for this event, create Hook Results section
+	if record.HookResults != nil {
+		card.Body = append(
+			card.Body,
+			adaptiveCardSection("Hook Results", getHookResultsList(record.HookResults), nil),
+		)
+	}
+
+	/*
+		Disable User Request Details Section - Core of alert details
+	*/
+
+	details := []adaptiveCardFact{
+		{Title: "Username", Value: record.Alert.Username},
+		{Title: "User IP", Value: record.Alert.UserIP},
+		{Title: "Alert/Search Name", Value: record.Alert.AlertName},
+		{Title: "Alert/Search ID", Value: record.Alert.SearchID},
+	}
+
+	if record.Alert.Tenant != "" {
+		details = append(details, adaptiveCardFact{Title: "Tenant", Value: record.Alert.Tenant})
+	}
+
+	if record.Alert.Policy != nil {
+		details = append(details, adaptiveCardFact{Title: "Alert Policy", Value: record.Alert.Policy.String()})
+	}
+
+	if len(record.Alert.Instances) > 0 {
+		details = append(details, adaptiveCardFact{
+			Title: "EZproxy Instances",
+			Value: strings.Join(record.Alert.Instances, ", "),
+		})
+	}
+
+	if record.Alert.ReportThreshold != nil {
+		details = append(details, adaptiveCardFact{
+			Title: "Report Threshold",
+			Value: record.Alert.ReportThreshold.String(),
+		})
+	}
+
+	if record.Alert.Approval != nil {
+		details = append(details, adaptiveCardFact{Title: "Approval", Value: record.Alert.Approval.String()})
+	}
+
+	if record.Alert.CircuitBreaker != nil {
+		details = append(details, adaptiveCardFact{
+			Title: "Circuit Breaker",
+			Value: record.Alert.CircuitBreaker.String(),
+		})
+	}
+
+	card.Body = append(card.Body, adaptiveCardSection("Disable User Request Details", "", details))
+
+	/*
+		Alert Request Summary Section - General client request details
+	*/
+
+	requestSummary := []adaptiveCardFact{
+		{Title: "Received at", Value: record.Alert.LocalTime},
+		{Title: "Endpoint path", Value: record.Alert.EndpointPath},
+		{Title: "HTTP Method", Value: record.Alert.HTTPMethod},
+		{Title: "Alert Sender IP", Value: record.Alert.PayloadSenderIP},
+	}
+
+	if record.Operator != "" {
+		requestSummary = append(requestSummary, adaptiveCardFact{Title: "Operator", Value: record.Operator})
+	}
+
+	card.Body = append(card.Body, adaptiveCardSection("Alert Request Summary", "", requestSummary))
+
+	/*
+		Alert Request Headers Section
+	*/
+
+	headerNames := make([]string, 0, len(record.Alert.Headers))
+	for header := range record.Alert.Headers {
+		headerNames = append(headerNames, header)
+	}
+	sort.Strings(headerNames)
+
+	requestHeaders := make([]adaptiveCardFact, 0, len(headerNames))
+	for _, header := range headerNames {
+		requestHeaders = append(requestHeaders, adaptiveCardFact{
+			Title: header,
+			Value: strings.Join(record.Alert.Headers[header], ", "),
+		})
+	}
+
+	card.Body = append(card.Body, adaptiveCardSection(
+		"Alert Request Headers",
+		fmt.Sprintf("%d alert request headers provided", len(record.Alert.Headers)),
+		requestHeaders,
+	))
+
+	/*
+		Message Branding/Trailer Section
+	*/
+
+	card.Body = append(card.Body, adaptiveCardElement{
+		Type:      "TextBlock",
+		Text:      config.MessageTrailer(config.BrandingMarkdownFormat),
+		Size:      "Small",
+		IsSubtle:  true,
+		Wrap:      true,
+		Separator: true,
+	})
+
+	/*
+		Actions
+	*/
+
+	if record.Alert.ResultsLink != "" {
+		card.Actions = append(card.Actions, adaptiveCardOpenURL("View in Splunk", record.Alert.ResultsLink, ""))
+	}
+
+	if record.Alert.Approval != nil && record.Alert.Approval.Pending() {
+		card.Actions = append(
+			card.Actions,
+			adaptiveCardOpenURL("Approve", record.Alert.Approval.ApproveURL, "positive"),
+			adaptiveCardOpenURL("Reject", record.Alert.Approval.RejectURL, "destructive"),
+		)
+	}
+
+	// Notifications not specific to a user (e.g., activity monitoring) do
+	// not offer to unblock one.
+	if unblockURL != nil && record.Alert.Username != "" {
+		actionURL, err := renderTeamsUnblockURL(unblockURL, record.Alert)
+		switch {
+		case err != nil:
+			log.Errorf("%s: omitting Unblock user action: %v", myFuncName, err)
+		default:
+			card.Actions = append(card.Actions, adaptiveCardOpenURL("Unblock user", actionURL, ""))
+		}
+	}
+
+	return adaptiveCardMessage{
+		Type: "message",
+		Attachments: []adaptiveCardAttachment{
+			{
+				ContentType: adaptiveCardContentType,
+				Content:     card,
+			},
+		},
+	}
+}
+
+// sendTeamsAdaptiveCard submits the provided Adaptive Card message to the
+// Teams Workflows webhook. This includes honoring the provided schedule in
+// order to comply with remote API rate limits.
+func sendTeamsAdaptiveCard(
+	ctx context.Context,
+	webhookURL string,
+	msg adaptiveCardMessage,
+	schedule time.Time,
+	retries int,
+	retriesDelay int,
+) NotifyResult {
+
+	myFuncName := caller.GetFuncName()
+
+	if webhookURL == "" {
+		return NotifyResult{
+			Err: fmt.Errorf(
+				"%s: webhookURL not defined, skipping message submission to Microsoft Teams channel",
+				myFuncName,
+			),
+			Success: false,
+		}
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return NotifyResult{
+			Err:     fmt.Errorf("%s: failed to encode Adaptive Card message: %w", myFuncName, err),
+			Success: false,
+		}
+	}
+
+	log.Debugf("%s: Time now is %v", myFuncName, time.Now().Format("15:04:05"))
+	log.Debugf("%s: Notification scheduled for: %v", myFuncName, schedule.Format("15:04:05"))
+
+	// Set delay timer to meet received notification schedule. This helps
+	// ensure that we delay the appropriate amount of time before we make our
+	// first attempt at sending a message to Microsoft Teams.
+	notificationDelay := time.Until(schedule)
+
+	notificationDelayTimer := time.NewTimer(notificationDelay)
+	defer notificationDelayTimer.Stop()
+
+	select {
+	case <-ctx.Done():
+		ctxErr := ctx.Err()
+		result := NotifyResult{
+			Val: fmt.Sprintf("%s: Received Done signal at %v: %v, shutting down",
+				myFuncName,
+				time.Now().Format("15:04:05"),
+				ctxErr.Error(),
+			),
+			Success: false,
+		}
+		log.Debug(result.Val)
+		return result
+
+	// Delay between message submission attempts; this will *always*
+	// delay, regardless of whether the attempt is the first one or not
+	case <-notificationDelayTimer.C:
+
+		log.Debugf("%s: Waited %v before notification attempt at %v",
+			myFuncName,
+			notificationDelay,
+			time.Now().Format("15:04:05"),
+		)
+
+		// check to see if context has expired during our delay
+		if ctx.Err() != nil {
+			result := NotifyResult{
+				Val: fmt.Sprintf(
+					"%s: context expired or cancelled at %v: %v, attempting to abort message submission",
+					myFuncName,
+					time.Now().Format("15:04:05"),
+					ctx.Err().Error(),
+				),
+				Success: false,
+			}
+
+			log.Debug(result.Val)
+
+			return result
+		}
+
+		// Workflows webhooks are plain HTTP endpoints which respond with
+		// 202 Accepted, so the same submission and retry logic as generic
+		// webhooks applies.
+		teamsCfg := webhookConfig{
+			url:                    webhookURL,
+			timeout:                config.NotifyMgrTeamsNotificationTimeout,
+			notificationRetries:    retries,
+			notificationRetryDelay: retriesDelay,
+		}
+
+		if err := sendWebhook(ctx, teamsCfg, payload); err != nil {
+			errMsg := NotifyResult{
+				Err: fmt.Errorf(
+					"%s: ERROR: Failed to submit message to Microsoft Teams at %v: %v",
+					myFuncName,
+					time.Now().Format("15:04:05"),
+					err,
+				),
+				Success: false,
+			}
+			log.Error(errMsg.Err.Error())
+			return errMsg
+		}
+
+		successMsg := NotifyResult{
+			Val: fmt.Sprintf(
+				"%s: Message successfully sent to Microsoft Teams at %v",
+				myFuncName,
+				time.Now().Format("15:04:05"),
+			),
+			Success: true,
+		}
+
+		// Note success for potential troubleshooting
+		log.Debug(successMsg.Val)
+
+		return successMsg
+
+	}
+
+}
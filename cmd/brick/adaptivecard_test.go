@@ -0,0 +1,206 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"text/template"
+
+	"github.com/atc0005/brick/events"
+)
+
+func TestCreateTeamsAdaptiveCardEnvelope(t *testing.T) {
+
+	record := testRecord()
+	record.Action = events.ActionSkippedCircuitBreakerTripped
+	record.Error = errors.New("circuit breaker open")
+
+	body, err := json.Marshal(createTeamsAdaptiveCard(record, nil))
+	if err != nil {
+		t.Fatalf("failed to encode Adaptive Card message: %v", err)
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatalf("failed to decode Adaptive Card message %s: %v", body, err)
+	}
+
+	if msg["type"] != "message" {
+		t.Errorf("message type = %v; want %q", msg["type"], "message")
+	}
+
+	attachments, ok := msg["attachments"].([]interface{})
+	if !ok || len(attachments) != 1 {
+		t.Fatalf("message attachments = %v; want one attachment", msg["attachments"])
+	}
+	attachment := attachments[0].(map[string]interface{})
+
+	if attachment["contentType"] != adaptiveCardContentType {
+		t.Errorf("attachment contentType = %v; want %q", attachment["contentType"], adaptiveCardContentType)
+	}
+
+	// Workflows webhooks expect contentUrl to be present, even if null
+	if contentURL, ok := attachment["contentUrl"]; !ok || contentURL != nil {
+		t.Errorf("attachment contentUrl = %v (present: %t); want null", contentURL, ok)
+	}
+
+	card := attachment["content"].(map[string]interface{})
+	for key, want := range map[string]interface{}{
+		"$schema": adaptiveCardSchema,
+		"type":    "AdaptiveCard",
+		"version": adaptiveCardVersion,
+		"msteams": map[string]interface{}{"width": "Full"},
+	} {
+		if !reflect.DeepEqual(card[key], want) {
+			t.Errorf("card %q = %v; want %v", key, card[key], want)
+		}
+	}
+
+	if _, ok := card["actions"]; ok {
+		t.Errorf("card actions = %v; want none", card["actions"])
+	}
+
+	cardBody := card["body"].([]interface{})
+	title := cardBody[0].(map[string]interface{})
+	if title["color"] != "Attention" {
+		t.Errorf("title color = %v; want %q for high priority record", title["color"], "Attention")
+	}
+
+	// the error is listed as a fact in the first section
+	errorsSection := cardBody[2].(map[string]interface{})
+	items := errorsSection["items"].([]interface{})
+	facts := items[len(items)-1].(map[string]interface{})["facts"]
+	wantFacts := []interface{}{map[string]interface{}{"title": "Error", "value": "circuit breaker open"}}
+	if !reflect.DeepEqual(facts, wantFacts) {
+		t.Errorf("errors section facts = %v; want %v", facts, wantFacts)
+	}
+}
+
+func TestCreateTeamsAdaptiveCardActions(t *testing.T) {
+
+	unblockURL := template.Must(
+		template.New("unblock").Parse("https://helpdesk.example.com/unblock?user={{ urlquery .Username }}"),
+	)
+	invalidUnblockURL := template.Must(template.New("unblock").Parse("{{ .Username }}"))
+
+	pending := &events.Approval{
+		ApproveURL: "https://brick.example.com/approve",
+		RejectURL:  "https://brick.example.com/reject",
+	}
+	decided := &events.Approval{
+		ApproveURL: "https://brick.example.com/approve",
+		RejectURL:  "https://brick.example.com/reject",
+		Decision:   events.ApprovalDecisionApprove,
+	}
+
+	tests := []struct {
+		name       string
+		alert      events.SplunkAlertEvent
+		unblockURL *template.Template
+		want       []adaptiveCardAction
+	}{
+		{
+			name:  "no actions",
+			alert: events.SplunkAlertEvent{Username: "jsmith"},
+		},
+		{
+			name:  "results link",
+			alert: events.SplunkAlertEvent{Username: "jsmith", ResultsLink: "https://splunk.example.com/results"},
+			want: []adaptiveCardAction{
+				{Type: "Action.OpenUrl", Title: "View in Splunk", URL: "https://splunk.example.com/results"},
+			},
+		},
+		{
+			name:  "pending approval",
+			alert: events.SplunkAlertEvent{Username: "jsmith", Approval: pending},
+			want: []adaptiveCardAction{
+				{Type: "Action.OpenUrl", Title: "Approve", URL: "https://brick.example.com/approve", Style: "positive"},
+				{Type: "Action.OpenUrl", Title: "Reject", URL: "https://brick.example.com/reject", Style: "destructive"},
+			},
+		},
+		{
+			name:  "decided approval",
+			alert: events.SplunkAlertEvent{Username: "jsmith", Approval: decided},
+		},
+		{
+			name:       "unblock user",
+			alert:      events.SplunkAlertEvent{Username: "j smith&co"},
+			unblockURL: unblockURL,
+			want: []adaptiveCardAction{
+				{Type: "Action.OpenUrl", Title: "Unblock user", URL: "https://helpdesk.example.com/unblock?user=j+smith%26co"},
+			},
+		},
+		{
+			name:       "unblock without username",
+			alert:      events.SplunkAlertEvent{},
+			unblockURL: unblockURL,
+		},
+		{
+			name:       "unblock URL not rendered as URL",
+			alert:      events.SplunkAlertEvent{Username: "jsmith"},
+			unblockURL: invalidUnblockURL,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			msg := createTeamsAdaptiveCard(events.Record{Alert: tt.alert}, tt.unblockURL)
+
+			got := msg.Attachments[0].Content.Actions
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("card actions = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTeamsUnblockURLTemplate(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		template string
+		wantNil  bool
+		wantErr  bool
+	}{
+		{name: "not specified", template: "", wantNil: true},
+		{name: "valid", template: "https://helpdesk.example.com/unblock?user={{ urlquery .Username }}"},
+		{name: "invalid syntax", template: "https://helpdesk.example.com/{{ .Username", wantErr: true},
+		{name: "unknown field", template: "https://helpdesk.example.com/{{ .Bogus }}", wantErr: true},
+		{name: "relative URL", template: "/unblock?user={{ .Username }}", wantErr: true},
+		{name: "unsupported scheme", template: "mailto:helpdesk@example.com?subject={{ .Username }}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			tmpl, err := newTeamsUnblockURLTemplate(tt.template)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newTeamsUnblockURLTemplate(%q) returned error %v; want error: %t", tt.template, err, tt.wantErr)
+			}
+
+			if err == nil && (tmpl == nil) != tt.wantNil {
+				t.Errorf("newTeamsUnblockURLTemplate(%q) = %v; want nil: %t", tt.template, tmpl, tt.wantNil)
+			}
+		})
+	}
+}
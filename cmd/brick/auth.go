@@ -0,0 +1,135 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/apex/log"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/atc0005/brick/config"
+)
+
+// apiCredentials is a collection of operator names and bcrypt password
+// hashes permitted to use the management endpoints provided by this
+// application.
+type apiCredentials map[string]string
+
+// unknownOperatorHash is compared against the password provided for an
+// unknown operator name so that response timing does not reveal whether the
+// operator name is valid.
+var unknownOperatorHash, _ = bcrypt.GenerateFromPassword(
+	[]byte("unknown operator"),
+	bcrypt.DefaultCost,
+)
+
+// enabled indicates whether any operator credentials have been configured.
+// The management endpoints are disabled if none are present.
+func (ac apiCredentials) enabled() bool {
+	return len(ac) > 0
+}
+
+// authenticate checks the HTTP Basic Authentication credentials provided
+// with the request against the configured operator credentials. The operator
+// name is returned along with true if the credentials are valid.
+func (ac apiCredentials) authenticate(r *http.Request) (string, bool) {
+
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+
+	hash, found := ac[name]
+	if !found {
+		_ = bcrypt.CompareHashAndPassword(unknownOperatorHash, []byte(password))
+		return "", false
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return "", false
+	}
+
+	return name, true
+}
+
+// secretsEqual compares the provided secrets in constant time. The SHA-256
+// digests of the secrets are compared instead of the secrets themselves as
+// subtle.ConstantTimeCompare returns immediately if the lengths differ,
+// which would reveal the length of the expected secret.
+func secretsEqual(provided string, expected string) bool {
+
+	providedSum := sha256.Sum256([]byte(provided))
+	expectedSum := sha256.Sum256([]byte(expected))
+
+	return subtle.ConstantTimeCompare(providedSum[:], expectedSum[:]) == 1
+}
+
+// requireOperator is a helper function used by management endpoint handlers
+// to confirm that the request was submitted by a known operator. If not, an
+// appropriate response is sent to the client and false is returned;
+// the handler should return without further processing.
+func requireOperator(ac apiCredentials, w http.ResponseWriter, r *http.Request) (string, bool) {
+
+	ctxLog := log.WithFields(log.Fields{
+		"url_path":    r.URL.Path,
+		"http_method": r.Method,
+	})
+
+	if !ac.enabled() {
+		ctxLog.Debug("management endpoint requested, but no API users are configured")
+		http.Error(
+			w,
+			"Sorry, this endpoint is disabled. Please see the documentation for enabling management endpoints.",
+			http.StatusForbidden,
+		)
+		return "", false
+	}
+
+	operator, ok := ac.authenticate(r)
+	if !ok {
+		ctxLog.Warn("rejecting management endpoint request with missing or invalid credentials")
+		w.Header().Set(
+			"WWW-Authenticate",
+			fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, config.MyAppName),
+		)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return "", false
+	}
+
+	ctxLog.WithField("operator", operator).Debug("management endpoint request authenticated")
+
+	return operator, true
+}
+
+// payloadAuthenticated indicates whether an alert payload request includes
+// the provided auth secret as a bearer token in the Authorization header.
+// The secret is not accepted elsewhere (e.g., as a query parameter) as
+// request URLs are commonly recorded in proxy and access logs.
+func payloadAuthenticated(secret string, r *http.Request) bool {
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+
+	return secretsEqual(strings.TrimPrefix(auth, "Bearer "), secret)
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/atc0005/brick/config"
+	"github.com/atc0005/brick/files"
+)
+
+// newBlocklist returns the blocklist files and feed selected by the provided
+// configuration for the named tenant. Usernames are read from the disabled
+// users files of the provided EZproxy instances of the tenant and the
+// provided ignored sources are used to honor ignored IP Addresses.
+func newBlocklist(
+	appConfig *config.Config,
+	name string,
+	instances files.EZproxyInstances,
+	ignoredSources files.IgnoredSources,
+) *files.Blocklist {
+
+	blocklists := appConfig.TenantBlocklists(name)
+
+	blocklistFiles := make([]files.BlocklistFile, 0, len(blocklists))
+	for _, blocklist := range blocklists {
+
+		var renderer files.BlocklistRenderer
+		switch blocklist.Format {
+		case config.BlocklistFormatNginx:
+			renderer = files.NginxBlocklist{}
+		case config.BlocklistFormatApache:
+			renderer = files.ApacheBlocklist{}
+		case config.BlocklistFormatIPList:
+			renderer = files.IPListBlocklist{}
+		case config.BlocklistFormatJSON:
+			renderer = files.JSONBlocklist{}
+		}
+
+		blocklistFiles = append(blocklistFiles, files.BlocklistFile{
+			FilePath:    blocklist.FilePath,
+			Permissions: blocklist.FilePermissions,
+			Renderer:    renderer,
+		})
+	}
+
+	return files.NewBlocklist(
+		appConfig.TenantBlocklistStateFile(name),
+		time.Duration(appConfig.DisabledUsersBlocklistDuration())*time.Minute,
+		blocklistFiles,
+		appConfig.DisabledUsersBlocklistFeed(),
+		instances,
+		ignoredSources,
+	)
+}
@@ -18,3 +18,7 @@ package main
 
 // MB represents 1 Megabyte
 const MB int64 = 1048576
+
+// highPriorityThemeColor is the Microsoft Teams message card theme color
+// used for notifications requiring prompt attention.
+const highPriorityThemeColor string = "#D70000"
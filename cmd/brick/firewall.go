@@ -0,0 +1,56 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/atc0005/brick/config"
+	"github.com/atc0005/brick/files"
+)
+
+// newFirewallBackend returns the firewall backend selected by the provided
+// configuration or nil if blocking reported user IP Addresses is not
+// enabled.
+func newFirewallBackend(appConfig *config.Config) files.FirewallBackend {
+
+	timeout := time.Duration(appConfig.FirewallTimeout()) * time.Second
+
+	switch appConfig.FirewallBackend() {
+	case config.FirewallBackendIPSet:
+		return files.IPSetFirewall{
+			Executable: appConfig.FirewallExecutablePath(),
+			Set:        appConfig.FirewallSet(),
+			Timeout:    timeout,
+		}
+	case config.FirewallBackendNftables:
+		return files.NftablesFirewall{
+			Executable: appConfig.FirewallExecutablePath(),
+			Table:      appConfig.FirewallNftablesTable(),
+			Set:        appConfig.FirewallSet(),
+			Timeout:    timeout,
+		}
+	case config.FirewallBackendFail2ban:
+		return files.Fail2banFirewall{
+			Executable: appConfig.FirewallExecutablePath(),
+			Jail:       appConfig.FirewallFail2banJail(),
+			Timeout:    timeout,
+		}
+	default:
+		return nil
+	}
+}
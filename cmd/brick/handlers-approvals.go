@@ -0,0 +1,170 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/config"
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/files"
+)
+
+// approvalConfirmationTemplateText is the page shown when an operator
+// follows an approve or reject link. The decision is only applied once the
+// operator submits the form; this prevents link scanners and previews from
+// acting on pending approval requests.
+const approvalConfirmationTemplateText string = `<!DOCTYPE html>
+<html>
+<head><title>{{ .AppName }}: Confirm {{ .Decision }}</title></head>
+<body>
+<h1>Confirm {{ .Decision }}</h1>
+<ul>
+<li>Username: {{ .Alert.Username }}</li>
+<li>User IP: {{ .Alert.UserIP }}</li>
+<li>Alert/Search Name: {{ .Alert.AlertName }}</li>
+<li>Alert Policy: {{ .Alert.Policy }}</li>
+<li>Received at: {{ .Alert.LocalTime }}</li>
+<li>Expires: {{ .Expires }}</li>
+</ul>
+<form method="post">
+<input type="hidden" name="id" value="{{ .ID }}">
+<input type="hidden" name="decision" value="{{ .Decision }}">
+<input type="hidden" name="sig" value="{{ .Signature }}">
+<button type="submit">{{ .Decision }}</button>
+</form>
+</body>
+</html>
+`
+
+var approvalConfirmationTemplate = template.Must(
+	template.New("approvalConfirmation").Parse(approvalConfirmationTemplateText),
+)
+
+// approvalsHandler handles requests to approve or reject pending approval
+// requests using the signed links included in approval request
+// notifications. GET requests display a confirmation form; POST requests
+// apply the decision. All requests require valid operator credentials and a
+// valid signature. Each approval request may only be decided once.
+func approvalsHandler(
+	approvals *files.Approvals,
+	credentials apiCredentials,
+) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ctxLog := log.WithFields(log.Fields{
+			"url_path":    r.URL.Path,
+			"http_method": r.Method,
+		})
+
+		ctxLog.Debug("approvalsHandler endpoint hit")
+
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			ctxLog.Debug("unsupported HTTP method received on approvals endpoint")
+			errorMsg := fmt.Sprintf(
+				"Sorry, this endpoint only accepts %s or %s requests. "+
+					"Please use the links provided in the approval request notification.",
+				http.MethodGet,
+				http.MethodPost,
+			)
+			http.Error(w, errorMsg, http.StatusMethodNotAllowed)
+			return
+		}
+
+		operator, ok := requireOperator(credentials, w, r)
+		if !ok {
+			return
+		}
+
+		// Limit request body to 1 MB
+		r.Body = http.MaxBytesReader(w, r.Body, 1*MB)
+
+		id := r.FormValue("id")
+		decision := r.FormValue("decision")
+		signature := r.FormValue("sig")
+
+		switch decision {
+		case events.ApprovalDecisionApprove:
+		case events.ApprovalDecisionReject:
+		default:
+			http.Error(w, fmt.Sprintf("invalid decision %q", decision), http.StatusBadRequest)
+			return
+		}
+
+		if err := approvals.VerifySignature(id, decision, signature); err != nil {
+			ctxLog.WithField("operator", operator).Warnf("rejecting approval request %q: %v", id, err)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		switch r.Method {
+
+		case http.MethodGet:
+
+			alert, found := approvals.Lookup(id)
+			if !found {
+				http.Error(w, files.ErrApprovalNotFound.Error(), http.StatusNotFound)
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err := approvalConfirmationTemplate.Execute(w, struct {
+				AppName   string
+				ID        string
+				Decision  string
+				Signature string
+				Expires   string
+				Alert     events.SplunkAlertEvent
+			}{
+				AppName:   config.MyAppName,
+				ID:        id,
+				Decision:  decision,
+				Signature: signature,
+				Expires:   alert.Approval.Expires.Format(time.RFC3339),
+				Alert:     alert,
+			}); err != nil {
+				ctxLog.Errorf("failed to render approval confirmation page: %v", err)
+			}
+
+		case http.MethodPost:
+
+			alert, err := approvals.Decide(id, decision, operator)
+			switch {
+			case errors.Is(err, files.ErrApprovalNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case err != nil:
+				ctxLog.Error(err.Error())
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			default:
+				fmt.Fprintf(
+					w,
+					"OK: Disabling username %q %s\n",
+					alert.Username,
+					alert.Approval,
+				)
+			}
+		}
+
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/files"
+)
+
+func TestApprovalsHandler(t *testing.T) {
+
+	credentials := testOperatorCredentials(t, "operator", "correct-password")
+
+	tests := []struct {
+		name        string
+		method      string
+		username    string
+		password    string
+		link        func(approval events.Approval) string
+		decided     bool
+		wantStatus  int
+		wantBody    string
+		wantPending bool
+	}{
+		{
+			name:        "GET shows confirmation without deciding",
+			method:      http.MethodGet,
+			username:    "operator",
+			password:    "correct-password",
+			link:        func(a events.Approval) string { return a.ApproveURL },
+			wantStatus:  http.StatusOK,
+			wantBody:    `<input type="hidden" name="decision" value="approve">`,
+			wantPending: true,
+		},
+		{
+			name:       "POST approves",
+			method:     http.MethodPost,
+			username:   "operator",
+			password:   "correct-password",
+			link:       func(a events.Approval) string { return a.ApproveURL },
+			wantStatus: http.StatusOK,
+			wantBody:   `OK: Disabling username "jsmith"`,
+		},
+		{
+			name:       "POST rejects",
+			method:     http.MethodPost,
+			username:   "operator",
+			password:   "correct-password",
+			link:       func(a events.Approval) string { return a.RejectURL },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "POST replayed",
+			method:     http.MethodPost,
+			username:   "operator",
+			password:   "correct-password",
+			link:       func(a events.Approval) string { return a.ApproveURL },
+			decided:    true,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "GET after decision",
+			method:     http.MethodGet,
+			username:   "operator",
+			password:   "correct-password",
+			link:       func(a events.Approval) string { return a.RejectURL },
+			decided:    true,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:     "tampered signature",
+			method:   http.MethodPost,
+			username: "operator",
+			password: "correct-password",
+			link: func(a events.Approval) string {
+				// reuse the reject signature to approve
+				return strings.Replace(a.RejectURL, "decision=reject", "decision=approve", 1)
+			},
+			wantStatus:  http.StatusForbidden,
+			wantPending: true,
+		},
+		{
+			name:        "wrong password",
+			method:      http.MethodPost,
+			username:    "operator",
+			password:    "wrong-password",
+			link:        func(a events.Approval) string { return a.ApproveURL },
+			wantStatus:  http.StatusUnauthorized,
+			wantPending: true,
+		},
+		{
+			name:        "unknown operator",
+			method:      http.MethodPost,
+			username:    "intruder",
+			password:    "correct-password",
+			link:        func(a events.Approval) string { return a.ApproveURL },
+			wantStatus:  http.StatusUnauthorized,
+			wantPending: true,
+		},
+		{
+			name:        "missing credentials",
+			method:      http.MethodPost,
+			link:        func(a events.Approval) string { return a.ApproveURL },
+			wantStatus:  http.StatusUnauthorized,
+			wantPending: true,
+		},
+		{
+			name:        "unsupported method",
+			method:      http.MethodPut,
+			username:    "operator",
+			password:    "correct-password",
+			link:        func(a events.Approval) string { return a.ApproveURL },
+			wantStatus:  http.StatusMethodNotAllowed,
+			wantPending: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			dir := t.TempDir()
+			approvals := files.NewApprovals(
+				filepath.Join(dir, "pending-approvals.json"),
+				"https://brick.example.org:8000",
+				"approvals-secret",
+				time.Hour,
+				events.ApprovalDecisionReject,
+				files.NewReportedUserEventsLog(filepath.Join(dir, "users.brick-reported.log"), 0600),
+				make(chan events.Record, 10),
+			)
+			if err := approvals.Start(func(events.SplunkAlertEvent) {}); err != nil {
+				t.Fatalf("Start() returned error: %v", err)
+			}
+
+			record := approvals.Request(events.SplunkAlertEvent{Username: "jsmith", UserIP: "192.0.2.10"})
+			if record.Error != nil {
+				t.Fatalf("Request() returned error record: %v", record.Error)
+			}
+			approval := *record.Alert.Approval
+
+			if tt.decided {
+				if _, err := approvals.Decide(approval.ID, events.ApprovalDecisionReject, "operator"); err != nil {
+					t.Fatalf("Decide() returned error: %v", err)
+				}
+			}
+
+			link, err := url.Parse(tt.link(approval))
+			if err != nil {
+				t.Fatalf("failed to parse approval link: %v", err)
+			}
+
+			req := httptest.NewRequest(tt.method, link.RequestURI(), nil)
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+			rec := httptest.NewRecorder()
+
+			approvalsHandler(approvals, credentials).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d; want %d (body: %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q; want it to contain %q", rec.Body.String(), tt.wantBody)
+			}
+
+			if _, pending := approvals.Lookup(approval.ID); pending != tt.wantPending {
+				t.Errorf("approval request pending = %t; want %t", pending, tt.wantPending)
+			}
+		})
+	}
+}
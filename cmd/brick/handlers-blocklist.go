@@ -0,0 +1,64 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/files"
+)
+
+// blocklistHandler serves the usernames listed in the disabled users files
+// and the IP Addresses blocked by this application as a JSON feed for other
+// systems to consume. All requests require valid operator credentials.
+func blocklistHandler(blocklist *files.Blocklist, credentials apiCredentials) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ctxLog := log.WithFields(log.Fields{
+			"url_path":    r.URL.Path,
+			"http_method": r.Method,
+		})
+
+		ctxLog.Debug("blocklistHandler endpoint hit")
+
+		if r.Method != http.MethodGet {
+			ctxLog.Debug("non-GET request received on blocklist endpoint")
+			errorMsg := fmt.Sprintf(
+				"Sorry, this endpoint only accepts %s requests. "+
+					"Please see the README for examples and then try again.",
+				http.MethodGet,
+			)
+			http.Error(w, errorMsg, http.StatusMethodNotAllowed)
+			return
+		}
+
+		if _, ok := requireOperator(credentials, w, r); !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(blocklist.Entries()); err != nil {
+			ctxLog.Errorf("failed to encode blocklist response: %v", err)
+		}
+
+	}
+}
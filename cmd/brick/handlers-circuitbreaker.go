@@ -0,0 +1,138 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/files"
+)
+
+// circuitBreakerAlertName is used in place of the alert/search name for
+// events generated by resetting the circuit breaker.
+const circuitBreakerAlertName string = "Circuit breaker management"
+
+// circuitBreakerStatusResponse represents the state of the mass-disable
+// circuit breaker as returned by the health endpoint.
+type circuitBreakerStatusResponse struct {
+	State         string `json:"state"`
+	MaxDisables   int    `json:"max_disables"`
+	WindowMinutes int    `json:"window_minutes"`
+	Disables      int    `json:"disables"`
+	TrippedAt     string `json:"tripped_at,omitempty"`
+	TrippedBy     string `json:"tripped_by,omitempty"`
+	Suppressed    int    `json:"suppressed"`
+	Error         string `json:"error,omitempty"`
+}
+
+// newCircuitBreakerStatusResponse converts a circuit breaker status into the
+// format returned to clients. Any error encountered while retrieving the
+// status is included.
+func newCircuitBreakerStatusResponse(status events.CircuitBreakerStatus, err error) circuitBreakerStatusResponse {
+
+	resp := circuitBreakerStatusResponse{
+		State:         status.State(),
+		MaxDisables:   status.MaxDisables,
+		WindowMinutes: int(status.Window / time.Minute),
+		Disables:      status.Disables,
+		TrippedBy:     status.TrippedBy,
+		Suppressed:    status.Suppressed,
+	}
+
+	if !status.TrippedAt.IsZero() {
+		resp.TrippedAt = status.TrippedAt.Format(time.RFC3339)
+	}
+
+	if err != nil {
+		resp.Error = err.Error()
+	}
+
+	return resp
+}
+
+// circuitBreakerResetHandler handles requests to reset the mass-disable
+// circuit breaker. All requests require valid operator credentials. The
+// reset is recorded in the reported user events log of the named tenant and
+// sent as a notification along with the operator name.
+func circuitBreakerResetHandler(
+	tenant string,
+	circuitBreaker *files.CircuitBreaker,
+	credentials apiCredentials,
+	reportedUserEventsLog *files.ReportedUserEventsLog,
+	notifyWorkQueue chan<- events.Record,
+) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ctxLog := log.WithFields(log.Fields{
+			"url_path":    r.URL.Path,
+			"http_method": r.Method,
+		})
+
+		ctxLog.Debug("circuitBreakerResetHandler endpoint hit")
+
+		if r.Method != http.MethodPost {
+			ctxLog.Debug("non-POST request received on circuit breaker reset endpoint")
+			errorMsg := fmt.Sprintf(
+				"Sorry, this endpoint only accepts %s requests. "+
+					"Please see the README for examples and then try again.",
+				http.MethodPost,
+			)
+			http.Error(w, errorMsg, http.StatusMethodNotAllowed)
+			return
+		}
+
+		operator, ok := requireOperator(credentials, w, r)
+		if !ok {
+			return
+		}
+
+		headers := r.Header.Clone()
+		headers.Del("Authorization")
+
+		alert := events.SplunkAlertEvent{
+			PayloadSenderIP: events.GetIP(r),
+			ArrivalTime:     time.Now().Format(time.RFC3339),
+			LocalTime:       time.Now().Format("2006-01-02 15:04:05"),
+			AlertName:       circuitBreakerAlertName,
+			EndpointPath:    r.URL.Path,
+			HTTPMethod:      r.Method,
+			Headers:         headers,
+			Tenant:          tenant,
+		}
+
+		status, err := files.ProcessCircuitBreakerResetEvent(
+			alert,
+			operator,
+			circuitBreaker,
+			reportedUserEventsLog,
+			notifyWorkQueue,
+		)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprintf(w, "OK: Reset circuit breaker (previously %s)\n", status)
+
+	}
+}
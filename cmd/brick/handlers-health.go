@@ -0,0 +1,105 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/config"
+	"github.com/atc0005/brick/files"
+)
+
+// This is a set of constants used to indicate the overall health of this
+// application as reported by the health endpoint.
+const (
+	healthStatusOK       string = "ok"
+	healthStatusDegraded string = "degraded"
+)
+
+// healthResponse represents the JSON payload returned by the health
+// endpoint.
+type healthResponse struct {
+	Status         string                       `json:"status"`
+	Version        string                       `json:"version"`
+	CircuitBreaker circuitBreakerStatusResponse `json:"circuit_breaker"`
+	Activity       []activitySenderStatus       `json:"activity,omitempty"`
+}
+
+// healthHandler reports the overall health of this application, including
+// the state of the mass-disable circuit breaker and, if enabled, when alert
+// payloads were last received. This endpoint does not require
+// authentication. If the circuit breaker is open (or its state cannot be
+// determined) or no alert payloads have been received within the activity
+// window the application is reported as degraded.
+func healthHandler(circuitBreaker *files.CircuitBreaker, activity *activityTracker) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ctxLog := log.WithFields(log.Fields{
+			"url_path":    r.URL.Path,
+			"http_method": r.Method,
+		})
+
+		ctxLog.Debug("healthHandler endpoint hit")
+
+		if r.Method != http.MethodGet {
+			ctxLog.Debug("non-GET request received on health endpoint")
+			errorMsg := fmt.Sprintf(
+				"Sorry, this endpoint only accepts %s requests. "+
+					"Please see the README for examples and then try again.",
+				http.MethodGet,
+			)
+			http.Error(w, errorMsg, http.StatusMethodNotAllowed)
+			return
+		}
+
+		status, err := circuitBreaker.Status()
+
+		resp := healthResponse{
+			Status:         healthStatusOK,
+			Version:        config.Version,
+			CircuitBreaker: newCircuitBreakerStatusResponse(status, err),
+		}
+
+		statusCode := http.StatusOK
+		if err != nil || status.Open {
+			resp.Status = healthStatusDegraded
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		if activity.enabled() {
+			resp.Activity = activity.status()
+			for _, sender := range resp.Activity {
+				if sender.Quiet {
+					resp.Status = healthStatusDegraded
+					statusCode = http.StatusServiceUnavailable
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			ctxLog.Errorf("failed to encode health response: %v", err)
+		}
+
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/files"
+)
+
+// ignoredListAlertName is used in place of the alert/search name for events
+// generated by the ignored entries management endpoints. These events are
+// not triggered by a remote monitoring system.
+const ignoredListAlertName string = "Ignore list management"
+
+// ignoredEntryRequest represents the JSON payload accepted by the ignored
+// entries management endpoints when adding a new entry.
+type ignoredEntryRequest struct {
+	Value   string `json:"value"`
+	Expires string `json:"expires"`
+	Owner   string `json:"owner"`
+	Reason  string `json:"reason"`
+}
+
+// ignoredEntryResponse represents a single ignored entry as returned by the
+// ignored entries management endpoints.
+type ignoredEntryResponse struct {
+	Value   string `json:"value"`
+	Expires string `json:"expires,omitempty"`
+	Owner   string `json:"owner,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Expired bool   `json:"expired"`
+}
+
+// newIgnoredEntryResponse converts an IgnoredEntry into the format returned
+// to clients of the ignored entries management endpoints.
+func newIgnoredEntryResponse(entry files.IgnoredEntry, now time.Time) ignoredEntryResponse {
+
+	resp := ignoredEntryResponse{
+		Value:   entry.Value,
+		Owner:   entry.Owner,
+		Reason:  entry.Reason,
+		Expired: entry.Expired(now),
+	}
+
+	if !entry.Expires.IsZero() {
+		resp.Expires = entry.Expires.Format(time.RFC3339)
+	}
+
+	return resp
+}
+
+// ignoredEntriesHandler handles requests to list (GET), add (POST) or remove
+// (DELETE) entries from the specified ignored entries file. The ipAddresses
+// flag indicates whether the file contains IP Addresses (vs usernames) and
+// controls how new entries are validated. All requests require valid
+// operator credentials. Changes are recorded in the reported user events log
+// and sent as notifications along with the operator name and the name of
+// the tenant, if any.
+func ignoredEntriesHandler(
+	tenant string,
+	ignoredEntriesFile string,
+	ipAddresses bool,
+	credentials apiCredentials,
+	reportedUserEventsLog *files.ReportedUserEventsLog,
+	notifyWorkQueue chan<- events.Record,
+) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ctxLog := log.WithFields(log.Fields{
+			"url_path":    r.URL.Path,
+			"http_method": r.Method,
+		})
+
+		ctxLog.Debug("ignoredEntriesHandler endpoint hit")
+
+		validateValue := files.ValidateIgnoredUsername
+		if ipAddresses {
+			validateValue = files.ValidateIgnoredIPAddress
+		}
+
+		operator, ok := requireOperator(credentials, w, r)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+
+		case http.MethodGet:
+
+			entries, err := files.ReadIgnoredEntries(ignoredEntriesFile)
+			if err != nil {
+				ctxLog.Error(err.Error())
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			now := time.Now()
+			resp := make([]ignoredEntryResponse, 0, len(entries))
+			for _, entry := range entries {
+				resp = append(resp, newIgnoredEntryResponse(entry, now))
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(resp); err != nil {
+				ctxLog.Errorf("failed to encode ignored entries response: %v", err)
+			}
+
+		case http.MethodPost:
+
+			// Limit request body to 1 MB
+			r.Body = http.MaxBytesReader(w, r.Body, 1*MB)
+
+			var req ignoredEntryRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				ctxLog.Errorf("Error decoding r.Body into ignoredEntryRequest: %v", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if err := validateValue(req.Value); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			entry := files.IgnoredEntry{
+				Value:  req.Value,
+				Owner:  req.Owner,
+				Reason: req.Reason,
+			}
+
+			// default to the operator making the request so that there is
+			// always someone to ask about the entry
+			if entry.Owner == "" {
+				entry.Owner = operator
+			}
+
+			if req.Expires != "" {
+				expires, err := files.ParseIgnoredEntryExpiration(req.Expires)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				entry.Expires = expires
+			}
+
+			if err := entry.Validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if err := files.ProcessAddIgnoredEntryEvent(
+				newIgnoredEntryAlert(r, tenant, entry.Value, ipAddresses),
+				operator,
+				ignoredEntriesFile,
+				entry,
+				reportedUserEventsLog,
+				notifyWorkQueue,
+			); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			if err := json.NewEncoder(w).Encode(newIgnoredEntryResponse(entry, time.Now())); err != nil {
+				ctxLog.Errorf("failed to encode ignored entry response: %v", err)
+			}
+
+		case http.MethodDelete:
+
+			value := r.URL.Query().Get("value")
+			if value == "" {
+				http.Error(w, "missing required value query parameter", http.StatusBadRequest)
+				return
+			}
+
+			removed, err := files.ProcessRemoveIgnoredEntryEvent(
+				newIgnoredEntryAlert(r, tenant, value, ipAddresses),
+				operator,
+				ignoredEntriesFile,
+				value,
+				reportedUserEventsLog,
+				notifyWorkQueue,
+			)
+
+			switch {
+			case err != nil:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			case !removed:
+				http.Error(
+					w,
+					fmt.Sprintf("no entry for %q found in %q", value, ignoredEntriesFile),
+					http.StatusNotFound,
+				)
+			default:
+				fmt.Fprintf(w, "OK: Removed %q from %q\n", value, ignoredEntriesFile)
+			}
+
+		default:
+
+			ctxLog.Debug("unsupported HTTP method received on ignored entries endpoint")
+			errorMsg := fmt.Sprintf(
+				"Sorry, this endpoint only accepts %s, %s or %s requests. "+
+					"Please see the README for examples and then try again.",
+				http.MethodGet,
+				http.MethodPost,
+				http.MethodDelete,
+			)
+			http.Error(w, errorMsg, http.StatusMethodNotAllowed)
+		}
+
+	}
+}
+
+// newIgnoredEntryAlert builds an alert from the details of a management
+// request so that changes to the ignored entries files can be logged and
+// reported using the same notifications as received alerts. The value is
+// recorded as the username or IP Address depending on which file is being
+// managed. The Authorization header is omitted from the recorded headers.
+// The alert is recorded as received by the named tenant, if any.
+func newIgnoredEntryAlert(r *http.Request, tenant string, value string, ipAddresses bool) events.SplunkAlertEvent {
+
+	headers := r.Header.Clone()
+	headers.Del("Authorization")
+
+	alert := events.SplunkAlertEvent{
+		PayloadSenderIP: events.GetIP(r),
+		ArrivalTime:     time.Now().Format(time.RFC3339),
+		LocalTime:       time.Now().Format("2006-01-02 15:04:05"),
+		AlertName:       ignoredListAlertName,
+		EndpointPath:    r.URL.Path,
+		HTTPMethod:      r.Method,
+		Headers:         headers,
+		Tenant:          tenant,
+	}
+
+	switch {
+	case ipAddresses:
+		alert.UserIP = value
+	default:
+		alert.Username = value
+	}
+
+	return alert
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/files"
+)
+
+func TestIgnoredEntriesHandler(t *testing.T) {
+
+	credentials := testOperatorCredentials(t, "operator", "correct-password")
+
+	initialLines := []string{
+		"jsmith | owner=adoe",
+		"broken | expires=someday",
+		"rjones",
+	}
+
+	tests := []struct {
+		name        string
+		credentials apiCredentials
+		method      string
+		target      string
+		body        string
+		username    string
+		password    string
+		wantStatus  int
+		wantValues  []string
+		wantEntries []string
+	}{
+		{
+			name:        "list with correct credentials",
+			credentials: credentials,
+			method:      http.MethodGet,
+			target:      "/api/v1/users/ignored",
+			username:    "operator",
+			password:    "correct-password",
+			wantStatus:  http.StatusOK,
+			wantValues:  []string{"jsmith", "rjones"},
+			wantEntries: initialLines,
+		},
+		{
+			name:        "list with wrong password",
+			credentials: credentials,
+			method:      http.MethodGet,
+			target:      "/api/v1/users/ignored",
+			username:    "operator",
+			password:    "wrong-password",
+			wantStatus:  http.StatusUnauthorized,
+			wantEntries: initialLines,
+		},
+		{
+			name:        "list without credentials",
+			credentials: credentials,
+			method:      http.MethodGet,
+			target:      "/api/v1/users/ignored",
+			wantStatus:  http.StatusUnauthorized,
+			wantEntries: initialLines,
+		},
+		{
+			name:        "list with no API users configured",
+			credentials: apiCredentials{},
+			method:      http.MethodGet,
+			target:      "/api/v1/users/ignored",
+			username:    "operator",
+			password:    "correct-password",
+			wantStatus:  http.StatusForbidden,
+			wantEntries: initialLines,
+		},
+		{
+			name:        "add with correct credentials",
+			credentials: credentials,
+			method:      http.MethodPost,
+			target:      "/api/v1/users/ignored",
+			body:        `{"value": "bwilliams", "reason": "service account"}`,
+			username:    "operator",
+			password:    "correct-password",
+			wantStatus:  http.StatusCreated,
+			wantEntries: append(
+				append([]string{}, initialLines...),
+				"bwilliams | owner=operator | reason=service account",
+			),
+		},
+		{
+			name:        "add with wrong password",
+			credentials: credentials,
+			method:      http.MethodPost,
+			target:      "/api/v1/users/ignored",
+			body:        `{"value": "bwilliams"}`,
+			username:    "operator",
+			password:    "wrong-password",
+			wantStatus:  http.StatusUnauthorized,
+			wantEntries: initialLines,
+		},
+		{
+			name:        "add with unknown operator",
+			credentials: credentials,
+			method:      http.MethodPost,
+			target:      "/api/v1/users/ignored",
+			body:        `{"value": "bwilliams"}`,
+			username:    "intruder",
+			password:    "correct-password",
+			wantStatus:  http.StatusUnauthorized,
+			wantEntries: initialLines,
+		},
+		{
+			name:        "remove with correct credentials",
+			credentials: credentials,
+			method:      http.MethodDelete,
+			target:      "/api/v1/users/ignored?value=JSMITH",
+			username:    "operator",
+			password:    "correct-password",
+			wantStatus:  http.StatusOK,
+			wantEntries: initialLines[1:],
+		},
+		{
+			name:        "remove with wrong password",
+			credentials: credentials,
+			method:      http.MethodDelete,
+			target:      "/api/v1/users/ignored?value=jsmith",
+			username:    "operator",
+			password:    "wrong-password",
+			wantStatus:  http.StatusUnauthorized,
+			wantEntries: initialLines,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			dir := t.TempDir()
+			ignoredUsersFile := filepath.Join(dir, "users.brick-ignored.txt")
+			if err := ioutil.WriteFile(ignoredUsersFile, []byte(strings.Join(initialLines, "\n")+"\n"), 0600); err != nil {
+				t.Fatalf("failed to write ignored users file: %v", err)
+			}
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+			rec := httptest.NewRecorder()
+
+			handler := ignoredEntriesHandler(
+				"",
+				ignoredUsersFile,
+				false,
+				tt.credentials,
+				files.NewReportedUserEventsLog(filepath.Join(dir, "users.brick-reported.log"), 0600),
+				make(chan events.Record, 10),
+			)
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d (body: %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			if tt.wantValues != nil {
+				var resp []ignoredEntryResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				values := make([]string, 0, len(resp))
+				for _, entry := range resp {
+					values = append(values, entry.Value)
+				}
+				if strings.Join(values, ",") != strings.Join(tt.wantValues, ",") {
+					t.Errorf("listed values = %q; want %q", values, tt.wantValues)
+				}
+			}
+
+			content, err := ioutil.ReadFile(ignoredUsersFile)
+			if err != nil {
+				t.Fatalf("failed to read ignored users file: %v", err)
+			}
+			gotEntries := strings.Split(strings.TrimSpace(string(content)), "\n")
+			if strings.Join(gotEntries, "\n") != strings.Join(tt.wantEntries, "\n") {
+				t.Errorf("ignored users file = %q; want %q", gotEntries, tt.wantEntries)
+			}
+		})
+	}
+}
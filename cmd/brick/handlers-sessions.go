@@ -0,0 +1,219 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/atc0005/go-ezproxy"
+
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/files"
+)
+
+// sessionsAlertName is used in place of the alert/search name for events
+// generated by the sessions management endpoints. These events are not
+// triggered by a remote monitoring system.
+const sessionsAlertName string = "Session management"
+
+var sessionIDRegex = regexp.MustCompile("^" + ezproxy.SessionIDRegex + "$")
+
+// sessionResponse represents a single session as returned by the sessions
+// management endpoints.
+type sessionResponse struct {
+	SessionID string `json:"session_id"`
+	Username  string `json:"username"`
+	IPAddress string `json:"ip_address"`
+	Instance  string `json:"instance"`
+}
+
+// sessionsHandler handles requests to list (GET) sessions recorded in the
+// active file of each EZproxy instance or terminate (DELETE) a specific session. Sessions may
+// be listed by username and IP Address using the username and ip query
+// parameters. The session to terminate is given as the last element of the
+// URL path. All requests require valid operator credentials since session
+// IDs can be used to hijack sessions. Terminations are recorded in the
+// reported user events log and sent as notifications along with the operator
+// name. Terminations are recorded as requested via the endpoints of the named
+// tenant, if any.
+func sessionsHandler(
+	tenant string,
+	credentials apiCredentials,
+	reportedUserEventsLog *files.ReportedUserEventsLog,
+	notifyWorkQueue chan<- events.Record,
+	instances files.EZproxyInstances,
+	ezproxyVerifyDelay int,
+) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		ctxLog := log.WithFields(log.Fields{
+			"url_path":    r.URL.Path,
+			"http_method": r.Method,
+		})
+
+		ctxLog.Debug("sessionsHandler endpoint hit")
+
+		operator, ok := requireOperator(credentials, w, r)
+		if !ok {
+			return
+		}
+
+		sessionID := strings.TrimPrefix(r.URL.Path, apiV1SessionEndpointPattern)
+		if r.URL.Path == apiV1SessionsEndpointPattern {
+			sessionID = ""
+		}
+
+		switch {
+
+		case r.Method == http.MethodGet && sessionID == "":
+
+			username := r.URL.Query().Get("username")
+			userIP := r.URL.Query().Get("ip")
+			if userIP != "" && net.ParseIP(userIP) == nil {
+				http.Error(
+					w,
+					fmt.Sprintf("%q is not a valid IP Address", userIP),
+					http.StatusBadRequest,
+				)
+				return
+			}
+
+			resp := make([]sessionResponse, 0)
+			for _, instance := range instances {
+				sessions, err := files.ListUserSessions(instance.ActiveFilePath, username, userIP)
+				if err != nil {
+					ctxLog.WithField("instance", instance.Name).Error(err.Error())
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				for _, session := range sessions {
+					resp = append(resp, sessionResponse{
+						SessionID: session.SessionID,
+						Username:  session.Username,
+						IPAddress: session.IPAddress,
+						Instance:  instance.Name,
+					})
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(resp); err != nil {
+				ctxLog.Errorf("failed to encode sessions response: %v", err)
+			}
+
+		case r.Method == http.MethodDelete && sessionID != "":
+
+			if !sessionIDRegex.MatchString(sessionID) {
+				http.Error(
+					w,
+					fmt.Sprintf("%q is not a valid session ID", sessionID),
+					http.StatusBadRequest,
+				)
+				return
+			}
+
+			headers := r.Header.Clone()
+			headers.Del("Authorization")
+
+			alert := events.SplunkAlertEvent{
+				PayloadSenderIP: events.GetIP(r),
+				ArrivalTime:     time.Now().Format(time.RFC3339),
+				LocalTime:       time.Now().Format("2006-01-02 15:04:05"),
+				AlertName:       sessionsAlertName,
+				EndpointPath:    r.URL.Path,
+				HTTPMethod:      r.Method,
+				Headers:         headers,
+				Tenant:          tenant,
+			}
+
+			record, err := files.ProcessTerminateSessionEvent(
+				alert,
+				operator,
+				sessionID,
+				reportedUserEventsLog,
+				notifyWorkQueue,
+				instances,
+				ezproxyVerifyDelay,
+			)
+
+			switch {
+			case errors.Is(err, files.ErrSessionNotFound):
+				http.Error(
+					w,
+					fmt.Sprintf("session %q not found in any active file", sessionID),
+					http.StatusNotFound,
+				)
+			case err != nil:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			case record.Error != nil:
+				http.Error(w, record.Error.Error(), http.StatusInternalServerError)
+			case len(record.SessionTerminationResults) == 1 &&
+				events.TerminationOutcome(record.SessionTerminationResults[0]) == events.TerminationOutcomeAlreadyGone:
+				fmt.Fprintf(
+					w,
+					"OK: Session %q for user %q was already gone\n",
+					sessionID,
+					record.Alert.Username,
+				)
+			default:
+				fmt.Fprintf(
+					w,
+					"OK: Terminated session %q for user %q\n",
+					sessionID,
+					record.Alert.Username,
+				)
+			}
+
+		case r.Method == http.MethodGet || r.Method == http.MethodDelete:
+
+			http.Error(
+				w,
+				fmt.Sprintf(
+					"Sorry, %s requests are only accepted by %s and %s requests by %s{id}. "+
+						"Please see the README for examples and then try again.",
+					http.MethodGet,
+					apiV1SessionsEndpointPattern,
+					http.MethodDelete,
+					apiV1SessionEndpointPattern,
+				),
+				http.StatusNotFound,
+			)
+
+		default:
+
+			ctxLog.Debug("unsupported HTTP method received on sessions endpoint")
+			errorMsg := fmt.Sprintf(
+				"Sorry, this endpoint only accepts %s or %s requests. "+
+					"Please see the README for examples and then try again.",
+				http.MethodGet,
+				http.MethodDelete,
+			)
+			http.Error(w, errorMsg, http.StatusMethodNotAllowed)
+		}
+
+	}
+}
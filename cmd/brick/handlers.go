@@ -39,6 +39,14 @@ const (
 	apiV1DisableUserEndpointPattern             string = "/api/v1/users/disable"
 	apiV1ViewDisabledUsersEndpointPattern       string = "/api/v1/users/list"
 	apiV1ViewDisabledUsersStatusEndpointPattern string = "/api/v1/users/status"
+	apiV1IgnoredUsersEndpointPattern            string = "/api/v1/ignored/users"
+	apiV1IgnoredIPAddressesEndpointPattern      string = "/api/v1/ignored/ips"
+	apiV1ApprovalsEndpointPattern               string = files.ApprovalsEndpointPath
+	apiV1HealthEndpointPattern                  string = "/api/v1/health"
+	apiV1CircuitBreakerResetEndpointPattern     string = "/api/v1/circuit-breaker/reset"
+	apiV1SessionsEndpointPattern                string = "/api/v1/sessions"
+	apiV1SessionEndpointPattern                 string = "/api/v1/sessions/"
+	apiV1BlocklistEndpointPattern               string = "/api/v1/blocklist"
 )
 
 // frontPageHandler is our catch-all handler. By default it tells clients to
@@ -86,16 +94,15 @@ func frontPageHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// disableUserHandler handles alert payloads requesting that the reported
+// user account be disabled. If an auth secret is provided (i.e., the
+// endpoint belongs to a tenant), payloads which do not include it are
+// rejected. Alerts are recorded as received by the named tenant, if any.
 func disableUserHandler(
-	reportedUserEventsLog *files.ReportedUserEventsLog,
-	disabledUsers *files.DisabledUsers,
-	ignoredSources files.IgnoredSources,
-	notifyWorkQueue chan<- events.Record,
-	terminateSessions bool,
-	ezproxyActiveFilePath string,
-	ezproxySessionsSearchDelay int,
-	ezproxySessionSearchRetries int,
-	ezproxyExecutable string,
+	tenant string,
+	authSecret string,
+	activity *activityTracker,
+	disableContext files.DisableContext,
 ) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -120,6 +127,15 @@ func disableUserHandler(
 			return
 		}
 
+		if authSecret != "" && !payloadAuthenticated(authSecret, r) {
+			log.WithFields(log.Fields{
+				"url_path": r.URL.Path,
+				"tenant":   tenant,
+			}).Warn("rejecting alert payload with missing or invalid auth secret")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
 		// Limit request body to 1 MB
 		r.Body = http.MaxBytesReader(w, r.Body, 1*MB)
 
@@ -159,6 +175,10 @@ func disableUserHandler(
 
 		}
 
+		// Record that a valid payload was received so that a quiet period
+		// (e.g., a broken Splunk search) can be detected
+		activity.seen(events.GetIP(r))
+
 		// Explicitly confirm that the payload was received so that the sender
 		// can go ahead and disconnect. This prevents holding up the sender
 		// while this application performs further (unrelated from the
@@ -182,7 +202,11 @@ func disableUserHandler(
 		// if we made it this far, the payload checks out and we should be
 		// able to safely retrieve values that we need. We will also append
 		// payload sender metadata values such as headers, endpoint path, etc
-		// so that we can report those later.
+		// so that we can report those later. The auth secret (if any) is
+		// omitted from the recorded headers.
+		headers := r.Header.Clone()
+		headers.Del("Authorization")
+
 		alert := events.SplunkAlertEvent{
 			Username:        payloadV2.Result.Username,
 			UserIP:          payloadV2.Result.SourceIP,
@@ -191,9 +215,11 @@ func disableUserHandler(
 			LocalTime:       time.Now().Format("2006-01-02 15:04:05"),
 			AlertName:       payloadV2.SearchName,
 			SearchID:        payloadV2.Sid,
+			ResultsLink:     payloadV2.ResultsLink,
 			EndpointPath:    r.URL.Path,
 			HTTPMethod:      r.Method,
-			Headers:         r.Header,
+			Headers:         headers,
+			Tenant:          tenant,
 		}
 
 		// All return values from subfunction calls are dropped into the
@@ -205,18 +231,7 @@ func disableUserHandler(
 		// connection is closed. There are probably other/better ways to
 		// achieve that specific result without using a goroutine, but the
 		// effect is worth noting for further exploration later.
-		go files.ProcessDisableEvent(
-			alert,
-			disabledUsers,
-			reportedUserEventsLog,
-			ignoredSources,
-			notifyWorkQueue,
-			terminateSessions,
-			ezproxyActiveFilePath,
-			ezproxySessionsSearchDelay,
-			ezproxySessionSearchRetries,
-			ezproxyExecutable,
-		)
+		go files.ProcessDisableEvent(alert, disableContext)
 
 	}
 }
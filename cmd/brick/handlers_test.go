@@ -0,0 +1,99 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atc0005/brick/files"
+)
+
+func TestDisableUserHandlerAuthSecret(t *testing.T) {
+
+	const secret = "replace-with-a-long-random-secret"
+
+	tests := []struct {
+		name          string
+		authSecret    string
+		target        string
+		authorization string
+		wantStatus    int
+	}{
+		{
+			// an empty (invalid) payload is sent so that authenticated
+			// requests stop at payload validation
+			name:          "correct bearer token",
+			authSecret:    secret,
+			target:        "/api/v1/users/disable",
+			authorization: "Bearer " + secret,
+			wantStatus:    http.StatusBadRequest,
+		},
+		{
+			name:          "wrong bearer token",
+			authSecret:    secret,
+			target:        "/api/v1/users/disable",
+			authorization: "Bearer wrong-secret",
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:       "missing bearer token",
+			authSecret: secret,
+			target:     "/api/v1/users/disable",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "secret as basic auth",
+			authSecret:    secret,
+			target:        "/api/v1/users/disable",
+			authorization: "Basic " + secret,
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:       "secret as query parameter",
+			authSecret: secret,
+			target:     "/api/v1/users/disable?secret=" + secret,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "no auth secret configured",
+			target:     "/api/v1/users/disable",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader("{}"))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+
+			handler := disableUserHandler("library-a", tt.authSecret, nil, files.DisableContext{})
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d; want %d (body: %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/atc0005/brick/events"
+)
+
+// testRecord returns an event record for a disabled username with the alert
+// fields displayed by the notification formats populated. Tests override the
+// fields they exercise.
+func testRecord() events.Record {
+
+	return events.Record{
+		Action: events.ActionSuccessDisabledUsername,
+		Note:   "Username disabled",
+		Alert: events.SplunkAlertEvent{
+			Username:        "jsmith",
+			UserIP:          "192.168.2.3",
+			AlertName:       "Brute force <library> & more",
+			SearchID:        "scheduler__abc",
+			Tenant:          "library-a",
+			Instances:       []string{"proxy-a", "proxy-b"},
+			LocalTime:       "2020-10-19 08:15:00",
+			EndpointPath:    "/api/v1/users/disable",
+			HTTPMethod:      http.MethodPost,
+			PayloadSenderIP: "10.1.2.3:51234",
+			Headers:         make(http.Header),
+		},
+	}
+}
+
+// testOperatorCredentials returns credentials for a single operator using
+// the provided password.
+func testOperatorCredentials(t *testing.T, name string, password string) apiCredentials {
+
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	return apiCredentials{name: string(hash)}
+}
@@ -0,0 +1,300 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/config"
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/internal/caller"
+)
+
+// hookOutputLimit is the maximum number of bytes of hook command output
+// included in notifications.
+const hookOutputLimit int = 2048
+
+// hook is an external command run after this application takes one of the
+// selected actions. The arguments and environment variables are rendered
+// from the event record. Only PATH and the inherited environment variables
+// are passed from the environment of this application.
+type hook struct {
+	name       string
+	events     []string
+	executable string
+	args       []*template.Template
+	env        []*template.Template
+	inheritEnv []string
+	timeout    time.Duration
+}
+
+// hooks is a collection of hook values.
+type hooks []hook
+
+// newHooks parses the argument and environment variable templates of the
+// provided hook settings. An error is returned if any template is invalid.
+func newHooks(hookConfigs []config.Hook) (hooks, error) {
+
+	parse := func(hookName string, kind string, index int, text string) (*template.Template, error) {
+		tmpl, err := template.New(fmt.Sprintf("%s-%s-%d", hookName, kind, index)).
+			Funcs(template.FuncMap{
+				"ToLower": strings.ToLower,
+			}).
+			Option("missingkey=error").
+			Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s template %q for hook %q: %w", kind, text, hookName, err)
+		}
+
+		return tmpl, nil
+	}
+
+	hs := make(hooks, 0, len(hookConfigs))
+	for _, hookConfig := range hookConfigs {
+
+		h := hook{
+			name:       hookConfig.Name,
+			events:     hookConfig.Events,
+			executable: hookConfig.ExecutablePath,
+			inheritEnv: hookConfig.InheritEnv,
+			timeout:    time.Duration(hookConfig.Timeout) * time.Second,
+		}
+
+		for i, arg := range hookConfig.Args {
+			tmpl, err := parse(h.name, "argument", i, arg)
+			if err != nil {
+				return nil, err
+			}
+			h.args = append(h.args, tmpl)
+		}
+
+		for i, env := range hookConfig.Env {
+			tmpl, err := parse(h.name, "environment", i, env)
+			if err != nil {
+				return nil, err
+			}
+			h.env = append(h.env, tmpl)
+		}
+
+		hs = append(hs, h)
+	}
+
+	return hs, nil
+}
+
+// hookEvent returns the hook event for the action recorded in the provided
+// event record or an empty string if hooks are not run for the action.
+func hookEvent(record events.Record) string {
+
+	switch record.Action {
+	case events.ActionSuccessDisabledUsername:
+		return config.HookEventDisabled
+
+	case events.ActionSuccessIgnoredUsername,
+		events.ActionSuccessIgnoredIPAddress:
+		return config.HookEventIgnored
+
+	case events.ActionSuccessTerminatedUserSession,
+		events.ActionSuccessReconciledSessions:
+		return config.HookEventTerminated
+
+	case events.ActionFailureDisableRequestReceived,
+		events.ActionFailureDisabledUsername,
+		events.ActionFailureDuplicatedUsername,
+		events.ActionFailureIgnoredUsername,
+		events.ActionFailureIgnoredIPAddress,
+		events.ActionFailureUserSessionLookupFailure,
+		events.ActionFailureTerminatedUserSession,
+		events.ActionFailureIgnoredEntryUpdate,
+		events.ActionFailureReportThreshold,
+		events.ActionFailureApprovalRequest,
+		events.ActionFailureCircuitBreaker,
+		events.ActionFailureCircuitBreakerReset,
+		events.ActionFailureNoActivity,
+		events.ActionFailureReconciledSessions,
+		events.ActionFailureBlockedIPAddress,
+		events.ActionFailureUnblockedIPAddress:
+		return config.HookEventFailure
+
+	default:
+		return ""
+	}
+}
+
+// forRecord returns the hooks which are run for the action recorded in the
+// provided event record.
+func (hs hooks) forRecord(record events.Record) hooks {
+
+	event := hookEvent(record)
+	if event == "" {
+		return nil
+	}
+
+	var selected hooks
+	for _, h := range hs {
+		for _, hookEvent := range h.events {
+			if hookEvent == event {
+				selected = append(selected, h)
+				break
+			}
+		}
+	}
+
+	return selected
+}
+
+// run runs each of the hooks in order for the provided event record and
+// returns their results. A failed hook does not prevent the hooks which
+// follow from being run.
+func (hs hooks) run(record events.Record) []events.HookResult {
+
+	results := make([]events.HookResult, 0, len(hs))
+	for _, h := range hs {
+		result := h.run(record)
+		if result.Error != nil {
+			log.Errorf("Hook %q failed for %q event record: %v", h.name, record.Action, result.Error)
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// run renders the arguments and environment variables of the hook from the
+// provided event record and runs the hook command, stopping it if it does
+// not exit within the hook timeout. The combined output of the command is
+// captured.
+func (h hook) run(record events.Record) events.HookResult {
+
+	result := events.HookResult{
+		Name:     h.name,
+		ExitCode: -1,
+	}
+
+	args := make([]string, 0, len(h.args))
+	for _, tmpl := range h.args {
+		arg, err := renderHookTemplate(tmpl, record)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		args = append(args, arg)
+	}
+
+	env := inheritedHookEnv(h.inheritEnv)
+	for _, tmpl := range h.env {
+		variable, err := renderHookTemplate(tmpl, record)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		env = append(env, variable)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	// Accepting a variable here is intentional; hook commands are
+	// site-specific.
+	//
+	// nolint:gosec
+	cmd := exec.CommandContext(ctx, h.executable, args...)
+	cmd.Env = env
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	log.Debugf("%s: Running hook %q: %s", caller.GetFuncName(), h.name, strings.Join(cmd.Args, " "))
+
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration = time.Since(start)
+	result.Output = truncateHookOutput(output.String())
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		result.Error = fmt.Errorf("%s did not exit within %v: %w", h.executable, h.timeout, ctx.Err())
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Error = fmt.Errorf("%s exited with code %d", h.executable, result.ExitCode)
+	case err != nil:
+		result.Error = fmt.Errorf("failed to run %s: %w", h.executable, err)
+	default:
+		result.ExitCode = 0
+	}
+
+	return result
+}
+
+// inheritedHookEnv returns PATH and the provided environment variables from
+// the environment of this application in the form NAME=value. Variables
+// which are not set are skipped. Other environment variables (e.g.,
+// credentials provided to this application) are never passed to hooks.
+func inheritedHookEnv(names []string) []string {
+
+	env := make([]string, 0, len(names)+1)
+	seen := make(map[string]bool, len(names)+1)
+
+	for _, name := range append([]string{"PATH"}, names...) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+
+	return env
+}
+
+// renderHookTemplate renders the provided hook argument or environment
+// variable template using the provided event record.
+func renderHookTemplate(tmpl *template.Template, record events.Record) (string, error) {
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, record); err != nil {
+		return "", fmt.Errorf("error rendering hook template %q: %w", tmpl.Name(), err)
+	}
+
+	return buf.String(), nil
+}
+
+// truncateHookOutput trims surrounding whitespace from the provided hook
+// command output and limits it to hookOutputLimit bytes.
+func truncateHookOutput(output string) string {
+
+	output = strings.TrimSpace(output)
+	if len(output) <= hookOutputLimit {
+		return output
+	}
+
+	return output[:hookOutputLimit] + " ... (truncated)"
+}
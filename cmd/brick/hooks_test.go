@@ -0,0 +1,298 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atc0005/brick/config"
+	"github.com/atc0005/brick/events"
+)
+
+// writeHookScript writes a shell script running the provided commands to a
+// temporary directory and returns the path to the script.
+func writeHookScript(t *testing.T, commands string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "hook.sh")
+
+	// The script must be executable in order to be run as a hook.
+	//
+	// #nosec G306
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+commands), 0700); err != nil {
+		t.Fatalf("failed to write hook script: %v", err)
+	}
+
+	return path
+}
+
+func TestNewHooksInvalidTemplate(t *testing.T) {
+
+	tests := []struct {
+		name string
+		hook config.Hook
+	}{
+		{
+			name: "argument syntax error",
+			hook: config.Hook{Name: "notify", Args: []string{"{{ .Alert.Username"}},
+		},
+		{
+			name: "environment syntax error",
+			hook: config.Hook{Name: "notify", Env: []string{"USER={{ .Alert.Username }"}},
+		},
+		{
+			name: "unknown function",
+			hook: config.Hook{Name: "notify", Args: []string{"{{ ToUpper .Alert.Username }}"}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newHooks([]config.Hook{tt.hook}); err == nil {
+				t.Fatal("expected error for invalid hook template")
+			}
+		})
+	}
+}
+
+func TestHooksForRecord(t *testing.T) {
+
+	hs, err := newHooks([]config.Hook{
+		{Name: "on-disable", Events: []string{config.HookEventDisabled}},
+		{Name: "on-failure", Events: []string{config.HookEventFailure}},
+		{Name: "on-both", Events: []string{config.HookEventDisabled, config.HookEventFailure}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create hooks: %v", err)
+	}
+
+	tests := []struct {
+		action string
+		want   []string
+	}{
+		{action: events.ActionSuccessDisabledUsername, want: []string{"on-disable", "on-both"}},
+		{action: events.ActionFailureDisabledUsername, want: []string{"on-failure", "on-both"}},
+		{action: events.ActionSuccessTerminatedUserSession, want: nil},
+		{action: events.ActionSuccessDisableRequestReceived, want: nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.action, func(t *testing.T) {
+			var got []string
+			for _, h := range hs.forRecord(events.Record{Action: tt.action}) {
+				got = append(got, h.name)
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got hooks %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHookRun(t *testing.T) {
+
+	record := events.Record{
+		Action: events.ActionSuccessDisabledUsername,
+		Alert: events.SplunkAlertEvent{
+			Username: "JSmith",
+			UserIP:   "192.168.2.3",
+		},
+	}
+
+	tests := []struct {
+		name         string
+		commands     string
+		args         []string
+		env          []string
+		timeout      time.Duration
+		wantOutput   string
+		wantExitCode int
+		wantErr      bool
+	}{
+		{
+			name:         "renders arguments and environment",
+			commands:     "printf '%s,' \"$@\"\nprintf '%s' \"$HOOK_IP\"\n",
+			args:         []string{"{{ ToLower .Alert.Username }}", "{{ .Action }}"},
+			env:          []string{"HOOK_IP={{ .Alert.UserIP }}"},
+			timeout:      5 * time.Second,
+			wantOutput:   "jsmith," + events.ActionSuccessDisabledUsername + ",192.168.2.3",
+			wantExitCode: 0,
+		},
+		{
+			name:         "unknown record field",
+			commands:     "echo unreachable\n",
+			args:         []string{"{{ .Alert.Missing }}"},
+			timeout:      5 * time.Second,
+			wantExitCode: -1,
+			wantErr:      true,
+		},
+		{
+			name:         "non-zero exit code",
+			commands:     "echo failed\nexit 3\n",
+			timeout:      5 * time.Second,
+			wantOutput:   "failed",
+			wantExitCode: 3,
+			wantErr:      true,
+		},
+		{
+			name:         "timeout",
+			commands:     "exec sleep 5\n",
+			timeout:      100 * time.Millisecond,
+			wantExitCode: -1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			hs, err := newHooks([]config.Hook{
+				{
+					Name:           "notify",
+					Events:         []string{config.HookEventDisabled},
+					ExecutablePath: writeHookScript(t, tt.commands),
+					Args:           tt.args,
+					Env:            tt.env,
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to create hooks: %v", err)
+			}
+			hs[0].timeout = tt.timeout
+
+			results := hs.run(record)
+			if len(results) != 1 {
+				t.Fatalf("got %d hook results, want 1", len(results))
+			}
+			result := results[0]
+
+			if result.Name != "notify" {
+				t.Errorf("got hook name %q, want %q", result.Name, "notify")
+			}
+			if result.ExitCode != tt.wantExitCode {
+				t.Errorf("got exit code %d, want %d", result.ExitCode, tt.wantExitCode)
+			}
+			if (result.Error != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error: %t", result.Error, tt.wantErr)
+			}
+			if result.Output != tt.wantOutput {
+				t.Errorf("got output %q, want %q", result.Output, tt.wantOutput)
+			}
+		})
+	}
+}
+
+// setTestEnv sets the provided environment variable for the duration of the
+// test.
+func setTestEnv(t *testing.T, name string, value string) {
+	t.Helper()
+
+	if err := os.Setenv(name, value); err != nil {
+		t.Fatalf("failed to set environment variable %q: %v", name, err)
+	}
+	t.Cleanup(func() {
+		_ = os.Unsetenv(name)
+	})
+}
+
+func TestInheritedHookEnv(t *testing.T) {
+
+	setTestEnv(t, "BRICK_TEST_INHERITED", "inherited")
+	setTestEnv(t, "BRICK_TEST_SECRET", "secret")
+
+	path := "PATH=" + os.Getenv("PATH")
+
+	tests := []struct {
+		name  string
+		names []string
+		want  []string
+	}{
+		{
+			name: "PATH only",
+			want: []string{path},
+		},
+		{
+			name:  "inherited variable",
+			names: []string{"BRICK_TEST_INHERITED"},
+			want:  []string{path, "BRICK_TEST_INHERITED=inherited"},
+		},
+		{
+			name:  "duplicate names",
+			names: []string{"PATH", "BRICK_TEST_INHERITED", "BRICK_TEST_INHERITED"},
+			want:  []string{path, "BRICK_TEST_INHERITED=inherited"},
+		},
+		{
+			name:  "unset variable skipped",
+			names: []string{"BRICK_TEST_UNSET"},
+			want:  []string{path},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := inheritedHookEnv(tt.names)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got environment %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHookRunEnvironment(t *testing.T) {
+
+	setTestEnv(t, "BRICK_TEST_INHERITED", "inherited")
+	setTestEnv(t, "BRICK_TEST_SECRET", "secret")
+
+	hs, err := newHooks([]config.Hook{
+		{
+			Name:           "notify",
+			Events:         []string{config.HookEventDisabled},
+			ExecutablePath: writeHookScript(t, "printf '%s,%s,%s' \"$BRICK_TEST_INHERITED\" \"$BRICK_TEST_SECRET\" \"$HOOK_USER\"\n"),
+			Env:            []string{"HOOK_USER={{ .Alert.Username }}"},
+			InheritEnv:     []string{"BRICK_TEST_INHERITED"},
+			Timeout:        5,
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create hooks: %v", err)
+	}
+
+	record := testRecord()
+	results := hs.run(record)
+	if len(results) != 1 {
+		t.Fatalf("got %d hook results, want 1", len(results))
+	}
+
+	if results[0].Error != nil {
+		t.Fatalf("hook returned error: %v", results[0].Error)
+	}
+
+	// variables which are not inherited are not passed to the hook
+	if want := "inherited,,jsmith"; results[0].Output != want {
+		t.Errorf("got output %q, want %q", results[0].Output, want)
+	}
+}
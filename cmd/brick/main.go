@@ -23,7 +23,9 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"os/user"
 	"syscall"
+	"time"
 
 	"github.com/atc0005/brick/config"
 	"github.com/atc0005/brick/events"
@@ -53,6 +55,44 @@ func main() {
 
 	log.Debugf("AppConfig: %+v", appConfig)
 
+	// parse and validate the templates used to generate disabled users file
+	// entries before accepting any alerts
+	disabledUsersTemplates, err := files.NewDisabledUsersTemplates(
+		appConfig.DisabledUsersEntryTemplateFile(),
+		appConfig.DisabledUsersQuarantineTemplateFile(),
+		appConfig.DisabledUsersFileEntrySuffix(),
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize application: %s", err)
+	}
+
+	// parse the hook argument and environment variable templates
+	recordHooks, err := newHooks(appConfig.Hooks())
+	if err != nil {
+		log.Fatalf("Failed to initialize application: %s", err)
+	}
+
+	// parse the template used to render webhook request bodies
+	webhookTemplate, err := newWebhookTemplate(appConfig.WebhookTemplateFile())
+	if err != nil {
+		log.Fatalf("Failed to initialize application: %s", err)
+	}
+
+	// parse the template used to render the Unblock user action of Adaptive
+	// Card messages
+	teamsUnblockURL, err := newTeamsUnblockURLTemplate(appConfig.TeamsUnblockURL())
+	if err != nil {
+		log.Fatalf("Failed to initialize application: %s", err)
+	}
+
+	if appConfig.CircuitBreakerReset() {
+		if err := resetCircuitBreaker(appConfig); err != nil {
+			log.Errorf("failed to reset circuit breaker: %v", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	mux := http.NewServeMux()
 
 	// Apply "default" timeout settings provided by Simon Frey; override the
@@ -109,7 +149,7 @@ func main() {
 
 	// Create "notifications manager" function as persistent goroutine to
 	// process incoming notification requests.
-	go NotifyMgr(ctx, appConfig, notifyWorkQueue, notifyDone)
+	go NotifyMgr(ctx, appConfig, recordHooks, webhookTemplate, teamsUnblockURL, notifyWorkQueue, notifyDone)
 
 	// Setup "listener" to cancel the parent context when Signal.Notify()
 	// indicates that SIGINT has been received
@@ -121,26 +161,53 @@ func main() {
 
 	// build objects representing output files, the templates used to generate
 	// those files and any files containing "ignored" users/IPs using our
-	// newly constructed config object.
-	reportedUserEventsLog := files.NewReportedUserEventsLog(
+	// newly constructed config object. The default endpoints are served by
+	// a tenant with an empty name; each configured tenant has its own.
+	defaultTenant := newTenant(
+		ctx,
+		appConfig,
+		"",
+		"",
 		appConfig.ReportedUsersLogFile(),
-		appConfig.ReportedUsersLogFilePermissions(),
-	)
-
-	disabledUsers := files.NewDisabledUsers(
-		appConfig.DisabledUsersFile(),
-		appConfig.DisabledUsersFileEntrySuffix(),
-		appConfig.DisabledUsersFilePermissions(),
-	)
-
-	ignoredSources := files.NewIgnoredSources(
 		appConfig.IgnoredUsersFile(),
 		appConfig.IgnoredIPAddressesFile(),
-		appConfig.IgnoreLookupErrors(),
+		appConfig.EZproxyInstances(),
+		disabledUsersTemplates,
+		notifyWorkQueue,
 	)
 
+	tenants := make(map[string]tenant, len(appConfig.Tenants()))
+	for _, tenantConfig := range appConfig.Tenants() {
+		t := newTenant(
+			ctx,
+			appConfig,
+			tenantConfig.Name,
+			tenantConfig.EndpointPrefix,
+			tenantConfig.ReportedUsersLogFile,
+			tenantConfig.IgnoredUsersFile,
+			tenantConfig.IgnoredIPAddressesFile,
+			tenantConfig.Instances,
+			disabledUsersTemplates,
+			notifyWorkQueue,
+		)
+		tenants[t.name] = t
+	}
+
+	// all tenants block IP Addresses using the same firewall backend
+	firewalls := []*files.Firewall{defaultTenant.firewall}
+	for _, t := range tenants {
+		firewalls = append(firewalls, t.firewall)
+	}
+	files.ShareFirewallBackend(firewalls...)
+
+	defaultTenant.start(ctx, appConfig, notifyWorkQueue)
+	for _, t := range tenants {
+		t.start(ctx, appConfig, notifyWorkQueue)
+	}
+
 	// GET requests
 	mux.HandleFunc(frontpageEndpointPattern, frontPageHandler)
+	mux.HandleFunc(apiV1HealthEndpointPattern, healthHandler(defaultTenant.circuitBreaker, defaultTenant.activity))
 	mux.HandleFunc(apiV1ViewDisabledUsersEndpointPattern, viewDisabledUsersHandler)
 	mux.HandleFunc(apiV1ViewDisabledUsersStatusEndpointPattern, viewDisabledUserStatusHandler)
 
@@ -148,24 +215,182 @@ func main() {
 	mux.HandleFunc(
 		apiV1DisableUserEndpointPattern,
 		disableUserHandler(
-			reportedUserEventsLog,
-			disabledUsers,
-			ignoredSources,
+			defaultTenant.name,
+			"",
+			defaultTenant.activity,
+			defaultTenant.disableContext(appConfig, notifyWorkQueue),
+		),
+	)
+
+	// GET, POST and DELETE requests (authenticated)
+	apiCreds := apiCredentials(appConfig.APICredentials())
+	if !apiCreds.enabled() {
+		log.Info("No API users configured; management endpoints are disabled")
+	}
+
+	mux.HandleFunc(
+		apiV1IgnoredUsersEndpointPattern,
+		ignoredEntriesHandler(
+			defaultTenant.name,
+			appConfig.IgnoredUsersFile(),
+			false,
+			apiCreds,
+			defaultTenant.reportedUserEventsLog,
+			notifyWorkQueue,
+		),
+	)
+
+	mux.HandleFunc(
+		apiV1IgnoredIPAddressesEndpointPattern,
+		ignoredEntriesHandler(
+			defaultTenant.name,
+			appConfig.IgnoredIPAddressesFile(),
+			true,
+			apiCreds,
+			defaultTenant.reportedUserEventsLog,
+			notifyWorkQueue,
+		),
+	)
+
+	if appConfig.DisabledUsersBlocklistFeed() {
+		mux.HandleFunc(
+			apiV1BlocklistEndpointPattern,
+			blocklistHandler(defaultTenant.blocklist, apiCreds),
+		)
+	}
+
+	mux.HandleFunc(
+		apiV1ApprovalsEndpointPattern,
+		approvalsHandler(defaultTenant.approvals, apiCreds),
+	)
+
+	mux.HandleFunc(
+		apiV1CircuitBreakerResetEndpointPattern,
+		circuitBreakerResetHandler(
+			defaultTenant.name,
+			defaultTenant.circuitBreaker,
+			apiCreds,
+			defaultTenant.reportedUserEventsLog,
 			notifyWorkQueue,
-			appConfig.EZproxyTerminateSessions(),
-			appConfig.EZproxyActiveFilePath(),
-			appConfig.EZproxySearchDelay(),
-			appConfig.EZproxySearchRetries(),
-			appConfig.EZproxyExecutablePath(),
 		),
 	)
 
+	sessionsHandlerFunc := sessionsHandler(
+		defaultTenant.name,
+		apiCreds,
+		defaultTenant.reportedUserEventsLog,
+		notifyWorkQueue,
+		defaultTenant.instances,
+		appConfig.EZproxyVerifyDelay(),
+	)
+
+	mux.HandleFunc(apiV1SessionsEndpointPattern, sessionsHandlerFunc)
+	mux.HandleFunc(apiV1SessionEndpointPattern, sessionsHandlerFunc)
+
+	// Each tenant receives alert payloads and management requests on its own
+	// copies of the endpoints, served beneath the tenant endpoint prefix.
+	// The prefix is removed before the request is handled.
+	for _, tenantConfig := range appConfig.Tenants() {
+
+		t := tenants[tenantConfig.Name]
+		prefix := tenantConfig.EndpointPrefix
+
+		log.Infof("Serving endpoints for tenant %q beneath %s", t.name, prefix)
+
+		mux.Handle(
+			prefix+apiV1DisableUserEndpointPattern,
+			http.StripPrefix(prefix, disableUserHandler(
+				t.name,
+				tenantConfig.AuthSecret,
+				t.activity,
+				t.disableContext(appConfig, notifyWorkQueue),
+			)),
+		)
+
+		tenantAPICreds := apiCredentials(tenantConfig.APICredentials())
+
+		mux.Handle(
+			prefix+apiV1IgnoredUsersEndpointPattern,
+			http.StripPrefix(prefix, ignoredEntriesHandler(
+				t.name,
+				tenantConfig.IgnoredUsersFile,
+				false,
+				tenantAPICreds,
+				t.reportedUserEventsLog,
+				notifyWorkQueue,
+			)),
+		)
+
+		mux.Handle(
+			prefix+apiV1IgnoredIPAddressesEndpointPattern,
+			http.StripPrefix(prefix, ignoredEntriesHandler(
+				t.name,
+				tenantConfig.IgnoredIPAddressesFile,
+				true,
+				tenantAPICreds,
+				t.reportedUserEventsLog,
+				notifyWorkQueue,
+			)),
+		)
+
+		tenantSessionsHandler := http.StripPrefix(prefix, sessionsHandler(
+			t.name,
+			tenantAPICreds,
+			t.reportedUserEventsLog,
+			notifyWorkQueue,
+			t.instances,
+			appConfig.EZproxyVerifyDelay(),
+		))
+
+		mux.Handle(prefix+apiV1SessionsEndpointPattern, tenantSessionsHandler)
+		mux.Handle(prefix+apiV1SessionEndpointPattern, tenantSessionsHandler)
+
+		mux.Handle(
+			prefix+apiV1HealthEndpointPattern,
+			http.StripPrefix(prefix, healthHandler(t.circuitBreaker, t.activity)),
+		)
+
+		if appConfig.DisabledUsersBlocklistFeed() {
+			mux.Handle(
+				prefix+apiV1BlocklistEndpointPattern,
+				http.StripPrefix(prefix, blocklistHandler(t.blocklist, tenantAPICreds)),
+			)
+		}
+
+		mux.Handle(
+			prefix+apiV1ApprovalsEndpointPattern,
+			http.StripPrefix(prefix, approvalsHandler(t.approvals, tenantAPICreds)),
+		)
+
+		mux.Handle(
+			prefix+apiV1CircuitBreakerResetEndpointPattern,
+			http.StripPrefix(prefix, circuitBreakerResetHandler(
+				t.name,
+				t.circuitBreaker,
+				tenantAPICreds,
+				t.reportedUserEventsLog,
+				notifyWorkQueue,
+			)),
+		)
+	}
+
 	// listen on specified port and IP Address, block until app is terminated
-	log.Infof("%s is listening on %s port %d",
-		config.MyAppName, appConfig.LocalIPAddress(), appConfig.LocalTCPPort())
+	log.Infof("%s is listening on %s port %d (TLS enabled: %t)",
+		config.MyAppName, appConfig.LocalIPAddress(), appConfig.LocalTCPPort(),
+		appConfig.TLSEnabled())
+
+	listenAndServe := httpServer.ListenAndServe
+	if appConfig.TLSEnabled() {
+		listenAndServe = func() error {
+			return httpServer.ListenAndServeTLS(
+				appConfig.TLSCertFile(),
+				appConfig.TLSKeyFile(),
+			)
+		}
+	}
 
 	// TODO: This can be handled in a cleaner fashion?
-	if err := httpServer.ListenAndServe(); err != nil {
+	if err := listenAndServe(); err != nil {
 
 		// Calling Shutdown() will immediately return ErrServerClosed, but
 		// based on reading the docs it sounds like any errors from closing
@@ -189,3 +414,68 @@ func main() {
 
 	log.Infof("%s successfully shutdown", config.MyAppName)
 }
+
+// resetCircuitBreaker resets the mass-disable circuit breaker of the default
+// endpoints and of each tenant on behalf of the user running this
+// application. This is used when the circuit breakers are reset via CLI flag
+// instead of the management API. Each reset is recorded in the reported user
+// events log of the tenant, but notifications are not sent as this
+// application exits immediately afterward.
+func resetCircuitBreaker(appConfig *config.Config) error {
+
+	operator := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		operator = current.Username
+	}
+
+	// the default endpoints are served by a tenant with an empty name
+	reportedUsersLogFiles := map[string]string{
+		"": appConfig.ReportedUsersLogFile(),
+	}
+	for _, tenantConfig := range appConfig.Tenants() {
+		reportedUsersLogFiles[tenantConfig.Name] = tenantConfig.ReportedUsersLogFile
+	}
+
+	// nothing reads from this queue; the buffer keeps the notification
+	// requests from blocking
+	notifyWorkQueue := make(chan events.Record, len(reportedUsersLogFiles))
+
+	for name, reportedUsersLogFile := range reportedUsersLogFiles {
+
+		alert := events.SplunkAlertEvent{
+			ArrivalTime:  time.Now().Format(time.RFC3339),
+			LocalTime:    time.Now().Format("2006-01-02 15:04:05"),
+			AlertName:    circuitBreakerAlertName,
+			EndpointPath: "--circuit-breaker-reset",
+			HTTPMethod:   "CLI",
+			Tenant:       name,
+		}
+
+		status, err := files.ProcessCircuitBreakerResetEvent(
+			alert,
+			operator,
+			files.NewCircuitBreaker(
+				appConfig.TenantCircuitBreakerStateFile(name),
+				appConfig.CircuitBreakerMaxDisables(),
+				time.Duration(appConfig.CircuitBreakerWindow())*time.Minute,
+			),
+			files.NewReportedUserEventsLog(
+				reportedUsersLogFile,
+				appConfig.ReportedUsersLogFilePermissions(),
+			),
+			notifyWorkQueue,
+		)
+		if err != nil {
+			return err
+		}
+
+		if name == "" {
+			fmt.Printf("Reset circuit breaker (previously %s)\n", status)
+			continue
+		}
+
+		fmt.Printf("Reset circuit breaker for tenant %q (previously %s)\n", name, status)
+	}
+
+	return nil
+}
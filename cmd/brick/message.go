@@ -19,9 +19,13 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/smtp"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"text/template"
@@ -113,9 +117,11 @@ func getTerminationResultsList(sTermResults []ezproxy.TerminateUserSessionResult
 		}
 
 		sessionResultsStringSets += fmt.Sprintf(
-			"- { SessionID: %q, IPAddress: %q, ExitCode: %q, StdOut: %q, StdErr: %q, Error: %q }\n\n",
+			"- { SessionID: %q, Username: %q, IPAddress: %q, Outcome: %q, ExitCode: %q, StdOut: %q, StdErr: %q, Error: %q }\n\n",
 			result.SessionID,
+			result.Username,
 			result.IPAddress,
+			events.TerminationOutcome(result),
 			strconv.Itoa(result.ExitCode),
 			result.StdOut,
 			result.StdErr,
@@ -126,6 +132,32 @@ func getTerminationResultsList(sTermResults []ezproxy.TerminateUserSessionResult
 	return sessionResultsStringSets
 }
 
+// getHookResultsList generates a Markdown list summarizing the results of
+// the post-action hooks run for an event.
+func getHookResultsList(hookResults []events.HookResult) string {
+
+	var hookResultsStringSets string
+	for _, result := range hookResults {
+
+		// guard against (nil) lack of error in results slice entry
+		errStr := "None"
+		if result.Error != nil {
+			errStr = result.Error.Error()
+		}
+
+		hookResultsStringSets += fmt.Sprintf(
+			"- { Name: %q, ExitCode: %q, Duration: %q, Output: %q, Error: %q }\n\n",
+			result.Name,
+			strconv.Itoa(result.ExitCode),
+			result.Duration.Round(time.Millisecond).String(),
+			result.Output,
+			errStr,
+		)
+	}
+
+	return hookResultsStringSets
+}
+
 // getMsgSummaryText evaluates the provided event Record and builds a message
 // suitable for display as the main or summary notification text. This message
 // is generated first from the Note field if available, second from the Error
@@ -167,6 +199,17 @@ func getMsgTitle(msgTitlePrefix string, record events.Record) string {
 
 	var msgCardTitle string
 
+	// flag all notifications for alerts handled by a dry-run policy so that
+	// simulated actions are not mistaken for real ones
+	if record.Alert.Policy != nil && record.Alert.Policy.DryRunEnabled() {
+		msgTitlePrefix += "[DRY-RUN] "
+	}
+
+	// make sure that notifications requiring prompt attention stand out
+	if record.HighPriority() {
+		msgTitlePrefix += "[URGENT] "
+	}
+
 	switch record.Action {
 
 	// case record.Error != nil:
@@ -189,12 +232,54 @@ func getMsgTitle(msgTitlePrefix string, record events.Record) string {
 	case events.ActionSuccessIgnoredIPAddress, events.ActionFailureIgnoredIPAddress:
 		msgCardTitle = msgTitlePrefix + "[step 2 of 3] " + record.Action
 
+	case events.ActionDryRunDisabledUsername:
+		msgCardTitle = msgTitlePrefix + "[step 2 of 3] " + record.Action
+
+	case events.ActionSkippedDisableUsername,
+		events.ActionSkippedDisableUsernameThreshold,
+		events.ActionFailureReportThreshold:
+		msgCardTitle = msgTitlePrefix + "[step 2 of 3] " + record.Action
+
+	case events.ActionPendingApprovalDisableUsername,
+		events.ActionSuccessApprovedDisableUsername,
+		events.ActionSuccessRejectedDisableUsername,
+		events.ActionFailureApprovalRequest:
+		msgCardTitle = msgTitlePrefix + "[step 2 of 3] " + record.Action
+
+	case events.ActionSkippedDisableUsernameSuspended,
+		events.ActionSkippedCircuitBreakerTripped,
+		events.ActionFailureCircuitBreaker:
+		msgCardTitle = msgTitlePrefix + "[step 2 of 3] " + record.Action
+
 	case events.ActionSuccessTerminatedUserSession,
 		events.ActionFailureUserSessionLookupFailure,
 		events.ActionFailureTerminatedUserSession,
-		events.ActionSkippedTerminateUserSessions:
+		events.ActionSkippedTerminateUserSessions,
+		events.ActionDryRunTerminatedUserSessions:
 		msgCardTitle = msgTitlePrefix + "[step 3 of 3] " + record.Action
 
+	case events.ActionSuccessIgnoredEntryAdded,
+		events.ActionSuccessIgnoredEntryRemoved,
+		events.ActionFailureIgnoredEntryUpdate,
+		events.ActionSuccessCircuitBreakerReset,
+		events.ActionFailureCircuitBreakerReset:
+		msgCardTitle = msgTitlePrefix + "[audit] " + record.Action
+
+	case events.ActionFailureNoActivity, events.ActionSuccessActivityResumed:
+		msgCardTitle = msgTitlePrefix + "[activity] " + record.Action
+
+	case events.ActionSuccessReconciledSessions,
+		events.ActionSkippedReconciledSessions,
+		events.ActionDryRunReconciledSessions,
+		events.ActionFailureReconciledSessions:
+		msgCardTitle = msgTitlePrefix + "[reconcile] " + record.Action
+
+	case events.ActionSuccessBlockedIPAddress,
+		events.ActionSuccessUnblockedIPAddress,
+		events.ActionFailureBlockedIPAddress,
+		events.ActionFailureUnblockedIPAddress:
+		msgCardTitle = msgTitlePrefix + "[firewall] " + record.Action
+
 	default:
 		msgCardTitle = msgTitlePrefix + " [UNKNOWN] " + record.Action
 		log.Warnf("UNKNOWN record: %v+\n", record)
@@ -218,6 +303,10 @@ func createTeamsMessage(record events.Record) goteamsnotify.MessageCard {
 
 	msgCard.Title = getMsgTitle(msgCardTitlePrefix, record)
 
+	if record.HighPriority() {
+		msgCard.ThemeColor = highPriorityThemeColor
+	}
+
 	// msgCard.Text = record.Note
 	msgCard.Text = getMsgSummaryText(record)
 
@@ -250,6 +339,21 @@ func createTeamsMessage(record events.Record) goteamsnotify.MessageCard {
 		sessionTerminationResultsSection.StartGroup = true
 
 		sessionTerminationResultsSection.Text = getTerminationResultsList(record.SessionTerminationResults)
+		if record.Verification != nil {
+			sessionTerminationResultsSection.Text = fmt.Sprintf(
+				"Verification: %s\n\n%s",
+				record.Verification,
+				sessionTerminationResultsSection.Text,
+			)
+		}
+
+		if scope := record.TerminationScope(); scope != "" {
+			sessionTerminationResultsSection.Text = fmt.Sprintf(
+				"Scope: %s\n\n%s",
+				scope,
+				sessionTerminationResultsSection.Text,
+			)
+		}
 
 		if err := msgCard.AddSection(sessionTerminationResultsSection); err != nil {
 			errMsg := fmt.Sprintf("Error returned from attempt to add sessionTerminationResultsSection: %v", err)
@@ -259,6 +363,22 @@ func createTeamsMessage(record events.Record) goteamsnotify.MessageCard {
 
 	}
 
+	// If any hooks were run for this event, create Hook Results section
+	if record.HookResults != nil {
+
+		hookResultsSection := goteamsnotify.NewMessageCardSection()
+		hookResultsSection.Title = "## Hook Results"
+		hookResultsSection.StartGroup = true
+		hookResultsSection.Text = getHookResultsList(record.HookResults)
+
+		if err := msgCard.AddSection(hookResultsSection); err != nil {
+			errMsg := fmt.Sprintf("Error returned from attempt to add hookResultsSection: %v", err)
+			log.Errorf("%s: %v", myFuncName, errMsg)
+			msgCard.Text = msgCard.Text + "\n\n" + goteamsnotify.TryToFormatAsCodeSnippet(errMsg)
+		}
+
+	}
+
 	/*
 		Disable User Request Details Section - Core of alert details
 	*/
@@ -272,6 +392,43 @@ func createTeamsMessage(record events.Record) goteamsnotify.MessageCard {
 	addFactPair(&msgCard, disableUserRequestDetailsSection, "Alert/Search Name", record.Alert.AlertName)
 	addFactPair(&msgCard, disableUserRequestDetailsSection, "Alert/Search ID", record.Alert.SearchID)
 
+	if record.Alert.Tenant != "" {
+		addFactPair(&msgCard, disableUserRequestDetailsSection, "Tenant", record.Alert.Tenant)
+	}
+
+	if record.Alert.Policy != nil {
+		addFactPair(&msgCard, disableUserRequestDetailsSection, "Alert Policy", record.Alert.Policy.String())
+	}
+
+	if len(record.Alert.Instances) > 0 {
+		addFactPair(&msgCard, disableUserRequestDetailsSection, "EZproxy Instances", strings.Join(record.Alert.Instances, ", "))
+	}
+
+	if record.Alert.ReportThreshold != nil {
+		addFactPair(&msgCard, disableUserRequestDetailsSection, "Report Threshold", record.Alert.ReportThreshold.String())
+	}
+
+	if record.Alert.Approval != nil {
+		addFactPair(&msgCard, disableUserRequestDetailsSection, "Approval", record.Alert.Approval.String())
+
+		if record.Alert.Approval.Pending() {
+			addFactPair(
+				&msgCard,
+				disableUserRequestDetailsSection,
+				"Approval Links",
+				fmt.Sprintf(
+					"[Approve](%s) | [Reject](%s)",
+					record.Alert.Approval.ApproveURL,
+					record.Alert.Approval.RejectURL,
+				),
+			)
+		}
+	}
+
+	if record.Alert.CircuitBreaker != nil {
+		addFactPair(&msgCard, disableUserRequestDetailsSection, "Circuit Breaker", record.Alert.CircuitBreaker.String())
+	}
+
 	if err := msgCard.AddSection(disableUserRequestDetailsSection); err != nil {
 		errMsg := fmt.Sprintf("Error returned from attempt to add disableUserRequestDetailsSection: %v", err)
 		log.Errorf("%s: %v", myFuncName, errMsg)
@@ -291,6 +448,10 @@ func createTeamsMessage(record events.Record) goteamsnotify.MessageCard {
 	addFactPair(&msgCard, alertRequestSummarySection, "HTTP Method", record.Alert.HTTPMethod)
 	addFactPair(&msgCard, alertRequestSummarySection, "Alert Sender IP", record.Alert.PayloadSenderIP)
 
+	if record.Operator != "" {
+		addFactPair(&msgCard, alertRequestSummarySection, "Operator", record.Operator)
+	}
+
 	if err := msgCard.AddSection(alertRequestSummarySection); err != nil {
 		errMsg := fmt.Sprintf("Error returned from attempt to add alertRequestSummarySection: %v", err)
 		log.Errorf("%s: %v", myFuncName, errMsg)
@@ -507,6 +668,11 @@ type emailConfig struct {
 	notificationRetries    int
 	notificationRetryDelay int
 	template               *template.Template
+
+	// tenantRecipientAddresses is the list of recipients for notifications
+	// for each tenant, keyed by tenant name. Notifications for a tenant are
+	// sent only to these recipients.
+	tenantRecipientAddresses map[string][]string
 }
 
 // createEmailMessage receives an event record and a collection of settings
@@ -554,15 +720,25 @@ func createEmailMessage(record events.Record, emailCfg emailConfig) string {
 		emailBody = renderedTmpl.String()
 	}
 
+	// flag messages requiring prompt attention for mail clients which
+	// support it
+	var priorityHeaders string
+	if record.HighPriority() {
+		priorityHeaders = "X-Priority: 1\r\n" +
+			"Importance: high\r\n"
+	}
+
 	email := fmt.Sprintf(
 		"To: %s\r\n"+
 			"From: %s\r\n"+
 			"Subject: %s\r\n"+
+			"%s"+
 			"\r\n"+
 			"%s\r\n",
 		strings.Join(emailCfg.recipientAddresses, ", "),
 		emailCfg.senderAddress,
 		emailSubject,
+		priorityHeaders,
 		emailBody,
 	)
 
@@ -944,3 +1120,361 @@ func sendEmailMessage(
 	}
 
 }
+
+// webhookResponseBodyLimit is the maximum number of bytes of a webhook
+// response body included in error messages.
+const webhookResponseBodyLimit int64 = 512
+
+// webhookConfig represents user-provided settings specific to sending
+// webhook notifications.
+type webhookConfig struct {
+	url                    string
+	headers                http.Header
+	timeout                time.Duration
+	notificationRateLimit  time.Duration
+	notificationRetries    int
+	notificationRetryDelay int
+	template               *template.Template
+
+	// tenantURLs is the webhook URL for notifications for each tenant, keyed
+	// by tenant name. Notifications for a tenant are sent only to this URL.
+	tenantURLs map[string]string
+
+	// tenantHeaders is the set of HTTP headers included with each webhook
+	// request for each tenant, keyed by tenant name. Only the headers of the
+	// tenant are sent to its webhook URL.
+	tenantHeaders map[string]http.Header
+}
+
+// newWebhookHeaders converts the provided list of headers in "Name: value"
+// format into the HTTP headers included with each webhook request. The
+// headers are expected to have been validated first.
+func newWebhookHeaders(headers []string) http.Header {
+
+	webhookHeaders := make(http.Header)
+	for _, header := range headers {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		webhookHeaders.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+
+	return webhookHeaders
+}
+
+// webhookTemplateData is the value used to render webhook templates.
+type webhookTemplateData struct {
+	Record  events.Record
+	Title   string
+	Summary string
+}
+
+// newWebhookTemplate parses the webhook template from the provided file or
+// the built-in template if a file is not specified. The template is rendered
+// using a sample record to confirm that it produces valid JSON. A json
+// template function is provided to encode values as JSON.
+func newWebhookTemplate(templateFile string) (*template.Template, error) {
+
+	templateText := defaultWebhookTemplate
+	if templateFile != "" {
+
+		// Reading this file via variable is intentional; sysadmins provide
+		// site-specific templates.
+		//
+		// #nosec G304
+		content, err := ioutil.ReadFile(filepath.Clean(templateFile))
+		if err != nil {
+			return nil, fmt.Errorf("error reading webhook template file %q: %w", templateFile, err)
+		}
+		templateText = string(content)
+	}
+
+	tmpl, err := template.New("webhookTemplate").Option("missingkey=error").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"trim": strings.TrimSpace,
+		"join": strings.Join,
+	}).Parse(templateText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+
+	sample := events.Record{
+		Alert: events.SplunkAlertEvent{
+			Username:  "sample-user",
+			UserIP:    "192.0.2.1",
+			AlertName: "sample-alert",
+		},
+		Note:   "sample note",
+		Action: events.ActionSuccessDisabledUsername,
+	}
+	if _, err := renderWebhookMessage(tmpl, sample); err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// renderWebhookMessage renders the JSON request body for the provided event
+// record using the provided template. An error is returned if the rendered
+// body is not valid JSON.
+func renderWebhookMessage(tmpl *template.Template, record events.Record) ([]byte, error) {
+
+	data := webhookTemplateData{
+		Record: record,
+
+		// getMsgTitle() and getMsgSummaryText() are used for all messages
+		Title:   getMsgTitle(config.MyAppName+": ", record),
+		Summary: getMsgSummaryText(record),
+	}
+
+	var renderedTmpl bytes.Buffer
+	if err := tmpl.Execute(&renderedTmpl, data); err != nil {
+		return nil, fmt.Errorf("error rendering webhook template: %w", err)
+	}
+
+	if !json.Valid(renderedTmpl.Bytes()) {
+		return nil, fmt.Errorf("webhook template did not render valid JSON")
+	}
+
+	return renderedTmpl.Bytes(), nil
+}
+
+// createWebhookMessage receives an event record and a collection of settings
+// used to generate the JSON request body for a webhook notification.
+func createWebhookMessage(record events.Record, webhookCfg webhookConfig) ([]byte, error) {
+	return renderWebhookMessage(webhookCfg.template, record)
+}
+
+// sendWebhook is an analogue of the abstraction/functionality provided by
+// goteamsnotify.SendWithRetry(...). The provided JSON request body is
+// submitted to the webhook URL via a POST request; responses other than 2xx
+// are treated as failures.
+func sendWebhook(
+	ctx context.Context,
+	webhookCfg webhookConfig,
+	webhookMsg []byte,
+) error {
+
+	myFuncName := caller.GetFuncName()
+
+	client := &http.Client{Timeout: webhookCfg.timeout}
+
+	send := func(ctx context.Context) error {
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookCfg.url, bytes.NewReader(webhookMsg))
+		if err != nil {
+			return fmt.Errorf("failed to prepare webhook request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		for name, values := range webhookCfg.headers {
+			req.Header[name] = values
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to submit webhook request: %w", err)
+		}
+
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				log.Errorf("%s: error closing webhook response body: %v", myFuncName, err)
+			}
+		}()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, webhookResponseBodyLimit))
+			return fmt.Errorf(
+				"unexpected response status %q: %s",
+				resp.Status,
+				strings.TrimSpace(string(body)),
+			)
+		}
+
+		return nil
+	}
+
+	var result error
+
+	// initial attempt + number of specified retries
+	attemptsAllowed := 1 + webhookCfg.notificationRetries
+
+	ourRetryDelay := time.Duration(webhookCfg.notificationRetryDelay) * time.Second
+
+	// attempt to send message to the webhook URL, retry specified number of
+	// times before giving up
+	for attempt := 1; attempt <= attemptsAllowed; attempt++ {
+
+		// Check here at the start of the loop iteration (either first or
+		// subsequent) in order to return early in an effort to prevent
+		// undesired message attempts after the context has been cancelled.
+		if ctx.Err() != nil {
+			errMsg := fmt.Errorf(
+				"%s: context cancelled or expired: %v; aborting message submission after %d of %d attempts",
+				myFuncName,
+				ctx.Err().Error(),
+				attempt,
+				attemptsAllowed,
+			)
+
+			if result != nil {
+				errMsg = fmt.Errorf("%w: %v", result, errMsg)
+			}
+
+			log.Error(errMsg.Error())
+			return errMsg
+		}
+
+		result = send(ctx)
+		if result != nil {
+
+			log.Errorf(
+				"%s: Attempt %d of %d to send message failed: %v",
+				myFuncName,
+				attempt,
+				attemptsAllowed,
+				result,
+			)
+
+			// apply retry delay if attempts remain and our context hasn't
+			// been cancelled yet
+			if attempt < attemptsAllowed && ctx.Err() == nil {
+				log.Debugf(
+					"%s: Context not cancelled yet, applying retry delay of %v",
+					myFuncName,
+					ourRetryDelay,
+				)
+				time.Sleep(ourRetryDelay)
+			}
+
+			continue
+		}
+
+		log.Debugf(
+			"%s: successfully sent message after %d of %d attempts",
+			myFuncName,
+			attempt,
+			attemptsAllowed,
+		)
+
+		break
+	}
+
+	return result
+}
+
+// sendWebhookMessage wraps and orchestrates another function call to send
+// webhook messages. This includes honoring the provided schedule in order to
+// comply with remote API rate limits.
+func sendWebhookMessage(
+	ctx context.Context,
+	webhookCfg webhookConfig,
+	webhookMsg []byte,
+	schedule time.Time,
+) NotifyResult {
+
+	myFuncName := caller.GetFuncName()
+
+	if webhookCfg.url == "" {
+		return NotifyResult{
+			Err: fmt.Errorf(
+				"%s: webhook URL not defined, skipping message submission",
+				myFuncName,
+			),
+			Success: false,
+		}
+	}
+
+	log.Debugf("%s: Time now is %v", myFuncName, time.Now().Format("15:04:05"))
+	log.Debugf("%s: Notification scheduled for: %v", myFuncName, schedule.Format("15:04:05"))
+
+	// Set delay timer to meet received notification schedule. This helps
+	// ensure that we delay the appropriate amount of time before we make our
+	// first attempt at sending a message to the webhook URL.
+	notificationDelay := time.Until(schedule)
+
+	notificationDelayTimer := time.NewTimer(notificationDelay)
+	defer notificationDelayTimer.Stop()
+	log.Debugf("%s: notificationDelayTimer created at %v with duration %v",
+		myFuncName,
+		time.Now().Format("15:04:05"),
+		notificationDelay,
+	)
+
+	select {
+	case <-ctx.Done():
+		ctxErr := ctx.Err()
+		msg := NotifyResult{
+			Val: fmt.Sprintf("%s: Received Done signal at %v: %v, shutting down",
+				myFuncName,
+				time.Now().Format("15:04:05"),
+				ctxErr.Error(),
+			),
+			Success: false,
+		}
+		log.Debug(msg.Val)
+		return msg
+
+	// Delay between message submission attempts; this will *always*
+	// delay, regardless of whether the attempt is the first one or not
+	case <-notificationDelayTimer.C:
+
+		log.Debugf("%s: Waited %v before notification attempt at %v",
+			myFuncName,
+			notificationDelay,
+			time.Now().Format("15:04:05"),
+		)
+
+		// check to see if context has expired during our delay
+		if ctx.Err() != nil {
+			msg := NotifyResult{
+				Val: fmt.Sprintf(
+					"%s: context expired or cancelled at %v: %v, attempting to abort message submission",
+					myFuncName,
+					time.Now().Format("15:04:05"),
+					ctx.Err().Error(),
+				),
+				Success: false,
+			}
+
+			log.Debug(msg.Val)
+
+			return msg
+		}
+
+		if err := sendWebhook(ctx, webhookCfg, webhookMsg); err != nil {
+			errMsg := NotifyResult{
+				Err: fmt.Errorf(
+					"%s: ERROR: Failed to submit message to webhook URL at %v: %v",
+					myFuncName,
+					time.Now().Format("15:04:05"),
+					err,
+				),
+				Success: false,
+			}
+			log.Error(errMsg.Err.Error())
+
+			return errMsg
+		}
+
+		successMsg := NotifyResult{
+			Val: fmt.Sprintf(
+				"%s: Message successfully sent to webhook URL at %v",
+				myFuncName,
+				time.Now().Format("15:04:05"),
+			),
+			Success: true,
+		}
+
+		// Note success for potential troubleshooting
+		log.Debug(successMsg.Val)
+
+		return successMsg
+
+	}
+
+}
@@ -0,0 +1,213 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/atc0005/brick/events"
+)
+
+func TestDefaultWebhookTemplate(t *testing.T) {
+
+	// the username and note include characters which must be escaped in
+	// JSON
+	disabledRecord := testRecord()
+	disabledRecord.Note = "line one\nline \"two\""
+	disabledRecord.Alert.Username = `j"smith\`
+
+	tests := []struct {
+		name   string
+		record events.Record
+		want   map[string]interface{}
+	}{
+		{
+			name:   "disabled user",
+			record: disabledRecord,
+			want: map[string]interface{}{
+				"action":          events.ActionSuccessDisabledUsername,
+				"high_priority":   false,
+				"note":            "line one\nline \"two\"",
+				"error":           nil,
+				"username":        `j"smith\`,
+				"user_ip":         "192.168.2.3",
+				"alert_name":      "Brute force <library> & more",
+				"search_id":       "scheduler__abc",
+				"tenant":          "library-a",
+				"instances":       []interface{}{"proxy-a", "proxy-b"},
+				"operator":        "",
+				"received_at":     "2020-10-19 08:15:00",
+				"alert_sender_ip": "10.1.2.3:51234",
+			},
+		},
+		{
+			name: "failure",
+			record: events.Record{
+				Action:   events.ActionFailureDisabledUsername,
+				Error:    errors.New(`failed to write "disabled users" file`),
+				Operator: "jdoe",
+				Alert: events.SplunkAlertEvent{
+					Username: "jsmith",
+				},
+			},
+			want: map[string]interface{}{
+				"action":          events.ActionFailureDisabledUsername,
+				"high_priority":   false,
+				"note":            "",
+				"error":           `failed to write "disabled users" file`,
+				"username":        "jsmith",
+				"user_ip":         "",
+				"alert_name":      "",
+				"search_id":       "",
+				"tenant":          "",
+				"instances":       nil,
+				"operator":        "jdoe",
+				"received_at":     "",
+				"alert_sender_ip": "",
+			},
+		},
+	}
+
+	tmpl, err := newWebhookTemplate("")
+	if err != nil {
+		t.Fatalf("newWebhookTemplate() returned error for built-in template: %v", err)
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			body, err := renderWebhookMessage(tmpl, tt.record)
+			if err != nil {
+				t.Fatalf("renderWebhookMessage() returned error: %v", err)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("failed to decode webhook body %s: %v", body, err)
+			}
+
+			// the title and summary are shared with the other notification
+			// formats; only confirm that they are present
+			for _, key := range []string{"title", "summary"} {
+				if value, ok := got[key].(string); !ok || value == "" {
+					t.Errorf("webhook body field %q = %v; want non-empty string", key, got[key])
+				}
+				delete(got, key)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("webhook body = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWebhookTemplateFile(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "custom fields",
+			template: `{"text": {{ json .Record.Action }}, "user": {{ json .Record.Alert.Username }}, "instances": {{ json (join .Record.Alert.Instances ",") }}}`,
+			want:     `{"text": "Username disabled", "user": "jsmith", "instances": "proxy-a,proxy-b"}`,
+		},
+		{
+			name:     "trimmed note",
+			template: `{"note": {{ json (trim .Record.Note) }}}`,
+			want:     `{"note": "sample"}`,
+		},
+		{
+			name:     "invalid syntax",
+			template: `{"user": {{ json .Record.Alert.Username }`,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown field",
+			template: `{"user": {{ json .Record.Alert.Bogus }}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "unquoted value renders invalid JSON",
+			template: `{"user": {{ .Record.Alert.Username }}}`,
+			wantErr:  true,
+		},
+	}
+
+	record := testRecord()
+	record.Note = "  sample \n"
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			templateFile := filepath.Join(t.TempDir(), "webhook.tmpl")
+			if err := ioutil.WriteFile(templateFile, []byte(tt.template), 0600); err != nil {
+				t.Fatalf("failed to write template file: %v", err)
+			}
+
+			tmpl, err := newWebhookTemplate(templateFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newWebhookTemplate() returned error %v; want error: %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			body, err := renderWebhookMessage(tmpl, record)
+			if err != nil {
+				t.Fatalf("renderWebhookMessage() returned error: %v", err)
+			}
+
+			if string(body) != tt.want {
+				t.Errorf("renderWebhookMessage() = %s; want %s", body, tt.want)
+			}
+		})
+	}
+
+	if _, err := newWebhookTemplate(filepath.Join(t.TempDir(), "missing.tmpl")); err == nil {
+		t.Error("newWebhookTemplate() returned nil error for missing template file")
+	}
+}
+
+func TestNewWebhookHeaders(t *testing.T) {
+
+	headers := newWebhookHeaders([]string{
+		"Authorization: Bearer abc:123",
+		" X-Source :brick ",
+		"X-Source: second",
+		"invalid",
+	})
+
+	want := map[string][]string{
+		"Authorization": {"Bearer abc:123"},
+		"X-Source":      {"brick", "second"},
+	}
+
+	if !reflect.DeepEqual(map[string][]string(headers), want) {
+		t.Errorf("newWebhookHeaders() = %v; want %v", headers, want)
+	}
+}
@@ -19,6 +19,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"runtime"
 	"strings"
 	"text/template"
@@ -65,7 +66,8 @@ type NotifyQueue struct {
 	Capacity int
 }
 
-// NotifyStats is a collection of stats for Teams and Email notifications
+// NotifyStats is a collection of stats for Teams, Email, Slack and webhook
+// notifications
 type NotifyStats struct {
 
 	// These fields are collected directly
@@ -76,10 +78,18 @@ type NotifyStats struct {
 	EmailMsgSent        int
 	EmailMsgSuccess     int
 	EmailMsgFailure     int
+	SlackMsgSent        int
+	SlackMsgSuccess     int
+	SlackMsgFailure     int
+	WebhookMsgSent      int
+	WebhookMsgSuccess   int
+	WebhookMsgFailure   int
 
 	// These fields are calculated from collected field values
-	TeamsMsgPending int
-	EmailMsgPending int
+	TeamsMsgPending   int
+	EmailMsgPending   int
+	SlackMsgPending   int
+	WebhookMsgPending int
 
 	TotalPendingMsg int
 	TotalSuccessMsg int
@@ -207,6 +217,24 @@ func notifyStatsMonitor(ctx context.Context, delay time.Duration, statsQueue <-c
 				stats.EmailMsgFailure,
 			)
 
+			ctxLog.Infof(
+				"notifyStatsMonitor: Slack: "+
+					"[%d total, %d pending, %d success, %d failure]",
+				stats.SlackMsgSent,
+				stats.SlackMsgPending,
+				stats.SlackMsgSuccess,
+				stats.SlackMsgFailure,
+			)
+
+			ctxLog.Infof(
+				"notifyStatsMonitor: Webhook: "+
+					"[%d total, %d pending, %d success, %d failure]",
+				stats.WebhookMsgSent,
+				stats.WebhookMsgPending,
+				stats.WebhookMsgSuccess,
+				stats.WebhookMsgFailure,
+			)
+
 		// received stats update; update our totals
 		case statsUpdate := <-statsQueue:
 
@@ -220,6 +248,14 @@ func notifyStatsMonitor(ctx context.Context, delay time.Duration, statsQueue <-c
 			stats.EmailMsgSuccess += statsUpdate.EmailMsgSuccess
 			stats.EmailMsgFailure += statsUpdate.EmailMsgFailure
 
+			stats.SlackMsgSent += statsUpdate.SlackMsgSent
+			stats.SlackMsgSuccess += statsUpdate.SlackMsgSuccess
+			stats.SlackMsgFailure += statsUpdate.SlackMsgFailure
+
+			stats.WebhookMsgSent += statsUpdate.WebhookMsgSent
+			stats.WebhookMsgSuccess += statsUpdate.WebhookMsgSuccess
+			stats.WebhookMsgFailure += statsUpdate.WebhookMsgFailure
+
 			// calculate non-collected stats here
 			stats.TeamsMsgPending = stats.TeamsMsgSent -
 				(stats.TeamsMsgSuccess + stats.TeamsMsgFailure)
@@ -227,9 +263,18 @@ func notifyStatsMonitor(ctx context.Context, delay time.Duration, statsQueue <-c
 			stats.EmailMsgPending = stats.EmailMsgSent -
 				(stats.EmailMsgSuccess + stats.EmailMsgFailure)
 
-			stats.TotalPendingMsg = stats.EmailMsgPending + stats.TeamsMsgPending
-			stats.TotalFailureMsg = stats.EmailMsgFailure + stats.TeamsMsgFailure
-			stats.TotalSuccessMsg = stats.EmailMsgSuccess + stats.TeamsMsgSuccess
+			stats.SlackMsgPending = stats.SlackMsgSent -
+				(stats.SlackMsgSuccess + stats.SlackMsgFailure)
+
+			stats.WebhookMsgPending = stats.WebhookMsgSent -
+				(stats.WebhookMsgSuccess + stats.WebhookMsgFailure)
+
+			stats.TotalPendingMsg = stats.EmailMsgPending + stats.TeamsMsgPending +
+				stats.SlackMsgPending + stats.WebhookMsgPending
+			stats.TotalFailureMsg = stats.EmailMsgFailure + stats.TeamsMsgFailure +
+				stats.SlackMsgFailure + stats.WebhookMsgFailure
+			stats.TotalSuccessMsg = stats.EmailMsgSuccess + stats.TeamsMsgSuccess +
+				stats.SlackMsgSuccess + stats.WebhookMsgSuccess
 
 		}
 	}
@@ -337,11 +382,17 @@ func notifyQueueMonitor(ctx context.Context, delay time.Duration, notifyQueues .
 
 // teamsNotifier is a persistent goroutine used to receive incoming
 // notification requests and spin off goroutines to create and send Microsoft
-// Teams messages.
+// Teams messages. Each message is sent to the webhook URL of the tenant
+// which received the alert in the message format of that tenant; the webhook
+// URL and message format for the default endpoints are keyed by an empty
+// tenant name. The optional unblock URL template is used by the Unblock user
+// action of Adaptive Card messages.
 // TODO: Refactor per GH-37
 func teamsNotifier(
 	ctx context.Context,
-	webhookURL string,
+	webhookURLs map[string]string,
+	messageFormats map[string]string,
+	unblockURL *template.Template,
 	sendTimeout time.Duration,
 	sendRateLimit time.Duration,
 	retries int,
@@ -437,16 +488,32 @@ func teamsNotifier(
 			go func(
 				ctx context.Context,
 				webhookURL string,
+				messageFormat string,
 				record events.Record,
 				schedule time.Time,
 				numRetries int,
 				retryDelay int,
 				resultQueue chan<- NotifyResult) {
 
+				if messageFormat == config.TeamsMessageFormatAdaptiveCard {
+					ourMessage := createTeamsAdaptiveCard(record, unblockURL)
+					resultQueue <- sendTeamsAdaptiveCard(ctx, webhookURL, ourMessage, schedule, numRetries, retryDelay)
+					return
+				}
+
 				ourMessage := createTeamsMessage(record)
 				resultQueue <- sendTeamsMessage(ctx, webhookURL, ourMessage, schedule, numRetries, retryDelay)
 
-			}(ctx, webhookURL, record, nextScheduledNotification, retries, retriesDelay, ourResultQueue)
+			}(
+				ctx,
+				webhookURLs[record.Alert.Tenant],
+				messageFormats[record.Alert.Tenant],
+				record,
+				nextScheduledNotification,
+				retries,
+				retriesDelay,
+				ourResultQueue,
+			)
 
 		case result := <-ourResultQueue:
 			if result.Err != nil {
@@ -463,9 +530,134 @@ func teamsNotifier(
 
 }
 
+// slackNotifier is a persistent goroutine used to receive incoming
+// notification requests and spin off goroutines to create and send Slack
+// messages. Each message is sent to the webhook URL of the tenant which
+// received the alert; the webhook URL for the default endpoints is keyed by
+// an empty tenant name.
+func slackNotifier(
+	ctx context.Context,
+	webhookURLs map[string]string,
+	sendTimeout time.Duration,
+	sendRateLimit time.Duration,
+	retries int,
+	retriesDelay int,
+	incoming <-chan events.Record,
+	notifyMgrResultQueue chan<- NotifyResult,
+	done chan<- struct{},
+) {
+
+	log.Debug("slackNotifier: Running")
+
+	// used by goroutines called by this function to return results
+	ourResultQueue := make(chan NotifyResult)
+
+	// Setup new scheduler that we can use to add an intentional delay between
+	// Slack notification attempts. This delay is added in order to rate limit
+	// our outgoing messages to comply with remote API limits.
+	// https://api.slack.com/docs/rate-limits
+	notifyScheduler := newNotifyScheduler(sendRateLimit)
+
+	for {
+
+		select {
+
+		case <-ctx.Done():
+
+			ctxErr := ctx.Err()
+			result := NotifyResult{
+				Val: fmt.Sprintf("slackNotifier: Received Done signal: %v, shutting down", ctxErr.Error()),
+			}
+			log.Debug(result.Val)
+
+			log.Debug("slackNotifier: Sending back results")
+			notifyMgrResultQueue <- result
+
+			log.Debug("slackNotifier: Closing notifyMgrResultQueue channel to signal shutdown")
+			close(notifyMgrResultQueue)
+
+			log.Debug("slackNotifier: Closing done channel to signal shutdown")
+			close(done)
+			log.Debug("slackNotifier: done channel closed, returning")
+			return
+
+		case record := <-incoming:
+
+			log.Debugf("slackNotifier: Request received at %v: %#v",
+				time.Now(), record)
+
+			log.Debug("Calculating next scheduled notification")
+			nextScheduledNotification := notifyScheduler()
+
+			log.Debugf("Now: %v, Next scheduled notification: %v",
+				time.Now().Format("15:04:05"),
+				nextScheduledNotification.Format("15:04:05"),
+			)
+
+			timeoutValue := config.GetNotificationTimeout(
+				sendTimeout,
+				nextScheduledNotification,
+				retries,
+				retriesDelay,
+			)
+
+			ctx, cancel := context.WithTimeout(ctx, timeoutValue)
+			defer cancel()
+
+			log.Debugf("slackNotifier: child context created with timeout duration %v", timeoutValue)
+
+			// see the teamsNotifier for why the context is checked here
+			log.Debug("slackNotifier: Checking context to determine whether we should proceed")
+
+			if ctx.Err() != nil {
+				result := NotifyResult{
+					Success: false,
+					Val:     "slackNotifier: context has been cancelled, aborting notification attempt",
+				}
+				log.Debug(result.Val)
+				notifyMgrResultQueue <- result
+
+				continue
+			}
+
+			log.Debug("slackNotifier: context not cancelled, proceeding with notification attempt")
+
+			// launch task in separate goroutine, each with its own schedule
+			log.Debug("slackNotifier: Launching message creation/submission in separate goroutine")
+
+			go func(
+				ctx context.Context,
+				webhookURL string,
+				record events.Record,
+				schedule time.Time,
+				numRetries int,
+				retryDelay int,
+				resultQueue chan<- NotifyResult) {
+
+				ourMessage := createSlackMessage(record)
+				resultQueue <- sendSlackMessage(ctx, webhookURL, ourMessage, schedule, numRetries, retryDelay)
+
+			}(ctx, webhookURLs[record.Alert.Tenant], record, nextScheduledNotification, retries, retriesDelay, ourResultQueue)
+
+		case result := <-ourResultQueue:
+			if result.Err != nil {
+				log.Errorf("slackNotifier: Error received from ourResultQueue: %v", result.Err)
+			} else {
+				log.Debugf("slackNotifier: OK: non-error status received on ourResultQueue: %v", result.Val)
+			}
+
+			notifyMgrResultQueue <- result
+
+		}
+
+	}
+
+}
+
 // emailNotifier is a persistent goroutine used to receive incoming
 // notification requests and spin off goroutines to create and send email
-// messages.
+// messages. Messages for alerts received by a tenant are sent only to the
+// recipients for that tenant.
 // TODO: Refactor per GH-37
 func emailNotifier(
 	ctx context.Context,
@@ -566,6 +758,9 @@ func emailNotifier(
 				emailCfg emailConfig,
 				resultQueue chan<- NotifyResult,
 			) {
+				if record.Alert.Tenant != "" {
+					emailCfg.recipientAddresses = emailCfg.tenantRecipientAddresses[record.Alert.Tenant]
+				}
 				ourMessage := createEmailMessage(record, emailCfg)
 				resultQueue <- sendEmailMessage(ctx, emailCfg, ourMessage, schedule)
 			}(ctx, record, nextScheduledNotification, emailCfg, ourResultQueue)
@@ -585,9 +780,146 @@ func emailNotifier(
 
 }
 
+// webhookNotifier is a persistent goroutine used to receive incoming
+// notification requests and spin off goroutines to create and send webhook
+// messages. Each message is sent to the webhook URL of the tenant which
+// received the alert; the webhook URL for the default endpoints is keyed by
+// an empty tenant name.
+func webhookNotifier(
+	ctx context.Context,
+	webhookCfg webhookConfig,
+	incoming <-chan events.Record,
+	notifyMgrResultQueue chan<- NotifyResult,
+	done chan<- struct{},
+) {
+
+	log.Debug("webhookNotifier: Running")
+
+	// used by goroutines called by this function to return results
+	ourResultQueue := make(chan NotifyResult)
+
+	// Setup new scheduler that we can use to add an intentional delay between
+	// webhook notification attempts. This delay is added in order to rate
+	// limit our outgoing messages to comply with remote API limits.
+	notifyScheduler := newNotifyScheduler(webhookCfg.notificationRateLimit)
+
+	for {
+
+		select {
+
+		case <-ctx.Done():
+
+			ctxErr := ctx.Err()
+			result := NotifyResult{
+				Val: fmt.Sprintf("webhookNotifier: Received Done signal: %v, shutting down", ctxErr.Error()),
+			}
+			log.Debug(result.Val)
+
+			log.Debug("webhookNotifier: Sending back results")
+			notifyMgrResultQueue <- result
+
+			log.Debug("webhookNotifier: Closing notifyMgrResultQueue channel to signal shutdown")
+			close(notifyMgrResultQueue)
+
+			log.Debug("webhookNotifier: Closing done channel to signal shutdown")
+			close(done)
+			log.Debug("webhookNotifier: done channel closed, returning")
+			return
+
+		case record := <-incoming:
+
+			log.Debugf("webhookNotifier: Request received at %v: %#v",
+				time.Now(), record)
+
+			log.Debug("Calculating next scheduled notification")
+			nextScheduledNotification := notifyScheduler()
+
+			log.Debugf("Now: %v, Next scheduled notification: %v",
+				time.Now().Format("15:04:05"),
+				nextScheduledNotification.Format("15:04:05"),
+			)
+
+			timeoutValue := config.GetNotificationTimeout(
+				webhookCfg.timeout,
+				nextScheduledNotification,
+				webhookCfg.notificationRetries,
+				webhookCfg.notificationRetryDelay,
+			)
+
+			ctx, cancel := context.WithTimeout(ctx, timeoutValue)
+			defer cancel()
+
+			log.Debugf("webhookNotifier: child context created with timeout duration %v", timeoutValue)
+
+			// see the teamsNotifier for why the context is checked here
+			log.Debug("webhookNotifier: Checking context to determine whether we should proceed")
+
+			if ctx.Err() != nil {
+				result := NotifyResult{
+					Success: false,
+					Val:     "webhookNotifier: context has been cancelled, aborting notification attempt",
+				}
+				log.Debug(result.Val)
+				notifyMgrResultQueue <- result
+
+				continue
+			}
+
+			log.Debug("webhookNotifier: context not cancelled, proceeding with notification attempt")
+
+			// launch task in separate goroutine, each with its own schedule
+			log.Debug("webhookNotifier: Launching message creation/submission in separate goroutine")
+
+			go func(
+				ctx context.Context,
+				record events.Record,
+				schedule time.Time,
+				webhookCfg webhookConfig,
+				resultQueue chan<- NotifyResult,
+			) {
+				webhookCfg.url = webhookCfg.tenantURLs[record.Alert.Tenant]
+				webhookCfg.headers = webhookCfg.tenantHeaders[record.Alert.Tenant]
+				ourMessage, err := createWebhookMessage(record, webhookCfg)
+				if err != nil {
+					resultQueue <- NotifyResult{
+						Err:     fmt.Errorf("webhookNotifier: failed to create webhook message: %w", err),
+						Success: false,
+					}
+					return
+				}
+				resultQueue <- sendWebhookMessage(ctx, webhookCfg, ourMessage, schedule)
+			}(ctx, record, nextScheduledNotification, webhookCfg, ourResultQueue)
+
+		case result := <-ourResultQueue:
+
+			if result.Err != nil {
+				log.Errorf("webhookNotifier: Error received from ourResultQueue: %v", result.Err)
+			} else {
+				log.Debugf("webhookNotifier: OK: non-error status received on ourResultQueue: %v", result.Val)
+			}
+
+			notifyMgrResultQueue <- result
+
+		}
+	}
+
+}
+
 // NotifyMgr receives event details from elsewhere in the application and
-// sends notifications to any enabled service (e.g., Microsoft Teams).
-func NotifyMgr(ctx context.Context, cfg *config.Config, notifyWorkQueue <-chan events.Record, done chan<- struct{}) {
+// sends notifications to any enabled service (e.g., Microsoft Teams). The
+// provided hooks are run for each event record before notifications for it
+// are sent so that the hook results can be included. The provided templates
+// are used to render the request body for webhook notifications and the URL
+// of the Unblock user action of Adaptive Card messages, respectively.
+func NotifyMgr(
+	ctx context.Context,
+	cfg *config.Config,
+	recordHooks hooks,
+	webhookTemplate *template.Template,
+	teamsUnblockURL *template.Template,
+	notifyWorkQueue <-chan events.Record,
+	done chan<- struct{},
+) {
 
 	log.Debug("NotifyMgr: Running")
 
@@ -607,11 +939,37 @@ func NotifyMgr(ctx context.Context, cfg *config.Config, notifyWorkQueue <-chan e
 	emailNotifyResultQueue := make(chan NotifyResult, config.NotifyMgrQueueDepth)
 	emailNotifyDone := make(chan struct{})
 
+	slackNotifyWorkQueue := make(chan events.Record, config.NotifyMgrQueueDepth)
+	slackNotifyResultQueue := make(chan NotifyResult, config.NotifyMgrQueueDepth)
+	slackNotifyDone := make(chan struct{})
+
+	webhookNotifyWorkQueue := make(chan events.Record, config.NotifyMgrQueueDepth)
+	webhookNotifyResultQueue := make(chan NotifyResult, config.NotifyMgrQueueDepth)
+	webhookNotifyDone := make(chan struct{})
+
 	notifyStatsQueue := make(chan NotifyStats, 1)
 
-	if !cfg.NotifyTeams() && !cfg.NotifyEmail() {
-		log.Warn("Teams and email notifications from this application are not enabled.")
-		log.Debug("NotifyMgr: Teams and email notifications not requested, not starting notifier goroutines")
+	// Notifications for alerts received by a tenant are sent only to the
+	// targets of that tenant. The targets for the default endpoints are keyed
+	// by an empty tenant name.
+	teamsWebhookURLs := map[string]string{"": cfg.TeamsWebhookURL()}
+	teamsMessageFormats := map[string]string{"": cfg.TeamsMessageFormat()}
+	emailRecipientAddresses := map[string][]string{"": cfg.EmailRecipientAddresses()}
+	slackWebhookURLs := map[string]string{"": cfg.SlackWebhookURL()}
+	webhookURLs := map[string]string{"": cfg.WebhookURL()}
+	webhookHeaders := map[string]http.Header{"": newWebhookHeaders(cfg.WebhookHeaders())}
+	for _, tenant := range cfg.Tenants() {
+		teamsWebhookURLs[tenant.Name] = tenant.TeamsWebhookURL
+		teamsMessageFormats[tenant.Name] = tenant.TeamsMessageFormat
+		emailRecipientAddresses[tenant.Name] = tenant.EmailRecipients
+		slackWebhookURLs[tenant.Name] = tenant.SlackWebhookURL
+		webhookURLs[tenant.Name] = tenant.WebhookURL
+		webhookHeaders[tenant.Name] = newWebhookHeaders(tenant.WebhookHeaders)
+	}
+
+	if !cfg.NotifyTeams() && !cfg.NotifyEmail() && !cfg.NotifySlack() && !cfg.NotifyWebhook() {
+		log.Warn("Teams, email, Slack and webhook notifications from this application are not enabled.")
+		log.Debug("NotifyMgr: Teams, email, Slack and webhook notifications not requested, not starting notifier goroutines")
 		// NOTE: Do not return/exit here.
 		//
 		// We cannot return/exit the function here because NotifyMgr HAS
@@ -630,7 +988,9 @@ func NotifyMgr(ctx context.Context, cfg *config.Config, notifyWorkQueue <-chan e
 		log.Debug("NotifyMgr: Starting up teamsNotifier")
 		go teamsNotifier(
 			ctx,
-			cfg.TeamsWebhookURL(),
+			teamsWebhookURLs,
+			teamsMessageFormats,
+			teamsUnblockURL,
 			config.NotifyMgrTeamsNotificationTimeout,
 			cfg.TeamsNotificationRateLimit(),
 			cfg.TeamsNotificationRetries(),
@@ -674,6 +1034,11 @@ func NotifyMgr(ctx context.Context, cfg *config.Config, notifyWorkQueue <-chan e
 					return i + 1
 				},
 				"trim": strings.TrimSpace,
+				"join": strings.Join,
+
+				// summarizes each session termination result; see
+				// events.TerminationOutcome
+				"outcome": events.TerminationOutcome,
 			}).Parse(activeTemplate))
 
 		// TODO: Refactor as fields for new email notifier (not sure of name
@@ -689,6 +1054,8 @@ func NotifyMgr(ctx context.Context, cfg *config.Config, notifyWorkQueue <-chan e
 			notificationRetries:    cfg.EmailNotificationRetries(),
 			notificationRetryDelay: cfg.EmailNotificationRetryDelay(),
 			template:               emailTemplate,
+
+			tenantRecipientAddresses: emailRecipientAddresses,
 		}
 
 		go emailNotifier(
@@ -700,6 +1067,56 @@ func NotifyMgr(ctx context.Context, cfg *config.Config, notifyWorkQueue <-chan e
 		)
 	}
 
+	// If enabled, start persistent goroutine to process request details and
+	// submit messages to Slack.
+	switch cfg.NotifySlack() {
+	case false:
+		log.Info("NotifyMgr: Slack notifications disabled")
+	case true:
+		log.Info("NotifyMgr: Slack notifications enabled")
+		log.Debug("NotifyMgr: Starting up slackNotifier")
+		go slackNotifier(
+			ctx,
+			slackWebhookURLs,
+			config.NotifyMgrSlackNotificationTimeout,
+			cfg.SlackNotificationRateLimit(),
+			cfg.SlackNotificationRetries(),
+			cfg.SlackNotificationRetryDelay(),
+			slackNotifyWorkQueue,
+			slackNotifyResultQueue,
+			slackNotifyDone,
+		)
+	}
+
+	// If enabled, start persistent goroutine to process request details and
+	// submit messages to the webhook URL.
+	switch cfg.NotifyWebhook() {
+	case false:
+		log.Info("NotifyMgr: Webhook notifications disabled")
+	case true:
+		log.Info("NotifyMgr: Webhook notifications enabled")
+		log.Debug("NotifyMgr: Starting up webhookNotifier")
+
+		webhookCfg := webhookConfig{
+			timeout:                config.NotifyMgrWebhookNotificationTimeout,
+			notificationRateLimit:  cfg.WebhookNotificationRateLimit(),
+			notificationRetries:    cfg.WebhookNotificationRetries(),
+			notificationRetryDelay: cfg.WebhookNotificationRetryDelay(),
+			template:               webhookTemplate,
+
+			tenantURLs:    webhookURLs,
+			tenantHeaders: webhookHeaders,
+		}
+
+		go webhookNotifier(
+			ctx,
+			webhookCfg,
+			webhookNotifyWorkQueue,
+			webhookNotifyResultQueue,
+			webhookNotifyDone,
+		)
+	}
+
 	// Monitor queues and report stats for each, even if the user has not
 	// opted to use notifications. This is done since we are tracking at least
 	// one queue (notifyStatsQueue) which is active even with notifiers
@@ -725,6 +1142,22 @@ func NotifyMgr(ctx context.Context, cfg *config.Config, notifyWorkQueue <-chan e
 			Name:    "teamsNotifyResultQueue",
 			Channel: teamsNotifyResultQueue,
 		},
+		{
+			Name:    "slackNotifyWorkQueue",
+			Channel: slackNotifyWorkQueue,
+		},
+		{
+			Name:    "slackNotifyResultQueue",
+			Channel: slackNotifyResultQueue,
+		},
+		{
+			Name:    "webhookNotifyWorkQueue",
+			Channel: webhookNotifyWorkQueue,
+		},
+		{
+			Name:    "webhookNotifyResultQueue",
+			Channel: webhookNotifyResultQueue,
+		},
 		{
 			Name:    "notifyStatsQueue",
 			Channel: notifyStatsQueue,
@@ -745,6 +1178,127 @@ func NotifyMgr(ctx context.Context, cfg *config.Config, notifyWorkQueue <-chan e
 		notifyStatsQueue,
 	)
 
+	// notify hands off the provided record to each enabled notifier which
+	// has a target for the tenant of the record
+	notify := func(record events.Record) {
+
+		// If we don't have *any* notifications enabled we will just
+		// discard the item we have pulled from the channel
+		if !cfg.NotifyEmail() && !cfg.NotifyTeams() && !cfg.NotifySlack() && !cfg.NotifyWebhook() {
+			log.Debug("NotifyMgr: Notifications are not currently enabled; ignoring notification request")
+			return
+		}
+
+		if cfg.NotifyTeams() && !record.NotifyTeams() {
+			log.Debugf("NotifyMgr: Teams notifications disabled by alert policy %s", record.Alert.Policy)
+		}
+
+		if cfg.NotifyTeams() && record.NotifyTeams() && teamsWebhookURLs[record.Alert.Tenant] == "" {
+			log.Debugf("NotifyMgr: No Teams webhook URL for tenant %q; skipping Teams notification", record.Alert.Tenant)
+		}
+
+		if cfg.NotifyTeams() && record.NotifyTeams() && teamsWebhookURLs[record.Alert.Tenant] != "" {
+			log.Debug("NotifyMgr: Creating new goroutine to place record into teamsNotifyWorkQueue")
+
+			// TODO: Perhaps record this *after* sending the record
+			// down the teamsNotifyWorkQueue channel? See other cases
+			// where we're using the same "record stat, then do it"
+			// approach.
+
+			go func() {
+				notifyStatsQueue <- NotifyStats{
+					TeamsMsgSent: 1,
+				}
+			}()
+
+			go func() {
+				log.Debugf("NotifyMgr: Existing items in teamsNotifyWorkQueue: %d", len(teamsNotifyWorkQueue))
+				log.Debug("NotifyMgr: Pending; placing record into teamsNotifyWorkQueue")
+				teamsNotifyWorkQueue <- record
+				log.Debug("NotifyMgr: Done; placed record into teamsNotifyWorkQueue")
+				log.Debugf("NotifyMgr: Items now in teamsNotifyWorkQueue: %d", len(teamsNotifyWorkQueue))
+			}()
+		}
+
+		if cfg.NotifyEmail() && !record.NotifyEmail() {
+			log.Debugf("NotifyMgr: Email notifications disabled by alert policy %s", record.Alert.Policy)
+		}
+
+		if cfg.NotifyEmail() && record.NotifyEmail() && len(emailRecipientAddresses[record.Alert.Tenant]) == 0 {
+			log.Debugf("NotifyMgr: No email recipients for tenant %q; skipping email notification", record.Alert.Tenant)
+		}
+
+		if cfg.NotifyEmail() && record.NotifyEmail() && len(emailRecipientAddresses[record.Alert.Tenant]) > 0 {
+			log.Debug("NotifyMgr: Creating new goroutine to place record in emailNotifyWorkQueue")
+
+			go func() {
+				notifyStatsQueue <- NotifyStats{
+					EmailMsgSent: 1,
+				}
+			}()
+
+			go func() {
+				log.Debugf("NotifyMgr: Existing items in emailNotifyWorkQueue: %d", len(emailNotifyWorkQueue))
+				log.Debug("NotifyMgr: Pending; placing record into emailNotifyWorkQueue")
+				emailNotifyWorkQueue <- record
+				log.Debug("NotifyMgr: Done; placed record into emailNotifyWorkQueue")
+				log.Debugf("NotifyMgr: Items now in emailNotifyWorkQueue: %d", len(emailNotifyWorkQueue))
+			}()
+		}
+
+		if cfg.NotifySlack() && !record.NotifySlack() {
+			log.Debugf("NotifyMgr: Slack notifications disabled by alert policy %s", record.Alert.Policy)
+		}
+
+		if cfg.NotifySlack() && record.NotifySlack() && slackWebhookURLs[record.Alert.Tenant] == "" {
+			log.Debugf("NotifyMgr: No Slack webhook URL for tenant %q; skipping Slack notification", record.Alert.Tenant)
+		}
+
+		if cfg.NotifySlack() && record.NotifySlack() && slackWebhookURLs[record.Alert.Tenant] != "" {
+			log.Debug("NotifyMgr: Creating new goroutine to place record in slackNotifyWorkQueue")
+
+			go func() {
+				notifyStatsQueue <- NotifyStats{
+					SlackMsgSent: 1,
+				}
+			}()
+
+			go func() {
+				log.Debugf("NotifyMgr: Existing items in slackNotifyWorkQueue: %d", len(slackNotifyWorkQueue))
+				log.Debug("NotifyMgr: Pending; placing record into slackNotifyWorkQueue")
+				slackNotifyWorkQueue <- record
+				log.Debug("NotifyMgr: Done; placed record into slackNotifyWorkQueue")
+				log.Debugf("NotifyMgr: Items now in slackNotifyWorkQueue: %d", len(slackNotifyWorkQueue))
+			}()
+		}
+
+		if cfg.NotifyWebhook() && !record.NotifyWebhook() {
+			log.Debugf("NotifyMgr: Webhook notifications disabled by alert policy %s", record.Alert.Policy)
+		}
+
+		if cfg.NotifyWebhook() && record.NotifyWebhook() && webhookURLs[record.Alert.Tenant] == "" {
+			log.Debugf("NotifyMgr: No webhook URL for tenant %q; skipping webhook notification", record.Alert.Tenant)
+		}
+
+		if cfg.NotifyWebhook() && record.NotifyWebhook() && webhookURLs[record.Alert.Tenant] != "" {
+			log.Debug("NotifyMgr: Creating new goroutine to place record in webhookNotifyWorkQueue")
+
+			go func() {
+				notifyStatsQueue <- NotifyStats{
+					WebhookMsgSent: 1,
+				}
+			}()
+
+			go func() {
+				log.Debugf("NotifyMgr: Existing items in webhookNotifyWorkQueue: %d", len(webhookNotifyWorkQueue))
+				log.Debug("NotifyMgr: Pending; placing record into webhookNotifyWorkQueue")
+				webhookNotifyWorkQueue <- record
+				log.Debug("NotifyMgr: Done; placed record into webhookNotifyWorkQueue")
+				log.Debugf("NotifyMgr: Items now in webhookNotifyWorkQueue: %d", len(webhookNotifyWorkQueue))
+			}()
+		}
+	}
+
 	for {
 
 		select {
@@ -805,6 +1359,44 @@ func NotifyMgr(ctx context.Context, cfg *config.Config, notifyWorkQueue <-chan e
 
 			}
 
+			if cfg.NotifySlack() {
+				log.Debug("NotifyMgr: Slack notifications are enabled, shutting down slackNotifier")
+
+				log.Debug("NotifyMgr: Ranging over slackNotifyResultQueue")
+				for result := range slackNotifyResultQueue {
+					evalResults("slackNotifyResultQueue", result)
+				}
+
+				log.Debug("NotifyMgr: Waiting on slackNotifyDone")
+				select {
+				case <-slackNotifyDone:
+					log.Debug("NotifyMgr: Received from slackNotifyDone")
+				case <-time.After(config.NotifyMgrServicesShutdownTimeout):
+					log.Debug("NotifyMgr: Timeout occurred while waiting for slackNotifyDone")
+					log.Debug("NotifyMgr: Proceeding with shutdown")
+				}
+
+			}
+
+			if cfg.NotifyWebhook() {
+				log.Debug("NotifyMgr: Webhook notifications are enabled, shutting down webhookNotifier")
+
+				log.Debug("NotifyMgr: Ranging over webhookNotifyResultQueue")
+				for result := range webhookNotifyResultQueue {
+					evalResults("webhookNotifyResultQueue", result)
+				}
+
+				log.Debug("NotifyMgr: Waiting on webhookNotifyDone")
+				select {
+				case <-webhookNotifyDone:
+					log.Debug("NotifyMgr: Received from webhookNotifyDone")
+				case <-time.After(config.NotifyMgrServicesShutdownTimeout):
+					log.Debug("NotifyMgr: Timeout occurred while waiting for webhookNotifyDone")
+					log.Debug("NotifyMgr: Proceeding with shutdown")
+				}
+
+			}
+
 			log.Debug("NotifyMgr: Closing done channel")
 			close(done)
 
@@ -821,53 +1413,19 @@ func NotifyMgr(ctx context.Context, cfg *config.Config, notifyWorkQueue <-chan e
 				}
 			}()
 
-			// If we don't have *any* notifications enabled we will just
-			// discard the item we have pulled from the channel
-			if !cfg.NotifyEmail() && !cfg.NotifyTeams() {
-				log.Debug("NotifyMgr: Notifications are not currently enabled; ignoring notification request")
-				continue
-			}
-
-			if cfg.NotifyTeams() {
-				log.Debug("NotifyMgr: Creating new goroutine to place record into teamsNotifyWorkQueue")
-
-				// TODO: Perhaps record this *after* sending the record
-				// down the teamsNotifyWorkQueue channel? See other cases
-				// where we're using the same "record stat, then do it"
-				// approach.
-
+			// Hooks may take a while to run, so they are run (and the
+			// notifications for the record, which include their results,
+			// are sent) without holding up other records
+			if selectedHooks := recordHooks.forRecord(record); len(selectedHooks) > 0 {
+				log.Debugf("NotifyMgr: Running %d hooks for %q event record", len(selectedHooks), record.Action)
 				go func() {
-					notifyStatsQueue <- NotifyStats{
-						TeamsMsgSent: 1,
-					}
-				}()
-
-				go func() {
-					log.Debugf("NotifyMgr: Existing items in teamsNotifyWorkQueue: %d", len(teamsNotifyWorkQueue))
-					log.Debug("NotifyMgr: Pending; placing record into teamsNotifyWorkQueue")
-					teamsNotifyWorkQueue <- record
-					log.Debug("NotifyMgr: Done; placed record into teamsNotifyWorkQueue")
-					log.Debugf("NotifyMgr: Items now in teamsNotifyWorkQueue: %d", len(teamsNotifyWorkQueue))
+					record.HookResults = selectedHooks.run(record)
+					notify(record)
 				}()
+				continue
 			}
 
-			if cfg.NotifyEmail() {
-				log.Debug("NotifyMgr: Creating new goroutine to place record in emailNotifyWorkQueue")
-
-				go func() {
-					notifyStatsQueue <- NotifyStats{
-						EmailMsgSent: 1,
-					}
-				}()
-
-				go func() {
-					log.Debugf("NotifyMgr: Existing items in emailNotifyWorkQueue: %d", len(emailNotifyWorkQueue))
-					log.Debug("NotifyMgr: Pending; placing record into emailNotifyWorkQueue")
-					emailNotifyWorkQueue <- record
-					log.Debug("NotifyMgr: Done; placed record into emailNotifyWorkQueue")
-					log.Debugf("NotifyMgr: Items now in emailNotifyWorkQueue: %d", len(emailNotifyWorkQueue))
-				}()
-			}
+			notify(record)
 
 		case result := <-teamsNotifyResultQueue:
 
@@ -921,6 +1479,56 @@ func NotifyMgr(ctx context.Context, cfg *config.Config, notifyWorkQueue <-chan e
 				notifyStatsQueue <- statsUpdate
 			}()
 
+		case result := <-slackNotifyResultQueue:
+
+			statsUpdate := NotifyStats{}
+
+			// NOTE: Only consider explicit success, not a non-error condition
+			// because cancellations and timeouts are (currently) treated as
+			// non-error, but they're not successful notifications.
+
+			if !result.Success {
+				if result.Err != nil {
+					log.Errorf("NotifyMgr: Error received from slackNotifyResultQueue: %v", result.Err)
+				}
+				statsUpdate.SlackMsgFailure = 1
+			}
+
+			if result.Success {
+				log.Debugf("NotifyMgr: OK: non-error status received on slackNotifyResultQueue: %v", result.Val)
+				log.Infof("NotifyMgr: %v", result.Val)
+				statsUpdate.SlackMsgSuccess = 1
+			}
+
+			go func() {
+				notifyStatsQueue <- statsUpdate
+			}()
+
+		case result := <-webhookNotifyResultQueue:
+
+			statsUpdate := NotifyStats{}
+
+			// NOTE: Only consider explicit success, not a non-error condition
+			// because cancellations and timeouts are (currently) treated as
+			// non-error, but they're not successful notifications.
+
+			if !result.Success {
+				if result.Err != nil {
+					log.Errorf("NotifyMgr: Error received from webhookNotifyResultQueue: %v", result.Err)
+				}
+				statsUpdate.WebhookMsgFailure = 1
+			}
+
+			if result.Success {
+				log.Debugf("NotifyMgr: non-error status received on webhookNotifyResultQueue: %v", result.Val)
+				log.Infof("NotifyMgr: %v", result.Val)
+				statsUpdate.WebhookMsgSuccess = 1
+			}
+
+			go func() {
+				notifyStatsQueue <- statsUpdate
+			}()
+
 		}
 
 	}
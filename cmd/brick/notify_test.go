@@ -0,0 +1,103 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atc0005/brick/events"
+)
+
+func TestWebhookNotifierTenantHeaders(t *testing.T) {
+
+	// the Authorization header received by each webhook URL, keyed by
+	// tenant name
+	received := make(chan map[string]string, 2)
+	newServer := func(tenant string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received <- map[string]string{tenant: r.Header.Get("Authorization")}
+		}))
+	}
+
+	defaultServer := newServer("")
+	defer defaultServer.Close()
+
+	tenantServer := newServer("library-a")
+	defer tenantServer.Close()
+
+	tmpl, err := newWebhookTemplate("")
+	if err != nil {
+		t.Fatalf("newWebhookTemplate() returned error for built-in template: %v", err)
+	}
+
+	webhookCfg := webhookConfig{
+		timeout:  5 * time.Second,
+		template: tmpl,
+		tenantURLs: map[string]string{
+			"":          defaultServer.URL,
+			"library-a": tenantServer.URL,
+		},
+		tenantHeaders: map[string]http.Header{
+			"":          newWebhookHeaders([]string{"Authorization: Bearer site-wide"}),
+			"library-a": newWebhookHeaders(nil),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	incoming := make(chan events.Record)
+	results := make(chan NotifyResult, 2)
+	done := make(chan struct{})
+	go webhookNotifier(ctx, webhookCfg, incoming, results, done)
+
+	tests := []struct {
+		tenant string
+		want   string
+	}{
+		{tenant: "library-a", want: ""},
+		{tenant: "", want: "Bearer site-wide"},
+	}
+
+	for _, tt := range tests {
+		incoming <- events.Record{
+			Action: events.ActionSuccessDisabledUsername,
+			Alert:  events.SplunkAlertEvent{Username: "jsmith", Tenant: tt.tenant},
+		}
+
+		if result := <-results; !result.Success {
+			t.Fatalf("webhook notification for tenant %q failed: %v", tt.tenant, result.Err)
+		}
+
+		got := <-received
+		authorization, ok := got[tt.tenant]
+		if !ok {
+			t.Errorf("webhook notification for tenant %q sent to %v", tt.tenant, got)
+		}
+
+		if authorization != tt.want {
+			t.Errorf("Authorization header for tenant %q = %q; want %q", tt.tenant, authorization, tt.want)
+		}
+	}
+
+	cancel()
+	<-done
+}
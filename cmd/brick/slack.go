@@ -0,0 +1,434 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/atc0005/brick/config"
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/internal/caller"
+)
+
+// Limits applied by Slack to Block Kit messages. Text exceeding these limits
+// is truncated so that messages are not rejected.
+// https://api.slack.com/reference/block-kit/blocks
+const (
+	slackMaxBlocks          int = 50
+	slackMaxHeaderLength    int = 150
+	slackMaxSectionLength   int = 3000
+	slackMaxFieldLength     int = 2000
+	slackMaxFieldsPerBlock  int = 10
+	slackTruncationEllipsis     = "…"
+)
+
+// slackText is a Block Kit text object.
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackBlock is a Block Kit layout block. Only the fields used by the header,
+// section, divider and context blocks are provided.
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+// slackMessage is the payload submitted to a Slack incoming webhook. Text is
+// used by Slack for notifications and as a fallback for clients which cannot
+// display blocks.
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// slackEscape escapes the characters which Slack treats as control
+// characters in mrkdwn text.
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// slackCode formats the provided value as inline code in an effort to keep
+// Slack from parsing special characters as mrkdwn formatting characters.
+func slackCode(value string) string {
+	if value == "" {
+		return "N/A"
+	}
+	return "`" + slackEscape(strings.ReplaceAll(value, "`", "'")) + "`"
+}
+
+// slackTruncate limits the provided text to the specified number of
+// characters.
+func slackTruncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-len([]rune(slackTruncationEllipsis))]) + slackTruncationEllipsis
+}
+
+// slackMrkdwn returns a mrkdwn text object for the provided text limited to
+// the specified number of characters.
+func slackMrkdwn(text string, limit int) *slackText {
+	return &slackText{Type: "mrkdwn", Text: slackTruncate(text, limit)}
+}
+
+// slackSection returns a section block with the provided title and (already
+// escaped) body text.
+func slackSection(title string, body string) slackBlock {
+	return slackBlock{
+		Type: "section",
+		Text: slackMrkdwn(fmt.Sprintf("*%s*\n%s", title, body), slackMaxSectionLength),
+	}
+}
+
+// slackField returns a section field with the provided key and one or many
+// values related to the key. Each value is formatted as inline code.
+func slackField(key string, values ...string) slackText {
+	formatted := make([]string, 0, len(values))
+	for _, value := range values {
+		formatted = append(formatted, slackCode(value))
+	}
+	return *slackMrkdwn(fmt.Sprintf("*%s*\n%s", slackEscape(key), strings.Join(formatted, " ")), slackMaxFieldLength)
+}
+
+// slackFieldSections returns the section blocks needed to display the
+// provided fields with the provided title and (already escaped) body text.
+// Slack permits a limited number of fields per section, so fields are split
+// across as many sections as needed.
+func slackFieldSections(title string, body string, fields []slackText) []slackBlock {
+
+	text := fmt.Sprintf("*%s*", title)
+	if body != "" {
+		text += "\n" + body
+	}
+
+	blocks := []slackBlock{{Type: "section", Text: slackMrkdwn(text, slackMaxSectionLength)}}
+	for start := 0; start < len(fields); start += slackMaxFieldsPerBlock {
+		end := start + slackMaxFieldsPerBlock
+		if end > len(fields) {
+			end = len(fields)
+		}
+		if start == 0 {
+			blocks[0].Fields = fields[start:end]
+			continue
+		}
+		blocks = append(blocks, slackBlock{Type: "section", Fields: fields[start:end]})
+	}
+
+	return blocks
+}
+
+// createSlackMessage receives an event Record and generates a Block Kit
+// message which is used to generate a Slack message. The sections mirror
+// those of Microsoft Teams messages.
+func createSlackMessage(record events.Record) slackMessage {
+
+	myFuncName := caller.GetFuncName()
+
+	log.Debugf("%s: alert received: %#v", myFuncName, record)
+
+	// getMsgTitle() and getMsgSummaryText() are used for all messages
+	title := getMsgTitle(config.MyAppName+": ", record)
+	summary := getMsgSummaryText(record)
+
+	msg := slackMessage{
+		Text: title + "\n" + summary,
+		Blocks: []slackBlock{
+			{
+				Type: "header",
+				Text: &slackText{Type: "plain_text", Text: slackTruncate(title, slackMaxHeaderLength)},
+			},
+			{
+				Type: "section",
+				Text: slackMrkdwn(slackEscape(summary), slackMaxSectionLength),
+			},
+			{Type: "divider"},
+		},
+	}
+
+	/*
+		Errors Section
+	*/
+
+	errorsText := "None"
+	if record.Error != nil {
+		errorsText = slackCode(record.Error.Error())
+	}
+	msg.Blocks = append(msg.Blocks, slackSection("Disable User Request Errors", errorsText))
+
+	// If Session Termination is enabled, create Termination Results section
+	if record.SessionTerminationResults != nil {
+
+		resultsText := slackEscape(getTerminationResultsList(record.SessionTerminationResults))
+		if record.Verification != nil {
+			resultsText = fmt.Sprintf("Verification: %s\n\n%s", slackEscape(record.Verification.String()), resultsText)
+		}
+
+		if scope := record.TerminationScope(); scope != "" {
+			resultsText = fmt.Sprintf("Scope: %s\n\n%s", slackEscape(scope), resultsText)
+		}
+
+		msg.Blocks = append(msg.Blocks, slackSection("Session Termination Results", resultsText))
+	}
+
+	// If any hooks were run for this event, create Hook Results section
+	if record.HookResults != nil {
+		msg.Blocks = append(
+			msg.Blocks,
+			slackSection("Hook Results", slackEscape(getHookResultsList(record.HookResults))),
+		)
+	}
+
+	/*
+		Disable User Request Details Section - Core of alert details
+	*/
+
+	details := []slackText{
+		slackField("Username", record.Alert.Username),
+		slackField("User IP", record.Alert.UserIP),
+		slackField("Alert/Search Name", record.Alert.AlertName),
+		slackField("Alert/Search ID", record.Alert.SearchID),
+	}
+
+	if record.Alert.Tenant != "" {
+		details = append(details, slackField("Tenant", record.Alert.Tenant))
+	}
+
+	if record.Alert.Policy != nil {
+		details = append(details, slackField("Alert Policy", record.Alert.Policy.String()))
+	}
+
+	if len(record.Alert.Instances) > 0 {
+		details = append(details, slackField("EZproxy Instances", strings.Join(record.Alert.Instances, ", ")))
+	}
+
+	if record.Alert.ReportThreshold != nil {
+		details = append(details, slackField("Report Threshold", record.Alert.ReportThreshold.String()))
+	}
+
+	if record.Alert.Approval != nil {
+		details = append(details, slackField("Approval", record.Alert.Approval.String()))
+
+		if record.Alert.Approval.Pending() {
+			details = append(details, *slackMrkdwn(
+				fmt.Sprintf(
+					"*Approval Links*\n<%s|Approve> | <%s|Reject>",
+					record.Alert.Approval.ApproveURL,
+					record.Alert.Approval.RejectURL,
+				),
+				slackMaxFieldLength,
+			))
+		}
+	}
+
+	if record.Alert.CircuitBreaker != nil {
+		details = append(details, slackField("Circuit Breaker", record.Alert.CircuitBreaker.String()))
+	}
+
+	msg.Blocks = append(msg.Blocks, slackFieldSections("Disable User Request Details", "", details)...)
+
+	/*
+		Alert Request Summary Section - General client request details
+	*/
+
+	requestSummary := []slackText{
+		slackField("Received at", record.Alert.LocalTime),
+		slackField("Endpoint path", record.Alert.EndpointPath),
+		slackField("HTTP Method", record.Alert.HTTPMethod),
+		slackField("Alert Sender IP", record.Alert.PayloadSenderIP),
+	}
+
+	if record.Operator != "" {
+		requestSummary = append(requestSummary, slackField("Operator", record.Operator))
+	}
+
+	msg.Blocks = append(msg.Blocks, slackFieldSections("Alert Request Summary", "", requestSummary)...)
+
+	/*
+		Alert Request Headers Section
+	*/
+
+	headerNames := make([]string, 0, len(record.Alert.Headers))
+	for header := range record.Alert.Headers {
+		headerNames = append(headerNames, header)
+	}
+	sort.Strings(headerNames)
+
+	requestHeaders := make([]slackText, 0, len(headerNames))
+	for _, header := range headerNames {
+		requestHeaders = append(requestHeaders, slackField(header, record.Alert.Headers[header]...))
+	}
+
+	msg.Blocks = append(msg.Blocks, slackFieldSections(
+		"Alert Request Headers",
+		fmt.Sprintf("%d alert request headers provided", len(record.Alert.Headers)),
+		requestHeaders,
+	)...)
+
+	// Guard against Slack rejecting messages with too many blocks; this
+	// leaves room for the trailer.
+	if len(msg.Blocks) >= slackMaxBlocks {
+		log.Warnf("%s: dropping %d blocks exceeding the Slack limit", myFuncName, len(msg.Blocks)-slackMaxBlocks+1)
+		msg.Blocks = msg.Blocks[:slackMaxBlocks-1]
+	}
+
+	/*
+		Message Branding/Trailer Section
+	*/
+
+	msg.Blocks = append(msg.Blocks, slackBlock{
+		Type:     "context",
+		Elements: []slackText{*slackMrkdwn(config.MessageTrailer(config.BrandingSlackFormat), slackMaxSectionLength)},
+	})
+
+	return msg
+}
+
+// sendSlackMessage submits the provided message to the Slack incoming
+// webhook. This includes honoring the provided schedule in order to comply
+// with remote API rate limits.
+func sendSlackMessage(
+	ctx context.Context,
+	webhookURL string,
+	msg slackMessage,
+	schedule time.Time,
+	retries int,
+	retriesDelay int,
+) NotifyResult {
+
+	myFuncName := caller.GetFuncName()
+
+	if webhookURL == "" {
+		return NotifyResult{
+			Err: fmt.Errorf(
+				"%s: webhookURL not defined, skipping message submission to Slack channel",
+				myFuncName,
+			),
+			Success: false,
+		}
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return NotifyResult{
+			Err:     fmt.Errorf("%s: failed to encode Slack message: %w", myFuncName, err),
+			Success: false,
+		}
+	}
+
+	log.Debugf("%s: Time now is %v", myFuncName, time.Now().Format("15:04:05"))
+	log.Debugf("%s: Notification scheduled for: %v", myFuncName, schedule.Format("15:04:05"))
+
+	// Set delay timer to meet received notification schedule. This helps
+	// ensure that we delay the appropriate amount of time before we make our
+	// first attempt at sending a message to Slack.
+	notificationDelay := time.Until(schedule)
+
+	notificationDelayTimer := time.NewTimer(notificationDelay)
+	defer notificationDelayTimer.Stop()
+
+	select {
+	case <-ctx.Done():
+		ctxErr := ctx.Err()
+		result := NotifyResult{
+			Val: fmt.Sprintf("%s: Received Done signal at %v: %v, shutting down",
+				myFuncName,
+				time.Now().Format("15:04:05"),
+				ctxErr.Error(),
+			),
+			Success: false,
+		}
+		log.Debug(result.Val)
+		return result
+
+	// Delay between message submission attempts; this will *always*
+	// delay, regardless of whether the attempt is the first one or not
+	case <-notificationDelayTimer.C:
+
+		log.Debugf("%s: Waited %v before notification attempt at %v",
+			myFuncName,
+			notificationDelay,
+			time.Now().Format("15:04:05"),
+		)
+
+		// check to see if context has expired during our delay
+		if ctx.Err() != nil {
+			result := NotifyResult{
+				Val: fmt.Sprintf(
+					"%s: context expired or cancelled at %v: %v, attempting to abort message submission",
+					myFuncName,
+					time.Now().Format("15:04:05"),
+					ctx.Err().Error(),
+				),
+				Success: false,
+			}
+
+			log.Debug(result.Val)
+
+			return result
+		}
+
+		// Slack incoming webhooks are plain HTTP endpoints, so the same
+		// submission and retry logic as generic webhooks applies.
+		slackCfg := webhookConfig{
+			url:                    webhookURL,
+			timeout:                config.NotifyMgrSlackNotificationTimeout,
+			notificationRetries:    retries,
+			notificationRetryDelay: retriesDelay,
+		}
+
+		if err := sendWebhook(ctx, slackCfg, payload); err != nil {
+			errMsg := NotifyResult{
+				Err: fmt.Errorf(
+					"%s: ERROR: Failed to submit message to Slack at %v: %v",
+					myFuncName,
+					time.Now().Format("15:04:05"),
+					err,
+				),
+				Success: false,
+			}
+			log.Error(errMsg.Err.Error())
+			return errMsg
+		}
+
+		successMsg := NotifyResult{
+			Val: fmt.Sprintf(
+				"%s: Message successfully sent to Slack at %v",
+				myFuncName,
+				time.Now().Format("15:04:05"),
+			),
+			Success: true,
+		}
+
+		// Note success for potential troubleshooting
+		log.Debug(successMsg.Val)
+
+		return successMsg
+
+	}
+
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/atc0005/brick/events"
+)
+
+// slackTestRecord returns the shared test event record with the provided
+// number of alert request headers added.
+func slackTestRecord(headers int) events.Record {
+
+	record := testRecord()
+
+	for i := 0; i < headers; i++ {
+		record.Alert.Headers.Set(fmt.Sprintf("X-Header-%03d", i), "value")
+	}
+
+	return record
+}
+
+func TestCreateSlackMessage(t *testing.T) {
+
+	record := slackTestRecord(12)
+	record.Alert.Approval = &events.Approval{
+		ApproveURL: "https://brick.example.com/api/v1/approvals?decision=approve",
+		RejectURL:  "https://brick.example.com/api/v1/approvals?decision=reject",
+	}
+
+	msg := createSlackMessage(record)
+
+	// the payload must survive encoding with the field names Slack expects
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("failed to encode Slack message: %v", err)
+	}
+
+	var decoded struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Type string `json:"type"`
+			Text *struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"text"`
+			Fields   []json.RawMessage `json:"fields"`
+			Elements []json.RawMessage `json:"elements"`
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("failed to decode Slack message %s: %v", body, err)
+	}
+
+	if decoded.Text == "" {
+		t.Error("Slack message fallback text is empty")
+	}
+
+	blocks := decoded.Blocks
+	if len(blocks) < 4 {
+		t.Fatalf("Slack message has %d blocks; want at least 4", len(blocks))
+	}
+
+	if blocks[0].Type != "header" || blocks[0].Text == nil || blocks[0].Text.Type != "plain_text" {
+		t.Errorf("first block = %+v; want plain_text header", blocks[0])
+	}
+
+	if blocks[2].Type != "divider" {
+		t.Errorf("third block type = %q; want divider", blocks[2].Type)
+	}
+
+	if last := blocks[len(blocks)-1]; last.Type != "context" || len(last.Elements) != 1 {
+		t.Errorf("last block = %+v; want context block with branding", last)
+	}
+
+	// no block may exceed the Slack field limit and all mrkdwn text must be
+	// escaped
+	for i, block := range blocks {
+		if len(block.Fields) > slackMaxFieldsPerBlock {
+			t.Errorf("block %d has %d fields; want at most %d", i, len(block.Fields), slackMaxFieldsPerBlock)
+		}
+	}
+
+	var texts []string
+	for _, block := range msg.Blocks {
+		if block.Text != nil {
+			texts = append(texts, block.Text.Text)
+		}
+		for _, field := range block.Fields {
+			texts = append(texts, field.Text)
+		}
+	}
+	allText := strings.Join(texts, "\n")
+
+	for _, want := range []string{
+		"`Brute force &lt;library&gt; &amp; more`",
+		"<https://brick.example.com/api/v1/approvals?decision=approve|Approve>",
+		"<https://brick.example.com/api/v1/approvals?decision=reject|Reject>",
+	} {
+		if !strings.Contains(allText, want) {
+			t.Errorf("Slack message does not contain %q: %s", want, allText)
+		}
+	}
+
+	// 12 headers are split across two sections
+	var headerSections int
+	for _, block := range msg.Blocks {
+		for _, field := range block.Fields {
+			if strings.HasPrefix(field.Text, "*X-Header-") {
+				headerSections++
+				break
+			}
+		}
+	}
+	if headerSections != 2 {
+		t.Errorf("alert request headers listed in %d sections; want 2", headerSections)
+	}
+}
+
+func TestCreateSlackMessageBlockLimit(t *testing.T) {
+
+	msg := createSlackMessage(slackTestRecord(1000))
+
+	if len(msg.Blocks) != slackMaxBlocks {
+		t.Errorf("Slack message has %d blocks; want %d", len(msg.Blocks), slackMaxBlocks)
+	}
+
+	if last := msg.Blocks[len(msg.Blocks)-1]; last.Type != "context" {
+		t.Errorf("last block type = %q; want context", last.Type)
+	}
+}
+
+func TestSlackFormatting(t *testing.T) {
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"escape", slackEscape("a < b && c > d"), "a &lt; b &amp;&amp; c &gt; d"},
+		{"code", slackCode("a`b<c"), "`a'b&lt;c`"},
+		{"empty code", slackCode(""), "N/A"},
+		{"field", slackField("Key <1>", "a", "").Text, "*Key &lt;1&gt;*\n`a` N/A"},
+		{"no truncation", slackTruncate("abc", 3), "abc"},
+		{"truncation", slackTruncate("abcdef", 4), "abc" + slackTruncationEllipsis},
+		{"multibyte truncation", slackTruncate("ääääää", 4), "äää" + slackTruncationEllipsis},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q; want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlackTextLimits(t *testing.T) {
+
+	long := strings.Repeat("x", 5000)
+
+	record := slackTestRecord(1)
+	record.Alert.AlertName = long
+	record.Alert.Username = long
+	record.Note = long
+
+	msg := createSlackMessage(record)
+
+	if n := utf8.RuneCountInString(msg.Blocks[0].Text.Text); n > slackMaxHeaderLength {
+		t.Errorf("header has %d characters; want at most %d", n, slackMaxHeaderLength)
+	}
+
+	for i, block := range msg.Blocks {
+		if block.Text != nil {
+			if n := utf8.RuneCountInString(block.Text.Text); n > slackMaxSectionLength {
+				t.Errorf("block %d text has %d characters; want at most %d", i, n, slackMaxSectionLength)
+			}
+		}
+		for j, field := range block.Fields {
+			if n := utf8.RuneCountInString(field.Text); n > slackMaxFieldLength {
+				t.Errorf("block %d field %d has %d characters; want at most %d", i, j, n, slackMaxFieldLength)
+			}
+		}
+	}
+}
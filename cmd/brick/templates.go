@@ -61,12 +61,23 @@ None
 {{ if .Record.SessionTerminationResults -}}
 **Session Termination Results**
 
+{{ with .Record.TerminationScope }}Scope: {{ . }}
+
+{{ end -}}
+{{ with .Record.Verification }}Verification: {{ . }}
+
+{{ end -}}
 {{ range $index, $element := .Record.SessionTerminationResults -}}
 
 Session {{ inc $index }}:
 
 * SessionID: {{ .SessionID }}
+* Username: {{ .Username }}
 * IPAddress: {{ .IPAddress }}
+* Outcome: {{ outcome . }}
+{{- with $.Record.Verification }}{{ if not .Error }}
+* Verification: {{ .SessionStatus $element.SessionID }}
+{{- end }}{{ end }}
 * ExitCode: {{ .ExitCode }}
 * StdOut: {{ .StdOut }}
 * StdErr: {{ .StdErr }}
@@ -75,12 +86,50 @@ Session {{ inc $index }}:
 {{ end }}
 {{- end }}
 
+{{ if .Record.HookResults -}}
+**Hook Results**
+
+{{ range $index, $element := .Record.HookResults -}}
+
+Hook {{ inc $index }}:
+
+* Name: {{ .Name }}
+* ExitCode: {{ .ExitCode }}
+* Duration: {{ .Duration }}
+* Output: {{ .Output }}
+* Error: {{ .Error }}
+
+{{ end }}
+{{- end }}
+
 **Disable User Request Details**
 
 * Username: {{ if .Record.Alert.Username }}{{ .Record.Alert.Username }}{{ else }}{{ $missingValue }}{{ end }}
 * User IP: {{ if .Record.Alert.UserIP }}{{ .Record.Alert.UserIP }}{{ else }}{{ $missingValue }}{{ end }}
 * Alert/Search Name: {{ if .Record.Alert.AlertName }}{{ .Record.Alert.AlertName }}{{ else }}{{ $missingValue }}{{ end }}
 * Alert/Search ID: {{ if .Record.Alert.SearchID }}{{ .Record.Alert.SearchID }}{{ else }}{{ $missingValue }}{{ end }}
+{{- if .Record.Alert.Tenant }}
+* Tenant: {{ .Record.Alert.Tenant }}
+{{- end }}
+{{- if .Record.Alert.Policy }}
+* Alert Policy: {{ .Record.Alert.Policy }}
+{{- end }}
+{{- if .Record.Alert.Instances }}
+* EZproxy Instances: {{ join .Record.Alert.Instances ", " }}
+{{- end }}
+{{- if .Record.Alert.ReportThreshold }}
+* Report Threshold: {{ .Record.Alert.ReportThreshold }}
+{{- end }}
+{{- with .Record.Alert.Approval }}
+* Approval: {{ . }}
+{{- if .Pending }}
+* Approve: {{ .ApproveURL }}
+* Reject: {{ .RejectURL }}
+{{- end }}
+{{- end }}
+{{- if .Record.Alert.CircuitBreaker }}
+* Circuit Breaker: {{ .Record.Alert.CircuitBreaker }}
+{{- end }}
 
 
 **Alert Request Summary**
@@ -89,6 +138,9 @@ Session {{ inc $index }}:
 * Endpoint path: {{ .Record.Alert.EndpointPath }}
 * HTTP Method: {{ .Record.Alert.HTTPMethod }}
 * Alert Sender IP: {{ .Record.Alert.PayloadSenderIP }}
+{{- if .Record.Operator }}
+* Operator: {{ .Record.Operator }}
+{{- end }}
 
 
 **Alert Request Headers**
@@ -132,20 +184,58 @@ const textileEmailTemplate string = `
 {{ if .Record.SessionTerminationResults -}}
 **Session Termination Results**
 
-| SessionID | IPAddress | ExitCode | StdOut | StdErr| Error |
+{{ with .Record.TerminationScope }}Scope: {{ . }}
+
+{{ end -}}
+{{ with .Record.Verification }}Verification: {{ . }}
+
+{{ end -}}
+| SessionID | Username | IPAddress | Outcome | ExitCode | StdOut | StdErr| Error |
 {{ range .Record.SessionTerminationResults -}}
-| {{ .SessionID }} | {{ .IPAddress }} | {{ .ExitCode }} | {{ .StdOut }} | {{ .StdErr }} | {{ .Error }} |
+| {{ .SessionID }} | {{ .Username }} | {{ .IPAddress }} | {{ outcome . }} | {{ .ExitCode }} | {{ .StdOut }} | {{ .StdErr }} | {{ .Error }} |
 {{ end }}
 {{- else -}}
 {{- end }}
 
 
+{{ if .Record.HookResults -}}
+**Hook Results**
+
+| Name | ExitCode | Duration | Output | Error |
+{{ range .Record.HookResults -}}
+| {{ .Name }} | {{ .ExitCode }} | {{ .Duration }} | {{ printf "%q" .Output }} | {{ .Error }} |
+{{ end }}
+{{- end }}
+
+
 **Disable User Request Details**
 
 | Username          | {{ if .Record.Alert.Username }}{{ .Record.Alert.Username }}{{ else }}{{ $missingValue }}{{ end }} |
 | User IP           | {{ if .Record.Alert.UserIP }}{{ .Record.Alert.UserIP }}{{ else }}{{ $missingValue }}{{ end }} |
 | Alert/Search Name | {{ if .Record.Alert.AlertName }}{{ .Record.Alert.AlertName }}{{ else }}{{ $missingValue }}{{ end }} |
 | Alert/Search ID   | {{ if .Record.Alert.SearchID }}{{ .Record.Alert.SearchID }}{{ else }}{{ $missingValue }}{{ end }} |
+{{- if .Record.Alert.Tenant }}
+| Tenant            | {{ .Record.Alert.Tenant }} |
+{{- end }}
+{{- if .Record.Alert.Policy }}
+| Alert Policy      | {{ .Record.Alert.Policy }} |
+{{- end }}
+{{- if .Record.Alert.Instances }}
+| EZproxy Instances | {{ join .Record.Alert.Instances ", " }} |
+{{- end }}
+{{- if .Record.Alert.ReportThreshold }}
+| Report Threshold  | {{ .Record.Alert.ReportThreshold }} |
+{{- end }}
+{{- with .Record.Alert.Approval }}
+| Approval          | {{ . }} |
+{{- if .Pending }}
+| Approve           | {{ .ApproveURL }} |
+| Reject            | {{ .RejectURL }} |
+{{- end }}
+{{- end }}
+{{- if .Record.Alert.CircuitBreaker }}
+| Circuit Breaker   | {{ .Record.Alert.CircuitBreaker }} |
+{{- end }}
 
 
 **Alert Request Summary**
@@ -154,6 +244,9 @@ const textileEmailTemplate string = `
 | Endpoint path   | {{ .Record.Alert.EndpointPath }} |
 | HTTP Method     | {{ .Record.Alert.HTTPMethod }} |
 | Alert Sender IP | {{ .Record.Alert.PayloadSenderIP }} |
+{{- if .Record.Operator }}
+| Operator        | {{ .Record.Operator }} |
+{{- end }}
 
 
 **Alert Request Headers**
@@ -166,3 +259,25 @@ const textileEmailTemplate string = `
 {{ .Branding }}
 
 `
+
+// defaultWebhookTemplate is used to render the JSON request body for webhook
+// notifications if a template file is not specified. Values are encoded
+// using the json template function so that the rendered body is valid JSON.
+const defaultWebhookTemplate string = `{
+  "title": {{ json .Title }},
+  "summary": {{ json .Summary }},
+  "action": {{ json .Record.Action }},
+  "high_priority": {{ json .Record.HighPriority }},
+  "note": {{ json .Record.Note }},
+  "error": {{ if .Record.Error }}{{ json .Record.Error.Error }}{{ else }}null{{ end }},
+  "username": {{ json .Record.Alert.Username }},
+  "user_ip": {{ json .Record.Alert.UserIP }},
+  "alert_name": {{ json .Record.Alert.AlertName }},
+  "search_id": {{ json .Record.Alert.SearchID }},
+  "tenant": {{ json .Record.Alert.Tenant }},
+  "instances": {{ json .Record.Alert.Instances }},
+  "operator": {{ json .Record.Operator }},
+  "received_at": {{ json .Record.Alert.LocalTime }},
+  "alert_sender_ip": {{ json .Record.Alert.PayloadSenderIP }}
+}
+`
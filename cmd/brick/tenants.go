@@ -0,0 +1,270 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/config"
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/files"
+	"github.com/atc0005/brick/internal/ezadmin"
+)
+
+// tenant bundles the output files, ignore lists, report counters, EZproxy
+// instances and state-backed components (circuit breaker, firewall,
+// blocklist, approvals and activity tracker) used to process alerts received
+// on the endpoints of a tenant. Tenants never share files or state. The
+// default endpoints are served by a tenant with an empty name.
+type tenant struct {
+	name                  string
+	reportedUserEventsLog *files.ReportedUserEventsLog
+	ignoredSources        files.IgnoredSources
+	reportCounters        *files.ReportCounters
+	instances             files.EZproxyInstances
+	circuitBreaker        *files.CircuitBreaker
+	firewall              *files.Firewall
+	blocklist             *files.Blocklist
+	approvals             *files.Approvals
+	activity              *activityTracker
+}
+
+// newTenant builds the resources for the named tenant using the provided
+// paths, EZproxy instance settings and disabled users file templates. An
+// empty name indicates the default endpoints. The state files of the tenant
+// are derived from the configured state files and the tenant name; the
+// provided endpoint prefix is used to build approval links.
+func newTenant(
+	ctx context.Context,
+	appConfig *config.Config,
+	name string,
+	endpointPrefix string,
+	reportedUsersLogFile string,
+	ignoredUsersFile string,
+	ignoredIPAddressesFile string,
+	instanceConfigs []config.EZproxyInstance,
+	disabledUsersTemplates files.DisabledUsersTemplates,
+	notifyWorkQueue chan<- events.Record,
+) tenant {
+
+	reportedUserEventsLog := files.NewReportedUserEventsLog(
+		reportedUsersLogFile,
+		appConfig.ReportedUsersLogFilePermissions(),
+	)
+
+	ignoredSources := files.NewIgnoredSources(
+		ignoredUsersFile,
+		ignoredIPAddressesFile,
+		appConfig.IgnoreLookupErrors(),
+	)
+
+	instances := newEZproxyInstances(ctx, appConfig, instanceConfigs, disabledUsersTemplates)
+
+	return tenant{
+		name:                  name,
+		reportedUserEventsLog: reportedUserEventsLog,
+		ignoredSources:        ignoredSources,
+		reportCounters: files.NewReportCounters(
+			appConfig.TenantThresholdStateFile(name),
+			appConfig.ThresholdRetention(),
+		),
+		instances: instances,
+		circuitBreaker: files.NewCircuitBreaker(
+			appConfig.TenantCircuitBreakerStateFile(name),
+			appConfig.CircuitBreakerMaxDisables(),
+			time.Duration(appConfig.CircuitBreakerWindow())*time.Minute,
+		),
+		firewall: files.NewFirewall(
+			appConfig.TenantFirewallStateFile(name),
+			time.Duration(appConfig.FirewallBlockDuration())*time.Minute,
+			newFirewallBackend(appConfig),
+			reportedUserEventsLog,
+			ignoredSources,
+			notifyWorkQueue,
+		),
+		blocklist: newBlocklist(appConfig, name, instances, ignoredSources),
+		approvals: files.NewApprovals(
+			appConfig.TenantApprovalsStateFile(name),
+			strings.TrimRight(appConfig.ApprovalsBaseURL(), "/")+endpointPrefix,
+			appConfig.ApprovalsSecret(),
+			time.Duration(appConfig.ApprovalsTimeout())*time.Minute,
+			appConfig.ApprovalsTimeoutAction(),
+			reportedUserEventsLog,
+			notifyWorkQueue,
+		),
+		activity: newActivityTracker(
+			name,
+			time.Duration(appConfig.ActivityWindow())*time.Minute,
+			appConfig.ActivitySenders(),
+			notifyWorkQueue,
+		),
+	}
+}
+
+// start runs the background tasks of the tenant until the provided context
+// is cancelled and loads any approval requests left pending when the
+// application was last stopped. Approved requests resume processing where
+// the original disable request left off. The application exits if the
+// pending approval requests cannot be loaded.
+func (t tenant) start(ctx context.Context, appConfig *config.Config, notifyWorkQueue chan<- events.Record) {
+
+	// Setup "blocklist" to keep the blocklist files up to date
+	go t.blocklist.Run(ctx)
+
+	// Setup "firewall" to unblock IP Addresses once their block expires
+	go t.firewall.Run(ctx)
+
+	// Setup "monitor" to warn when alert payloads stop arriving
+	go t.activity.monitor(ctx, config.ActivityMonitorCheckInterval)
+
+	reconciler := files.NewReconciler(
+		time.Duration(appConfig.ReconcileInterval())*time.Minute,
+		appConfig.ReconcileAction() == config.ReconcileActionTerminate,
+		t.name,
+		t.instances,
+		appConfig.AlertPolicies(),
+		appConfig.DefaultAlertPolicy(),
+		t.reportedUserEventsLog,
+		notifyWorkQueue,
+	)
+
+	// Setup "reconciler" to find disabled users which still have active
+	// sessions
+	go reconciler.Run(ctx)
+
+	disableContext := t.disableContext(appConfig, notifyWorkQueue)
+
+	if err := t.approvals.Start(func(alert events.SplunkAlertEvent) {
+		if alert.Tenant != t.name {
+			log.Errorf(
+				"approved request for user %q was received by tenant %q, not %q; not disabling user account",
+				alert.Username,
+				alert.Tenant,
+				t.name,
+			)
+			return
+		}
+
+		files.ProcessApprovedDisableEvent(alert, disableContext)
+	}); err != nil {
+		log.Errorf("failed to load pending approval requests: %v", err)
+		os.Exit(1)
+	}
+}
+
+// disableContext returns the context used to process disable requests
+// received on the endpoints of the tenant.
+func (t tenant) disableContext(appConfig *config.Config, notifyWorkQueue chan<- events.Record) files.DisableContext {
+
+	return files.DisableContext{
+		ReportedUserEventsLog: t.reportedUserEventsLog,
+		IgnoredSources:        t.ignoredSources,
+		ReportCounters:        t.reportCounters,
+		Approvals:             t.approvals,
+		CircuitBreaker:        t.circuitBreaker,
+		Firewall:              t.firewall,
+		Blocklist:             t.blocklist,
+		NotifyWorkQueue:       notifyWorkQueue,
+		AlertPolicies:         appConfig.AlertPolicies(),
+		DefaultAlertPolicy:    appConfig.DefaultAlertPolicy(),
+		Instances:             t.instances,
+		AuditFileLookback:     appConfig.EZproxyAuditFileLookback(),
+		SessionsSearchDelay:   appConfig.EZproxySearchDelay(),
+		SessionSearchRetries:  appConfig.EZproxySearchRetries(),
+		VerifyDelay:           appConfig.EZproxyVerifyDelay(),
+	}
+}
+
+// newEZproxyInstances builds the disabled users file (using the provided
+// templates), active file watcher and session terminator for each of the
+// provided EZproxy instance settings.
+// An active file watcher is started for each instance. The application exits
+// if a session terminator cannot be created.
+func newEZproxyInstances(
+	ctx context.Context,
+	appConfig *config.Config,
+	instanceConfigs []config.EZproxyInstance,
+	disabledUsersTemplates files.DisabledUsersTemplates,
+) files.EZproxyInstances {
+
+	instances := make(files.EZproxyInstances, 0, len(instanceConfigs))
+	for _, instanceConfig := range instanceConfigs {
+
+		activeFileWatcher := files.NewActiveFileWatcher(
+			instanceConfig.ActiveFilePath,
+			time.Duration(appConfig.EZproxyWatchInterval())*time.Millisecond,
+		)
+
+		// Setup "watcher" to keep an index of sessions recorded in the
+		// EZproxy active file for this instance
+		go activeFileWatcher.Run(ctx)
+
+		var terminator files.SessionTerminator = files.ExecTerminator{
+			Executable:  instanceConfig.ExecutablePath,
+			Timeout:     time.Duration(appConfig.EZproxyKillTimeout()) * time.Second,
+			Concurrency: appConfig.EZproxyKillConcurrency(),
+			Retries:     appConfig.EZproxyKillRetries(),
+			RetryDelay:  time.Duration(appConfig.EZproxyKillRetryDelay()) * time.Second,
+		}
+
+		if instanceConfig.Terminator == config.TerminatorAdmin {
+			adminClient, err := ezadmin.NewClient(
+				instanceConfig.AdminURL,
+				instanceConfig.AdminUsername,
+				instanceConfig.AdminPassword,
+			)
+			if err != nil {
+				log.Errorf(
+					"failed to setup EZproxy administrative web interface client for instance %q: %v",
+					instanceConfig.Name,
+					err,
+				)
+				os.Exit(1)
+			}
+			if err := adminClient.SetTimeout(appConfig.EZproxyKillTimeout()); err != nil {
+				log.Errorf(
+					"failed to setup EZproxy administrative web interface client for instance %q: %v",
+					instanceConfig.Name,
+					err,
+				)
+				os.Exit(1)
+			}
+			terminator = adminClient
+		}
+
+		instances = append(instances, files.EZproxyInstance{
+			Name: instanceConfig.Name,
+			DisabledUsers: files.NewDisabledUsers(
+				instanceConfig.DisabledUsersFile,
+				appConfig.DisabledUsersFileEntrySuffix(),
+				disabledUsersTemplates,
+				appConfig.DisabledUsersFilePermissions(),
+			),
+			ActiveFileWatcher: activeFileWatcher,
+			ActiveFilePath:    instanceConfig.ActiveFilePath,
+			AuditFileDirPath:  instanceConfig.AuditFileDirPath,
+			Terminator:        terminator,
+		})
+	}
+
+	return instances
+}
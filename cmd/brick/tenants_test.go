@@ -0,0 +1,177 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/files"
+)
+
+// tenantTestPayload is a sanitized alert payload captured from Splunk.
+const tenantTestPayload string = "../../contrib/tests/splunk-sanitized-payload-formatted.json"
+
+func TestTenantEndpointRouting(t *testing.T) {
+
+	const secret = "replace-with-a-long-random-secret"
+
+	payload, err := ioutil.ReadFile(tenantTestPayload)
+	if err != nil {
+		t.Fatalf("failed to read alert payload: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		target        string
+		authorization string
+		wantStatus    int
+		wantTenant    string
+		wantRecords   bool
+	}{
+		{
+			name:        "default endpoints",
+			target:      "/api/v1/users/disable",
+			wantStatus:  http.StatusOK,
+			wantTenant:  "",
+			wantRecords: true,
+		},
+		{
+			name:          "tenant endpoints",
+			target:        "/library-a/api/v1/users/disable",
+			authorization: "Bearer " + secret,
+			wantStatus:    http.StatusOK,
+			wantTenant:    "library-a",
+			wantRecords:   true,
+		},
+		{
+			name:          "tenant endpoints with wrong auth secret",
+			target:        "/library-a/api/v1/users/disable",
+			authorization: "Bearer wrong-secret",
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "unknown tenant",
+			target:        "/library-b/api/v1/users/disable",
+			authorization: "Bearer " + secret,
+			wantStatus:    http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			dir := t.TempDir()
+			notifyWorkQueue := make(chan events.Record, 10)
+
+			// each tenant ignores the reported username so that processing
+			// ends once the ignore lists of the tenant have been checked
+			reportedLogs := make(map[string]string)
+			handlers := make(map[string]http.HandlerFunc)
+			for _, tenant := range []string{"", "library-a"} {
+
+				ignoredUsersFile := filepath.Join(dir, "users-"+tenant+".brick-ignored.txt")
+				if err := ioutil.WriteFile(ignoredUsersFile, []byte("abc0001\n"), 0600); err != nil {
+					t.Fatalf("failed to write ignored users file: %v", err)
+				}
+
+				authSecret := ""
+				if tenant != "" {
+					authSecret = secret
+				}
+
+				reportedLogs[tenant] = filepath.Join(dir, "users-"+tenant+".brick-reported.log")
+				handlers[tenant] = disableUserHandler(
+					tenant,
+					authSecret,
+					newActivityTracker(tenant, 0, nil, notifyWorkQueue),
+					files.DisableContext{
+						ReportedUserEventsLog: files.NewReportedUserEventsLog(reportedLogs[tenant], 0600),
+						IgnoredSources:        files.NewIgnoredSources(ignoredUsersFile, filepath.Join(dir, "missing.txt"), true),
+						NotifyWorkQueue:       notifyWorkQueue,
+						DefaultAlertPolicy:    events.AlertPolicy{Name: events.DefaultAlertPolicyName},
+					},
+				)
+			}
+
+			mux := http.NewServeMux()
+			mux.Handle(apiV1DisableUserEndpointPattern, handlers[""])
+			mux.Handle(
+				"/library-a"+apiV1DisableUserEndpointPattern,
+				http.StripPrefix("/library-a", handlers["library-a"]),
+			)
+
+			req := httptest.NewRequest(http.MethodPost, tt.target, bytes.NewReader(payload))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d (body: %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			if !tt.wantRecords {
+				select {
+				case record := <-notifyWorkQueue:
+					t.Errorf("unexpected record for tenant %q: %s", record.Alert.Tenant, record.Action)
+				case <-time.After(100 * time.Millisecond):
+				}
+				return
+			}
+
+			// records are sent from separate goroutines and may arrive in
+			// any order
+			got := make(map[string]string)
+			for len(got) < 2 {
+				select {
+				case record := <-notifyWorkQueue:
+					got[record.Action] = record.Alert.Tenant
+				case <-time.After(5 * time.Second):
+					t.Fatalf("records received = %v; want 2 records", got)
+				}
+			}
+
+			for _, wantAction := range []string{
+				events.ActionSuccessDisableRequestReceived,
+				events.ActionSuccessIgnoredUsername,
+			} {
+				if tenant, ok := got[wantAction]; !ok || tenant != tt.wantTenant {
+					t.Errorf("records received = %v; want %q for tenant %q", got, wantAction, tt.wantTenant)
+				}
+			}
+
+			// events are only recorded in the reported users log of the
+			// tenant which received the alert
+			for tenant, path := range reportedLogs {
+				content, _ := ioutil.ReadFile(path)
+				if got := len(content) > 0; got != (tenant == tt.wantTenant) {
+					t.Errorf("reported users log for tenant %q written: %t; want %t", tenant, got, tenant == tt.wantTenant)
+				}
+			}
+		})
+	}
+}
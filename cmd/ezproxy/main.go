@@ -42,6 +42,12 @@ func main() {
 
 	log.Debug("Starting EZproxy mock binary")
 
+	// called as: ezproxy serve ADDRESS USERNAME PASSWORD
+	if len(os.Args) > 1 && strings.EqualFold(os.Args[1], subCmdNameServe) {
+		serve(os.Args[2:])
+		return
+	}
+
 	// called as: ezproxy kill SESSION_ID_HERE
 
 	switch len(os.Args) {
@@ -0,0 +1,168 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/go-ezproxy"
+
+	"github.com/atc0005/brick/internal/ezadmin"
+)
+
+// subCmdNameServe is the name of the subcommand used to start the mock
+// EZproxy administrative web interface.
+const subCmdNameServe string = "serve"
+
+// cookieName is the name of the session cookie set after a successful login.
+const cookieName string = "ezproxy"
+
+// adminServer is a mock of the EZproxy administrative web interface used to
+// test session termination via the admin terminator. Sessions are tracked
+// in memory only; any valid session ID is treated as active until it is
+// terminated.
+type adminServer struct {
+	username string
+	password string
+
+	mutex  sync.Mutex
+	logins map[string]bool
+	killed map[string]bool
+}
+
+// serve starts the mock administrative web interface and blocks until it
+// fails.
+//
+// called as: ezproxy serve ADDRESS USERNAME PASSWORD
+func serve(args []string) {
+
+	if len(args) != 3 {
+		fmt.Printf("usage: %s %s ADDRESS USERNAME PASSWORD\n", os.Args[0], subCmdNameServe)
+		os.Exit(1)
+	}
+
+	server := adminServer{
+		username: args[1],
+		password: args[2],
+		logins:   make(map[string]bool),
+		killed:   make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(ezadmin.LoginPath, server.login)
+	mux.HandleFunc(ezadmin.KillPath, server.kill)
+
+	log.Infof("Mock EZproxy administrative web interface listening on %s", args[0])
+
+	if err := http.ListenAndServe(args[0], mux); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// login sets a session cookie if valid administrator credentials are
+// submitted.
+func (s *adminServer) login(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.PostFormValue(ezadmin.UsernameField) != s.username ||
+		r.PostFormValue(ezadmin.PasswordField) != s.password {
+		log.Infof("Rejected login for %q", r.PostFormValue(ezadmin.UsernameField))
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.mutex.Lock()
+	s.logins[hex.EncodeToString(token)] = true
+	s.mutex.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    hex.EncodeToString(token),
+		Path:     "/",
+		HttpOnly: true,
+	})
+
+	log.Infof("Accepted login for %q", s.username)
+	fmt.Fprintln(w, "Login successful")
+}
+
+// kill terminates the submitted session for logged in administrators,
+// responding with the same text as the `kill` subcommand.
+func (s *adminServer) kill(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cookie, err := r.Cookie(cookieName)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// EZproxy redirects requests without a valid login session to the
+	// login page
+	if err != nil || !s.logins[cookie.Value] {
+		http.Redirect(w, r, ezadmin.LoginPath, http.StatusFound)
+		return
+	}
+
+	sessionID := r.PostFormValue(ezadmin.SessionField)
+	if sessionID == "" {
+		http.Error(w, ezproxy.KillSubCmdExitTextSessionNotSpecified, http.StatusBadRequest)
+		return
+	}
+
+	if ok, _ := regexp.MatchString("^"+ezproxy.SessionIDRegex+"$", sessionID); !ok {
+		http.Error(w, "invalid session ID pattern provided", http.StatusBadRequest)
+		return
+	}
+
+	if s.killed[sessionID] {
+		log.Infof("Session %s does not exist", sessionID)
+		http.Error(
+			w,
+			fmt.Sprintf(ezproxy.KillSubCmdExitTextTemplateSessionDoesNotExist, sessionID),
+			http.StatusNotFound,
+		)
+		return
+	}
+
+	s.killed[sessionID] = true
+
+	log.Infof("Session %s terminated", sessionID)
+	fmt.Fprintf(w, ezproxy.KillSubCmdExitTextTemplateSessionTerminated+"\n", sessionID)
+}
@@ -42,12 +42,20 @@ func (c *Config) String() string {
 		"UnifiedConfig: { "+
 			"Network.LocalTCPPort: %v, "+
 			"Network.LocalIPAddress: %v, "+
+			"Network.TLSCertFile: %q, "+
+			"Network.TLSKeyFile: %q, "+
 			"Logging.Level: %s, "+
 			"Logging.Output: %s, "+
 			"Logging.Format: %s, "+
 			"DisabledUsers.File: %s, "+
 			"DisabledUsers.EntrySuffix: %s, "+
+			"DisabledUsers.EntryTemplate: %q, "+
+			"DisabledUsers.QuarantineTemplate: %q, "+
 			"DisabledUsers.FilePermissions: %v, "+
+			"DisabledUsers.Blocklists: %v, "+
+			"DisabledUsers.BlocklistFeed: %t, "+
+			"DisabledUsers.BlocklistDuration: %d, "+
+			"DisabledUsers.BlocklistStateFile: %q, "+
 			"ReportedUsers.LogFile: %q, "+
 			"ReportedUsers.LogFilePermissions: %v, "+
 			"IgnoredUsers.File: %q, "+
@@ -55,10 +63,13 @@ func (c *Config) String() string {
 			"IgnoredIPAddresses.File: %q, "+
 			"IsSetIgnoredIPAddressesFile: %t, "+
 			"IgnoreLookupErrors: %t, "+
+			"DryRun: %t, "+
 			"MSTeams.WebhookURL: %q, "+
 			"MSTeams.RateLimit: %v, "+
 			"MSTeams.Retries: %v, "+
 			"MSTeams.RetryDelay: %v, "+
+			"MSTeams.MessageFormat: %q, "+
+			"MSTeams.UnblockURL: %q, "+
 			"NotifyTeams: %t, "+
 			"NotifyEmail: %t, "+
 			"Email.Server: %q, "+
@@ -69,21 +80,83 @@ func (c *Config) String() string {
 			"Email.RateLimit: %v, "+
 			"Email.Retries: %v, "+
 			"Email.RetryDelay: %v, "+
+			"Slack.WebhookURL: %q, "+
+			"Slack.RateLimit: %v, "+
+			"Slack.Retries: %v, "+
+			"Slack.RetryDelay: %v, "+
+			"NotifySlack: %t, "+
+			"Webhook.URL: %q, "+
+			"Webhook.Headers: %d configured, "+
+			"Webhook.TemplateFile: %q, "+
+			"Webhook.RateLimit: %v, "+
+			"Webhook.Retries: %v, "+
+			"Webhook.RetryDelay: %v, "+
+			"NotifyWebhook: %t, "+
+			"EZproxy.Terminator: %q, "+
 			"EZproxy.ExecutablePath: %v, "+
+			"EZproxy.AdminURL: %q, "+
+			"EZproxy.AdminUsername: %q, "+
+			"IsSetEZproxyAdminPassword: %t, "+
 			"EZproxy.ActiveFilePath: %v, "+
+			"EZproxy.WatchInterval: %v, "+
 			"EZproxy.AuditFileDirPath: %v, "+
+			"EZproxy.AuditFileLookback: %v, "+
 			"EZproxy.SearchRetries: %v, "+
 			"EZproxy.SearchDelay: %v, "+
 			"EZproxy.TerminateSessions: %t, "+
+			"EZproxy.TerminateScope: %v, "+
+			"EZproxy.KillTimeout: %v, "+
+			"EZproxy.KillConcurrency: %v, "+
+			"EZproxy.KillRetries: %v, "+
+			"EZproxy.KillRetryDelay: %v, "+
+			"EZproxy.VerifyDelay: %v, "+
+			"Thresholds.Reports: %d, "+
+			"Thresholds.AlertNames: %d, "+
+			"Thresholds.Window: %d, "+
+			"Thresholds.StateFile: %q, "+
+			"Approvals.BaseURL: %q, "+
+			"IsSetApprovalsSecret: %t, "+
+			"Approvals.Timeout: %d, "+
+			"Approvals.TimeoutAction: %q, "+
+			"Approvals.StateFile: %q, "+
+			"CircuitBreaker.MaxDisables: %d, "+
+			"CircuitBreaker.Window: %d, "+
+			"CircuitBreaker.StateFile: %q, "+
+			"Activity.Window: %d, "+
+			"Activity.Senders: %v, "+
+			"Reconcile.Interval: %d, "+
+			"Reconcile.Action: %q, "+
+			"Firewall.Backend: %q, "+
+			"Firewall.BlockDuration: %d, "+
+			"Firewall.ExecutablePath: %q, "+
+			"Firewall.Set: %q, "+
+			"Firewall.NftablesTable: %q, "+
+			"Firewall.Fail2banJail: %q, "+
+			"Firewall.Timeout: %d, "+
+			"Firewall.StateFile: %q, "+
+			"Instances: %v, "+
+			"Tenants: %v, "+
+			"Hooks: %v, "+
+			"Policies: %v, "+
+			"DefaultPolicy: %v, "+
+			"API.Users: %d configured, "+
 			"ConfigFile: %q}",
 		c.LocalTCPPort(),
 		c.LocalIPAddress(),
+		c.TLSCertFile(),
+		c.TLSKeyFile(),
 		c.LogLevel(),
 		c.LogOutput(),
 		c.LogFormat(),
 		c.DisabledUsersFile(),
 		c.DisabledUsersFileEntrySuffix(),
+		c.DisabledUsersEntryTemplateFile(),
+		c.DisabledUsersQuarantineTemplateFile(),
 		c.DisabledUsersFilePermissions(),
+		c.DisabledUsersBlocklists(),
+		c.DisabledUsersBlocklistFeed(),
+		c.DisabledUsersBlocklistDuration(),
+		c.DisabledUsersBlocklistStateFile(),
 		c.ReportedUsersLogFile(),
 		c.ReportedUsersLogFilePermissions(),
 		c.IgnoredUsersFile(),
@@ -91,10 +164,13 @@ func (c *Config) String() string {
 		c.IgnoredIPAddressesFile(),
 		c.IsSetIgnoredIPAddressesFile(),
 		c.IgnoreLookupErrors(),
+		c.DryRun(),
 		c.TeamsWebhookURL(),
 		c.TeamsNotificationRateLimit(),
 		c.TeamsNotificationRetries(),
 		c.TeamsNotificationRetryDelay(),
+		c.TeamsMessageFormat(),
+		c.TeamsUnblockURL(),
 		c.NotifyTeams(),
 		c.NotifyEmail(),
 		c.EmailServer(),
@@ -105,16 +181,110 @@ func (c *Config) String() string {
 		c.EmailNotificationRateLimit(),
 		c.EmailNotificationRetries(),
 		c.EmailNotificationRetryDelay(),
+		c.SlackWebhookURL(),
+		c.SlackNotificationRateLimit(),
+		c.SlackNotificationRetries(),
+		c.SlackNotificationRetryDelay(),
+		c.NotifySlack(),
+		c.WebhookURL(),
+		len(c.WebhookHeaders()),
+		c.WebhookTemplateFile(),
+		c.WebhookNotificationRateLimit(),
+		c.WebhookNotificationRetries(),
+		c.WebhookNotificationRetryDelay(),
+		c.NotifyWebhook(),
+		c.EZproxyTerminator(),
 		c.EZproxyExecutablePath(),
+		c.EZproxyAdminURL(),
+		c.EZproxyAdminUsername(),
+		c.EZproxyAdminPassword() != "",
 		c.EZproxyActiveFilePath(),
+		c.EZproxyWatchInterval(),
 		c.EZproxyAuditFileDirPath(),
+		c.EZproxyAuditFileLookback(),
 		c.EZproxySearchRetries(),
 		c.EZproxySearchDelay(),
 		c.EZproxyTerminateSessions(),
+		c.EZproxyTerminateScope(),
+		c.EZproxyKillTimeout(),
+		c.EZproxyKillConcurrency(),
+		c.EZproxyKillRetries(),
+		c.EZproxyKillRetryDelay(),
+		c.EZproxyVerifyDelay(),
+		c.ThresholdReports(),
+		c.ThresholdAlertNames(),
+		c.ThresholdWindow(),
+		c.ThresholdStateFile(),
+		c.ApprovalsBaseURL(),
+		c.ApprovalsSecret() != "",
+		c.ApprovalsTimeout(),
+		c.ApprovalsTimeoutAction(),
+		c.ApprovalsStateFile(),
+		c.CircuitBreakerMaxDisables(),
+		c.CircuitBreakerWindow(),
+		c.CircuitBreakerStateFile(),
+		c.ActivityWindow(),
+		c.ActivitySenders(),
+		c.ReconcileInterval(),
+		c.ReconcileAction(),
+		c.FirewallBackend(),
+		c.FirewallBlockDuration(),
+		c.FirewallExecutablePath(),
+		c.FirewallSet(),
+		c.FirewallNftablesTable(),
+		c.FirewallFail2banJail(),
+		c.FirewallTimeout(),
+		c.FirewallStateFile(),
+		c.ezproxyInstanceNames(),
+		c.tenantNames(),
+		c.hookNames(),
+		c.AlertPolicies(),
+		c.DefaultAlertPolicy(),
+		len(c.APIUsers()),
 		c.ConfigFile(),
 	)
 }
 
+// ezproxyInstanceNames returns the names of the configured EZproxy instances
+// for use in log messages; other instance settings (e.g., administrator
+// passwords) are omitted.
+func (c *Config) ezproxyInstanceNames() []string {
+
+	instances := c.EZproxyInstances()
+	names := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		names = append(names, instance.Name)
+	}
+
+	return names
+}
+
+// tenantNames returns the names of the configured tenants for use in log
+// messages; other tenant settings (e.g., auth secrets) are omitted.
+func (c *Config) tenantNames() []string {
+
+	tenants := c.Tenants()
+	names := make([]string, 0, len(tenants))
+	for _, tenant := range tenants {
+		names = append(names, tenant.Name)
+	}
+
+	return names
+}
+
+// hookNames returns the names of the configured hooks for use in log
+// messages; other hook settings (e.g., environment variables) are omitted.
+func (c *Config) hookNames() []string {
+
+	hooks := c.Hooks()
+	names := make([]string, 0, len(hooks))
+	for _, hook := range hooks {
+		names = append(names, hook.Name)
+	}
+
+	return names
+}
+
 // Version emits version information and associated branding details whenever
 // the user specifies the `--version` flag. The application exits after
 // displaying this information.
@@ -161,6 +331,7 @@ func MessageTrailer(format BrandingFormat) string {
 	switch format {
 	case BrandingMarkdownFormat:
 	case BrandingTextileFormat:
+	case BrandingSlackFormat:
 	default:
 		errMsg := fmt.Sprintf("Invalid branding format %q used!", format)
 		log.Warn(errMsg)
@@ -312,5 +483,12 @@ func (c *Config) LoadConfigFile(fileHandle io.Reader) error {
 		return err
 	}
 
+	// compile match criteria once instead of for each received alert
+	for i := range c.fileConfig.Policies {
+		if err := c.fileConfig.Policies[i].Compile(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -52,16 +52,125 @@ const (
 	// Redmine instance configured to use the (older) Textile formatting
 	// option.
 	BrandingTextileFormat BrandingFormat = `Message generated by "%s":%s (%s) at %s`
+
+	// BrandingSlackFormat is used as a Slack mrkdwn-compatible template for
+	// message "trailers" or "footers" on outgoing notifications. This is
+	// primarily used for Slack notifications.
+	BrandingSlackFormat BrandingFormat = `Message generated by <%[2]s|%[1]s> (%[3]s) at %[4]s`
 )
 
 // emailRegex is a regular expression provided by the W3C for email address
 // format validation.
 var emailRegex = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// Supported values for the EZproxy terminator setting.
+const (
+
+	// TerminatorExec terminates user sessions by running the `kill`
+	// subcommand of the EZproxy executable.
+	TerminatorExec string = "exec"
+
+	// TerminatorAdmin terminates user sessions using the EZproxy
+	// administrative web interface.
+	TerminatorAdmin string = "admin"
+)
+
+// Supported values for the reconcile action setting.
+const (
+
+	// ReconcileActionReport reports active sessions found for disabled users
+	// without terminating them.
+	ReconcileActionReport string = "report"
+
+	// ReconcileActionTerminate terminates active sessions found for disabled
+	// users.
+	ReconcileActionTerminate string = "terminate"
+)
+
+// Supported values for the Microsoft Teams message format setting.
+const (
+
+	// TeamsMessageFormatMessageCard submits legacy MessageCard messages as
+	// expected by Office 365 connector webhooks.
+	TeamsMessageFormatMessageCard string = "messagecard"
+
+	// TeamsMessageFormatAdaptiveCard submits Adaptive Card messages as
+	// expected by Teams Workflows (Power Automate) webhooks.
+	TeamsMessageFormatAdaptiveCard string = "adaptivecard"
+)
+
+// Supported values for the firewall backend setting.
+const (
+
+	// FirewallBackendNone disables blocking of reported user IP Addresses.
+	FirewallBackendNone string = "none"
+
+	// FirewallBackendIPSet blocks reported user IP Addresses by adding them
+	// to an ipset set.
+	FirewallBackendIPSet string = "ipset"
+
+	// FirewallBackendNftables blocks reported user IP Addresses by adding
+	// them to an nftables set.
+	FirewallBackendNftables string = "nftables"
+
+	// FirewallBackendFail2ban blocks reported user IP Addresses by banning
+	// them in a fail2ban jail.
+	FirewallBackendFail2ban string = "fail2ban"
+)
+
+// Supported values for the blocklist format setting.
+const (
+
+	// BlocklistFormatNginx lists blocked IP Addresses as nginx deny
+	// directives.
+	BlocklistFormatNginx string = "nginx"
+
+	// BlocklistFormatApache lists blocked IP Addresses as Apache HTTP Server
+	// "Require not ip" directives.
+	BlocklistFormatApache string = "apache"
+
+	// BlocklistFormatIPList lists blocked IP Addresses one per line.
+	BlocklistFormatIPList string = "ip-list"
+
+	// BlocklistFormatJSON lists blocked usernames and IP Addresses as a JSON
+	// document.
+	BlocklistFormatJSON string = "json"
+)
+
+// Supported values for the hook events setting.
+const (
+
+	// HookEventDisabled runs the hook when a user account is disabled.
+	HookEventDisabled string = "disabled"
+
+	// HookEventIgnored runs the hook when a reported user account is not
+	// disabled due to an ignored user account or IP Address entry.
+	HookEventIgnored string = "ignored"
+
+	// HookEventTerminated runs the hook when user sessions are terminated.
+	HookEventTerminated string = "terminated"
+
+	// HookEventFailure runs the hook when any action fails.
+	HookEventFailure string = "failure"
+)
+
+// DefaultEZproxyInstanceName is the name of the EZproxy instance derived from
+// the EZproxy and disabled users settings when no instances are specified.
+const DefaultEZproxyInstanceName string = "default"
+
+// apiUserDelimiter separates the operator name from the bcrypt password hash
+// in each entry of the API users list (e.g., "jsmith:$2y$10$...").
+const apiUserDelimiter string = ":"
+
 // Default (flag, config file, etc) settings if not overridden by user input
 const (
 	defaultLocalTCPPort int    = 8000
 	defaultLocalIP      string = "localhost"
+
+	// Requests are served over plain HTTP unless a certificate and private
+	// key are provided.
+	defaultTLSCertFile string = ""
+	defaultTLSKeyFile  string = ""
 	defaultLogLevel     string = "info"
 	defaultLogOutput    string = "stdout"
 	defaultLogFormat    string = "text"
@@ -77,6 +186,26 @@ const (
 	defaultDisabledUsersFile            string      = "/var/cache/brick/users.brick-disabled.txt"
 	defaultDisabledUsersFilePerms       os.FileMode = 0o644
 
+	// The built-in disabled users entry template is used and user accounts
+	// cannot be quarantined unless template files are provided
+	defaultDisabledUsersEntryTemplateFile      string = ""
+	defaultDisabledUsersQuarantineTemplateFile string = ""
+
+	// The blocked usernames and IP Addresses are not served unless requested
+	defaultDisabledUsersBlocklistFeed bool = false
+
+	// defaultDisabledUsersBlocklistDuration is the number of minutes
+	// each reported user IP Address remains listed in the blocklist.
+	defaultDisabledUsersBlocklistDuration int = 60
+
+	// defaultDisabledUsersBlocklistStateFile is the file used to persist the
+	// IP Addresses listed in the blocklist.
+	defaultDisabledUsersBlocklistStateFile string = "/var/cache/brick/blocklist-blocks.json"
+
+	// defaultHookTimeout is the number of seconds each hook command is
+	// allowed to run if the hook does not specify a timeout.
+	defaultHookTimeout int = 30
+
 	defaultReportedUsersLogFile      string      = "/var/log/brick/users.brick-reported.log"
 	defaultReportedUsersLogFilePerms os.FileMode = 0o644
 	defaultIgnoredUsersFile          string      = "/usr/local/etc/brick/users.brick-ignored.txt"
@@ -84,6 +213,9 @@ const (
 
 	defaultIgnoreLookupErrors bool = true
 
+	// Actions taken in response to received alerts are performed by default
+	defaultDryRun bool = false
+
 	// No assumptions can be safely made here; user has to supply this
 	defaultMSTeamsWebhookURL string = ""
 
@@ -102,6 +234,15 @@ const (
 	// attempts; applies to Microsoft Teams notifications only.
 	defaultMSTeamsRetryDelay int = 5
 
+	// defaultMSTeamsMessageFormat is the format of messages submitted to
+	// Microsoft Teams webhook URLs. Existing connector webhooks continue to
+	// work without changes.
+	defaultMSTeamsMessageFormat string = TeamsMessageFormatMessageCard
+
+	// The Unblock user action of Adaptive Card messages is omitted unless
+	// a URL is provided.
+	defaultMSTeamsUnblockURL string = ""
+
 	// defaultSMTPServerFQDN is the SMTP server that this application should
 	// connect to for email message delivery.
 	defaultSMTPServerFQDN string = ""
@@ -130,19 +271,71 @@ const (
 	// message delivery attempts.
 	defaultSMTPRetryDelay int = 2
 
+	// No assumptions can be safely made here; user has to supply this
+	defaultSlackWebhookURL string = ""
+
+	// defaultSlackRateLimit is the number of seconds to wait between Slack
+	// notification attempts. Slack incoming webhooks accept roughly one
+	// message per second.
+	defaultSlackRateLimit int = 2
+
+	// defaultSlackRetries is the number of attempts that this application
+	// will make to deliver Slack messages before giving up.
+	defaultSlackRetries int = 2
+
+	// defaultSlackRetryDelay is the number of seconds to wait between Slack
+	// message delivery attempts.
+	defaultSlackRetryDelay int = 5
+
+	// No assumptions can be safely made here; user has to supply this
+	defaultWebhookURL string = ""
+
+	// defaultWebhookTemplateFile is empty to indicate that the built-in
+	// template is used to render webhook request bodies.
+	defaultWebhookTemplateFile string = ""
+
+	// defaultWebhookRateLimit is the number of seconds to wait between
+	// webhook notification attempts.
+	defaultWebhookRateLimit int = 5
+
+	// defaultWebhookRetries is the number of attempts that this application
+	// will make to deliver webhook messages before giving up.
+	defaultWebhookRetries int = 2
+
+	// defaultWebhookRetryDelay is the number of seconds to wait between
+	// webhook message delivery attempts.
+	defaultWebhookRetryDelay int = 5
+
 	// defaultEZproxyExecutablePath is the fully-qualified path to the EZproxy
 	// binary.
 	defaultEZproxyExecutablePath string = "/usr/local/ezproxy/ezproxy"
 
+	// defaultEZproxyTerminator runs the EZproxy executable to terminate
+	// sessions.
+	defaultEZproxyTerminator string = TerminatorExec
+
+	// The EZproxy administrative web interface is not used by default.
+	defaultEZproxyAdminURL      string = ""
+	defaultEZproxyAdminUsername string = ""
+	defaultEZproxyAdminPassword string = ""
+
 	// defaultEZproxyActiveFilePath is the text file that contains information
 	// on active users and virtual web server proxies. This file is also known
 	// as the Active Users and Hosts file or the "state" file and is found in
 	// the same directory as the EZproxy executable.
 	defaultEZproxyActiveFilePath string = "/usr/local/ezproxy/ezproxy.hst"
 
+	// defaultEZproxyWatchInterval disables watching the active file; the
+	// active file is read for each session lookup.
+	defaultEZproxyWatchInterval int = 0
+
 	// defaultEZproxyAuditFileDirPath is the path where audit logs are stored.
 	defaultEZproxyAuditFileDirPath string = "/usr/local/ezproxy/audit"
 
+	// defaultEZproxyAuditFileLookback disables audit file lookups; only the
+	// active file is used to find sessions.
+	defaultEZproxyAuditFileLookback int = 0
+
 	// defaultEZproxySearchRetries is the number of retry attempts that are
 	// made to lookup sessions for a specified username after receiving zero
 	// search results.
@@ -154,6 +347,122 @@ const (
 
 	// defaultEZproxyTerminateSessions is the toggle for sessions termination
 	defaultEZproxyTerminateSessions bool = false
+
+	// defaultEZproxyKillTimeout is the time limit in seconds for each
+	// attempt to terminate a session.
+	defaultEZproxyKillTimeout int = 10
+
+	// defaultEZproxyKillConcurrency is the number of sessions terminated at
+	// the same time.
+	defaultEZproxyKillConcurrency int = 4
+
+	// defaultEZproxyKillRetries is the number of additional attempts made to
+	// terminate a session after a transient failure.
+	defaultEZproxyKillRetries int = 2
+
+	// defaultEZproxyKillRetryDelay is the delay in seconds before the first
+	// retry of a failed session termination.
+	defaultEZproxyKillRetryDelay int = 1
+
+	// defaultEZproxyVerifyDelay disables verification of session termination
+	// by default.
+	defaultEZproxyVerifyDelay int = 0
+
+	// defaultEZproxyTerminateScope selects all sessions for the reported
+	// username for termination.
+	defaultEZproxyTerminateScope string = "username"
+
+	// defaultThresholdReports is the number of reports required before a
+	// user account is disabled; the threshold is disabled by default.
+	defaultThresholdReports int = 0
+
+	// defaultThresholdAlertNames is the number of distinct alert names
+	// required before a user account is disabled; the threshold is disabled
+	// by default.
+	defaultThresholdAlertNames int = 0
+
+	// defaultThresholdWindow is the number of minutes in which reports are
+	// counted toward the threshold.
+	defaultThresholdWindow int = 30
+
+	// defaultThresholdStateFile is the file used to persist report counters.
+	defaultThresholdStateFile string = "/var/cache/brick/report-counters.json"
+
+	// No assumptions can be safely made here; user has to supply these if
+	// any alert policy requires approval
+	defaultApprovalsBaseURL string = ""
+	defaultApprovalsSecret  string = ""
+
+	// defaultApprovalsTimeout is the number of minutes approval requests
+	// remain pending before the timeout action is applied.
+	defaultApprovalsTimeout int = 60
+
+	// defaultApprovalsTimeoutAction errs on the side of caution; user
+	// accounts are not disabled without an explicit approval.
+	defaultApprovalsTimeoutAction string = "reject"
+
+	// defaultApprovalsStateFile is the file used to persist pending approval
+	// requests.
+	defaultApprovalsStateFile string = "/var/cache/brick/pending-approvals.json"
+
+	// defaultCircuitBreakerMaxDisables is the number of distinct user
+	// accounts which may be disabled within the window; the circuit breaker
+	// is disabled by default.
+	defaultCircuitBreakerMaxDisables int = 0
+
+	// defaultCircuitBreakerWindow is the number of minutes in which disabled
+	// user accounts are counted toward the limit.
+	defaultCircuitBreakerWindow int = 10
+
+	// defaultCircuitBreakerStateFile is the file used to persist the circuit
+	// breaker state.
+	defaultCircuitBreakerStateFile string = "/var/cache/brick/circuit-breaker.json"
+
+	// defaultActivityWindow is the number of minutes in which at least one
+	// valid alert payload is expected; activity monitoring is disabled by
+	// default.
+	defaultActivityWindow int = 0
+
+	// defaultReconcileInterval is the number of minutes between checks for
+	// disabled users with active sessions; reconciliation is disabled by
+	// default.
+	defaultReconcileInterval int = 0
+
+	// defaultReconcileAction reports active sessions found for disabled users
+	// without terminating them.
+	defaultReconcileAction string = ReconcileActionReport
+
+	// defaultFirewallBackend disables blocking of reported user IP
+	// Addresses; fail2ban may still be used to act on the reported users
+	// log.
+	defaultFirewallBackend string = FirewallBackendNone
+
+	// defaultFirewallBlockDuration is the number of minutes each reported
+	// user IP Address remains blocked.
+	defaultFirewallBlockDuration int = 60
+
+	// defaultFirewallExecutablePath is empty so that the executable for the
+	// selected backend is found via PATH.
+	defaultFirewallExecutablePath string = ""
+
+	// defaultFirewallSet is the name of the ipset or nftables set to which
+	// blocked IP Addresses are added.
+	defaultFirewallSet string = "brick-blocked"
+
+	// defaultFirewallNftablesTable is the address family and name of the
+	// nftables table containing the set.
+	defaultFirewallNftablesTable string = "inet brick"
+
+	// defaultFirewallFail2banJail is the name of the fail2ban jail in which
+	// blocked IP Addresses are banned.
+	defaultFirewallFail2banJail string = "brick"
+
+	// defaultFirewallTimeout is the number of seconds each run of the
+	// firewall executable is allowed to take.
+	defaultFirewallTimeout int = 10
+
+	// defaultFirewallStateFile is the file used to persist active blocks.
+	defaultFirewallStateFile string = "/var/cache/brick/firewall-blocks.json"
 )
 
 // TODO: Expose these settings via flags, config file
@@ -197,6 +506,22 @@ const (
 	// value. The GetNotificationTimeout method does just that.
 	NotifyMgrEmailNotificationTimeout time.Duration = 30 * time.Second
 
+	// NotifyMgrSlackNotificationTimeout is the timeout setting applied to
+	// each Slack notification attempt. This value does NOT take into account
+	// the number of configured retries, retry delays or overall rate limit
+	// for Slack notifications. The final timeout value applied to each
+	// notification attempt should be based on those calculations and this
+	// value. The GetNotificationTimeout method does just that.
+	NotifyMgrSlackNotificationTimeout time.Duration = 10 * time.Second
+
+	// NotifyMgrWebhookNotificationTimeout is the timeout setting applied to
+	// each webhook notification attempt. This value does NOT take into
+	// account the number of configured retries, retry delays or overall rate
+	// limit for webhook notifications. The final timeout value applied to
+	// each notification attempt should be based on those calculations and
+	// this value. The GetNotificationTimeout method does just that.
+	NotifyMgrWebhookNotificationTimeout time.Duration = 10 * time.Second
+
 	// NotifyStatsMonitorDelay limits notification stats logging to no more
 	// often than this duration. This limiter is to keep from logging the
 	// details so often that the information simply becomes noise.
@@ -208,6 +533,10 @@ const (
 	NotifyQueueMonitorDelay time.Duration = 15 * time.Second
 )
 
+// ActivityMonitorCheckInterval is how often the activity monitor checks
+// whether alert payloads have been received within the activity window.
+const ActivityMonitorCheckInterval time.Duration = 15 * time.Second
+
 // NotifyMgrQueueDepth is the number of items allowed into the queue/channel
 // at one time. Senders with items for the notification "pipeline" that do not
 // fit within the allocated space will block until space in the queue opens.
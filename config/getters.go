@@ -17,10 +17,15 @@
 package config
 
 import (
+	"net"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/Showmax/go-fqdn"
+
+	"github.com/atc0005/brick/events"
 )
 
 /******************************************************************
@@ -97,6 +102,54 @@ func (c Config) LocalIPAddress() string {
 	}
 }
 
+// TLSCertFile returns the user-provided path to the certificate used to
+// serve requests over HTTPS or an empty string if not provided. CLI flag
+// values take precedence if provided.
+func (c Config) TLSCertFile() string {
+
+	switch {
+	case c.cliConfig.Network.TLSCertFile != nil:
+		return *c.cliConfig.Network.TLSCertFile
+	case c.fileConfig.Network.TLSCertFile != nil:
+		return *c.fileConfig.Network.TLSCertFile
+	default:
+		return defaultTLSCertFile
+	}
+}
+
+// TLSKeyFile returns the user-provided path to the private key for the
+// certificate used to serve requests over HTTPS or an empty string if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) TLSKeyFile() string {
+
+	switch {
+	case c.cliConfig.Network.TLSKeyFile != nil:
+		return *c.cliConfig.Network.TLSKeyFile
+	case c.fileConfig.Network.TLSKeyFile != nil:
+		return *c.fileConfig.Network.TLSKeyFile
+	default:
+		return defaultTLSKeyFile
+	}
+}
+
+// TLSEnabled indicates whether requests are served over HTTPS.
+func (c Config) TLSEnabled() bool {
+	return c.TLSCertFile() != "" && c.TLSKeyFile() != ""
+}
+
+// ListenerIsLoopback indicates whether this application only listens for
+// requests on a loopback address (e.g., localhost or 127.0.0.1).
+func (c Config) ListenerIsLoopback() bool {
+
+	if strings.EqualFold(c.LocalIPAddress(), "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(c.LocalIPAddress())
+
+	return ip != nil && ip.IsLoopback()
+}
+
 // DisabledUsersFile returns the user-provided path to the EZproxy include
 // file where this application should write disabled user accounts or the
 // default value if not provided. CLI flag values take precedence if provided.
@@ -254,6 +307,20 @@ func (c Config) IgnoreLookupErrors() bool {
 	}
 }
 
+// DryRun returns the user-provided choice regarding simulating actions taken
+// in response to received alerts or the default value if not provided. CLI
+// flag values take precedence if provided.
+func (c Config) DryRun() bool {
+	switch {
+	case c.cliConfig.DryRun != nil:
+		return *c.cliConfig.DryRun
+	case c.fileConfig.DryRun != nil:
+		return *c.fileConfig.DryRun
+	default:
+		return defaultDryRun
+	}
+}
+
 // DisabledUsersFileEntrySuffix returns the user-provided disabled users entry
 // suffix or the default value if not provided. CLI flag values take
 // precedence if provided.
@@ -269,6 +336,101 @@ func (c Config) DisabledUsersFileEntrySuffix() string {
 	}
 }
 
+// DisabledUsersEntryTemplateFile returns the user-provided path to the
+// disabled users entry template file or the default value if not provided.
+// CLI flag values take precedence if provided.
+func (c Config) DisabledUsersEntryTemplateFile() string {
+	switch {
+	case c.cliConfig.DisabledUsers.EntryTemplate != nil:
+		return *c.cliConfig.DisabledUsers.EntryTemplate
+	case c.fileConfig.DisabledUsers.EntryTemplate != nil:
+		return *c.fileConfig.DisabledUsers.EntryTemplate
+	default:
+		return defaultDisabledUsersEntryTemplateFile
+	}
+}
+
+// DisabledUsersQuarantineTemplateFile returns the user-provided path to the
+// disabled users quarantine template file or the default value if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) DisabledUsersQuarantineTemplateFile() string {
+	switch {
+	case c.cliConfig.DisabledUsers.QuarantineTemplate != nil:
+		return *c.cliConfig.DisabledUsers.QuarantineTemplate
+	case c.fileConfig.DisabledUsers.QuarantineTemplate != nil:
+		return *c.fileConfig.DisabledUsers.QuarantineTemplate
+	default:
+		return defaultDisabledUsersQuarantineTemplateFile
+	}
+}
+
+// DisabledUsersBlocklists returns the user-provided list of blocklist files.
+// The disabled users file permissions are applied to blocklist files which
+// do not specify permissions. Blocklists may only be specified via
+// configuration file.
+func (c Config) DisabledUsersBlocklists() []Blocklist {
+
+	blocklists := make([]Blocklist, 0, len(c.fileConfig.DisabledUsers.Blocklists))
+	for _, blocklist := range c.fileConfig.DisabledUsers.Blocklists {
+		if blocklist.FilePermissions == 0 {
+			blocklist.FilePermissions = c.DisabledUsersFilePermissions()
+		}
+		blocklists = append(blocklists, blocklist)
+	}
+
+	return blocklists
+}
+
+// DisabledUsersBlocklistFeed returns the user-provided choice of whether the
+// blocked usernames and IP Addresses are served by the blocklist endpoint or
+// the default value if not provided. CLI flag values take precedence if
+// provided.
+func (c Config) DisabledUsersBlocklistFeed() bool {
+	switch {
+	case c.cliConfig.DisabledUsers.BlocklistFeed != nil:
+		return *c.cliConfig.DisabledUsers.BlocklistFeed
+	case c.fileConfig.DisabledUsers.BlocklistFeed != nil:
+		return *c.fileConfig.DisabledUsers.BlocklistFeed
+	default:
+		return defaultDisabledUsersBlocklistFeed
+	}
+}
+
+// DisabledUsersBlocklistEnabled indicates whether any blocklist files or the
+// blocklist feed have been configured.
+func (c Config) DisabledUsersBlocklistEnabled() bool {
+	return len(c.DisabledUsersBlocklists()) > 0 || c.DisabledUsersBlocklistFeed()
+}
+
+// DisabledUsersBlocklistDuration returns the user-provided number of
+// minutes each reported user IP Address remains listed in the blocklist or
+// the default value if not provided. CLI flag values take precedence if
+// provided.
+func (c Config) DisabledUsersBlocklistDuration() int {
+	switch {
+	case c.cliConfig.DisabledUsers.BlocklistDuration != nil:
+		return *c.cliConfig.DisabledUsers.BlocklistDuration
+	case c.fileConfig.DisabledUsers.BlocklistDuration != nil:
+		return *c.fileConfig.DisabledUsers.BlocklistDuration
+	default:
+		return defaultDisabledUsersBlocklistDuration
+	}
+}
+
+// DisabledUsersBlocklistStateFile returns the user-provided path to the file
+// used to persist the IP Addresses listed in the blocklist or the default
+// value if not provided. CLI flag values take precedence if provided.
+func (c Config) DisabledUsersBlocklistStateFile() string {
+	switch {
+	case c.cliConfig.DisabledUsers.BlocklistStateFile != nil:
+		return *c.cliConfig.DisabledUsers.BlocklistStateFile
+	case c.fileConfig.DisabledUsers.BlocklistStateFile != nil:
+		return *c.fileConfig.DisabledUsers.BlocklistStateFile
+	default:
+		return defaultDisabledUsersBlocklistStateFile
+	}
+}
+
 // TeamsWebhookURL returns the user-provided webhook URL used for Teams
 // notifications or the default value if not provided. CLI flag values take
 // precedence if provided.
@@ -335,6 +497,36 @@ func (c Config) TeamsNotificationRetryDelay() int {
 	}
 }
 
+// TeamsMessageFormat returns the user-provided format of the messages
+// submitted to Microsoft Teams webhook URLs or the default value if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) TeamsMessageFormat() string {
+
+	switch {
+	case c.cliConfig.MSTeams.MessageFormat != nil:
+		return *c.cliConfig.MSTeams.MessageFormat
+	case c.fileConfig.MSTeams.MessageFormat != nil:
+		return *c.fileConfig.MSTeams.MessageFormat
+	default:
+		return defaultMSTeamsMessageFormat
+	}
+}
+
+// TeamsUnblockURL returns the user-provided template for the URL opened by
+// the Unblock user action of Adaptive Card messages or the default value if
+// not provided. CLI flag values take precedence if provided.
+func (c Config) TeamsUnblockURL() string {
+
+	switch {
+	case c.cliConfig.MSTeams.UnblockURL != nil:
+		return *c.cliConfig.MSTeams.UnblockURL
+	case c.fileConfig.MSTeams.UnblockURL != nil:
+		return *c.fileConfig.MSTeams.UnblockURL
+	default:
+		return defaultMSTeamsUnblockURL
+	}
+}
+
 // NotifyTeams indicates whether or not notifications should be sent to a
 // Microsoft Teams channel.
 func (c Config) NotifyTeams() bool {
@@ -346,7 +538,20 @@ func (c Config) NotifyTeams() bool {
 
 	// For now, use the same logic that validate() uses to determine whether
 	// validation checks should be run.
-	return c.TeamsWebhookURL() != ""
+	//
+	// Teams notifications are also sent if any tenant specifies a webhook
+	// URL; only notifications for that tenant are sent to it.
+	if c.TeamsWebhookURL() != "" {
+		return true
+	}
+
+	for _, tenant := range c.fileConfig.Tenants {
+		if tenant.TeamsWebhookURL != "" {
+			return true
+		}
+	}
+
+	return false
 
 }
 
@@ -496,6 +701,192 @@ func (c Config) EmailNotificationRetryDelay() int {
 	}
 }
 
+// SlackWebhookURL returns the user-provided Slack incoming webhook URL or
+// the default value if not provided. CLI flag values take precedence if
+// provided.
+func (c Config) SlackWebhookURL() string {
+	switch {
+	case c.cliConfig.Slack.WebhookURL != nil:
+		return *c.cliConfig.Slack.WebhookURL
+	case c.fileConfig.Slack.WebhookURL != nil:
+		return *c.fileConfig.Slack.WebhookURL
+	default:
+		return defaultSlackWebhookURL
+	}
+}
+
+// SlackNotificationRateLimit returns a time.Duration value based on the
+// user-provided rate limit in seconds between Slack notifications or the
+// default value if not provided. CLI flag values take precedence if provided.
+func (c Config) SlackNotificationRateLimit() time.Duration {
+	var rateLimitSeconds int
+	switch {
+	case c.cliConfig.Slack.RateLimit != nil:
+		rateLimitSeconds = *c.cliConfig.Slack.RateLimit
+	case c.fileConfig.Slack.RateLimit != nil:
+		rateLimitSeconds = *c.fileConfig.Slack.RateLimit
+	default:
+		rateLimitSeconds = defaultSlackRateLimit
+	}
+
+	return time.Duration(rateLimitSeconds) * time.Second
+}
+
+// SlackNotificationRetries returns the user-provided retry limit before
+// giving up on Slack message delivery or the default value if not provided.
+// CLI flag values take precedence if provided.
+func (c Config) SlackNotificationRetries() int {
+	switch {
+	case c.cliConfig.Slack.Retries != nil:
+		return *c.cliConfig.Slack.Retries
+	case c.fileConfig.Slack.Retries != nil:
+		return *c.fileConfig.Slack.Retries
+	default:
+		return defaultSlackRetries
+	}
+}
+
+// SlackNotificationRetryDelay returns the user-provided delay between retry
+// attempts for Slack notifications or the default value if not provided.
+// CLI flag values take precedence if provided.
+func (c Config) SlackNotificationRetryDelay() int {
+	switch {
+	case c.cliConfig.Slack.RetryDelay != nil:
+		return *c.cliConfig.Slack.RetryDelay
+	case c.fileConfig.Slack.RetryDelay != nil:
+		return *c.fileConfig.Slack.RetryDelay
+	default:
+		return defaultSlackRetryDelay
+	}
+}
+
+// NotifySlack indicates whether or not notifications should be sent to a
+// Slack channel. Slack notifications are also sent if any tenant specifies a
+// Slack webhook URL; only notifications for that tenant are sent to it.
+func (c Config) NotifySlack() bool {
+
+	if c.SlackWebhookURL() != "" {
+		return true
+	}
+
+	for _, tenant := range c.fileConfig.Tenants {
+		if tenant.SlackWebhookURL != "" {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// WebhookURL returns the user-provided URL of the HTTP endpoint which
+// receives webhook notifications or the default value if not provided. CLI
+// flag values take precedence if provided.
+func (c Config) WebhookURL() string {
+	switch {
+	case c.cliConfig.Webhook.URL != nil:
+		return *c.cliConfig.Webhook.URL
+	case c.fileConfig.Webhook.URL != nil:
+		return *c.fileConfig.Webhook.URL
+	default:
+		return defaultWebhookURL
+	}
+}
+
+// WebhookHeaders returns the user-provided list of HTTP headers in "Name:
+// value" format included with each webhook request or an empty list if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) WebhookHeaders() []string {
+	switch {
+	case c.cliConfig.Webhook.Headers != nil:
+		return c.cliConfig.Webhook.Headers
+	case c.fileConfig.Webhook.Headers != nil:
+		return c.fileConfig.Webhook.Headers
+	default:
+		return make([]string, 0)
+	}
+}
+
+// WebhookTemplateFile returns the user-provided, fully-qualified path to the
+// template used to render webhook request bodies or the default value if not
+// provided. An empty value indicates that the built-in template is used. CLI
+// flag values take precedence if provided.
+func (c Config) WebhookTemplateFile() string {
+	switch {
+	case c.cliConfig.Webhook.TemplateFile != nil:
+		return *c.cliConfig.Webhook.TemplateFile
+	case c.fileConfig.Webhook.TemplateFile != nil:
+		return *c.fileConfig.Webhook.TemplateFile
+	default:
+		return defaultWebhookTemplateFile
+	}
+}
+
+// WebhookNotificationRateLimit returns a time.Duration value based on the
+// user-provided rate limit in seconds between webhook notifications or the
+// default value if not provided. CLI flag values take precedence if provided.
+func (c Config) WebhookNotificationRateLimit() time.Duration {
+	var rateLimitSeconds int
+	switch {
+	case c.cliConfig.Webhook.RateLimit != nil:
+		rateLimitSeconds = *c.cliConfig.Webhook.RateLimit
+	case c.fileConfig.Webhook.RateLimit != nil:
+		rateLimitSeconds = *c.fileConfig.Webhook.RateLimit
+	default:
+		rateLimitSeconds = defaultWebhookRateLimit
+	}
+
+	return time.Duration(rateLimitSeconds) * time.Second
+}
+
+// WebhookNotificationRetries returns the user-provided retry limit before
+// giving up on webhook message delivery or the default value if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) WebhookNotificationRetries() int {
+	switch {
+	case c.cliConfig.Webhook.Retries != nil:
+		return *c.cliConfig.Webhook.Retries
+	case c.fileConfig.Webhook.Retries != nil:
+		return *c.fileConfig.Webhook.Retries
+	default:
+		return defaultWebhookRetries
+	}
+}
+
+// WebhookNotificationRetryDelay returns the user-provided delay between
+// retry attempts for webhook notifications or the default value if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) WebhookNotificationRetryDelay() int {
+	switch {
+	case c.cliConfig.Webhook.RetryDelay != nil:
+		return *c.cliConfig.Webhook.RetryDelay
+	case c.fileConfig.Webhook.RetryDelay != nil:
+		return *c.fileConfig.Webhook.RetryDelay
+	default:
+		return defaultWebhookRetryDelay
+	}
+}
+
+// NotifyWebhook indicates whether or not notifications should be sent to a
+// generic HTTP endpoint. Webhook notifications are also sent if any tenant
+// specifies a webhook URL; only notifications for that tenant are sent to
+// it.
+func (c Config) NotifyWebhook() bool {
+
+	if c.WebhookURL() != "" {
+		return true
+	}
+
+	for _, tenant := range c.fileConfig.Tenants {
+		if tenant.WebhookURL != "" {
+			return true
+		}
+	}
+
+	return false
+
+}
+
 // EZproxyExecutablePath returns the user-provided, fully-qualified path to
 // the EZproxy executable or the default value if not provided. CLI flag
 // values take precedence if provided.
@@ -510,6 +901,64 @@ func (c Config) EZproxyExecutablePath() string {
 	}
 }
 
+// EZproxyTerminator returns the user-provided choice of how user sessions are
+// terminated or the default value if not provided. CLI flag values take
+// precedence if provided.
+func (c Config) EZproxyTerminator() string {
+	switch {
+	case c.cliConfig.EZproxy.Terminator != nil:
+		return *c.cliConfig.EZproxy.Terminator
+	case c.fileConfig.EZproxy.Terminator != nil:
+		return *c.fileConfig.EZproxy.Terminator
+	default:
+		return defaultEZproxyTerminator
+	}
+}
+
+// EZproxyAdminURL returns the user-provided base URL of the EZproxy server
+// used to log into the administrative web interface or the default value if
+// not provided. CLI flag values take precedence if provided.
+func (c Config) EZproxyAdminURL() string {
+	switch {
+	case c.cliConfig.EZproxy.AdminURL != nil:
+		return *c.cliConfig.EZproxy.AdminURL
+	case c.fileConfig.EZproxy.AdminURL != nil:
+		return *c.fileConfig.EZproxy.AdminURL
+	default:
+		return defaultEZproxyAdminURL
+	}
+}
+
+// EZproxyAdminUsername returns the user-provided name of the EZproxy
+// administrator account used to log into the administrative web interface or
+// the default value if not provided. CLI flag values take precedence if
+// provided.
+func (c Config) EZproxyAdminUsername() string {
+	switch {
+	case c.cliConfig.EZproxy.AdminUsername != nil:
+		return *c.cliConfig.EZproxy.AdminUsername
+	case c.fileConfig.EZproxy.AdminUsername != nil:
+		return *c.fileConfig.EZproxy.AdminUsername
+	default:
+		return defaultEZproxyAdminUsername
+	}
+}
+
+// EZproxyAdminPassword returns the user-provided password for the EZproxy
+// administrator account used to log into the administrative web interface or
+// the default value if not provided. CLI flag values take precedence if
+// provided.
+func (c Config) EZproxyAdminPassword() string {
+	switch {
+	case c.cliConfig.EZproxy.AdminPassword != nil:
+		return *c.cliConfig.EZproxy.AdminPassword
+	case c.fileConfig.EZproxy.AdminPassword != nil:
+		return *c.fileConfig.EZproxy.AdminPassword
+	default:
+		return defaultEZproxyAdminPassword
+	}
+}
+
 // EZproxyActiveFilePath returns the user-provided, fully-qualified path to
 // the EZproxy Active Users and Hosts "state" file or the default value if not
 // provided. CLI flag values take precedence if provided.
@@ -524,6 +973,20 @@ func (c Config) EZproxyActiveFilePath() string {
 	}
 }
 
+// EZproxyWatchInterval returns the user-provided number of milliseconds
+// between checks of the EZproxy active file for changes or the default value
+// if not provided. CLI flag values take precedence if provided.
+func (c Config) EZproxyWatchInterval() int {
+	switch {
+	case c.cliConfig.EZproxy.WatchInterval != nil:
+		return *c.cliConfig.EZproxy.WatchInterval
+	case c.fileConfig.EZproxy.WatchInterval != nil:
+		return *c.fileConfig.EZproxy.WatchInterval
+	default:
+		return defaultEZproxyWatchInterval
+	}
+}
+
 // EZproxyAuditFileDirPath returns the user-provided, fully-qualified path to
 // the EZproxy audit files directory or the default value if not provided. CLI
 // flag values take precedence if provided.
@@ -538,6 +1001,21 @@ func (c Config) EZproxyAuditFileDirPath() string {
 	}
 }
 
+// EZproxyAuditFileLookback returns the user-provided number of minutes in
+// which login events recorded in the EZproxy audit files are used to find
+// sessions or the default value if not provided. CLI flag values take
+// precedence if provided.
+func (c Config) EZproxyAuditFileLookback() int {
+	switch {
+	case c.cliConfig.EZproxy.AuditFileLookback != nil:
+		return *c.cliConfig.EZproxy.AuditFileLookback
+	case c.fileConfig.EZproxy.AuditFileLookback != nil:
+		return *c.fileConfig.EZproxy.AuditFileLookback
+	default:
+		return defaultEZproxyAuditFileLookback
+	}
+}
+
 // EZproxySearchRetries returns the user-provided number of retry attempts to
 // make for session lookup attempts that return zero results or the default
 // value if not provided. CLI flag values take precedence if provided.
@@ -580,3 +1058,756 @@ func (c Config) EZproxyTerminateSessions() bool {
 		return defaultEZproxyTerminateSessions
 	}
 }
+
+// EZproxyTerminateScope returns the user-provided termination scope applied
+// to alert policies which do not specify one or the default value if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) EZproxyTerminateScope() string {
+	switch {
+	case c.cliConfig.EZproxy.TerminateScope != nil:
+		return *c.cliConfig.EZproxy.TerminateScope
+	case c.fileConfig.EZproxy.TerminateScope != nil:
+		return *c.fileConfig.EZproxy.TerminateScope
+	default:
+		return defaultEZproxyTerminateScope
+	}
+}
+
+// EZproxyKillTimeout returns the user-provided time limit in seconds for each
+// attempt to terminate a session or the default value if not provided. CLI
+// flag values take precedence if provided.
+func (c Config) EZproxyKillTimeout() int {
+	switch {
+	case c.cliConfig.EZproxy.KillTimeout != nil:
+		return *c.cliConfig.EZproxy.KillTimeout
+	case c.fileConfig.EZproxy.KillTimeout != nil:
+		return *c.fileConfig.EZproxy.KillTimeout
+	default:
+		return defaultEZproxyKillTimeout
+	}
+}
+
+// EZproxyKillConcurrency returns the user-provided maximum number of sessions
+// terminated at the same time or the default value if not provided. CLI flag
+// values take precedence if provided.
+func (c Config) EZproxyKillConcurrency() int {
+	switch {
+	case c.cliConfig.EZproxy.KillConcurrency != nil:
+		return *c.cliConfig.EZproxy.KillConcurrency
+	case c.fileConfig.EZproxy.KillConcurrency != nil:
+		return *c.fileConfig.EZproxy.KillConcurrency
+	default:
+		return defaultEZproxyKillConcurrency
+	}
+}
+
+// EZproxyKillRetries returns the user-provided number of additional attempts
+// made to terminate a session after a transient failure or the default value
+// if not provided. CLI flag values take precedence if provided.
+func (c Config) EZproxyKillRetries() int {
+	switch {
+	case c.cliConfig.EZproxy.KillRetries != nil:
+		return *c.cliConfig.EZproxy.KillRetries
+	case c.fileConfig.EZproxy.KillRetries != nil:
+		return *c.fileConfig.EZproxy.KillRetries
+	default:
+		return defaultEZproxyKillRetries
+	}
+}
+
+// EZproxyKillRetryDelay returns the user-provided delay in seconds before the
+// first retry of a failed session termination or the default value if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) EZproxyKillRetryDelay() int {
+	switch {
+	case c.cliConfig.EZproxy.KillRetryDelay != nil:
+		return *c.cliConfig.EZproxy.KillRetryDelay
+	case c.fileConfig.EZproxy.KillRetryDelay != nil:
+		return *c.fileConfig.EZproxy.KillRetryDelay
+	default:
+		return defaultEZproxyKillRetryDelay
+	}
+}
+
+// EZproxyVerifyDelay returns the user-provided delay in seconds after session
+// termination before the active file is checked to confirm that terminated
+// sessions are gone or the default value if not provided. CLI flag values
+// take precedence if provided.
+func (c Config) EZproxyVerifyDelay() int {
+	switch {
+	case c.cliConfig.EZproxy.VerifyDelay != nil:
+		return *c.cliConfig.EZproxy.VerifyDelay
+	case c.fileConfig.EZproxy.VerifyDelay != nil:
+		return *c.fileConfig.EZproxy.VerifyDelay
+	default:
+		return defaultEZproxyVerifyDelay
+	}
+}
+
+// EZproxyInstances returns the user-provided list of EZproxy instances with
+// the executable path, terminator and administrator credentials set from the
+// EZproxy settings where not specified. If no instances are provided, a
+// single instance is returned using the EZproxy and disabled users settings.
+// Instances may only be specified via configuration file.
+func (c Config) EZproxyInstances() []EZproxyInstance {
+
+	if len(c.fileConfig.Instances) == 0 {
+		return []EZproxyInstance{
+			{
+				Name:              DefaultEZproxyInstanceName,
+				DisabledUsersFile: c.DisabledUsersFile(),
+				ActiveFilePath:    c.EZproxyActiveFilePath(),
+				AuditFileDirPath:  c.EZproxyAuditFileDirPath(),
+				ExecutablePath:    c.EZproxyExecutablePath(),
+				Terminator:        c.EZproxyTerminator(),
+				AdminURL:          c.EZproxyAdminURL(),
+				AdminUsername:     c.EZproxyAdminUsername(),
+				AdminPassword:     c.EZproxyAdminPassword(),
+			},
+		}
+	}
+
+	return c.applyInstanceDefaults(c.fileConfig.Instances)
+}
+
+// applyInstanceDefaults returns a copy of the provided EZproxy instances
+// with the executable path, terminator and administrator credentials set
+// from the EZproxy settings if not specified.
+func (c Config) applyInstanceDefaults(instances []EZproxyInstance) []EZproxyInstance {
+
+	updated := make([]EZproxyInstance, 0, len(instances))
+	for _, instance := range instances {
+		if instance.ExecutablePath == "" {
+			instance.ExecutablePath = c.EZproxyExecutablePath()
+		}
+		if instance.Terminator == "" {
+			instance.Terminator = c.EZproxyTerminator()
+		}
+		if instance.AdminUsername == "" {
+			instance.AdminUsername = c.EZproxyAdminUsername()
+		}
+		if instance.AdminPassword == "" {
+			instance.AdminPassword = c.EZproxyAdminPassword()
+		}
+		updated = append(updated, instance)
+	}
+
+	return updated
+}
+
+// Tenants returns the user-provided list of tenants or an empty list if not
+// provided. Tenants may only be specified via configuration file.
+//
+// The endpoint prefix defaults to the tenant name, the Microsoft Teams
+// message format defaults to the Microsoft Teams setting and settings not
+// specified by the EZproxy instances of each tenant are set from the EZproxy
+// settings.
+func (c Config) Tenants() []Tenant {
+
+	tenants := make([]Tenant, 0, len(c.fileConfig.Tenants))
+	for _, tenant := range c.fileConfig.Tenants {
+		prefix := tenant.EndpointPrefix
+		if prefix == "" {
+			prefix = tenant.Name
+		}
+		tenant.EndpointPrefix = "/" + strings.Trim(prefix, "/")
+		if tenant.TeamsMessageFormat == "" {
+			tenant.TeamsMessageFormat = c.TeamsMessageFormat()
+		}
+		tenant.Instances = c.applyInstanceDefaults(tenant.Instances)
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants
+}
+
+// APICredentials returns the operator credentials permitted to use the
+// management endpoints of the tenant as a map of operator name to password.
+// An empty map indicates that the management endpoints of the tenant are
+// disabled.
+func (t Tenant) APICredentials() map[string]string {
+	return apiCredentials(t.Users)
+}
+
+// Hooks returns the user-provided list of hooks or an empty list if not
+// provided. The default timeout is applied to hooks which do not specify
+// one. Hooks may only be specified via configuration file.
+func (c Config) Hooks() []Hook {
+
+	hooks := make([]Hook, 0, len(c.fileConfig.Hooks))
+	for _, hook := range c.fileConfig.Hooks {
+		if hook.Timeout == 0 {
+			hook.Timeout = defaultHookTimeout
+		}
+		hooks = append(hooks, hook)
+	}
+
+	return hooks
+}
+
+// AlertPolicies returns the user-provided list of alert policies or an empty
+// list if not provided. Alert policies may only be specified via
+// configuration file.
+//
+// Threshold, dry-run and termination scope settings not specified by a
+// policy are set from the global settings.
+func (c Config) AlertPolicies() events.AlertPolicies {
+
+	policies := make(events.AlertPolicies, 0, len(c.fileConfig.Policies))
+	for _, policy := range c.fileConfig.Policies {
+		policies = append(policies, c.applyPolicyDefaults(policy))
+	}
+
+	return policies
+}
+
+// DefaultAlertPolicy returns the policy applied to alerts which do not match
+// any user-provided alert policy. User accounts are disabled and sessions are
+// terminated only if session termination is enabled.
+func (c Config) DefaultAlertPolicy() events.AlertPolicy {
+
+	action := events.PolicyActionDisable
+	if c.EZproxyTerminateSessions() {
+		action = events.PolicyActionDisableTerminate
+	}
+
+	return c.applyPolicyDefaults(events.AlertPolicy{
+		Name:   events.DefaultAlertPolicyName,
+		Action: action,
+	})
+}
+
+// applyPolicyDefaults sets any threshold, dry-run or termination scope
+// settings not specified by the provided alert policy from the global
+// settings.
+func (c Config) applyPolicyDefaults(policy events.AlertPolicy) events.AlertPolicy {
+
+	if policy.ThresholdReports == nil {
+		reports := c.ThresholdReports()
+		policy.ThresholdReports = &reports
+	}
+
+	if policy.ThresholdAlertNames == nil {
+		alertNames := c.ThresholdAlertNames()
+		policy.ThresholdAlertNames = &alertNames
+	}
+
+	if policy.ThresholdWindow == nil {
+		window := c.ThresholdWindow()
+		policy.ThresholdWindow = &window
+	}
+
+	if policy.DryRun == nil {
+		dryRun := c.DryRun()
+		policy.DryRun = &dryRun
+	}
+
+	if policy.TerminateScope == nil {
+		terminateScope := c.EZproxyTerminateScope()
+		policy.TerminateScope = &terminateScope
+	}
+
+	return policy
+}
+
+// ThresholdReports returns the user-provided number of reports for the same
+// username required within the threshold window before the user account is
+// disabled or the default value if not provided. CLI flag values take
+// precedence if provided.
+func (c Config) ThresholdReports() int {
+	switch {
+	case c.cliConfig.Thresholds.Reports != nil:
+		return *c.cliConfig.Thresholds.Reports
+	case c.fileConfig.Thresholds.Reports != nil:
+		return *c.fileConfig.Thresholds.Reports
+	default:
+		return defaultThresholdReports
+	}
+}
+
+// ThresholdAlertNames returns the user-provided number of distinct alert
+// names required within the threshold window before the user account is
+// disabled or the default value if not provided. CLI flag values take
+// precedence if provided.
+func (c Config) ThresholdAlertNames() int {
+	switch {
+	case c.cliConfig.Thresholds.AlertNames != nil:
+		return *c.cliConfig.Thresholds.AlertNames
+	case c.fileConfig.Thresholds.AlertNames != nil:
+		return *c.fileConfig.Thresholds.AlertNames
+	default:
+		return defaultThresholdAlertNames
+	}
+}
+
+// ThresholdWindow returns the user-provided number of minutes in which
+// reports are counted toward the threshold or the default value if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) ThresholdWindow() int {
+	switch {
+	case c.cliConfig.Thresholds.Window != nil:
+		return *c.cliConfig.Thresholds.Window
+	case c.fileConfig.Thresholds.Window != nil:
+		return *c.fileConfig.Thresholds.Window
+	default:
+		return defaultThresholdWindow
+	}
+}
+
+// ThresholdStateFile returns the user-provided path to the file used to
+// persist report counters or the default value if not provided. CLI flag
+// values take precedence if provided.
+func (c Config) ThresholdStateFile() string {
+	switch {
+	case c.cliConfig.Thresholds.StateFile != nil:
+		return *c.cliConfig.Thresholds.StateFile
+	case c.fileConfig.Thresholds.StateFile != nil:
+		return *c.fileConfig.Thresholds.StateFile
+	default:
+		return defaultThresholdStateFile
+	}
+}
+
+// TenantThresholdStateFile returns the path to the file used to persist
+// report counters for the named tenant. The tenant name is added to the
+// report counters state file name so that reports received by each tenant
+// are counted separately.
+func (c Config) TenantThresholdStateFile(name string) string {
+	return tenantFilePath(c.ThresholdStateFile(), name)
+}
+
+// TenantCircuitBreakerStateFile returns the path to the file used to persist
+// the circuit breaker state for the named tenant. The tenant name is added
+// to the circuit breaker state file name so that the user accounts disabled
+// by each tenant are counted separately.
+func (c Config) TenantCircuitBreakerStateFile(name string) string {
+	return tenantFilePath(c.CircuitBreakerStateFile(), name)
+}
+
+// TenantApprovalsStateFile returns the path to the file used to persist
+// pending approval requests for the named tenant. The tenant name is added
+// to the approvals state file name so that each tenant only decides its own
+// approval requests.
+func (c Config) TenantApprovalsStateFile(name string) string {
+	return tenantFilePath(c.ApprovalsStateFile(), name)
+}
+
+// TenantFirewallStateFile returns the path to the file used to persist the
+// IP Addresses blocked for the named tenant. The tenant name is added to the
+// firewall state file name so that the blocks of each tenant are tracked
+// separately.
+func (c Config) TenantFirewallStateFile(name string) string {
+	return tenantFilePath(c.FirewallStateFile(), name)
+}
+
+// TenantBlocklistStateFile returns the path to the file used to persist the
+// IP Addresses listed in the blocklist for the named tenant. The tenant name
+// is added to the blocklist state file name so that the blocklist of each
+// tenant is tracked separately.
+func (c Config) TenantBlocklistStateFile(name string) string {
+	return tenantFilePath(c.DisabledUsersBlocklistStateFile(), name)
+}
+
+// TenantBlocklists returns the blocklist files for the named tenant. The
+// tenant name is added to the name of each blocklist file so that the
+// usernames and IP Addresses of each tenant are listed separately.
+func (c Config) TenantBlocklists(name string) []Blocklist {
+
+	blocklists := c.DisabledUsersBlocklists()
+	for i := range blocklists {
+		blocklists[i].FilePath = tenantFilePath(blocklists[i].FilePath, name)
+	}
+
+	return blocklists
+}
+
+// tenantFilePath adds the tenant name to the name of the file at the
+// provided path, before the file extension (e.g., state.json becomes
+// state-library-a.json). The path is returned as-is for the default
+// endpoints, which are served by a tenant with an empty name.
+func tenantFilePath(path string, name string) string {
+
+	if name == "" {
+		return path
+	}
+
+	ext := filepath.Ext(path)
+
+	return strings.TrimSuffix(path, ext) + "-" + name + ext
+}
+
+// ThresholdRetention returns the period of time for which report counters
+// should be retained. This is the largest threshold window in use by any
+// alert policy.
+func (c Config) ThresholdRetention() time.Duration {
+
+	retention := c.DefaultAlertPolicy().ReportThreshold().Window
+	for _, policy := range c.AlertPolicies() {
+		if window := policy.ReportThreshold().Window; window > retention {
+			retention = window
+		}
+	}
+
+	return retention
+}
+
+// ApprovalsBaseURL returns the user-provided URL used to build approval
+// links or the default value if not provided. CLI flag values take
+// precedence if provided.
+func (c Config) ApprovalsBaseURL() string {
+	switch {
+	case c.cliConfig.Approvals.BaseURL != nil:
+		return *c.cliConfig.Approvals.BaseURL
+	case c.fileConfig.Approvals.BaseURL != nil:
+		return *c.fileConfig.Approvals.BaseURL
+	default:
+		return defaultApprovalsBaseURL
+	}
+}
+
+// ApprovalsSecret returns the user-provided key used to sign approval links
+// or the default value if not provided. CLI flag values take precedence if
+// provided.
+func (c Config) ApprovalsSecret() string {
+	switch {
+	case c.cliConfig.Approvals.Secret != nil:
+		return *c.cliConfig.Approvals.Secret
+	case c.fileConfig.Approvals.Secret != nil:
+		return *c.fileConfig.Approvals.Secret
+	default:
+		return defaultApprovalsSecret
+	}
+}
+
+// ApprovalsTimeout returns the user-provided number of minutes approval
+// requests remain pending or the default value if not provided. CLI flag
+// values take precedence if provided.
+func (c Config) ApprovalsTimeout() int {
+	switch {
+	case c.cliConfig.Approvals.Timeout != nil:
+		return *c.cliConfig.Approvals.Timeout
+	case c.fileConfig.Approvals.Timeout != nil:
+		return *c.fileConfig.Approvals.Timeout
+	default:
+		return defaultApprovalsTimeout
+	}
+}
+
+// ApprovalsTimeoutAction returns the user-provided decision applied to
+// approval requests which time out or the default value if not provided.
+// CLI flag values take precedence if provided.
+func (c Config) ApprovalsTimeoutAction() string {
+	switch {
+	case c.cliConfig.Approvals.TimeoutAction != nil:
+		return *c.cliConfig.Approvals.TimeoutAction
+	case c.fileConfig.Approvals.TimeoutAction != nil:
+		return *c.fileConfig.Approvals.TimeoutAction
+	default:
+		return defaultApprovalsTimeoutAction
+	}
+}
+
+// ApprovalsStateFile returns the user-provided path to the file used to
+// persist pending approval requests or the default value if not provided.
+// CLI flag values take precedence if provided.
+func (c Config) ApprovalsStateFile() string {
+	switch {
+	case c.cliConfig.Approvals.StateFile != nil:
+		return *c.cliConfig.Approvals.StateFile
+	case c.fileConfig.Approvals.StateFile != nil:
+		return *c.fileConfig.Approvals.StateFile
+	default:
+		return defaultApprovalsStateFile
+	}
+}
+
+// ApprovalsRequired indicates whether any alert policy requires operator
+// approval before disabling user accounts.
+func (c Config) ApprovalsRequired() bool {
+	for _, policy := range c.AlertPolicies() {
+		if policy.RequireApproval {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CircuitBreakerMaxDisables returns the user-provided number of distinct
+// user accounts which may be disabled within the circuit breaker window or
+// the default value if not provided. CLI flag values take precedence if
+// provided.
+func (c Config) CircuitBreakerMaxDisables() int {
+	switch {
+	case c.cliConfig.CircuitBreaker.MaxDisables != nil:
+		return *c.cliConfig.CircuitBreaker.MaxDisables
+	case c.fileConfig.CircuitBreaker.MaxDisables != nil:
+		return *c.fileConfig.CircuitBreaker.MaxDisables
+	default:
+		return defaultCircuitBreakerMaxDisables
+	}
+}
+
+// CircuitBreakerWindow returns the user-provided number of minutes in which
+// disabled user accounts are counted toward the circuit breaker limit or the
+// default value if not provided. CLI flag values take precedence if
+// provided.
+func (c Config) CircuitBreakerWindow() int {
+	switch {
+	case c.cliConfig.CircuitBreaker.Window != nil:
+		return *c.cliConfig.CircuitBreaker.Window
+	case c.fileConfig.CircuitBreaker.Window != nil:
+		return *c.fileConfig.CircuitBreaker.Window
+	default:
+		return defaultCircuitBreakerWindow
+	}
+}
+
+// CircuitBreakerStateFile returns the user-provided path to the file used to
+// persist the circuit breaker state or the default value if not provided.
+// CLI flag values take precedence if provided.
+func (c Config) CircuitBreakerStateFile() string {
+	switch {
+	case c.cliConfig.CircuitBreaker.StateFile != nil:
+		return *c.cliConfig.CircuitBreaker.StateFile
+	case c.fileConfig.CircuitBreaker.StateFile != nil:
+		return *c.fileConfig.CircuitBreaker.StateFile
+	default:
+		return defaultCircuitBreakerStateFile
+	}
+}
+
+// CircuitBreakerReset indicates whether the user requested that the circuit
+// breaker be reset. CLI flag values are the only way to specify a value for
+// this setting.
+func (c Config) CircuitBreakerReset() bool {
+	switch {
+	case c.cliConfig.CircuitBreaker.Reset != nil:
+		return *c.cliConfig.CircuitBreaker.Reset
+	default:
+		return false
+	}
+}
+
+// ActivityWindow returns the user-provided number of minutes in which at
+// least one valid alert payload is expected or the default value if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) ActivityWindow() int {
+	switch {
+	case c.cliConfig.Activity.Window != nil:
+		return *c.cliConfig.Activity.Window
+	case c.fileConfig.Activity.Window != nil:
+		return *c.fileConfig.Activity.Window
+	default:
+		return defaultActivityWindow
+	}
+}
+
+// ActivitySenders returns the user-provided list of alert sender IP
+// Addresses individually expected to send alert payloads within the activity
+// window or an empty list if not provided. CLI flag values take precedence
+// if provided.
+func (c Config) ActivitySenders() []string {
+	switch {
+	case c.cliConfig.Activity.Senders != nil:
+		return c.cliConfig.Activity.Senders
+	case c.fileConfig.Activity.Senders != nil:
+		return c.fileConfig.Activity.Senders
+	default:
+		return make([]string, 0)
+	}
+}
+
+// ReconcileInterval returns the user-provided number of minutes between
+// checks for disabled users with active sessions or the default value if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) ReconcileInterval() int {
+	switch {
+	case c.cliConfig.Reconcile.Interval != nil:
+		return *c.cliConfig.Reconcile.Interval
+	case c.fileConfig.Reconcile.Interval != nil:
+		return *c.fileConfig.Reconcile.Interval
+	default:
+		return defaultReconcileInterval
+	}
+}
+
+// ReconcileAction returns the user-provided action taken for active sessions
+// found for disabled users or the default value if not provided. CLI flag
+// values take precedence if provided.
+func (c Config) ReconcileAction() string {
+	switch {
+	case c.cliConfig.Reconcile.Action != nil:
+		return *c.cliConfig.Reconcile.Action
+	case c.fileConfig.Reconcile.Action != nil:
+		return *c.fileConfig.Reconcile.Action
+	default:
+		return defaultReconcileAction
+	}
+}
+
+// FirewallBackend returns the user-provided firewall used to block reported
+// user IP Addresses or the default value if not provided. CLI flag values
+// take precedence if provided.
+func (c Config) FirewallBackend() string {
+	switch {
+	case c.cliConfig.Firewall.Backend != nil:
+		return *c.cliConfig.Firewall.Backend
+	case c.fileConfig.Firewall.Backend != nil:
+		return *c.fileConfig.Firewall.Backend
+	default:
+		return defaultFirewallBackend
+	}
+}
+
+// FirewallBlockDuration returns the user-provided number of minutes each IP
+// Address remains blocked or the default value if not provided. CLI flag
+// values take precedence if provided.
+func (c Config) FirewallBlockDuration() int {
+	switch {
+	case c.cliConfig.Firewall.BlockDuration != nil:
+		return *c.cliConfig.Firewall.BlockDuration
+	case c.fileConfig.Firewall.BlockDuration != nil:
+		return *c.fileConfig.Firewall.BlockDuration
+	default:
+		return defaultFirewallBlockDuration
+	}
+}
+
+// FirewallExecutablePath returns the user-provided path to the executable
+// used by the firewall backend or the name of the executable for the
+// selected backend if not provided. CLI flag values take precedence if
+// provided.
+func (c Config) FirewallExecutablePath() string {
+	switch {
+	case c.cliConfig.Firewall.ExecutablePath != nil:
+		return *c.cliConfig.Firewall.ExecutablePath
+	case c.fileConfig.Firewall.ExecutablePath != nil:
+		return *c.fileConfig.Firewall.ExecutablePath
+	}
+
+	switch c.FirewallBackend() {
+	case FirewallBackendIPSet:
+		return "ipset"
+	case FirewallBackendNftables:
+		return "nft"
+	case FirewallBackendFail2ban:
+		return "fail2ban-client"
+	default:
+		return defaultFirewallExecutablePath
+	}
+}
+
+// FirewallSet returns the user-provided name of the ipset or nftables set to
+// which blocked IP Addresses are added or the default value if not provided.
+// CLI flag values take precedence if provided.
+func (c Config) FirewallSet() string {
+	switch {
+	case c.cliConfig.Firewall.Set != nil:
+		return *c.cliConfig.Firewall.Set
+	case c.fileConfig.Firewall.Set != nil:
+		return *c.fileConfig.Firewall.Set
+	default:
+		return defaultFirewallSet
+	}
+}
+
+// FirewallNftablesTable returns the user-provided address family and name
+// of the nftables table containing the set or the default value if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) FirewallNftablesTable() string {
+	switch {
+	case c.cliConfig.Firewall.NftablesTable != nil:
+		return *c.cliConfig.Firewall.NftablesTable
+	case c.fileConfig.Firewall.NftablesTable != nil:
+		return *c.fileConfig.Firewall.NftablesTable
+	default:
+		return defaultFirewallNftablesTable
+	}
+}
+
+// FirewallFail2banJail returns the user-provided name of the fail2ban jail
+// in which blocked IP Addresses are banned or the default value if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) FirewallFail2banJail() string {
+	switch {
+	case c.cliConfig.Firewall.Fail2banJail != nil:
+		return *c.cliConfig.Firewall.Fail2banJail
+	case c.fileConfig.Firewall.Fail2banJail != nil:
+		return *c.fileConfig.Firewall.Fail2banJail
+	default:
+		return defaultFirewallFail2banJail
+	}
+}
+
+// FirewallTimeout returns the user-provided number of seconds each run of
+// the firewall executable is allowed to take or the default value if not
+// provided. CLI flag values take precedence if provided.
+func (c Config) FirewallTimeout() int {
+	switch {
+	case c.cliConfig.Firewall.Timeout != nil:
+		return *c.cliConfig.Firewall.Timeout
+	case c.fileConfig.Firewall.Timeout != nil:
+		return *c.fileConfig.Firewall.Timeout
+	default:
+		return defaultFirewallTimeout
+	}
+}
+
+// FirewallStateFile returns the user-provided path to the file used to
+// persist active blocks or the default value if not provided. CLI flag
+// values take precedence if provided.
+func (c Config) FirewallStateFile() string {
+	switch {
+	case c.cliConfig.Firewall.StateFile != nil:
+		return *c.cliConfig.Firewall.StateFile
+	case c.fileConfig.Firewall.StateFile != nil:
+		return *c.fileConfig.Firewall.StateFile
+	default:
+		return defaultFirewallStateFile
+	}
+}
+
+// APIUsers returns the user-provided list of operator credentials permitted
+// to use the management endpoints or an empty list if not provided. CLI flag
+// values take precedence if provided.
+func (c Config) APIUsers() []string {
+	switch {
+	case c.cliConfig.API.Users != nil:
+		return c.cliConfig.API.Users
+	case c.fileConfig.API.Users != nil:
+		return c.fileConfig.API.Users
+	default:
+		return make([]string, 0)
+	}
+}
+
+// APICredentials returns the user-provided operator credentials permitted to
+// use the management endpoints as a map of operator name to bcrypt password
+// hash. An empty map indicates that the management endpoints are disabled.
+func (c Config) APICredentials() map[string]string {
+	return apiCredentials(c.APIUsers())
+}
+
+// apiCredentials converts the provided API users list entries to a map of
+// operator name to bcrypt password hash.
+func apiCredentials(users []string) map[string]string {
+
+	credentials := make(map[string]string, len(users))
+	for _, user := range users {
+		name, password := splitAPIUser(user)
+		credentials[name] = password
+	}
+
+	return credentials
+}
+
+// splitAPIUser splits an API users list entry into the operator name and
+// bcrypt password hash. Only the first delimiter is significant.
+func splitAPIUser(user string) (string, string) {
+	parts := strings.SplitN(user, apiUserDelimiter, 2)
+	if len(parts) != 2 {
+		return strings.TrimSpace(parts[0]), ""
+	}
+	return strings.TrimSpace(parts[0]), parts[1]
+}
@@ -20,6 +20,8 @@ import (
 	"os"
 
 	"github.com/alexflint/go-arg"
+
+	"github.com/atc0005/brick/events"
 )
 
 // Config is a unified set of configuration values for this application. This
@@ -48,6 +50,15 @@ type Network struct {
 	// LocalIPAddress is the IP Address that this application should listen on
 	// for incoming requests
 	LocalIPAddress *string `toml:"local_ip_address" arg:"--ip-address,env:BRICK_LOCAL_IP_ADDRESS" help:"Local IP Address that this application should listen on for incoming HTTP requests."`
+
+	// TLSCertFile is the fully-qualified path to the PEM encoded certificate
+	// (chain) used to serve requests over HTTPS. Requests are served over
+	// plain HTTP if not specified.
+	TLSCertFile *string `toml:"tls_cert_file" arg:"--tls-cert-file,env:BRICK_TLS_CERT_FILE" help:"Fully-qualified path to the PEM encoded certificate (chain) used to serve requests over HTTPS. Requests are served over plain HTTP if not specified."`
+
+	// TLSKeyFile is the fully-qualified path to the PEM encoded private key
+	// for the certificate used to serve requests over HTTPS.
+	TLSKeyFile *string `toml:"tls_key_file" arg:"--tls-key-file,env:BRICK_TLS_KEY_FILE" help:"Fully-qualified path to the PEM encoded private key for the certificate used to serve requests over HTTPS."`
 }
 
 // Logging is a collection of logging-related settings provided via CLI and
@@ -79,9 +90,56 @@ type DisabledUsers struct {
 	// to the disabled users file in order to deny login access.
 	EntrySuffix *string `toml:"entry_suffix" arg:"--disabled-users-entry-suffix,env:BRICK_DISABLED_USERS_ENTRY_SUFFIX" help:"The string that is appended after every username added to the disabled users file in order to deny login access."`
 
+	// EntryTemplate is the fully-qualified path to a template file used to
+	// generate the entry written to the disabled users file when a user
+	// account is disabled. The built-in template is used if not provided.
+	EntryTemplate *string `toml:"entry_template" arg:"--disabled-users-entry-template,env:BRICK_DISABLED_USERS_ENTRY_TEMPLATE" help:"Fully-qualified path to a template file used to generate the entry written to the disabled users file when a user account is disabled. The built-in template is used if not provided."`
+
+	// QuarantineTemplate is the fully-qualified path to a template file used
+	// to generate the entry written to the disabled users file when a user
+	// account is quarantined (e.g., moved to a restricted EZproxy group).
+	// Required if any alert policy quarantines user accounts.
+	QuarantineTemplate *string `toml:"quarantine_template" arg:"--disabled-users-quarantine-template,env:BRICK_DISABLED_USERS_QUARANTINE_TEMPLATE" help:"Fully-qualified path to a template file used to generate the entry written to the disabled users file when a user account is quarantined. Required if any alert policy quarantines user accounts."`
+
 	// Permissions is the desired file permissions when this file is created.
 	// Note: The ezproxy daemon will need to be able to read this file.
 	FilePermissions *os.FileMode `toml:"file_permissions" arg:"--disabled-users-file-perms,env:BRICK_DISABLED_USERS_FILE_PERMISSIONS" help:"Desired file permissions when this file is created. Note: The ezproxy daemon will need to be able to read this file."`
+
+	// Blocklists is the list of files regenerated from the usernames listed
+	// in the disabled users files and the IP Addresses blocked by this
+	// application (e.g., for use by nginx or Apache HTTP Server). Blocklists
+	// may only be specified via configuration file.
+	Blocklists []Blocklist `toml:"blocklists" arg:"-"`
+
+	// BlocklistFeed controls whether the usernames listed in the disabled
+	// users files and the IP Addresses blocked by this application are
+	// served as a JSON feed by the blocklist endpoint.
+	BlocklistFeed *bool `toml:"blocklist_feed" arg:"--disabled-users-blocklist-feed,env:BRICK_DISABLED_USERS_BLOCKLIST_FEED" help:"Whether the usernames listed in the disabled users files and the IP Addresses blocked by this application are served as a JSON feed by the blocklist endpoint. The endpoint requires API user credentials."`
+
+	// BlocklistDuration is the number of minutes each reported user IP
+	// Address remains listed in the blocklist.
+	BlocklistDuration *int `toml:"blocklist_duration" arg:"--disabled-users-blocklist-duration,env:BRICK_DISABLED_USERS_BLOCKLIST_DURATION" help:"The number of minutes each reported user IP Address remains listed in the blocklist files and feed before it is automatically removed."`
+
+	// BlocklistStateFile is the fully-qualified path to the file used to
+	// persist the IP Addresses listed in the blocklist.
+	BlocklistStateFile *string `toml:"blocklist_state_file" arg:"--disabled-users-blocklist-state,env:BRICK_DISABLED_USERS_BLOCKLIST_STATE_FILE" help:"Fully-qualified path to the file used to persist the IP Addresses listed in the blocklist files and feed across application restarts."`
+}
+
+// Blocklist represents a file regenerated whenever the usernames listed in
+// the disabled users files or the IP Addresses blocked by this application
+// change. Blocklists may only be specified via configuration file.
+type Blocklist struct {
+
+	// Format is the format of the blocklist file; one of nginx, apache,
+	// ip-list or json.
+	Format string `toml:"format"`
+
+	// FilePath is the fully-qualified path to the blocklist file.
+	FilePath string `toml:"file_path"`
+
+	// FilePermissions is the desired file permissions for the blocklist
+	// file. Defaults to the disabled users file permissions.
+	FilePermissions os.FileMode `toml:"file_permissions"`
 }
 
 // ReportedUsers represents the path to, and permissions for, the file
@@ -152,6 +210,17 @@ type MSTeams struct {
 	// Retries is the number of attempts that this application will make to
 	// deliver Microsoft Teams messages before giving up.
 	Retries *int `toml:"retries" arg:"--teams-notify-retries,env:BRICK_MSTEAMS_WEBHOOK_RETRIES" help:"The number of attempts that this application will make to deliver Microsoft Teams messages before giving up."`
+
+	// MessageFormat is the format of the messages submitted to the webhook
+	// URL. Legacy Office 365 connector webhooks expect MessageCard messages
+	// while Teams Workflows (Power Automate) webhooks expect Adaptive Cards.
+	MessageFormat *string `toml:"message_format" arg:"--teams-message-format,env:BRICK_MSTEAMS_MESSAGE_FORMAT" help:"The format of the messages submitted to the Microsoft Teams webhook URL. One of messagecard (legacy Office 365 connector webhooks) or adaptivecard (Teams Workflows webhooks)."`
+
+	// UnblockURL is a template for the URL opened by the "Unblock user"
+	// action of Adaptive Card messages (e.g., a helpdesk form or runbook).
+	// The template is rendered using the alert; the action is omitted if not
+	// specified.
+	UnblockURL *string `toml:"unblock_url" arg:"--teams-unblock-url,env:BRICK_MSTEAMS_UNBLOCK_URL" help:"Template for the URL opened by the Unblock user action of Adaptive Card messages (e.g., https://helpdesk.example.com/unblock?user={{ urlquery .Username }}). The template is rendered using the alert. The action is omitted if not specified."`
 }
 
 // Email represents the various configuration settings ued to send email
@@ -203,6 +272,67 @@ type Email struct {
 	Retries *int `toml:"retries" arg:"--email-notify-retries,env:BRICK_EMAIL_NOTIFY_RETRIES" help:"The number of attempts that this application will make to deliver email messages before giving up."`
 }
 
+// Slack represents the various configuration settings used to send
+// notifications to a Slack channel via an incoming webhook.
+type Slack struct {
+
+	// WebhookURL is the full URL of the Slack incoming webhook used to submit
+	// messages to a Slack channel. This URL is in the form of
+	// https://hooks.slack.com/services/xxx and needs to be created in advance
+	// by adding an incoming webhook to a Slack app.
+	WebhookURL *string `toml:"webhook_url" arg:"--slack-webhook-url,env:BRICK_SLACK_WEBHOOK_URL" help:"The Slack incoming webhook URL. If specified, this application will attempt to send client request details to the Slack channel associated with the webhook URL."`
+
+	// RateLimit is the number of seconds to wait between Slack notification
+	// attempts. This rate limit is intended to help prevent unintentional
+	// abuse of remote services and is applied regardless of whether the last
+	// notification attempt was initially successful or required one or more
+	// retry attempts.
+	RateLimit *int `toml:"rate_limit" arg:"--slack-notify-rate-limit,env:BRICK_SLACK_NOTIFY_RATE_LIMIT" help:"The number of seconds to wait between Slack notification attempts. This rate limit is intended to help prevent unintentional abuse of remote services and is applied regardless of whether the last notification attempt was initially successful or required one or more retry attempts."`
+
+	// RetryDelay is the number of seconds to wait between Slack message
+	// delivery retry attempts.
+	RetryDelay *int `toml:"retry_delay" arg:"--slack-notify-retry-delay,env:BRICK_SLACK_NOTIFY_RETRY_DELAY" help:"The number of seconds to wait between Slack message delivery retry attempts."`
+
+	// Retries is the number of attempts that this application will make to
+	// deliver Slack messages before giving up.
+	Retries *int `toml:"retries" arg:"--slack-notify-retries,env:BRICK_SLACK_NOTIFY_RETRIES" help:"The number of attempts that this application will make to deliver Slack messages before giving up."`
+}
+
+// Webhook represents the various configuration settings used to send
+// notifications to a generic HTTP endpoint (e.g., chat tools, SOAR platforms
+// or ticket systems). The JSON request body is rendered from the event
+// record using a text/template template.
+type Webhook struct {
+
+	// URL is the full URL of the HTTP endpoint which receives notifications
+	// from this application via POST requests.
+	URL *string `toml:"url" arg:"--webhook-url,env:BRICK_WEBHOOK_URL" help:"The full URL of the HTTP endpoint which receives notifications from this application via POST requests. If specified, this application will attempt to send client request details to this endpoint."`
+
+	// Headers is the list of HTTP headers in "Name: value" format included
+	// with each webhook request (e.g., an Authorization header).
+	Headers []string `toml:"headers" arg:"--webhook-headers,env:BRICK_WEBHOOK_HEADERS" help:"The list of HTTP headers in 'Name: value' format included with each webhook request (e.g., an Authorization header)."`
+
+	// TemplateFile is the fully-qualified path to the text/template template
+	// used to render the JSON request body from the event record. A built-in
+	// template is used if not specified.
+	TemplateFile *string `toml:"template_file" arg:"--webhook-template-file,env:BRICK_WEBHOOK_TEMPLATE_FILE" help:"The fully-qualified path to the text/template template used to render the JSON request body from the event record. A built-in template is used if not specified."`
+
+	// RateLimit is the number of seconds to wait between webhook
+	// notification attempts. This rate limit is intended to help prevent
+	// unintentional abuse of remote services and is applied regardless of
+	// whether the last notification attempt was initially successful or
+	// required one or more retry attempts.
+	RateLimit *int `toml:"rate_limit" arg:"--webhook-notify-rate-limit,env:BRICK_WEBHOOK_NOTIFY_RATE_LIMIT" help:"The number of seconds to wait between webhook notification attempts. This rate limit is intended to help prevent unintentional abuse of remote services and is applied regardless of whether the last notification attempt was initially successful or required one or more retry attempts."`
+
+	// RetryDelay is the number of seconds to wait between webhook message
+	// delivery retry attempts.
+	RetryDelay *int `toml:"retry_delay" arg:"--webhook-notify-retry-delay,env:BRICK_WEBHOOK_NOTIFY_RETRY_DELAY" help:"The number of seconds to wait between webhook message delivery retry attempts."`
+
+	// Retries is the number of attempts that this application will make to
+	// deliver webhook messages before giving up.
+	Retries *int `toml:"retries" arg:"--webhook-notify-retries,env:BRICK_WEBHOOK_NOTIFY_RETRIES" help:"The number of attempts that this application will make to deliver webhook messages before giving up."`
+}
+
 // EZproxy represents that various configuration settings used to interact
 // with EZproxy and files/settings used by EZproxy.
 type EZproxy struct {
@@ -213,11 +343,37 @@ type EZproxy struct {
 	// executable is required for session termination.
 	ExecutablePath *string `toml:"executable_path" arg:"--ezproxy-executable-path,env:BRICK_EZPROXY_EXECUTABLE_PATH" help:"The fully-qualified path to the EZproxy executable/binary. This executable is usually named 'ezproxy' and is set to start at system boot. The fully-qualified path to this executable is required for session termination."`
 
+	// Terminator selects how user sessions are terminated. One of exec (run
+	// the `kill` subcommand of the EZproxy executable) or admin (use the
+	// EZproxy administrative web interface). The admin terminator allows
+	// this application to run on a host other than the EZproxy server.
+	Terminator *string `toml:"terminator" arg:"--ezproxy-terminator,env:BRICK_EZPROXY_TERMINATOR" help:"How user sessions are terminated. One of exec (run the kill subcommand of the EZproxy executable) or admin (use the EZproxy administrative web interface). The admin terminator allows this application to run on a host other than the EZproxy server."`
+
+	// AdminURL is the base URL of the EZproxy server used by the admin
+	// terminator to log into the administrative web interface.
+	AdminURL *string `toml:"admin_url" arg:"--ezproxy-admin-url,env:BRICK_EZPROXY_ADMIN_URL" help:"The base URL of the EZproxy server (e.g., https://ezproxy.example.edu:2443) used by the admin terminator to log into the administrative web interface."`
+
+	// AdminUsername is the name of the EZproxy administrator account used by
+	// the admin terminator.
+	AdminUsername *string `toml:"admin_username" arg:"--ezproxy-admin-username,env:BRICK_EZPROXY_ADMIN_USERNAME" help:"The name of the EZproxy administrator account used by the admin terminator."`
+
+	// AdminPassword is the password for the EZproxy administrator account
+	// used by the admin terminator.
+	AdminPassword *string `toml:"admin_password" arg:"--ezproxy-admin-password,env:BRICK_EZPROXY_ADMIN_PASSWORD" help:"The password for the EZproxy administrator account used by the admin terminator."`
+
 	// ActiveFilePath is the fully-qualified path to the Active Users and
 	// Hosts "state" file used by EZproxy (and this application) to track
 	// current sessions and hosts managed by EZproxy.
 	ActiveFilePath *string `toml:"active_file_path" arg:"--ezproxy-active-file-path,env:BRICK_EZPROXY_ACTIVE_FILE_PATH" help:"The fully-qualified path to the Active Users and Hosts 'state' file used by EZproxy (and this application) to track current sessions and hosts managed by EZproxy."`
 
+	// WatchInterval is the number of milliseconds between checks of the
+	// active file for changes. If set, the sessions recorded in the active
+	// file are kept in memory and updated whenever the file changes; session
+	// lookups use this index and return as soon as matching sessions are
+	// found instead of reading the active file for each attempt. A value of
+	// 0 disables watching the active file.
+	WatchInterval *int `toml:"watch_interval" arg:"--ezproxy-watch-interval,env:BRICK_EZPROXY_WATCH_INTERVAL" help:"The number of milliseconds between checks of the EZproxy active file for changes. If set, the sessions recorded in the active file are kept in memory and updated whenever the file changes; session lookups use this index and return as soon as matching sessions are found instead of reading the active file for each attempt. A value of 0 disables watching the active file."`
+
 	// AuditFileDirPath is the path to the directory containing the EZproxy
 	// audit files. The assumption is made that all files within are based on
 	// YYYYMMDD.txt pattern. Any other file pattern found within this path is
@@ -225,6 +381,14 @@ type EZproxy struct {
 	// by a sysadmin of a specific file).
 	AuditFileDirPath *string `toml:"audit_file_dir_path" arg:"--ezproxy-audit-file-dir-path,env:BRICK_EZPROXY_AUDIT_FILE_DIR_PATH" help:"The path to the directory containing the EZproxy audit files. The assumption is made that all files within are based on YYYYMMDD.txt pattern. Any other file pattern found within this path is ignored (e.g, .zip or .tar or whatnot for a one-off quick backup made by a sysadmin of a specific file)."`
 
+	// AuditFileLookback is the number of minutes in which login events
+	// recorded in the EZproxy audit files are used to find sessions for a
+	// reported user. These sessions are merged with those found in the
+	// active file; this finds sessions which EZproxy has not yet written to
+	// the active file. A value of 0 (the default) disables audit file
+	// lookups.
+	AuditFileLookback *int `toml:"audit_file_lookback" arg:"--ezproxy-audit-file-lookback,env:BRICK_EZPROXY_AUDIT_FILE_LOOKBACK" help:"The number of minutes in which login events recorded in the EZproxy audit files are used to find sessions for a reported user. These sessions are merged with those found in the active file; this finds sessions which EZproxy has not yet written to the active file. A value of 0 (the default) disables audit file lookups."`
+
 	// SearchRetries is the number of retries allowed for the audit log and
 	// active files before the application accepts that "cannot find matching
 	// session IDs for specific user" is really the truth of it and not a race
@@ -255,6 +419,349 @@ type EZproxy struct {
 	// reported users log file and temporarily block the source IP in order to
 	// force session timeout.
 	TerminateSessions *bool `toml:"terminate_sessions" arg:"--ezproxy-terminate-sessions,env:BRICK_EZPROXY_TERMINATE_SESSIONS" help:"Whether session termination support is enabled. If false, session termination will not be initiated by this application, though current session IDs found as part of preparing for termination will still be logged for troubleshooting purposes. 	// If setting (or leaving) this as false, the assumption is that either no handling of reported users is desired (other than perhaps logging and notification) or that a tool such as fail2ban is used to monitor the reported users log file and temporarily block the source IP in order to force session timeout."`
+
+	// TerminateScope determines which sessions are terminated for alerts
+	// matching alert policies which do not specify a termination scope
+	// (including the default policy). One of username (all sessions for the
+	// reported username), username-ip (only sessions for the reported
+	// username associated with the reported user IP Address) or ip (all
+	// sessions associated with the reported user IP Address regardless of
+	// username).
+	TerminateScope *string `toml:"terminate_scope" arg:"--ezproxy-terminate-scope,env:BRICK_EZPROXY_TERMINATE_SCOPE" help:"Which sessions are terminated for alerts matching alert policies which do not specify a termination scope. One of username (all sessions for the reported username), username-ip (only sessions for the reported username associated with the reported user IP Address) or ip (all sessions associated with the reported user IP Address regardless of username)."`
+
+	// KillTimeout is the time limit in seconds for each attempt to terminate a
+	// session. The EZproxy executable is stopped if it does not exit within this
+	// limit; for the admin terminator, this limits each request to the
+	// administrative web interface.
+	KillTimeout *int `toml:"kill_timeout" arg:"--ezproxy-kill-timeout,env:BRICK_EZPROXY_KILL_TIMEOUT" help:"The time limit in seconds for each attempt to terminate a session. The EZproxy executable is stopped if it does not exit within this limit; for the admin terminator, this limits each request to the administrative web interface."`
+
+	// KillConcurrency is the maximum number of sessions for a reported user
+	// which are terminated at the same time using the EZproxy executable.
+	KillConcurrency *int `toml:"kill_concurrency" arg:"--ezproxy-kill-concurrency,env:BRICK_EZPROXY_KILL_CONCURRENCY" help:"The maximum number of sessions for a reported user which are terminated at the same time using the EZproxy executable."`
+
+	// KillRetries is the number of additional attempts made to terminate a
+	// session using the EZproxy executable after a transient failure (e.g., a
+	// timeout or an unexpected exit code).
+	KillRetries *int `toml:"kill_retries" arg:"--ezproxy-kill-retries,env:BRICK_EZPROXY_KILL_RETRIES" help:"The number of additional attempts made to terminate a session using the EZproxy executable after a transient failure (e.g., a timeout or an unexpected exit code)."`
+
+	// KillRetryDelay is the delay in seconds before the first retry of a failed
+	// session termination. The delay is doubled for each retry which follows.
+	KillRetryDelay *int `toml:"kill_retry_delay" arg:"--ezproxy-kill-retry-delay,env:BRICK_EZPROXY_KILL_RETRY_DELAY" help:"The delay in seconds before the first retry of a failed session termination. The delay is doubled for each retry which follows."`
+
+	// VerifyDelay is the delay in seconds after session termination before
+	// the active file is checked to confirm that terminated sessions are gone
+	// and that no new sessions have appeared for the reported user. A value
+	// of 0 disables this check.
+	VerifyDelay *int `toml:"verify_delay" arg:"--ezproxy-verify-delay,env:BRICK_EZPROXY_VERIFY_DELAY" help:"The delay in seconds after session termination before the active file is checked to confirm that terminated sessions are gone. New sessions found for the reported user are also terminated. A value of 0 disables this check."`
+}
+
+// EZproxyInstance represents the settings for one of several EZproxy servers
+// managed by this application. Each instance has its own disabled users file,
+// active file and (optionally) audit file directory. The executable path,
+// terminator and administrator credentials default to those in the EZproxy
+// section if not specified. Instances may only be specified via
+// configuration file.
+type EZproxyInstance struct {
+
+	// Name is a unique, human-readable name for the instance used in alert
+	// policies, log messages and notifications.
+	Name string `toml:"name"`
+
+	// DisabledUsersFile is the fully-qualified path to the EZproxy include
+	// file for this instance where disabled user accounts are written.
+	DisabledUsersFile string `toml:"disabled_users_file"`
+
+	// ActiveFilePath is the fully-qualified path to the active users and
+	// hosts file for this instance.
+	ActiveFilePath string `toml:"active_file_path"`
+
+	// AuditFileDirPath is the path to the directory containing the audit
+	// files for this instance. Audit file lookups are skipped for this
+	// instance if not specified.
+	AuditFileDirPath string `toml:"audit_file_dir_path"`
+
+	// ExecutablePath is the fully-qualified path to the EZproxy executable
+	// for this instance used by the exec terminator.
+	ExecutablePath string `toml:"executable_path"`
+
+	// Terminator is how sessions for this instance are terminated; one of
+	// exec or admin.
+	Terminator string `toml:"terminator"`
+
+	// AdminURL is the base URL of this instance used by the admin
+	// terminator.
+	AdminURL string `toml:"admin_url"`
+
+	// AdminUsername is the name of the administrator account for this
+	// instance used by the admin terminator.
+	AdminUsername string `toml:"admin_username"`
+
+	// AdminPassword is the password for the administrator account for this
+	// instance used by the admin terminator.
+	AdminPassword string `toml:"admin_password"`
+}
+
+// Tenant represents the settings for one of several organizations (e.g.,
+// libraries sharing a server) served by this application. Each tenant
+// receives alert payloads on its own endpoints and has its own EZproxy
+// instances, reported users log, ignore lists and notification targets.
+// Tenants may only be specified via configuration file.
+type Tenant struct {
+
+	// Name is a unique, human-readable name for the tenant used in log
+	// messages and notifications.
+	Name string `toml:"name"`
+
+	// EndpointPrefix is the URL path prefix for the endpoints of this
+	// tenant (e.g., /library-a). Defaults to the tenant name.
+	EndpointPrefix string `toml:"endpoint_prefix"`
+
+	// AuthSecret is the shared secret which alert payloads sent to the
+	// endpoints of this tenant must provide.
+	AuthSecret string `toml:"auth_secret"`
+
+	// ReportedUsersLogFile is the fully-qualified path to the log file for
+	// this tenant where reported users and actions taken are recorded.
+	ReportedUsersLogFile string `toml:"reported_users_log_file"`
+
+	// IgnoredUsersFile is the fully-qualified path to the file containing
+	// usernames which should not be disabled for this tenant.
+	IgnoredUsersFile string `toml:"ignored_users_file"`
+
+	// IgnoredIPAddressesFile is the fully-qualified path to the file
+	// containing IP Addresses which should not be associated with users
+	// disabled for this tenant.
+	IgnoredIPAddressesFile string `toml:"ignored_ips_file"`
+
+	// TeamsWebhookURL is the Microsoft Teams webhook URL for notifications
+	// for this tenant. Teams notifications are not sent for this tenant if
+	// not specified.
+	TeamsWebhookURL string `toml:"teams_webhook_url"`
+
+	// TeamsMessageFormat is the format of the messages submitted to the
+	// Microsoft Teams webhook URL for this tenant. Defaults to the Microsoft
+	// Teams message format.
+	TeamsMessageFormat string `toml:"teams_message_format"`
+
+	// EmailRecipients is the list of email addresses which receive
+	// notifications for this tenant. Email notifications are not sent for
+	// this tenant if not specified.
+	EmailRecipients []string `toml:"email_recipients"`
+
+	// SlackWebhookURL is the Slack incoming webhook URL for notifications
+	// for this tenant. Slack notifications are not sent for this tenant if
+	// not specified.
+	SlackWebhookURL string `toml:"slack_webhook_url"`
+
+	// WebhookURL is the URL of the HTTP endpoint which receives webhook
+	// notifications for this tenant. Webhook notifications are not sent for
+	// this tenant if not specified.
+	WebhookURL string `toml:"webhook_url"`
+
+	// WebhookHeaders is the list of HTTP headers in "Name: value" format
+	// included with each webhook request for this tenant. The webhook
+	// headers of the default endpoints are never sent to the webhook URL of
+	// a tenant.
+	WebhookHeaders []string `toml:"webhook_headers"`
+
+	// Users is the list of operator credentials (name:password) permitted to
+	// use the management endpoints of this tenant. The management endpoints
+	// of this tenant are disabled if not specified.
+	Users []string `toml:"users"`
+
+	// Instances is the list of EZproxy servers managed for this tenant. At
+	// least one instance is required.
+	Instances []EZproxyInstance `toml:"instances"`
+}
+
+// Hook represents an external command run after this application takes one
+// of the selected actions (e.g., to open a ticket or force a password reset
+// for a disabled user account). The arguments and environment variables are
+// text/template templates rendered from the event record. Hooks may only be
+// specified via configuration file.
+type Hook struct {
+
+	// Name is a unique, human-readable name for the hook used in log
+	// messages and notifications.
+	Name string `toml:"name"`
+
+	// Events is the list of events which run the hook; one or more of
+	// disabled, ignored, terminated or failure.
+	Events []string `toml:"events"`
+
+	// ExecutablePath is the fully-qualified path to the command run by the
+	// hook.
+	ExecutablePath string `toml:"executable_path"`
+
+	// Args is the list of argument templates passed to the command.
+	Args []string `toml:"args"`
+
+	// Env is the list of environment variable templates, each in the form
+	// NAME=value, added to the environment of the command.
+	Env []string `toml:"env"`
+
+	// InheritEnv is the list of names of environment variables passed from
+	// the environment of this application to the command in addition to
+	// PATH. No other environment variables are passed to the command.
+	InheritEnv []string `toml:"inherit_env"`
+
+	// Timeout is the number of seconds the command is allowed to run before
+	// it is stopped.
+	Timeout int `toml:"timeout"`
+}
+
+// Thresholds represents the various configuration settings used to require
+// multiple reports for the same username before the user account is
+// disabled. These settings apply to all alert policies which do not specify
+// their own threshold settings.
+type Thresholds struct {
+
+	// Reports is the number of reports for the same username required within
+	// the threshold window before the user account is disabled. Reports
+	// below the threshold are logged, but no further action is taken. A
+	// value of 0 disables this threshold.
+	Reports *int `toml:"reports" arg:"--threshold-reports,env:BRICK_THRESHOLD_REPORTS" help:"The number of reports for the same username required within the threshold window before the user account is disabled. Reports below the threshold are logged, but no further action is taken. A value of 0 disables this threshold."`
+
+	// AlertNames is the number of distinct alert names reporting the same
+	// username required within the threshold window before the user account
+	// is disabled. A value of 0 disables this threshold.
+	AlertNames *int `toml:"alert_names" arg:"--threshold-alert-names,env:BRICK_THRESHOLD_ALERT_NAMES" help:"The number of distinct alert names reporting the same username required within the threshold window before the user account is disabled. A value of 0 disables this threshold."`
+
+	// Window is the number of minutes in which reports for the same username
+	// are counted toward the threshold.
+	Window *int `toml:"window" arg:"--threshold-window,env:BRICK_THRESHOLD_WINDOW" help:"The number of minutes in which reports for the same username are counted toward the threshold."`
+
+	// StateFile is the fully-qualified path to the file used to persist
+	// report counters across application restarts.
+	StateFile *string `toml:"state_file" arg:"--threshold-state-file,env:BRICK_THRESHOLD_STATE_FILE" help:"Fully-qualified path to the file used to persist report counters across application restarts."`
+}
+
+// Approvals represents the various configuration settings used to hold
+// disable requests for operator approval. These settings apply to alert
+// policies which require approval.
+type Approvals struct {
+
+	// BaseURL is the externally reachable URL of this application used to
+	// build the approve and reject links included in notifications.
+	BaseURL *string `toml:"base_url" arg:"--approvals-base-url,env:BRICK_APPROVALS_BASE_URL" help:"The externally reachable URL of this application (e.g., https://brick.example.org:8000) used to build the approve and reject links included in approval request notifications."`
+
+	// Secret is the key used to sign approve and reject links.
+	Secret *string `toml:"secret" arg:"--approvals-secret,env:BRICK_APPROVALS_SECRET" help:"The key used to sign approve and reject links. Use a long, random value. Changing this value invalidates the links for pending approval requests."`
+
+	// Timeout is the number of minutes approval requests remain pending
+	// before the timeout action is applied.
+	Timeout *int `toml:"timeout" arg:"--approvals-timeout,env:BRICK_APPROVALS_TIMEOUT" help:"The number of minutes approval requests remain pending before the timeout action is applied."`
+
+	// TimeoutAction is the decision applied to approval requests which time
+	// out.
+	TimeoutAction *string `toml:"timeout_action" arg:"--approvals-timeout-action,env:BRICK_APPROVALS_TIMEOUT_ACTION" help:"The decision applied to approval requests which time out; approve or reject."`
+
+	// StateFile is the fully-qualified path to the file used to persist
+	// pending approval requests across application restarts.
+	StateFile *string `toml:"state_file" arg:"--approvals-state-file,env:BRICK_APPROVALS_STATE_FILE" help:"Fully-qualified path to the file used to persist pending approval requests across application restarts."`
+}
+
+// CircuitBreaker represents the various configuration settings used to limit
+// the number of user accounts disabled within a short period of time in
+// order to protect against runaway alerts.
+type CircuitBreaker struct {
+
+	// MaxDisables is the number of distinct user accounts which may be
+	// disabled within the window before the circuit breaker trips. A value
+	// of 0 disables the circuit breaker.
+	MaxDisables *int `toml:"max_disables" arg:"--circuit-breaker-max-disables,env:BRICK_CIRCUIT_BREAKER_MAX_DISABLES" help:"The number of distinct user accounts which may be disabled within the circuit breaker window. If more are reported, the circuit breaker trips and no further user accounts are disabled until an operator resets it. A value of 0 disables the circuit breaker."`
+
+	// Window is the number of minutes in which disabled user accounts are
+	// counted toward the limit.
+	Window *int `toml:"window" arg:"--circuit-breaker-window,env:BRICK_CIRCUIT_BREAKER_WINDOW" help:"The number of minutes in which disabled user accounts are counted toward the circuit breaker limit."`
+
+	// StateFile is the fully-qualified path to the file used to persist the
+	// circuit breaker state across application restarts.
+	StateFile *string `toml:"state_file" arg:"--circuit-breaker-state-file,env:BRICK_CIRCUIT_BREAKER_STATE_FILE" help:"Fully-qualified path to the file used to persist the circuit breaker state across application restarts."`
+
+	// Reset indicates that the circuit breaker should be reset and the
+	// application should then exit. This setting may only be specified via
+	// CLI flag.
+	Reset *bool `toml:"-" arg:"--circuit-breaker-reset" help:"Reset a tripped circuit breaker and exit. A running instance of this application resumes disabling user accounts once the circuit breaker is reset."`
+}
+
+// Activity represents the various configuration settings used to detect
+// periods when no alert payloads are received (e.g., due to a broken Splunk
+// search, an expired token or a firewall change).
+type Activity struct {
+
+	// Window is the number of minutes in which at least one valid alert
+	// payload is expected. A value of 0 disables activity monitoring.
+	Window *int `toml:"window" arg:"--activity-window,env:BRICK_ACTIVITY_WINDOW" help:"The number of minutes in which at least one valid alert payload is expected. If none are received, a warning notification is sent, followed by a recovery notification once alert payloads are received again. A value of 0 disables activity monitoring."`
+
+	// Senders is the list of alert sender IP Addresses which are each
+	// expected to send at least one valid alert payload within the window.
+	Senders []string `toml:"senders" arg:"--activity-senders,env:BRICK_ACTIVITY_SENDERS" help:"The comma or space-separated list of alert sender IP Addresses (e.g., Splunk search heads) which are each expected to send at least one valid alert payload within the activity window."`
+}
+
+// Reconcile represents the various configuration settings used to
+// periodically check for disabled users which still have active sessions
+// (e.g., sessions which failed to terminate or users disabled by hand).
+type Reconcile struct {
+
+	// Interval is the number of minutes between checks of the EZproxy active
+	// file for sessions belonging to disabled users. A value of 0 disables
+	// reconciliation.
+	Interval *int `toml:"interval" arg:"--reconcile-interval,env:BRICK_RECONCILE_INTERVAL" help:"The number of minutes between checks of the EZproxy active file for sessions belonging to users listed in the disabled users file. A value of 0 disables reconciliation."`
+
+	// Action is what is done with active sessions found for disabled users.
+	Action *string `toml:"action" arg:"--reconcile-action,env:BRICK_RECONCILE_ACTION" help:"What is done with active sessions found for disabled users. report logs the sessions and sends a notification, terminate also terminates the sessions."`
+}
+
+// Firewall represents the various configuration settings used to block the
+// IP Address reported for disabled user accounts using a host firewall.
+type Firewall struct {
+
+	// Backend is the firewall used to block reported user IP Addresses.
+	Backend *string `toml:"backend" arg:"--firewall-backend,env:BRICK_FIREWALL_BACKEND" help:"The firewall used to block the reported user IP Address when a user account is disabled. One of none, ipset, nftables or fail2ban."`
+
+	// BlockDuration is the number of minutes each IP Address remains
+	// blocked before it is automatically unblocked.
+	BlockDuration *int `toml:"block_duration" arg:"--firewall-block-duration,env:BRICK_FIREWALL_BLOCK_DURATION" help:"The number of minutes each IP Address remains blocked before it is automatically unblocked."`
+
+	// ExecutablePath is the path to the executable used by the firewall
+	// backend. The executable for the backend is found via PATH if not
+	// provided.
+	ExecutablePath *string `toml:"executable_path" arg:"--firewall-executable-path,env:BRICK_FIREWALL_EXECUTABLE_PATH" help:"The path to the executable used by the firewall backend (ipset, nft or fail2ban-client). The executable is found via PATH if not provided."`
+
+	// Set is the name of the ipset or nftables set to which blocked IP
+	// Addresses are added.
+	Set *string `toml:"set" arg:"--firewall-set,env:BRICK_FIREWALL_SET" help:"The name of the existing ipset or nftables set to which blocked IP Addresses are added."`
+
+	// NftablesTable is the address family and name of the nftables table
+	// containing the set.
+	NftablesTable *string `toml:"nftables_table" arg:"--firewall-nftables-table,env:BRICK_FIREWALL_NFTABLES_TABLE" help:"The address family and name of the existing nftables table containing the set (e.g., inet brick)."`
+
+	// Fail2banJail is the name of the fail2ban jail in which blocked IP
+	// Addresses are banned.
+	Fail2banJail *string `toml:"fail2ban_jail" arg:"--firewall-fail2ban-jail,env:BRICK_FIREWALL_FAIL2BAN_JAIL" help:"The name of the existing fail2ban jail in which blocked IP Addresses are banned."`
+
+	// Timeout is the number of seconds each run of the firewall executable
+	// is allowed to take.
+	Timeout *int `toml:"timeout" arg:"--firewall-timeout,env:BRICK_FIREWALL_TIMEOUT" help:"The number of seconds each run of the firewall executable is allowed to take before it is stopped."`
+
+	// StateFile is the fully-qualified path to the file used to persist
+	// active blocks across application restarts.
+	StateFile *string `toml:"state_file" arg:"--firewall-state-file,env:BRICK_FIREWALL_STATE_FILE" help:"Fully-qualified path to the file used to persist active blocks across application restarts so that IP Addresses are unblocked once their block expires."`
+}
+
+// API represents the various configuration settings used to control access
+// to the management endpoints provided by this application (e.g., those used
+// to manage the ignored user accounts and IP Addresses lists).
+type API struct {
+
+	// Users is the list of operator credentials permitted to use the
+	// management endpoints, each in the form name:hash where hash is the
+	// bcrypt hash of the password of the operator (e.g., as generated by
+	// htpasswd -nbB). The name is recorded in audit log entries and
+	// notifications for changes made by that operator. If no users are
+	// specified, the management endpoints are disabled.
+	Users []string `toml:"users" arg:"--api-users,env:BRICK_API_USERS" help:"The comma or space-separated list of operator credentials permitted to use the management endpoints, each in the form name:hash where hash is the bcrypt hash of the password of the operator (e.g., as generated by htpasswd -nbB name password). The name is recorded in audit log entries and notifications for changes made by that operator. If no users are specified, the management endpoints are disabled. Requires HTTPS unless listening on a loopback address."`
 }
 
 // configTemplate is our base configuration template used to collect values
@@ -273,7 +780,50 @@ type configTemplate struct {
 	IgnoredIPAddresses
 	MSTeams
 	Email
+	Slack
+	Webhook
 	EZproxy
+	Thresholds
+	Approvals
+	CircuitBreaker
+	Activity
+	Reconcile
+	Firewall
+	API
+
+	// Policies is the ordered list of alert policies used to determine how
+	// this application responds to received alerts. The first matching
+	// policy applies. Alerts which do not match any policy are handled by
+	// the default policy, which is derived from the TerminateSessions
+	// setting. Threshold, dry-run and termination scope settings not
+	// specified by a policy are inherited from the global settings. Policies
+	// may only be specified via configuration file.
+	Policies []events.AlertPolicy `toml:"policies" arg:"-"`
+
+	// Instances is the list of EZproxy servers managed by this application.
+	// Disable requests are applied to all instances unless limited by the
+	// alert policy. If no instances are specified, a single instance is
+	// derived from the EZproxy and disabled users settings. Instances may
+	// only be specified via configuration file.
+	Instances []EZproxyInstance `toml:"instances" arg:"-"`
+
+	// Tenants is the list of organizations served by this application whose
+	// alert payloads, output files and notifications are kept separate from
+	// those of other tenants. Tenants may only be specified via
+	// configuration file.
+	Tenants []Tenant `toml:"tenants" arg:"-"`
+
+	// Hooks is the list of external commands run after this application
+	// takes the selected actions. Hook results are included in the
+	// notifications for the event. Hooks may only be specified via
+	// configuration file.
+	Hooks []Hook `toml:"hooks" arg:"-"`
+
+	// DryRun controls whether actions taken in response to received alerts
+	// are only simulated. If enabled, received alerts are fully processed,
+	// but the disabled users file is not updated and sessions are not
+	// terminated. Alert policies may override this setting.
+	DryRun *bool `toml:"dry_run" arg:"--dry-run,env:BRICK_DRY_RUN" help:"Whether actions taken in response to received alerts are only simulated. If enabled, received alerts are fully processed, but the disabled users file is not updated and sessions are not terminated. The actions which would have been taken are logged and reported with a [DRY-RUN] marker. Alert policies may override this setting."`
 
 	IgnoreLookupErrors *bool `toml:"ignore_lookup_errors" arg:"--ignore-lookup-errors,env:BRICK_IGNORE_LOOKUP_ERRORS" help:"Whether application should continue if attempts to lookup existing disabled or ignored status for a username or IP Address fail."`
 
@@ -18,8 +18,15 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
 
 	"github.com/apex/log"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/atc0005/brick/events"
 
 	goteamsnotify "github.com/atc0005/go-teams-notify/v2"
 )
@@ -140,6 +147,15 @@ func validate(c Config) error {
 		return fmt.Errorf("local IP Address not provided")
 	}
 
+	if (c.TLSCertFile() == "") != (c.TLSKeyFile() == "") {
+		log.Debugf(
+			"incomplete TLS settings specified: cert file %q, key file %q",
+			c.TLSCertFile(),
+			c.TLSKeyFile(),
+		)
+		return fmt.Errorf("both TLS certificate and private key files are required to serve requests over HTTPS")
+	}
+
 	switch c.LogLevel() {
 	case LogLevelFatal:
 	case LogLevelError:
@@ -195,12 +211,17 @@ func validate(c Config) error {
 		return fmt.Errorf("empty path to ignored ip addresses file provided")
 	}
 
+	if err := validateTeamsMessageFormat(c.TeamsMessageFormat()); err != nil {
+		log.Debugf("unsupported Microsoft Teams message format specified: %q", c.TeamsMessageFormat())
+		return err
+	}
+
 	// Not having a webhook URL is a valid choice. Perform validation if value
 	// is provided.
 	if c.TeamsWebhookURL() != "" {
 		log.Debugf("Microsoft Teams WebhookURL provided: %v", c.TeamsWebhookURL())
 
-		if ok, err := goteamsnotify.IsValidWebhookURL(c.TeamsWebhookURL()); !ok {
+		if err := validateTeamsWebhookURL(c.TeamsWebhookURL(), c.TeamsMessageFormat()); err != nil {
 			return err
 		}
 	}
@@ -315,18 +336,145 @@ func validate(c Config) error {
 
 	}
 
-	if c.EZproxyExecutablePath() == "" {
-		return fmt.Errorf("path to EZproxy executable file not provided")
+	// Not having a Slack webhook URL is a valid choice. Perform validation
+	// if value is provided.
+	if c.SlackWebhookURL() != "" {
+		log.Debugf("Slack webhook URL provided: %q", c.SlackWebhookURL())
+
+		if err := validateWebhookURL(c.SlackWebhookURL()); err != nil {
+			log.Debug(err.Error())
+			return err
+		}
+	}
+
+	if c.SlackNotificationRateLimit() < 0 {
+		log.Debugf("unsupported rate limit specified for Slack notifications: %d ", c.SlackNotificationRateLimit())
+		return fmt.Errorf(
+			"invalid rate limit specified for Slack notifications: %d",
+			c.SlackNotificationRateLimit(),
+		)
+	}
+
+	if c.SlackNotificationRetryDelay() < 0 {
+		log.Debugf("unsupported retry delay specified for Slack notifications: %d ", c.SlackNotificationRetryDelay())
+		return fmt.Errorf(
+			"invalid retry delay specified for Slack notifications: %d",
+			c.SlackNotificationRetryDelay(),
+		)
+	}
+
+	if c.SlackNotificationRetries() < 0 {
+		log.Debugf("unsupported retry limit specified for Slack notifications: %d ", c.SlackNotificationRetries())
+		return fmt.Errorf(
+			"invalid retries limit specified for Slack notifications: %d",
+			c.SlackNotificationRetries(),
+		)
+	}
+
+	// Not specifying a webhook URL is a valid choice. Perform validation of
+	// this and other related values if the URL is provided.
+	if c.WebhookURL() != "" {
+		log.Debugf("Webhook URL provided: %q", c.WebhookURL())
+
+		if err := validateWebhookURL(c.WebhookURL()); err != nil {
+			log.Debug(err.Error())
+			return err
+		}
+	}
+
+	if c.NotifyWebhook() {
+
+		if err := validateWebhookHeaders(c.WebhookHeaders()); err != nil {
+			log.Debug(err.Error())
+			return err
+		}
+
+		if c.WebhookNotificationRateLimit() < 0 {
+			log.Debugf(
+				"unsupported rate limit specified for webhook notifications: %d ",
+				c.WebhookNotificationRateLimit(),
+			)
+			return fmt.Errorf(
+				"invalid rate limit specified for webhook notifications: %d",
+				c.WebhookNotificationRateLimit(),
+			)
+		}
+
+		if c.WebhookNotificationRetryDelay() < 0 {
+			log.Debugf(
+				"unsupported retry delay specified for webhook notifications: %d ",
+				c.WebhookNotificationRetryDelay(),
+			)
+			return fmt.Errorf(
+				"invalid retry delay specified for webhook notifications: %d",
+				c.WebhookNotificationRetryDelay(),
+			)
+		}
+
+		if c.WebhookNotificationRetries() < 0 {
+			log.Debugf(
+				"unsupported retry limit specified for webhook notifications: %d",
+				c.WebhookNotificationRetries(),
+			)
+			return fmt.Errorf(
+				"invalid retries limit specified for webhook notifications: %d",
+				c.WebhookNotificationRetries(),
+			)
+		}
+	}
+
+	switch c.EZproxyTerminator() {
+	case TerminatorExec:
+		if c.EZproxyExecutablePath() == "" {
+			return fmt.Errorf("path to EZproxy executable file not provided")
+		}
+	case TerminatorAdmin:
+		adminURL, err := url.Parse(c.EZproxyAdminURL())
+		if err != nil || adminURL.Host == "" ||
+			(adminURL.Scheme != "http" && adminURL.Scheme != "https") {
+			log.Debugf("unsupported EZproxy admin URL specified: %q", c.EZproxyAdminURL())
+			return fmt.Errorf(
+				"invalid EZproxy admin URL %q; expected http or https URL",
+				c.EZproxyAdminURL(),
+			)
+		}
+		if c.EZproxyAdminUsername() == "" || c.EZproxyAdminPassword() == "" {
+			return fmt.Errorf("EZproxy admin username and password required for %s terminator", TerminatorAdmin)
+		}
+	default:
+		log.Debugf("unsupported EZproxy terminator specified: %q", c.EZproxyTerminator())
+		return fmt.Errorf(
+			"invalid EZproxy terminator %q; expected one of %s, %s",
+			c.EZproxyTerminator(),
+			TerminatorExec,
+			TerminatorAdmin,
+		)
 	}
 
 	if c.EZproxyActiveFilePath() == "" {
 		return fmt.Errorf("path to EZproxy active users state file not provided")
 	}
 
+	if c.EZproxyWatchInterval() < 0 {
+		log.Debugf("unsupported watch interval specified for EZproxy active file: %d ", c.EZproxyWatchInterval())
+		return fmt.Errorf(
+			"invalid watch interval specified for EZproxy active file: %d",
+			c.EZproxyWatchInterval(),
+		)
+	}
+
 	if c.EZproxyAuditFileDirPath() == "" {
 		return fmt.Errorf("path to EZproxy audit file directory not provided")
 	}
 
+	if c.EZproxyAuditFileLookback() < 0 {
+		log.Debugf("unsupported lookback specified for EZproxy audit files: %d ", c.EZproxyAuditFileLookback())
+		return fmt.Errorf(
+			"invalid lookback specified for EZproxy audit files: %d",
+			c.EZproxyAuditFileLookback(),
+		)
+	}
+
 	if c.EZproxySearchDelay() < 0 {
 		log.Debugf("unsupported delay specified for EZproxy session lookup attempts: %d ", c.EZproxySearchDelay())
 		return fmt.Errorf(
@@ -343,7 +491,763 @@ func validate(c Config) error {
 		)
 	}
 
+	if err := events.ValidateTerminateScope(c.EZproxyTerminateScope()); err != nil {
+		log.Debugf("unsupported termination scope specified: %v", err)
+		return fmt.Errorf("invalid EZproxy termination scope: %w", err)
+	}
+
+	if c.EZproxyKillTimeout() < 1 {
+		log.Debugf("unsupported timeout specified for EZproxy session termination: %d", c.EZproxyKillTimeout())
+		return fmt.Errorf(
+			"invalid timeout specified for EZproxy session termination: %d; expected 1 or more seconds",
+			c.EZproxyKillTimeout(),
+		)
+	}
+
+	if c.EZproxyKillConcurrency() < 1 {
+		log.Debugf("unsupported concurrency specified for EZproxy session termination: %d", c.EZproxyKillConcurrency())
+		return fmt.Errorf(
+			"invalid concurrency specified for EZproxy session termination: %d; expected 1 or more",
+			c.EZproxyKillConcurrency(),
+		)
+	}
+
+	if c.EZproxyKillRetries() < 0 {
+		log.Debugf("unsupported retry limit specified for EZproxy session termination: %d", c.EZproxyKillRetries())
+		return fmt.Errorf(
+			"invalid retries limit specified for EZproxy session termination: %d",
+			c.EZproxyKillRetries(),
+		)
+	}
+
+	if c.EZproxyKillRetryDelay() < 0 {
+		log.Debugf("unsupported retry delay specified for EZproxy session termination: %d", c.EZproxyKillRetryDelay())
+		return fmt.Errorf(
+			"invalid retry delay specified for EZproxy session termination: %d",
+			c.EZproxyKillRetryDelay(),
+		)
+	}
+
+	if c.EZproxyVerifyDelay() < 0 {
+		log.Debugf("unsupported verify delay specified for EZproxy session termination: %d", c.EZproxyVerifyDelay())
+		return fmt.Errorf(
+			"invalid verify delay specified for EZproxy session termination: %d",
+			c.EZproxyVerifyDelay(),
+		)
+	}
+
+	if c.ThresholdReports() < 0 {
+		log.Debugf("unsupported report threshold specified: %d", c.ThresholdReports())
+		return fmt.Errorf(
+			"invalid report threshold specified: %d",
+			c.ThresholdReports(),
+		)
+	}
+
+	if c.ThresholdAlertNames() < 0 {
+		log.Debugf("unsupported distinct alert names threshold specified: %d", c.ThresholdAlertNames())
+		return fmt.Errorf(
+			"invalid distinct alert names threshold specified: %d",
+			c.ThresholdAlertNames(),
+		)
+	}
+
+	if c.ThresholdWindow() < 1 {
+		log.Debugf("unsupported threshold window specified: %d", c.ThresholdWindow())
+		return fmt.Errorf(
+			"invalid threshold window specified: %d; expected 1 or more minutes",
+			c.ThresholdWindow(),
+		)
+	}
+
+	if c.ThresholdStateFile() == "" {
+		return fmt.Errorf("path to report counters state file not provided")
+	}
+
+	// EZproxy instance names and disabled users files must be unique across
+	// all tenants
+	allInstances := c.EZproxyInstances()
+	for _, tenant := range c.Tenants() {
+		allInstances = append(allInstances, tenant.Instances...)
+	}
+
+	instanceNames := make(map[string]bool)
+	disabledUsersFiles := make(map[string]bool)
+	for _, instance := range allInstances {
+		if err := validateEZproxyInstance(instance); err != nil {
+			log.Debug(err.Error())
+			return err
+		}
+		if instanceNames[instance.Name] {
+			log.Debugf("duplicate EZproxy instance name specified: %q", instance.Name)
+			return fmt.Errorf("duplicate EZproxy instance name %q", instance.Name)
+		}
+		if disabledUsersFiles[instance.DisabledUsersFile] {
+			log.Debugf("duplicate disabled users file specified: %q", instance.DisabledUsersFile)
+			return fmt.Errorf(
+				"disabled users file %q specified for more than one EZproxy instance",
+				instance.DisabledUsersFile,
+			)
+		}
+		instanceNames[instance.Name] = true
+		disabledUsersFiles[instance.DisabledUsersFile] = true
+	}
+
+	tenantNames := make(map[string]bool)
+	endpointPrefixes := make(map[string]bool)
+	tenantFiles := map[string]bool{
+		c.ReportedUsersLogFile():   true,
+		c.IgnoredUsersFile():       true,
+		c.IgnoredIPAddressesFile(): true,
+	}
+	for _, tenant := range c.Tenants() {
+		if err := validateTenant(tenant, c.NotifyEmail()); err != nil {
+			log.Debug(err.Error())
+			return err
+		}
+		if tenantNames[tenant.Name] {
+			log.Debugf("duplicate tenant name specified: %q", tenant.Name)
+			return fmt.Errorf("duplicate tenant name %q", tenant.Name)
+		}
+		if endpointPrefixes[tenant.EndpointPrefix] {
+			log.Debugf("duplicate tenant endpoint prefix specified: %q", tenant.EndpointPrefix)
+			return fmt.Errorf("endpoint prefix %q specified for more than one tenant", tenant.EndpointPrefix)
+		}
+		for _, file := range []string{
+			tenant.ReportedUsersLogFile,
+			tenant.IgnoredUsersFile,
+			tenant.IgnoredIPAddressesFile,
+		} {
+			if tenantFiles[file] {
+				log.Debugf("file %q specified for tenant %q is shared", file, tenant.Name)
+				return fmt.Errorf(
+					"file %q specified for tenant %q is not unique; each tenant requires its own files",
+					file,
+					tenant.Name,
+				)
+			}
+			tenantFiles[file] = true
+		}
+		tenantNames[tenant.Name] = true
+		endpointPrefixes[tenant.EndpointPrefix] = true
+	}
+
+	policyNames := map[string]bool{events.DefaultAlertPolicyName: true}
+	for _, policy := range c.AlertPolicies() {
+		if err := policy.Validate(); err != nil {
+			log.Debug(err.Error())
+			return err
+		}
+		if policyNames[policy.Name] {
+			log.Debugf("duplicate or reserved alert policy name specified: %q", policy.Name)
+			return fmt.Errorf("duplicate or reserved alert policy name %q", policy.Name)
+		}
+		policyNames[policy.Name] = true
+
+		if policy.Quarantine() && c.DisabledUsersQuarantineTemplateFile() == "" {
+			log.Debugf("quarantine template not provided for alert policy %q", policy.Name)
+			return fmt.Errorf(
+				"alert policy %q quarantines user accounts, but path to disabled users quarantine template file not provided",
+				policy.Name,
+			)
+		}
+
+		for _, name := range policy.Instances {
+			if !instanceNames[name] {
+				log.Debugf("unknown EZproxy instance %q specified by alert policy %q", name, policy.Name)
+				return fmt.Errorf(
+					"alert policy %q specifies unknown EZproxy instance %q",
+					policy.Name,
+					name,
+				)
+			}
+		}
+	}
+
+	if c.ApprovalsTimeout() < 1 {
+		log.Debugf("unsupported approvals timeout specified: %d", c.ApprovalsTimeout())
+		return fmt.Errorf(
+			"invalid approvals timeout specified: %d; expected 1 or more minutes",
+			c.ApprovalsTimeout(),
+		)
+	}
+
+	switch c.ApprovalsTimeoutAction() {
+	case events.ApprovalDecisionApprove:
+	case events.ApprovalDecisionReject:
+	default:
+		log.Debugf("unsupported approvals timeout action specified: %q", c.ApprovalsTimeoutAction())
+		return fmt.Errorf(
+			"invalid approvals timeout action %q; expected one of %s, %s",
+			c.ApprovalsTimeoutAction(),
+			events.ApprovalDecisionApprove,
+			events.ApprovalDecisionReject,
+		)
+	}
+
+	if c.ApprovalsRequired() {
+
+		// approval links must be signed and decisions must be attributed to
+		// a known operator
+		switch {
+		case c.ApprovalsBaseURL() == "":
+			return fmt.Errorf("approvals base URL not provided; required by alert policies requiring approval")
+		case c.ApprovalsSecret() == "":
+			return fmt.Errorf("approvals secret not provided; required by alert policies requiring approval")
+		case c.ApprovalsStateFile() == "":
+			return fmt.Errorf("path to pending approvals state file not provided")
+		case len(c.APIUsers()) == 0:
+			return fmt.Errorf("no API users configured; required to approve or reject pending approval requests")
+		}
+
+		// approval links sent for tenant alerts are handled by the approvals
+		// endpoint of the tenant, which only accepts the tenant's own users
+		for _, tenant := range c.Tenants() {
+			if len(tenant.Users) == 0 {
+				log.Debugf("no API users configured for tenant %q", tenant.Name)
+				return fmt.Errorf(
+					"no API users configured for tenant %q; required to approve or reject pending approval requests",
+					tenant.Name,
+				)
+			}
+		}
+
+		if u, err := url.Parse(c.ApprovalsBaseURL()); err != nil || u.Scheme == "" || u.Host == "" {
+			log.Debugf("unsupported approvals base URL specified: %q", c.ApprovalsBaseURL())
+			return fmt.Errorf(
+				"invalid approvals base URL %q; expected URL in the form https://brick.example.org:8000",
+				c.ApprovalsBaseURL(),
+			)
+		}
+	}
+
+	if c.CircuitBreakerMaxDisables() < 0 {
+		log.Debugf("unsupported circuit breaker limit specified: %d", c.CircuitBreakerMaxDisables())
+		return fmt.Errorf(
+			"invalid circuit breaker limit specified: %d",
+			c.CircuitBreakerMaxDisables(),
+		)
+	}
+
+	if c.CircuitBreakerWindow() < 1 {
+		log.Debugf("unsupported circuit breaker window specified: %d", c.CircuitBreakerWindow())
+		return fmt.Errorf(
+			"invalid circuit breaker window specified: %d; expected 1 or more minutes",
+			c.CircuitBreakerWindow(),
+		)
+	}
+
+	if c.CircuitBreakerStateFile() == "" {
+		return fmt.Errorf("path to circuit breaker state file not provided")
+	}
+
+	if c.ActivityWindow() < 0 {
+		log.Debugf("unsupported activity window specified: %d", c.ActivityWindow())
+		return fmt.Errorf(
+			"invalid activity window specified: %d",
+			c.ActivityWindow(),
+		)
+	}
+
+	for _, sender := range c.ActivitySenders() {
+		if net.ParseIP(sender) == nil {
+			log.Debugf("unsupported activity sender specified: %q", sender)
+			return fmt.Errorf(
+				"invalid activity sender %q; expected IP Address",
+				sender,
+			)
+		}
+	}
+
+	if len(c.ActivitySenders()) > 0 && c.ActivityWindow() == 0 {
+		return fmt.Errorf("activity senders specified, but activity window not set")
+	}
+
+	if c.ReconcileInterval() < 0 {
+		log.Debugf("unsupported reconcile interval specified: %d", c.ReconcileInterval())
+		return fmt.Errorf(
+			"invalid reconcile interval specified: %d",
+			c.ReconcileInterval(),
+		)
+	}
+
+	switch c.ReconcileAction() {
+	case ReconcileActionReport:
+	case ReconcileActionTerminate:
+	default:
+		log.Debugf("unsupported reconcile action specified: %q", c.ReconcileAction())
+		return fmt.Errorf(
+			"invalid reconcile action %q; expected %q or %q",
+			c.ReconcileAction(),
+			ReconcileActionReport,
+			ReconcileActionTerminate,
+		)
+	}
+
+	switch c.FirewallBackend() {
+	case FirewallBackendNone:
+	case FirewallBackendIPSet, FirewallBackendNftables:
+		if strings.TrimSpace(c.FirewallSet()) == "" {
+			return fmt.Errorf("firewall set not provided for firewall backend %q", c.FirewallBackend())
+		}
+		if c.FirewallBackend() == FirewallBackendNftables && len(strings.Fields(c.FirewallNftablesTable())) != 2 {
+			log.Debugf("unsupported nftables table specified: %q", c.FirewallNftablesTable())
+			return fmt.Errorf(
+				"invalid nftables table %q; expected address family and table name (e.g., %q)",
+				c.FirewallNftablesTable(),
+				defaultFirewallNftablesTable,
+			)
+		}
+	case FirewallBackendFail2ban:
+		if strings.TrimSpace(c.FirewallFail2banJail()) == "" {
+			return fmt.Errorf("fail2ban jail not provided for firewall backend %q", c.FirewallBackend())
+		}
+	default:
+		log.Debugf("unsupported firewall backend specified: %q", c.FirewallBackend())
+		return fmt.Errorf(
+			"invalid firewall backend %q; expected one of %s, %s, %s, %s",
+			c.FirewallBackend(),
+			FirewallBackendNone,
+			FirewallBackendIPSet,
+			FirewallBackendNftables,
+			FirewallBackendFail2ban,
+		)
+	}
+
+	if c.FirewallBackend() != FirewallBackendNone {
+		if c.FirewallBlockDuration() < 1 {
+			log.Debugf("unsupported firewall block duration specified: %d", c.FirewallBlockDuration())
+			return fmt.Errorf(
+				"invalid firewall block duration specified: %d; expected 1 or more minutes",
+				c.FirewallBlockDuration(),
+			)
+		}
+
+		if c.FirewallTimeout() < 1 {
+			log.Debugf("unsupported firewall timeout specified: %d", c.FirewallTimeout())
+			return fmt.Errorf(
+				"invalid firewall timeout specified: %d; expected 1 or more seconds",
+				c.FirewallTimeout(),
+			)
+		}
+
+		if c.FirewallStateFile() == "" {
+			return fmt.Errorf("path to firewall state file not provided")
+		}
+	}
+
+	if c.DisabledUsersBlocklistEnabled() {
+		if c.DisabledUsersBlocklistDuration() < 1 {
+			log.Debugf("unsupported blocklist duration specified: %d", c.DisabledUsersBlocklistDuration())
+			return fmt.Errorf(
+				"invalid blocklist duration specified: %d; expected 1 or more minutes",
+				c.DisabledUsersBlocklistDuration(),
+			)
+		}
+
+		if c.DisabledUsersBlocklistStateFile() == "" {
+			return fmt.Errorf("path to blocklist state file not provided")
+		}
+	}
+
+	if err := validateBlocklists(c.DisabledUsersBlocklists()); err != nil {
+		return err
+	}
+
+	hookNames := make(map[string]bool)
+	for _, hook := range c.Hooks() {
+		if err := validateHook(hook); err != nil {
+			return err
+		}
+		if hookNames[hook.Name] {
+			log.Debugf("duplicate hook name specified: %q", hook.Name)
+			return fmt.Errorf("duplicate hook name %q", hook.Name)
+		}
+		hookNames[hook.Name] = true
+	}
+
+	if err := validateAPIUsers(c.APIUsers()); err != nil {
+		return err
+	}
+
+	// Operator credentials are sent using HTTP Basic authentication, so
+	// refuse to enable the management endpoints unless those credentials are
+	// protected by TLS or never leave the local system.
+	managementEnabled := len(c.APIUsers()) > 0
+	for _, tenant := range c.Tenants() {
+		if len(tenant.Users) > 0 {
+			managementEnabled = true
+		}
+	}
+	if managementEnabled && !c.TLSEnabled() && !c.ListenerIsLoopback() {
+		log.Debugf(
+			"API users specified without TLS on non-loopback address %q",
+			c.LocalIPAddress(),
+		)
+		return fmt.Errorf(
+			"API users require TLS certificate and key files unless listening on a loopback address (current: %q)",
+			c.LocalIPAddress(),
+		)
+	}
+
 	// if we made it this far then we signal all is well
 	return nil
 
 }
+
+// validateBlocklists confirms that each of the provided blocklists uses a
+// supported format and that blocklist file paths are not repeated.
+func validateBlocklists(blocklists []Blocklist) error {
+
+	filePaths := make(map[string]bool, len(blocklists))
+	for _, blocklist := range blocklists {
+
+		switch blocklist.Format {
+		case BlocklistFormatNginx, BlocklistFormatApache, BlocklistFormatIPList, BlocklistFormatJSON:
+		default:
+			log.Debugf("unsupported blocklist format specified: %q", blocklist.Format)
+			return fmt.Errorf(
+				"invalid blocklist format %q specified for blocklist %q; expected one of %q, %q, %q or %q",
+				blocklist.Format,
+				blocklist.FilePath,
+				BlocklistFormatNginx,
+				BlocklistFormatApache,
+				BlocklistFormatIPList,
+				BlocklistFormatJSON,
+			)
+		}
+
+		if blocklist.FilePath == "" {
+			return fmt.Errorf("path to %s blocklist file not provided", blocklist.Format)
+		}
+
+		if filePaths[blocklist.FilePath] {
+			log.Debugf("duplicate blocklist file specified: %q", blocklist.FilePath)
+			return fmt.Errorf("blocklist file %q specified more than once", blocklist.FilePath)
+		}
+		filePaths[blocklist.FilePath] = true
+	}
+
+	return nil
+}
+
+// validateHook confirms that the provided hook has a name, an executable
+// path, a supported timeout, at least one supported event and valid
+// environment variable settings. The argument and environment variable
+// templates are parsed when the hooks are created.
+func validateHook(hook Hook) error {
+
+	if hook.Name == "" {
+		return fmt.Errorf("hook name not provided")
+	}
+
+	if hook.ExecutablePath == "" {
+		return fmt.Errorf("executable path not provided for hook %q", hook.Name)
+	}
+
+	if hook.Timeout < 1 {
+		log.Debugf("unsupported timeout specified for hook %q: %d", hook.Name, hook.Timeout)
+		return fmt.Errorf(
+			"invalid timeout specified for hook %q: %d; expected 1 or more seconds",
+			hook.Name,
+			hook.Timeout,
+		)
+	}
+
+	if len(hook.Events) == 0 {
+		return fmt.Errorf("events not provided for hook %q", hook.Name)
+	}
+
+	for _, event := range hook.Events {
+		switch event {
+		case HookEventDisabled, HookEventIgnored, HookEventTerminated, HookEventFailure:
+
+		// This application never re-enables user accounts; disabled users
+		// file entries are removed by hand. Rejecting the event explicitly
+		// keeps a hook from silently never running.
+		case "enabled":
+			log.Debugf("unsupported event specified for hook %q: %q", hook.Name, event)
+			return fmt.Errorf(
+				"invalid event %q specified for hook %q; user accounts are not re-enabled by this application",
+				event,
+				hook.Name,
+			)
+
+		default:
+			log.Debugf("unsupported event specified for hook %q: %q", hook.Name, event)
+			return fmt.Errorf(
+				"invalid event %q specified for hook %q; expected one of %q, %q, %q or %q",
+				event,
+				hook.Name,
+				HookEventDisabled,
+				HookEventIgnored,
+				HookEventTerminated,
+				HookEventFailure,
+			)
+		}
+	}
+
+	for _, env := range hook.Env {
+		if !strings.Contains(env, "=") {
+			return fmt.Errorf(
+				"invalid environment variable %q specified for hook %q; expected NAME=value",
+				env,
+				hook.Name,
+			)
+		}
+	}
+
+	for _, name := range hook.InheritEnv {
+		if name == "" || strings.Contains(name, "=") {
+			return fmt.Errorf(
+				"invalid inherited environment variable name %q specified for hook %q",
+				name,
+				hook.Name,
+			)
+		}
+	}
+
+	return nil
+}
+
+// validateWebhookURL confirms that the provided webhook URL is an absolute
+// http or https URL.
+func validateWebhookURL(webhookURL string) error {
+
+	u, err := url.Parse(webhookURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid webhook URL %q; expected http or https URL", webhookURL)
+	}
+
+	return nil
+}
+
+// validateTeamsMessageFormat confirms that the provided Microsoft Teams
+// message format is supported.
+func validateTeamsMessageFormat(format string) error {
+
+	switch format {
+	case TeamsMessageFormatMessageCard, TeamsMessageFormatAdaptiveCard:
+		return nil
+	default:
+		return fmt.Errorf(
+			"invalid Microsoft Teams message format %q; expected one of %s, %s",
+			format,
+			TeamsMessageFormatMessageCard,
+			TeamsMessageFormatAdaptiveCard,
+		)
+	}
+}
+
+// validateTeamsWebhookURL confirms that the provided Microsoft Teams webhook
+// URL is valid for the provided message format. Connector webhook URLs are
+// validated by the Microsoft Teams library; Workflows webhook URLs are
+// hosted elsewhere (e.g., logic.azure.com) and are only required to be
+// absolute http or https URLs.
+func validateTeamsWebhookURL(webhookURL string, format string) error {
+
+	if format == TeamsMessageFormatAdaptiveCard {
+		return validateWebhookURL(webhookURL)
+	}
+
+	if ok, err := goteamsnotify.IsValidWebhookURL(webhookURL); !ok {
+		return fmt.Errorf(
+			"%w; Teams Workflows webhook URLs require the %s message format",
+			err,
+			TeamsMessageFormatAdaptiveCard,
+		)
+	}
+
+	return nil
+}
+
+// validateWebhookHeaders confirms that each of the provided webhook headers
+// is in "Name: value" format.
+func validateWebhookHeaders(headers []string) error {
+
+	for _, header := range headers {
+		parts := strings.SplitN(header, ":", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("invalid webhook header %q; expected 'Name: value' format", header)
+		}
+	}
+
+	return nil
+}
+
+// validateAPIUsers confirms that each of the provided API users list entries
+// specifies a name and bcrypt password hash and that names are not repeated.
+func validateAPIUsers(users []string) error {
+
+	apiUsers := make(map[string]bool, len(users))
+	for _, user := range users {
+		name, hash := splitAPIUser(user)
+		switch {
+		case name == "" || hash == "":
+			log.Debugf("invalid API user entry specified: missing name or password hash (name: %q)", name)
+			return fmt.Errorf(
+				"invalid API user entry for %q; expected name:hash",
+				name,
+			)
+		case !isBcryptHash(hash):
+			log.Debugf("invalid API user entry specified: password for %q is not a bcrypt hash", name)
+			return fmt.Errorf(
+				"invalid API user entry for %q; password must be a bcrypt hash (e.g., as generated by htpasswd -nbB)",
+				name,
+			)
+		case apiUsers[name]:
+			log.Debugf("duplicate API user entry specified: %q", name)
+			return fmt.Errorf("duplicate API user entry for %q", name)
+		}
+		apiUsers[name] = true
+	}
+
+	return nil
+}
+
+// isBcryptHash indicates whether the provided value is a bcrypt hash (e.g.,
+// the $2y$ hashes generated by htpasswd).
+func isBcryptHash(value string) bool {
+	_, err := bcrypt.Cost([]byte(value))
+	return err == nil
+}
+
+// tenantNameRegex matches valid tenant names. Tenant names are used in
+// endpoint paths and state file names.
+var tenantNameRegex = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// validateTenant confirms that the provided tenant has a name, an endpoint
+// prefix, an auth secret, its own output files and at least one EZproxy
+// instance, and that its notification targets are valid. Email recipients
+// may only be specified if email notifications are enabled. The endpoint
+// prefix and instance settings are expected to be applied first.
+func validateTenant(tenant Tenant, emailEnabled bool) error {
+
+	if !tenantNameRegex.MatchString(tenant.Name) {
+		return fmt.Errorf(
+			"invalid tenant name %q; expected letters, digits, '.', '_' or '-'",
+			tenant.Name,
+		)
+	}
+
+	if tenant.EndpointPrefix == "/" {
+		return fmt.Errorf("invalid endpoint prefix for tenant %q; the root path is reserved", tenant.Name)
+	}
+
+	if tenant.AuthSecret == "" {
+		return fmt.Errorf("auth secret not provided for tenant %q", tenant.Name)
+	}
+
+	if tenant.ReportedUsersLogFile == "" {
+		return fmt.Errorf("path to reported users log file not provided for tenant %q", tenant.Name)
+	}
+
+	if tenant.IgnoredUsersFile == "" {
+		return fmt.Errorf("path to ignored users file not provided for tenant %q", tenant.Name)
+	}
+
+	if tenant.IgnoredIPAddressesFile == "" {
+		return fmt.Errorf("path to ignored ip addresses file not provided for tenant %q", tenant.Name)
+	}
+
+	if err := validateTeamsMessageFormat(tenant.TeamsMessageFormat); err != nil {
+		return fmt.Errorf("invalid Microsoft Teams message format for tenant %q: %w", tenant.Name, err)
+	}
+
+	if tenant.TeamsWebhookURL != "" {
+		if err := validateTeamsWebhookURL(tenant.TeamsWebhookURL, tenant.TeamsMessageFormat); err != nil {
+			return fmt.Errorf("invalid Microsoft Teams webhook URL for tenant %q: %w", tenant.Name, err)
+		}
+	}
+
+	if tenant.SlackWebhookURL != "" {
+		if err := validateWebhookURL(tenant.SlackWebhookURL); err != nil {
+			return fmt.Errorf("invalid Slack webhook URL for tenant %q: %w", tenant.Name, err)
+		}
+	}
+
+	if tenant.WebhookURL != "" {
+		if err := validateWebhookURL(tenant.WebhookURL); err != nil {
+			return fmt.Errorf("invalid webhook URL for tenant %q: %w", tenant.Name, err)
+		}
+	}
+
+	if err := validateWebhookHeaders(tenant.WebhookHeaders); err != nil {
+		return fmt.Errorf("invalid webhook headers for tenant %q: %w", tenant.Name, err)
+	}
+
+	if len(tenant.EmailRecipients) > 0 {
+		if !emailEnabled {
+			return fmt.Errorf(
+				"email recipients specified for tenant %q, but email notifications are not enabled",
+				tenant.Name,
+			)
+		}
+		if err := validateEmailAddresses(tenant.EmailRecipients, "recipient"); err != nil {
+			return fmt.Errorf("invalid email recipients for tenant %q: %w", tenant.Name, err)
+		}
+	}
+
+	if err := validateAPIUsers(tenant.Users); err != nil {
+		return fmt.Errorf("invalid API users for tenant %q: %w", tenant.Name, err)
+	}
+
+	if len(tenant.Instances) == 0 {
+		return fmt.Errorf("no EZproxy instances specified for tenant %q", tenant.Name)
+	}
+
+	return nil
+}
+
+// validateEZproxyInstance confirms that the provided EZproxy instance has a
+// name, the required file paths and valid terminator settings. Settings
+// inherited from the EZproxy section are expected to be applied first.
+func validateEZproxyInstance(instance EZproxyInstance) error {
+
+	if strings.TrimSpace(instance.Name) == "" {
+		return fmt.Errorf("EZproxy instance name not provided")
+	}
+
+	if instance.DisabledUsersFile == "" {
+		return fmt.Errorf("path to disabled users file not provided for EZproxy instance %q", instance.Name)
+	}
+
+	if instance.ActiveFilePath == "" {
+		return fmt.Errorf("path to EZproxy active users state file not provided for EZproxy instance %q", instance.Name)
+	}
+
+	switch instance.Terminator {
+	case TerminatorExec:
+		if instance.ExecutablePath == "" {
+			return fmt.Errorf("path to EZproxy executable file not provided for EZproxy instance %q", instance.Name)
+		}
+	case TerminatorAdmin:
+		adminURL, err := url.Parse(instance.AdminURL)
+		if err != nil || adminURL.Host == "" ||
+			(adminURL.Scheme != "http" && adminURL.Scheme != "https") {
+			return fmt.Errorf(
+				"invalid EZproxy admin URL %q for EZproxy instance %q; expected http or https URL",
+				instance.AdminURL,
+				instance.Name,
+			)
+		}
+		if instance.AdminUsername == "" || instance.AdminPassword == "" {
+			return fmt.Errorf(
+				"EZproxy admin username and password required for %s terminator for EZproxy instance %q",
+				TerminatorAdmin,
+				instance.Name,
+			)
+		}
+	default:
+		return fmt.Errorf(
+			"invalid EZproxy terminator %q for EZproxy instance %q; expected one of %s, %s",
+			instance.Terminator,
+			instance.Name,
+			TerminatorExec,
+			TerminatorAdmin,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"fmt"
+	"time"
+)
+
+// This is a set of constants used to indicate the decision made for a
+// pending approval request. These values are also used to specify the
+// action taken when a pending approval request times out.
+const (
+	ApprovalDecisionApprove string = "approve"
+	ApprovalDecisionReject  string = "reject"
+)
+
+// ApprovalTimeoutApprover is recorded as the approver for approval requests
+// which time out before an operator makes a decision.
+const ApprovalTimeoutApprover string = "(timeout)"
+
+// Approval represents a request for an operator to approve or reject
+// disabling a reported user account.
+type Approval struct {
+
+	// ID is the unique identifier for the approval request.
+	ID string
+
+	// Requested is when the approval request was created.
+	Requested time.Time
+
+	// Expires is when the approval request times out and the configured
+	// timeout action is applied.
+	Expires time.Time
+
+	// ApproveURL is the signed, single-use link used to approve the request.
+	ApproveURL string
+
+	// RejectURL is the signed, single-use link used to reject the request.
+	RejectURL string
+
+	// Decision is one of the approval decision values or empty if the
+	// request is still pending.
+	Decision string
+
+	// Approver is the name of the operator who made the decision, or
+	// ApprovalTimeoutApprover if the request timed out.
+	Approver string
+
+	// Decided is when the decision was made.
+	Decided time.Time
+}
+
+// Pending indicates whether a decision has yet to be made for the approval
+// request.
+func (a Approval) Pending() bool {
+	return a.Decision == ""
+}
+
+// Approved indicates whether the approval request has been approved.
+func (a Approval) Approved() bool {
+	return a.Decision == ApprovalDecisionApprove
+}
+
+// String provides a brief summary of the approval request status for use in
+// log messages and notifications.
+func (a Approval) String() string {
+
+	switch {
+	case a.Pending():
+		return fmt.Sprintf(
+			"pending (ID: %s, expires: %s)",
+			a.ID,
+			a.Expires.Format(time.RFC3339),
+		)
+	case a.Approved():
+		return fmt.Sprintf(
+			"approved by %s at %s (ID: %s)",
+			a.Approver,
+			a.Decided.Format(time.RFC3339),
+			a.ID,
+		)
+	default:
+		return fmt.Sprintf(
+			"rejected by %s at %s (ID: %s)",
+			a.Approver,
+			a.Decided.Format(time.RFC3339),
+			a.ID,
+		)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"fmt"
+	"time"
+)
+
+// This is a set of constants used to indicate the state of the mass-disable
+// circuit breaker.
+const (
+	CircuitBreakerStateDisabled string = "disabled"
+	CircuitBreakerStateClosed   string = "closed"
+	CircuitBreakerStateOpen     string = "open"
+)
+
+// CircuitBreakerStatus reflects the state of the mass-disable circuit
+// breaker. Once more than MaxDisables distinct user accounts are disabled
+// within Window the circuit breaker trips (opens) and no further user
+// accounts are disabled until an operator resets it.
+type CircuitBreakerStatus struct {
+
+	// MaxDisables is the number of distinct user accounts which may be
+	// disabled within the window. A value of zero disables the circuit
+	// breaker.
+	MaxDisables int
+
+	// Window is the period of time in which disabled user accounts are
+	// counted.
+	Window time.Duration
+
+	// Disables is the number of distinct user accounts disabled within the
+	// window.
+	Disables int
+
+	// Open indicates whether the circuit breaker has tripped.
+	Open bool
+
+	// TrippedAt is when the circuit breaker tripped.
+	TrippedAt time.Time
+
+	// TrippedBy is the username whose disable request tripped the circuit
+	// breaker.
+	TrippedBy string
+
+	// Suppressed is the number of disable requests suppressed since the
+	// circuit breaker tripped, including the request which tripped it.
+	Suppressed int
+}
+
+// Enabled indicates whether a mass-disable limit has been set.
+func (cbs CircuitBreakerStatus) Enabled() bool {
+	return cbs.MaxDisables > 0
+}
+
+// Tripped indicates whether the current disable request is the one which
+// tripped the circuit breaker.
+func (cbs CircuitBreakerStatus) Tripped() bool {
+	return cbs.Open && cbs.Suppressed == 1
+}
+
+// State returns one of the circuit breaker state values. A tripped circuit
+// breaker is reported as open even if the limit has since been removed.
+func (cbs CircuitBreakerStatus) State() string {
+
+	switch {
+	case cbs.Open:
+		return CircuitBreakerStateOpen
+	case !cbs.Enabled():
+		return CircuitBreakerStateDisabled
+	default:
+		return CircuitBreakerStateClosed
+	}
+}
+
+// String provides a summary of the circuit breaker state, e.g., "closed (3
+// of 20 disables within 10m0s)".
+func (cbs CircuitBreakerStatus) String() string {
+
+	switch {
+	case cbs.Open:
+		return fmt.Sprintf(
+			"%s (tripped by %q at %s; %d disables suppressed)",
+			cbs.State(),
+			cbs.TrippedBy,
+			cbs.TrippedAt.Format(time.RFC3339),
+			cbs.Suppressed,
+		)
+	case !cbs.Enabled():
+		return CircuitBreakerStateDisabled
+	default:
+		return fmt.Sprintf(
+			"%s (%d of %d disables within %s)",
+			cbs.State(),
+			cbs.Disables,
+			cbs.MaxDisables,
+			cbs.Window,
+		)
+	}
+}
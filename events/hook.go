@@ -0,0 +1,66 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"fmt"
+	"time"
+)
+
+// HookResult is the result of running a post-action hook (an external
+// command) for an event Record. The results of all hooks run for a Record
+// are included in notifications for that Record.
+type HookResult struct {
+
+	// Name is the configured name of the hook.
+	Name string
+
+	// ExitCode is the exit code of the hook command. This is -1 if the
+	// command could not be started or did not exit on its own.
+	ExitCode int
+
+	// Output is the combined standard output and standard error of the
+	// hook command, truncated if overly long.
+	Output string
+
+	// Duration is how long the hook command ran.
+	Duration time.Duration
+
+	// Error is set if the hook command could not be run, did not exit
+	// within the configured timeout or exited with a non-zero exit code.
+	Error error
+}
+
+// String provides a brief summary of the hook result suitable for use in
+// notifications.
+func (hr HookResult) String() string {
+
+	if hr.Error != nil {
+		return fmt.Sprintf(
+			"%s: failed after %v: %v",
+			hr.Name,
+			hr.Duration.Round(time.Millisecond),
+			hr.Error,
+		)
+	}
+
+	return fmt.Sprintf(
+		"%s: succeeded in %v",
+		hr.Name,
+		hr.Duration.Round(time.Millisecond),
+	)
+}
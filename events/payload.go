@@ -193,6 +193,10 @@ type SplunkAlertEvent struct {
 	// the alert.
 	SearchID string
 
+	// ResultsLink is the link to the results of the Splunk search associated
+	// with the alert.
+	ResultsLink string
+
 	// EndpointPath is the handler path where the payload was received.
 	EndpointPath string
 
@@ -202,4 +206,35 @@ type SplunkAlertEvent struct {
 
 	// Headers is a set of HTTP headers sent with the alert payload.
 	Headers http.Header
+
+	// Policy is the alert policy selected for this alert when processing
+	// begins. This policy determines which actions are taken and which
+	// notifications are sent. This field is nil for events not generated in
+	// response to a received alert.
+	Policy *AlertPolicy
+
+	// ReportThreshold reflects the number of reports recorded for the
+	// reported username within the threshold window for the alert policy.
+	// This field is nil if no threshold applies to this alert.
+	ReportThreshold *ReportThresholdStatus
+
+	// Approval is the approval request created for this alert if the alert
+	// policy requires operator approval before disabling the user account.
+	// This field is nil if no approval is required.
+	Approval *Approval
+
+	// CircuitBreaker reflects the state of the mass-disable circuit breaker
+	// if it prevented the user account from being disabled. This field is
+	// nil otherwise.
+	CircuitBreaker *CircuitBreakerStatus
+
+	// Instances is the list of names of the EZproxy instances on which this
+	// alert is acted. This field is empty if only one EZproxy instance is
+	// configured.
+	Instances []string
+
+	// Tenant is the name of the tenant whose endpoints received this alert.
+	// Notifications are sent only to the targets of this tenant. This field
+	// is empty for alerts received on the default endpoints.
+	Tenant string
 }
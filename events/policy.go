@@ -0,0 +1,571 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// This is a set of constants used with the AlertPolicy.Action field to
+// indicate how this application should respond to alerts matching the
+// policy.
+const (
+
+	// PolicyActionReportOnly logs and notifies that a user was reported, but
+	// neither disables the user account nor terminates sessions.
+	PolicyActionReportOnly string = "report-only"
+
+	// PolicyActionDisable disables the user account, but does not terminate
+	// active sessions.
+	PolicyActionDisable string = "disable"
+
+	// PolicyActionDisableTerminate disables the user account and terminates
+	// active sessions.
+	PolicyActionDisableTerminate string = "disable-terminate"
+
+	// PolicyActionTerminateOnly terminates active sessions, but does not
+	// disable the user account.
+	PolicyActionTerminateOnly string = "terminate-only"
+
+	// PolicyActionQuarantine quarantines the user account by writing the
+	// quarantine template to the disabled users file instead of the disable
+	// template, but does not terminate active sessions.
+	PolicyActionQuarantine string = "quarantine"
+
+	// PolicyActionQuarantineTerminate quarantines the user account and
+	// terminates active sessions so that the restrictions apply on the next
+	// login.
+	PolicyActionQuarantineTerminate string = "quarantine-terminate"
+)
+
+// This is a set of constants used with the AlertPolicy.TerminateScope field
+// to indicate which sessions are terminated for alerts matching the policy.
+const (
+
+	// TerminateScopeUsername terminates all sessions for the reported
+	// username regardless of the IP Address associated with each session.
+	TerminateScopeUsername string = "username"
+
+	// TerminateScopeUsernameIP terminates only those sessions for the
+	// reported username associated with the reported user IP Address.
+	TerminateScopeUsernameIP string = "username-ip"
+
+	// TerminateScopeIP terminates all sessions associated with the reported
+	// user IP Address regardless of username.
+	TerminateScopeIP string = "ip"
+)
+
+// DefaultAlertPolicyName is the name of the policy applied to alerts which do
+// not match any user-provided policy.
+const DefaultAlertPolicyName string = "default"
+
+// AlertPolicy is a rule which determines how this application responds to
+// received alerts. All specified match criteria (alert names, senders,
+// usernames, user IP Addresses) must match for the policy to apply; within
+// each list of criteria, any one entry matching is sufficient. A policy
+// without match criteria matches all alerts.
+type AlertPolicy struct {
+
+	// Name is a unique, human-readable name for the policy used in log
+	// messages and notifications.
+	Name string `toml:"name"`
+
+	// AlertNames is a list of case-insensitive patterns matched against the
+	// name of the alert (Splunk search) which generated the payload. The `*`
+	// and `?` wildcard characters are supported.
+	AlertNames []string `toml:"alert_names"`
+
+	// Senders is a list of IP Addresses or CIDR networks matched against the
+	// IP Address of the system which submitted the payload.
+	Senders []string `toml:"senders"`
+
+	// Usernames is a list of case-insensitive patterns matched against the
+	// reported username. The `*` and `?` wildcard characters are supported.
+	Usernames []string `toml:"usernames"`
+
+	// UserIPs is a list of IP Addresses or CIDR networks matched against the
+	// reported user's IP Address.
+	UserIPs []string `toml:"user_ips"`
+
+	// Action is one of the supported policy actions: report-only, disable,
+	// disable-terminate or terminate-only.
+	Action string `toml:"action"`
+
+	// NotifyTeams optionally disables Microsoft Teams notifications for
+	// alerts matching this policy. If not set, the global setting applies.
+	// This setting cannot enable notifications which are not configured.
+	NotifyTeams *bool `toml:"notify_teams"`
+
+	// NotifyEmail optionally disables email notifications for alerts
+	// matching this policy. If not set, the global setting applies. This
+	// setting cannot enable notifications which are not configured.
+	NotifyEmail *bool `toml:"notify_email"`
+
+	// NotifySlack optionally disables Slack notifications for alerts
+	// matching this policy. If not set, the global setting applies. This
+	// setting cannot enable notifications which are not configured.
+	NotifySlack *bool `toml:"notify_slack"`
+
+	// NotifyWebhook optionally disables webhook notifications for alerts
+	// matching this policy. If not set, the global setting applies. This
+	// setting cannot enable notifications which are not configured.
+	NotifyWebhook *bool `toml:"notify_webhook"`
+
+	// ThresholdReports is the number of reports for the same username
+	// required within the threshold window before the user account is
+	// disabled. If not set, the global setting applies.
+	ThresholdReports *int `toml:"threshold_reports"`
+
+	// ThresholdAlertNames is the number of distinct alert names reporting the
+	// same username required within the threshold window before the user
+	// account is disabled. If not set, the global setting applies.
+	ThresholdAlertNames *int `toml:"threshold_alert_names"`
+
+	// ThresholdWindow is the number of minutes in which reports are counted
+	// toward the threshold. If not set, the global setting applies.
+	ThresholdWindow *int `toml:"threshold_window"`
+
+	// DryRun indicates whether actions for alerts matching this policy are
+	// only simulated. If enabled, the disabled users file is not updated and
+	// sessions are not terminated, but all other processing is performed and
+	// the actions which would have been taken are logged and reported. If
+	// not set, the global setting applies.
+	DryRun *bool `toml:"dry_run"`
+
+	// TerminateScope is one of the supported termination scopes: username,
+	// username-ip or ip. This determines which sessions are terminated for
+	// alerts matching this policy. If not set, the global setting applies.
+	TerminateScope *string `toml:"terminate_scope"`
+
+	// RequireApproval indicates whether an operator must approve disabling
+	// user accounts for alerts matching this policy. If enabled, the disable
+	// request is held as pending until approved, rejected or timed out.
+	RequireApproval bool `toml:"require_approval"`
+
+	// Instances is the list of names of the EZproxy instances on which user
+	// accounts are disabled and sessions terminated for alerts matching this
+	// policy. If not set, all instances are used.
+	Instances []string `toml:"instances"`
+
+	// matchers holds the compiled match criteria. This is set by Compile when
+	// the policy is loaded so that patterns and networks are not parsed for
+	// each received alert.
+	matchers *alertPolicyMatchers
+}
+
+// alertPolicyMatchers is the compiled form of the match criteria of an alert
+// policy.
+type alertPolicyMatchers struct {
+	alertNames []*regexp.Regexp
+	senders    []*net.IPNet
+	usernames  []*regexp.Regexp
+	userIPs    []*net.IPNet
+}
+
+// AlertPolicies is a collection of AlertPolicy values evaluated in order.
+type AlertPolicies []AlertPolicy
+
+// Match returns the first policy which matches the provided alert. If no
+// policy matches, the provided fallback policy is returned.
+func (aps AlertPolicies) Match(alert SplunkAlertEvent, fallback AlertPolicy) AlertPolicy {
+
+	for _, policy := range aps {
+		if policy.Matches(alert) {
+			return policy
+		}
+	}
+
+	return fallback
+}
+
+// Matches indicates whether the provided alert satisfies all match criteria
+// for the policy. The match criteria are compiled for each call if Compile
+// has not been called for the policy; invalid networks never match.
+func (ap AlertPolicy) Matches(alert SplunkAlertEvent) bool {
+
+	m := ap.matchers
+	if m == nil {
+		m, _ = compileAlertPolicyMatchers(ap)
+	}
+
+	switch {
+	case len(ap.AlertNames) > 0 && !anyPatternMatches(m.alertNames, alert.AlertName):
+		return false
+	case len(ap.Senders) > 0 && !anyNetworkContains(m.senders, SenderIP(alert.PayloadSenderIP)):
+		return false
+	case len(ap.Usernames) > 0 && !anyPatternMatches(m.usernames, alert.Username):
+		return false
+	case len(ap.UserIPs) > 0 && !anyNetworkContains(m.userIPs, alert.UserIP):
+		return false
+	}
+
+	return true
+}
+
+// Compile parses the match criteria for the policy once so that they are
+// not parsed for each received alert. This is called when the policy is
+// loaded; an error is returned if a network is invalid.
+func (ap *AlertPolicy) Compile() error {
+
+	m, err := compileAlertPolicyMatchers(*ap)
+	if err != nil {
+		return fmt.Errorf("invalid match criteria for alert policy %q: %w", ap.Name, err)
+	}
+
+	ap.matchers = m
+
+	return nil
+}
+
+// compileAlertPolicyMatchers compiles the match criteria of the provided
+// policy. Invalid networks are skipped; the first such error is returned
+// along with the matchers compiled from the remaining criteria.
+func compileAlertPolicyMatchers(ap AlertPolicy) (*alertPolicyMatchers, error) {
+
+	var firstErr error
+
+	compileNetworks := func(networks []string) []*net.IPNet {
+		ipNets := make([]*net.IPNet, 0, len(networks))
+		for _, network := range networks {
+			ipNet, err := parseNetwork(network)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			ipNets = append(ipNets, ipNet)
+		}
+
+		return ipNets
+	}
+
+	compilePatterns := func(patterns []string) []*regexp.Regexp {
+		exprs := make([]*regexp.Regexp, 0, len(patterns))
+		for _, pattern := range patterns {
+			exprs = append(exprs, patternToRegexp(pattern))
+		}
+
+		return exprs
+	}
+
+	m := alertPolicyMatchers{
+		alertNames: compilePatterns(ap.AlertNames),
+		senders:    compileNetworks(ap.Senders),
+		usernames:  compilePatterns(ap.Usernames),
+		userIPs:    compileNetworks(ap.UserIPs),
+	}
+
+	return &m, firstErr
+}
+
+// Disable indicates whether user accounts should be disabled (or
+// quarantined) for alerts matching this policy.
+func (ap AlertPolicy) Disable() bool {
+	switch ap.Action {
+	case PolicyActionDisable, PolicyActionDisableTerminate:
+		return true
+	default:
+		return ap.Quarantine()
+	}
+}
+
+// Quarantine indicates whether user accounts should be quarantined instead
+// of disabled for alerts matching this policy.
+func (ap AlertPolicy) Quarantine() bool {
+	return ap.Action == PolicyActionQuarantine || ap.Action == PolicyActionQuarantineTerminate
+}
+
+// Terminate indicates whether active sessions should be terminated for
+// alerts matching this policy.
+func (ap AlertPolicy) Terminate() bool {
+	switch ap.Action {
+	case PolicyActionDisableTerminate, PolicyActionTerminateOnly, PolicyActionQuarantineTerminate:
+		return true
+	default:
+		return false
+	}
+}
+
+// Teams indicates whether Microsoft Teams notifications are permitted for
+// alerts matching this policy.
+func (ap AlertPolicy) Teams() bool {
+	return ap.NotifyTeams == nil || *ap.NotifyTeams
+}
+
+// Email indicates whether email notifications are permitted for alerts
+// matching this policy.
+func (ap AlertPolicy) Email() bool {
+	return ap.NotifyEmail == nil || *ap.NotifyEmail
+}
+
+// Slack indicates whether Slack notifications are permitted for alerts
+// matching this policy.
+func (ap AlertPolicy) Slack() bool {
+	return ap.NotifySlack == nil || *ap.NotifySlack
+}
+
+// Webhook indicates whether webhook notifications are permitted for alerts
+// matching this policy.
+func (ap AlertPolicy) Webhook() bool {
+	return ap.NotifyWebhook == nil || *ap.NotifyWebhook
+}
+
+// DryRunEnabled indicates whether actions for alerts matching this policy
+// are only simulated.
+func (ap AlertPolicy) DryRunEnabled() bool {
+	return ap.DryRun != nil && *ap.DryRun
+}
+
+// TerminationScope returns the scope used to select the sessions terminated
+// for alerts matching this policy. If not set, sessions are selected by
+// username.
+func (ap AlertPolicy) TerminationScope() string {
+	if ap.TerminateScope == nil || *ap.TerminateScope == "" {
+		return TerminateScopeUsername
+	}
+
+	return *ap.TerminateScope
+}
+
+// TerminationScopeDescription provides a brief summary of the sessions
+// selected for termination for the provided alert using the termination
+// scope for this policy.
+func (ap AlertPolicy) TerminationScopeDescription(alert SplunkAlertEvent) string {
+
+	switch ap.TerminationScope() {
+	case TerminateScopeUsernameIP:
+		return fmt.Sprintf(
+			"%s (sessions for username %q from IP %q)",
+			TerminateScopeUsernameIP,
+			alert.Username,
+			alert.UserIP,
+		)
+	case TerminateScopeIP:
+		return fmt.Sprintf(
+			"%s (all sessions from IP %q)",
+			TerminateScopeIP,
+			alert.UserIP,
+		)
+	default:
+		return fmt.Sprintf(
+			"%s (all sessions for username %q)",
+			TerminateScopeUsername,
+			alert.Username,
+		)
+	}
+}
+
+// ReportThreshold returns the number of reports required before user
+// accounts are disabled for alerts matching this policy. Unset values are
+// treated as zero; the caller is expected to apply global settings first.
+func (ap AlertPolicy) ReportThreshold() ReportThreshold {
+
+	var threshold ReportThreshold
+
+	if ap.ThresholdReports != nil {
+		threshold.Reports = *ap.ThresholdReports
+	}
+
+	if ap.ThresholdAlertNames != nil {
+		threshold.AlertNames = *ap.ThresholdAlertNames
+	}
+
+	if ap.ThresholdWindow != nil {
+		threshold.Window = time.Duration(*ap.ThresholdWindow) * time.Minute
+	}
+
+	return threshold
+}
+
+// String provides a brief summary of the policy for use in log messages.
+func (ap AlertPolicy) String() string {
+
+	var flags string
+	if ap.RequireApproval {
+		flags += ", approval required"
+	}
+	if ap.Terminate() && ap.TerminationScope() != TerminateScopeUsername {
+		flags += ", scope: " + ap.TerminationScope()
+	}
+	if ap.DryRunEnabled() {
+		flags += ", dry-run"
+	}
+	if len(ap.Instances) > 0 {
+		flags += ", instances: " + strings.Join(ap.Instances, ", ")
+	}
+
+	return fmt.Sprintf("%s (action: %s%s)", ap.Name, ap.Action, flags)
+}
+
+// Validate confirms that the policy has a name, a supported action and valid
+// match criteria.
+func (ap AlertPolicy) Validate() error {
+
+	if strings.TrimSpace(ap.Name) == "" {
+		return fmt.Errorf("alert policy name not provided")
+	}
+
+	switch ap.Action {
+	case PolicyActionReportOnly:
+	case PolicyActionDisable:
+	case PolicyActionDisableTerminate:
+	case PolicyActionTerminateOnly:
+	case PolicyActionQuarantine:
+	case PolicyActionQuarantineTerminate:
+	default:
+		return fmt.Errorf(
+			"invalid action %q for alert policy %q; expected one of %s, %s, %s, %s, %s, %s",
+			ap.Action,
+			ap.Name,
+			PolicyActionReportOnly,
+			PolicyActionDisable,
+			PolicyActionDisableTerminate,
+			PolicyActionTerminateOnly,
+			PolicyActionQuarantine,
+			PolicyActionQuarantineTerminate,
+		)
+	}
+
+	if ap.TerminateScope != nil {
+		if err := ValidateTerminateScope(*ap.TerminateScope); err != nil {
+			return fmt.Errorf("invalid termination scope for alert policy %q: %w", ap.Name, err)
+		}
+	}
+
+	for _, value := range []*int{ap.ThresholdReports, ap.ThresholdAlertNames} {
+		if value != nil && *value < 0 {
+			return fmt.Errorf(
+				"invalid threshold %d for alert policy %q; expected 0 or greater",
+				*value,
+				ap.Name,
+			)
+		}
+	}
+
+	if ap.ThresholdWindow != nil && *ap.ThresholdWindow < 1 {
+		return fmt.Errorf(
+			"invalid threshold window %d for alert policy %q; expected 1 or more minutes",
+			*ap.ThresholdWindow,
+			ap.Name,
+		)
+	}
+
+	for _, networks := range [][]string{ap.Senders, ap.UserIPs} {
+		for _, network := range networks {
+			if _, err := parseNetwork(network); err != nil {
+				return fmt.Errorf("invalid match criteria for alert policy %q: %w", ap.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateTerminateScope confirms that the provided value is one of the
+// supported termination scopes.
+func ValidateTerminateScope(scope string) error {
+
+	switch scope {
+	case TerminateScopeUsername:
+	case TerminateScopeUsernameIP:
+	case TerminateScopeIP:
+	default:
+		return fmt.Errorf(
+			"%q is not a supported termination scope; expected one of %s, %s, %s",
+			scope,
+			TerminateScopeUsername,
+			TerminateScopeUsernameIP,
+			TerminateScopeIP,
+		)
+	}
+
+	return nil
+}
+
+// anyPatternMatches indicates whether any of the provided compiled wildcard
+// patterns match the given value.
+func anyPatternMatches(patterns []*regexp.Regexp, value string) bool {
+
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// patternToRegexp converts a wildcard pattern supporting `*` (any sequence of
+// characters) and `?` (any single character) into an anchored,
+// case-insensitive regular expression.
+func patternToRegexp(pattern string) *regexp.Regexp {
+
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, `.*`)
+	expr = strings.ReplaceAll(expr, `\?`, `.`)
+
+	return regexp.MustCompile(`(?i)^` + expr + `$`)
+}
+
+// anyNetworkContains indicates whether any of the provided networks contain
+// the given IP Address.
+func anyNetworkContains(networks []*net.IPNet, ipAddr string) bool {
+
+	ip := net.ParseIP(ipAddr)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range networks {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseNetwork parses the provided CIDR network or individual IP Address,
+// returning an individual IP Address as a single-host network.
+func parseNetwork(network string) (*net.IPNet, error) {
+
+	if strings.Contains(network, "/") {
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid CIDR network: %w", network, err)
+		}
+		return ipNet, nil
+	}
+
+	ip := net.ParseIP(network)
+	if ip == nil {
+		return nil, fmt.Errorf("%q is not a valid IP Address", network)
+	}
+
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 8 * net.IPv4len
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPatternToRegexp(t *testing.T) {
+
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"Brute force*", "brute force - library", true},
+		{"Brute force*", "Not brute force", false},
+		{"user?", "user1", true},
+		{"user?", "user12", false},
+		{"a.b", "a.b", true},
+		{"a.b", "axb", false},
+		{"(test)", "(TEST)", true},
+		{"*", "", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.pattern+"/"+tt.value, func(t *testing.T) {
+			if got := patternToRegexp(tt.pattern).MatchString(tt.value); got != tt.want {
+				t.Errorf("patternToRegexp(%q) matching %q = %t; want %t", tt.pattern, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNetwork(t *testing.T) {
+
+	tests := []struct {
+		network  string
+		contains string
+		want     bool
+		wantErr  bool
+	}{
+		{"10.0.0.0/8", "10.1.2.3", true, false},
+		{"10.0.0.0/8", "192.168.1.1", false, false},
+		{"192.168.1.10", "192.168.1.10", true, false},
+		{"192.168.1.10", "192.168.1.11", false, false},
+		{"2001:db8::/32", "2001:db8::1", true, false},
+		{"2001:db8::1", "2001:db8::1", true, false},
+		{"10.0.0.0/33", "", false, true},
+		{"example.com", "", false, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.network, func(t *testing.T) {
+			ipNet, err := parseNetwork(tt.network)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNetwork(%q) returned error %v; want error: %t", tt.network, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := anyNetworkContains([]*net.IPNet{ipNet}, tt.contains); got != tt.want {
+				t.Errorf("network %q contains %q = %t; want %t", tt.network, tt.contains, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertPolicyMatches(t *testing.T) {
+
+	alert := SplunkAlertEvent{
+		AlertName:       "Brute force - library",
+		Username:        "JSmith",
+		UserIP:          "192.168.5.10",
+		PayloadSenderIP: "10.1.2.3:51234",
+	}
+
+	tests := []struct {
+		name   string
+		policy AlertPolicy
+		want   bool
+	}{
+		{"no criteria", AlertPolicy{}, true},
+		{"alert name", AlertPolicy{AlertNames: []string{"brute force*"}}, true},
+		{"alert name mismatch", AlertPolicy{AlertNames: []string{"phishing*"}}, false},
+		{"any alert name", AlertPolicy{AlertNames: []string{"phishing*", "brute*"}}, true},
+		{"sender network", AlertPolicy{Senders: []string{"10.0.0.0/8"}}, true},
+		{"sender mismatch", AlertPolicy{Senders: []string{"172.16.0.0/12"}}, false},
+		{"username", AlertPolicy{Usernames: []string{"jsmith"}}, true},
+		{"user IP", AlertPolicy{UserIPs: []string{"192.168.5.10"}}, true},
+		{"user IP mismatch", AlertPolicy{UserIPs: []string{"192.168.5.11"}}, false},
+		{
+			"all criteria",
+			AlertPolicy{
+				AlertNames: []string{"brute*"},
+				Senders:    []string{"10.1.2.3"},
+				Usernames:  []string{"j*"},
+				UserIPs:    []string{"192.168.0.0/16"},
+			},
+			true,
+		},
+		{
+			"one criteria mismatch",
+			AlertPolicy{
+				AlertNames: []string{"brute*"},
+				Usernames:  []string{"x*"},
+			},
+			false,
+		},
+		{"invalid network never matches", AlertPolicy{UserIPs: []string{"bogus"}}, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			// uncompiled policy
+			if got := tt.policy.Matches(alert); got != tt.want {
+				t.Errorf("Matches() = %t; want %t", got, tt.want)
+			}
+
+			// compiled policy; invalid networks are rejected
+			compiled := tt.policy
+			if err := compiled.Compile(); err != nil {
+				if tt.want {
+					t.Fatalf("Compile() returned error: %v", err)
+				}
+				return
+			}
+			if got := compiled.Matches(alert); got != tt.want {
+				t.Errorf("Matches() after Compile() = %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertPoliciesMatch(t *testing.T) {
+
+	policies := AlertPolicies{
+		{Name: "phishing", AlertNames: []string{"phishing*"}},
+		{Name: "brute", AlertNames: []string{"brute*"}},
+		{Name: "catch-all"},
+	}
+	fallback := AlertPolicy{Name: DefaultAlertPolicyName}
+
+	if got := policies.Match(SplunkAlertEvent{AlertName: "Brute force"}, fallback); got.Name != "brute" {
+		t.Errorf("Match() = %q; want %q", got.Name, "brute")
+	}
+
+	if got := policies[:2].Match(SplunkAlertEvent{AlertName: "Other"}, fallback); got.Name != DefaultAlertPolicyName {
+		t.Errorf("Match() = %q; want %q", got.Name, DefaultAlertPolicyName)
+	}
+}
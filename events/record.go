@@ -36,7 +36,28 @@ const (
 	ActionSuccessIgnoredIPAddress       string = "Username ignored due to ignore IP entry"
 	ActionSuccessTerminatedUserSession  string = "User sessions terminated"
 
-	ActionSkippedTerminateUserSessions string = "User sessions termination not enabled; skipped"
+	ActionSuccessIgnoredEntryAdded   string = "Ignore list entry added"
+	ActionSuccessIgnoredEntryRemoved string = "Ignore list entry removed"
+	ActionSuccessCircuitBreakerReset string = "Circuit breaker reset"
+	ActionSuccessActivityResumed     string = "Alert payloads received again"
+	ActionSuccessReconciledSessions  string = "Active sessions for disabled users terminated"
+	ActionSuccessBlockedIPAddress    string = "IP Address blocked"
+	ActionSuccessUnblockedIPAddress  string = "IP Address unblocked"
+
+	ActionSkippedTerminateUserSessions    string = "User sessions termination not enabled; skipped"
+	ActionSkippedDisableUsername          string = "Username disable not enabled by alert policy; skipped"
+	ActionSkippedDisableUsernameThreshold string = "Username disable threshold not reached; skipped"
+	ActionSkippedDisableUsernameSuspended string = "Username disable suspended by circuit breaker; skipped"
+	ActionSkippedCircuitBreakerTripped    string = "Circuit breaker tripped; disabling user accounts suspended"
+	ActionSkippedReconciledSessions       string = "Active sessions found for disabled users; termination not enabled"
+
+	ActionPendingApprovalDisableUsername string = "Username disable pending approval"
+	ActionSuccessApprovedDisableUsername string = "Username disable approved"
+	ActionSuccessRejectedDisableUsername string = "Username disable rejected"
+
+	ActionDryRunDisabledUsername       string = "Username would be disabled (dry-run)"
+	ActionDryRunTerminatedUserSessions string = "User sessions would be terminated (dry-run)"
+	ActionDryRunReconciledSessions     string = "Active sessions for disabled users would be terminated (dry-run)"
 
 	ActionFailureDisableRequestReceived   string = "Disable user account request log failure"
 	ActionFailureDisabledUsername         string = "Username disable failure"
@@ -45,6 +66,15 @@ const (
 	ActionFailureIgnoredIPAddress         string = "IP Address ignore status check failure"
 	ActionFailureUserSessionLookupFailure string = "Failed to lookup user sessions"
 	ActionFailureTerminatedUserSession    string = "User session termination failure"
+	ActionFailureIgnoredEntryUpdate       string = "Ignore list update failure"
+	ActionFailureReportThreshold          string = "Report threshold check failure"
+	ActionFailureApprovalRequest          string = "Approval request failure"
+	ActionFailureCircuitBreaker           string = "Circuit breaker check failure"
+	ActionFailureCircuitBreakerReset      string = "Circuit breaker reset failure"
+	ActionFailureNoActivity               string = "No alert payloads received within activity window"
+	ActionFailureReconciledSessions       string = "Disabled users sessions reconciliation failure"
+	ActionFailureBlockedIPAddress         string = "IP Address block failure"
+	ActionFailureUnblockedIPAddress       string = "IP Address unblock failure"
 )
 
 // Record is a collection of details that is saved to log files, sent by
@@ -80,6 +110,20 @@ type Record struct {
 	// SessionTerminationResults is a collection of results from attempts to
 	// terminate sessions for the username specified in the alert payload.
 	SessionTerminationResults []ezproxy.TerminateUserSessionResult
+
+	// Operator is the name of the authenticated user responsible for a
+	// change made via the management API (e.g., adding an ignored entry).
+	// This field is empty for events generated in response to alerts.
+	Operator string
+
+	// Verification is the result of checking the EZproxy active file for
+	// terminated sessions after session termination. This field is nil if
+	// verification is not enabled.
+	Verification *TerminationVerification
+
+	// HookResults is the collection of results from post-action hooks run
+	// for this event. This field is nil if no hooks were run.
+	HookResults []HookResult
 }
 
 // NewRecord is a factory function that creates a Record from provided
@@ -151,6 +195,16 @@ func (rc Record) Valid() (bool, error) {
 	case ActionSuccessIgnoredIPAddress:
 	case ActionSuccessTerminatedUserSession:
 	case ActionSkippedTerminateUserSessions:
+	case ActionSkippedDisableUsername:
+	case ActionSkippedDisableUsernameThreshold:
+	case ActionSkippedDisableUsernameSuspended:
+	case ActionSkippedCircuitBreakerTripped:
+	case ActionPendingApprovalDisableUsername:
+	case ActionSuccessApprovedDisableUsername:
+	case ActionSuccessRejectedDisableUsername:
+	case ActionDryRunDisabledUsername:
+	case ActionDryRunTerminatedUserSessions:
+	case ActionDryRunReconciledSessions:
 	case ActionFailureDisableRequestReceived:
 	case ActionFailureDisabledUsername:
 	case ActionFailureDuplicatedUsername:
@@ -158,6 +212,23 @@ func (rc Record) Valid() (bool, error) {
 	case ActionFailureIgnoredIPAddress:
 	case ActionFailureUserSessionLookupFailure:
 	case ActionFailureTerminatedUserSession:
+	case ActionSuccessIgnoredEntryAdded:
+	case ActionSuccessIgnoredEntryRemoved:
+	case ActionFailureIgnoredEntryUpdate:
+	case ActionFailureReportThreshold:
+	case ActionFailureApprovalRequest:
+	case ActionSuccessCircuitBreakerReset:
+	case ActionFailureCircuitBreaker:
+	case ActionFailureCircuitBreakerReset:
+	case ActionSuccessActivityResumed:
+	case ActionFailureNoActivity:
+	case ActionSuccessReconciledSessions:
+	case ActionSkippedReconciledSessions:
+	case ActionFailureReconciledSessions:
+	case ActionSuccessBlockedIPAddress:
+	case ActionSuccessUnblockedIPAddress:
+	case ActionFailureBlockedIPAddress:
+	case ActionFailureUnblockedIPAddress:
 	default:
 		return false, fmt.Errorf(
 			"empty or invalid Action field value provided: %s",
@@ -169,6 +240,55 @@ func (rc Record) Valid() (bool, error) {
 
 }
 
+// NotifyTeams indicates whether the alert policy associated with this
+// Record permits Microsoft Teams notifications. Records without an
+// associated policy and high priority Records are always eligible.
+func (rc Record) NotifyTeams() bool {
+	return rc.HighPriority() || rc.Alert.Policy == nil || rc.Alert.Policy.Teams()
+}
+
+// NotifyEmail indicates whether the alert policy associated with this Record
+// permits email notifications. Records without an associated policy and high
+// priority Records are always eligible.
+func (rc Record) NotifyEmail() bool {
+	return rc.HighPriority() || rc.Alert.Policy == nil || rc.Alert.Policy.Email()
+}
+
+// NotifySlack indicates whether the alert policy associated with this Record
+// permits Slack notifications. Records without an associated policy and high
+// priority Records are always eligible.
+func (rc Record) NotifySlack() bool {
+	return rc.HighPriority() || rc.Alert.Policy == nil || rc.Alert.Policy.Slack()
+}
+
+// NotifyWebhook indicates whether the alert policy associated with this
+// Record permits webhook notifications. Records without an associated policy
+// and high priority Records are always eligible.
+func (rc Record) NotifyWebhook() bool {
+	return rc.HighPriority() || rc.Alert.Policy == nil || rc.Alert.Policy.Webhook()
+}
+
+// HighPriority indicates whether this Record requires prompt attention from
+// an operator (e.g., the mass-disable circuit breaker has tripped). High
+// priority notifications are flagged as such and are sent regardless of
+// alert policy notification settings.
+func (rc Record) HighPriority() bool {
+	return rc.Action == ActionSkippedCircuitBreakerTripped
+}
+
+// TerminationScope provides a brief summary of the sessions selected for
+// termination using the termination scope of the alert policy associated
+// with this Record. An empty string is returned for Records without an
+// associated policy (e.g., sessions terminated by an operator).
+func (rc Record) TerminationScope() string {
+
+	if rc.Alert.Policy == nil {
+		return ""
+	}
+
+	return rc.Alert.Policy.TerminationScopeDescription(rc.Alert)
+}
+
 // Records is a collection of Record values intended to allow easier bulk
 // processing of event details.
 type Records []Record
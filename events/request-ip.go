@@ -22,7 +22,9 @@
 package events
 
 import (
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/apex/log"
 )
@@ -39,3 +41,18 @@ func GetIP(r *http.Request) string {
 	}
 	return r.RemoteAddr
 }
+
+// SenderIP extracts the IP Address from a value returned by GetIP. This
+// value may be a host:port pair (remote address) or a comma-separated list
+// of addresses (X-Forwarded-For header), in which case the first (client)
+// address is used.
+func SenderIP(payloadSenderIP string) string {
+
+	sender := strings.TrimSpace(strings.Split(payloadSenderIP, ",")[0])
+
+	if host, _, err := net.SplitHostPort(sender); err == nil {
+		return host
+	}
+
+	return sender
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import "testing"
+
+func TestSenderIP(t *testing.T) {
+
+	tests := []struct {
+		payloadSenderIP string
+		want            string
+	}{
+		{"10.1.2.3:51234", "10.1.2.3"},
+		{"10.1.2.3", "10.1.2.3"},
+		{"[2001:db8::1]:443", "2001:db8::1"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"192.168.1.5, 10.0.0.1", "192.168.1.5"},
+		{" 192.168.1.5 ,10.0.0.1", "192.168.1.5"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.payloadSenderIP, func(t *testing.T) {
+			if got := SenderIP(tt.payloadSenderIP); got != tt.want {
+				t.Errorf("SenderIP(%q) = %q; want %q", tt.payloadSenderIP, got, tt.want)
+			}
+		})
+	}
+}
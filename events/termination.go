@@ -0,0 +1,144 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atc0005/go-ezproxy"
+)
+
+// These are the outcomes of attempts to terminate a user session, as
+// determined from the recorded termination results.
+const (
+
+	// TerminationOutcomeTerminated indicates that the session was
+	// terminated.
+	TerminationOutcomeTerminated string = "terminated"
+
+	// TerminationOutcomeAlreadyGone indicates that the session no longer
+	// existed when termination was attempted (e.g., the user logged out or
+	// the session was terminated by another request). This is not treated
+	// as an error.
+	TerminationOutcomeAlreadyGone string = "already gone"
+
+	// TerminationOutcomeFailed indicates that the session could not be
+	// terminated.
+	TerminationOutcomeFailed string = "failed"
+)
+
+// TerminationOutcome returns the outcome of the attempt to terminate a user
+// session. Results for sessions which no longer exist are expected to be
+// recorded without an error, using the exit code returned by the EZproxy
+// executable for this case.
+func TerminationOutcome(result ezproxy.TerminateUserSessionResult) string {
+	switch {
+	case result.Error != nil:
+		return TerminationOutcomeFailed
+	case result.ExitCode == ezproxy.KillSubCmdExitCodeSessionDoesNotExist:
+		return TerminationOutcomeAlreadyGone
+	default:
+		return TerminationOutcomeTerminated
+	}
+}
+
+// These are the verification statuses of terminated sessions, as determined
+// by checking the EZproxy active file after session termination.
+const (
+
+	// VerificationStatusVerified indicates that the session is no longer
+	// recorded in the active file.
+	VerificationStatusVerified string = "verified"
+
+	// VerificationStatusStillActive indicates that the session is still
+	// recorded in the active file after it was reported as terminated.
+	VerificationStatusStillActive string = "still active"
+
+	// VerificationStatusNewSession indicates that the session appeared for
+	// the reported user after the original sessions were terminated and was
+	// terminated in turn.
+	VerificationStatusNewSession string = "new session"
+)
+
+// TerminationVerification is the result of checking the EZproxy active file
+// for terminated sessions after session termination.
+type TerminationVerification struct {
+
+	// Delay is how long after session termination the active file was
+	// checked.
+	Delay time.Duration
+
+	// Remaining is the list of IDs for sessions reported as terminated (or
+	// already gone) which are still recorded in the active file.
+	Remaining []string
+
+	// NewSessions is the list of IDs for sessions which appeared for the
+	// reported user after the original sessions were terminated.
+	NewSessions []string
+
+	// Error is the error (if any) encountered while checking the active
+	// file.
+	Error error
+}
+
+// SessionStatus returns the verification status of the specified session.
+func (v TerminationVerification) SessionStatus(sessionID string) string {
+
+	for _, id := range v.Remaining {
+		if id == sessionID {
+			return VerificationStatusStillActive
+		}
+	}
+
+	for _, id := range v.NewSessions {
+		if id == sessionID {
+			return VerificationStatusNewSession
+		}
+	}
+
+	return VerificationStatusVerified
+}
+
+// String provides a summary of the verification result.
+func (v TerminationVerification) String() string {
+
+	switch {
+	case v.Error != nil:
+		return fmt.Sprintf("unable to verify after %v: %v", v.Delay, v.Error)
+
+	case len(v.Remaining) > 0:
+		return fmt.Sprintf(
+			"%d sessions still active after %v: %s",
+			len(v.Remaining),
+			v.Delay,
+			strings.Join(v.Remaining, ", "),
+		)
+
+	case len(v.NewSessions) > 0:
+		return fmt.Sprintf(
+			"sessions verified after %v; %d new sessions terminated: %s",
+			v.Delay,
+			len(v.NewSessions),
+			strings.Join(v.NewSessions, ", "),
+		)
+
+	default:
+		return fmt.Sprintf("sessions verified after %v", v.Delay)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReportThreshold is the number of reports for the same username required
+// within a time window before the user account is disabled. A report count
+// of zero disables the associated criteria. If both criteria are enabled,
+// reaching either one is sufficient.
+type ReportThreshold struct {
+
+	// Reports is the number of reports (from any alert) required.
+	Reports int
+
+	// AlertNames is the number of distinct alert names required.
+	AlertNames int
+
+	// Window is the period of time in which reports are counted.
+	Window time.Duration
+}
+
+// Enabled indicates whether any threshold criteria have been set. If not,
+// user accounts are disabled upon the first report.
+func (rt ReportThreshold) Enabled() bool {
+	return rt.Reports > 0 || rt.AlertNames > 0
+}
+
+// ReportThresholdStatus reflects the number of reports recorded for a
+// username within the threshold window, including the current report.
+type ReportThresholdStatus struct {
+	Threshold ReportThreshold
+
+	// Reports is the number of reports recorded within the window.
+	Reports int
+
+	// AlertNames is the number of distinct alert names recorded within the
+	// window.
+	AlertNames int
+}
+
+// Met indicates whether the number of reports recorded within the window
+// satisfies either of the threshold criteria.
+func (rts ReportThresholdStatus) Met() bool {
+
+	switch {
+	case !rts.Threshold.Enabled():
+		return true
+	case rts.Threshold.Reports > 0 && rts.Reports >= rts.Threshold.Reports:
+		return true
+	case rts.Threshold.AlertNames > 0 && rts.AlertNames >= rts.Threshold.AlertNames:
+		return true
+	default:
+		return false
+	}
+}
+
+// String provides a summary of progress toward the threshold, e.g., "2 of 3
+// reports within 30m0s".
+func (rts ReportThresholdStatus) String() string {
+
+	progress := make([]string, 0, 2)
+
+	if rts.Threshold.Reports > 0 {
+		progress = append(progress, fmt.Sprintf(
+			"%d of %d reports",
+			rts.Reports,
+			rts.Threshold.Reports,
+		))
+	}
+
+	if rts.Threshold.AlertNames > 0 {
+		progress = append(progress, fmt.Sprintf(
+			"%d of %d distinct alert names",
+			rts.AlertNames,
+			rts.Threshold.AlertNames,
+		))
+	}
+
+	return fmt.Sprintf(
+		"%s within %s",
+		strings.Join(progress, " or "),
+		rts.Threshold.Window,
+	)
+}
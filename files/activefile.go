@@ -0,0 +1,276 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/go-ezproxy"
+	"github.com/atc0005/go-ezproxy/activefile"
+
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/internal/caller"
+)
+
+// allSessionsReaderUsername is provided when creating active file readers
+// used only to read all sessions. The reader requires a username, but it is
+// not used when reading all sessions.
+const allSessionsReaderUsername string = "*"
+
+// ErrActiveFileNotLoaded indicates that the active file has not yet been
+// successfully read by the watcher.
+var ErrActiveFileNotLoaded = errors.New("active file not yet loaded")
+
+// ActiveFileWatcher keeps an in-memory index of the sessions recorded in the
+// EZproxy active file. The file is checked for changes (modification time or
+// size) at the configured interval and read again only when it changes.
+// Session lookups use the index instead of reading the active file and may
+// wait, up to a deadline, for matching sessions to be written by EZproxy.
+type ActiveFileWatcher struct {
+
+	// FilePath is the fully-qualified path to the EZproxy active file.
+	FilePath string
+
+	// Interval is how often the active file is checked for changes. A value
+	// of 0 disables the watcher.
+	Interval time.Duration
+
+	mutex      *sync.Mutex
+	changed    chan struct{}
+	modTime    time.Time
+	size       int64
+	loaded     bool
+	err        error
+	byUsername map[string]ezproxy.UserSessions
+	byIP       map[string]ezproxy.UserSessions
+}
+
+// NewActiveFileWatcher constructs a new ActiveFileWatcher for the specified
+// active file. The Run method must be called to start watching the file.
+func NewActiveFileWatcher(path string, interval time.Duration) *ActiveFileWatcher {
+	return &ActiveFileWatcher{
+		FilePath:   path,
+		Interval:   interval,
+		mutex:      &sync.Mutex{},
+		changed:    make(chan struct{}),
+		byUsername: make(map[string]ezproxy.UserSessions),
+		byIP:       make(map[string]ezproxy.UserSessions),
+	}
+}
+
+// Enabled indicates whether the active file is watched. If not, sessions are
+// looked up by reading the active file for each request.
+func (w *ActiveFileWatcher) Enabled() bool {
+	return w != nil && w.Interval > 0
+}
+
+// Run reads the active file and then checks it for changes at the configured
+// interval until the provided context is cancelled.
+func (w *ActiveFileWatcher) Run(ctx context.Context) {
+
+	if !w.Enabled() {
+		return
+	}
+
+	log.Debugf(
+		"%s: checking %q for changes every %v",
+		caller.GetFuncName(),
+		w.FilePath,
+		w.Interval,
+	)
+
+	w.check()
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debugf("%s: context cancelled, no longer watching %q", caller.GetFuncName(), w.FilePath)
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// Lookup returns the indexed sessions within the specified termination scope
+// for the provided username and user IP Address.
+func (w *ActiveFileWatcher) Lookup(scope string, username string, userIP string) (ezproxy.UserSessions, error) {
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.lookup(scope, username, userIP)
+}
+
+// Wait returns the indexed sessions within the specified termination scope
+// for the provided username and user IP Address. If no sessions match, Wait
+// blocks until matching sessions are written to the active file or the
+// deadline passes, whichever comes first.
+func (w *ActiveFileWatcher) Wait(deadline time.Time, scope string, username string, userIP string) (ezproxy.UserSessions, error) {
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	for {
+
+		w.mutex.Lock()
+		sessions, err := w.lookup(scope, username, userIP)
+		changed := w.changed
+		w.mutex.Unlock()
+
+		if len(sessions) > 0 {
+			return sessions, nil
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return sessions, err
+		}
+	}
+}
+
+// lookup implements Lookup; the caller is expected to hold the mutex.
+func (w *ActiveFileWatcher) lookup(scope string, username string, userIP string) (ezproxy.UserSessions, error) {
+
+	if !w.loaded {
+		if w.err != nil {
+			return nil, w.err
+		}
+		return nil, ErrActiveFileNotLoaded
+	}
+
+	if scope == events.TerminateScopeUsername {
+		return append(ezproxy.UserSessions(nil), w.byUsername[strings.ToLower(username)]...), nil
+	}
+
+	ip := net.ParseIP(userIP)
+	if ip == nil {
+		return nil, fmt.Errorf(
+			"termination scope %q requires a valid user IP Address; received %q",
+			scope,
+			userIP,
+		)
+	}
+
+	var sessions ezproxy.UserSessions
+	for _, session := range w.byIP[ip.String()] {
+		if sessionInScope(session, scope, username, ip) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions, nil
+}
+
+// check reads the active file and rebuilds the index if the file has changed
+// since it was last read. Any goroutines waiting for sessions are woken once
+// the index is rebuilt.
+func (w *ActiveFileWatcher) check() {
+
+	info, err := os.Stat(w.FilePath)
+	if err != nil {
+		w.fail(fmt.Errorf("error checking active file %q for changes: %w", w.FilePath, err))
+		return
+	}
+
+	w.mutex.Lock()
+	unchanged := w.loaded && info.ModTime().Equal(w.modTime) && info.Size() == w.size
+	w.mutex.Unlock()
+
+	if unchanged {
+		return
+	}
+
+	reader, err := activefile.NewReader(allSessionsReaderUsername, w.FilePath)
+	if err != nil {
+		w.fail(fmt.Errorf("error while creating activeFile reader for %q: %w", w.FilePath, err))
+		return
+	}
+
+	allUserSessions, err := reader.AllUserSessions()
+	if err != nil {
+		w.fail(fmt.Errorf("error reading sessions from active file %q: %w", w.FilePath, err))
+		return
+	}
+
+	byUsername := make(map[string]ezproxy.UserSessions)
+	byIP := make(map[string]ezproxy.UserSessions)
+	for _, session := range allUserSessions {
+		username := strings.ToLower(session.Username)
+		byUsername[username] = append(byUsername[username], session)
+
+		if ip := net.ParseIP(session.IPAddress); ip != nil {
+			byIP[ip.String()] = append(byIP[ip.String()], session)
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.err != nil {
+		log.Infof("Active file %q read successfully after earlier errors", w.FilePath)
+	}
+
+	w.modTime = info.ModTime()
+	w.size = info.Size()
+	w.loaded = true
+	w.err = nil
+	w.byUsername = byUsername
+	w.byIP = byIP
+
+	close(w.changed)
+	w.changed = make(chan struct{})
+
+	log.Debugf(
+		"%s: indexed %d sessions for %d usernames from %q",
+		caller.GetFuncName(),
+		len(allUserSessions),
+		len(byUsername),
+		w.FilePath,
+	)
+}
+
+// fail records an error reading the active file. The index is discarded so
+// that lookups fall back to other sources instead of using stale sessions.
+// Only the first of repeated errors is logged.
+func (w *ActiveFileWatcher) fail(err error) {
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.err == nil {
+		log.Warn(err.Error())
+	}
+
+	w.err = err
+	w.loaded = false
+	w.byUsername = make(map[string]ezproxy.UserSessions)
+	w.byIP = make(map[string]ezproxy.UserSessions)
+}
@@ -0,0 +1,218 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atc0005/go-ezproxy"
+
+	"github.com/atc0005/brick/events"
+)
+
+// activeFileContent returns the provided sessions using the layout of the
+// EZproxy active file.
+func activeFileContent(sessions ezproxy.UserSessions) []byte {
+
+	var content strings.Builder
+	for _, session := range sessions {
+		fmt.Fprintf(&content, "S %s 1 2 3 4 %s\nL %s\n", session.SessionID, session.IPAddress, session.Username)
+	}
+
+	return []byte(content.String())
+}
+
+// writeActiveFile writes the provided sessions to the active file at the
+// specified path.
+func writeActiveFile(t *testing.T, path string, sessions ezproxy.UserSessions) {
+
+	t.Helper()
+
+	if err := ioutil.WriteFile(path, activeFileContent(sessions), 0600); err != nil {
+		t.Fatalf("failed to write active file: %v", err)
+	}
+}
+
+// sessionIDs returns the sorted session IDs of the provided sessions.
+func sessionIDs(sessions ezproxy.UserSessions) []string {
+
+	ids := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		ids = append(ids, session.SessionID)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+func TestActiveFileWatcherLookup(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "ezproxy.hst")
+	writeActiveFile(t, path, ezproxy.UserSessions{
+		{SessionID: "AAAAAAAAAAAAAAA", IPAddress: "192.168.2.3", Username: "JSmith"},
+		{SessionID: "BBBBBBBBBBBBBBB", IPAddress: "192.168.2.4", Username: "jsmith"},
+		{SessionID: "CCCCCCCCCCCCCCC", IPAddress: "192.168.2.3", Username: "adoe"},
+	})
+
+	w := NewActiveFileWatcher(path, time.Second)
+
+	if _, err := w.Lookup(events.TerminateScopeUsername, "jsmith", ""); !errors.Is(err, ErrActiveFileNotLoaded) {
+		t.Fatalf("Lookup() before first check returned error %v; want %v", err, ErrActiveFileNotLoaded)
+	}
+
+	w.check()
+
+	tests := []struct {
+		scope    string
+		username string
+		userIP   string
+		want     []string
+		wantErr  bool
+	}{
+		{events.TerminateScopeUsername, "JSMITH", "", []string{"AAAAAAAAAAAAAAA", "BBBBBBBBBBBBBBB"}, false},
+		{events.TerminateScopeUsername, "missing", "", []string{}, false},
+		{events.TerminateScopeUsernameIP, "jsmith", "192.168.2.3", []string{"AAAAAAAAAAAAAAA"}, false},
+		{events.TerminateScopeIP, "jsmith", "192.168.2.3", []string{"AAAAAAAAAAAAAAA", "CCCCCCCCCCCCCCC"}, false},
+		{events.TerminateScopeIP, "jsmith", "not-an-ip", nil, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.scope+"/"+tt.username+"/"+tt.userIP, func(t *testing.T) {
+
+			sessions, err := w.Lookup(tt.scope, tt.username, tt.userIP)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lookup() returned error %v; want error: %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := sessionIDs(sessions); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Lookup() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActiveFileWatcherReindex(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "ezproxy.hst")
+	writeActiveFile(t, path, ezproxy.UserSessions{
+		{SessionID: "AAAAAAAAAAAAAAA", IPAddress: "192.168.2.3", Username: "jsmith"},
+	})
+
+	w := NewActiveFileWatcher(path, time.Second)
+	w.check()
+
+	lookup := func() []string {
+		t.Helper()
+		sessions, err := w.Lookup(events.TerminateScopeUsername, "jsmith", "")
+		if err != nil {
+			t.Fatalf("Lookup() returned error: %v", err)
+		}
+		return sessionIDs(sessions)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to check active file: %v", err)
+	}
+
+	// A file with the same size and modification time is not read again.
+	writeActiveFile(t, path, ezproxy.UserSessions{
+		{SessionID: "BBBBBBBBBBBBBBB", IPAddress: "192.168.2.3", Username: "jsmith"},
+	})
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("failed to reset active file modification time: %v", err)
+	}
+	w.check()
+	if got := lookup(); len(got) != 1 || got[0] != "AAAAAAAAAAAAAAA" {
+		t.Errorf("Lookup() after unchanged check = %q; want previous index", got)
+	}
+
+	// A changed file is read again and the index rebuilt.
+	writeActiveFile(t, path, ezproxy.UserSessions{
+		{SessionID: "BBBBBBBBBBBBBBB", IPAddress: "192.168.2.3", Username: "jsmith"},
+		{SessionID: "CCCCCCCCCCCCCCC", IPAddress: "192.168.2.4", Username: "jsmith"},
+	})
+	w.check()
+	if got := lookup(); strings.Join(got, ",") != "BBBBBBBBBBBBBBB,CCCCCCCCCCCCCCC" {
+		t.Errorf("Lookup() after change = %q; want rebuilt index", got)
+	}
+
+	// A missing file discards the index instead of keeping stale sessions.
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove active file: %v", err)
+	}
+	w.check()
+	if _, err := w.Lookup(events.TerminateScopeUsername, "jsmith", ""); err == nil {
+		t.Error("Lookup() after active file removal returned nil error")
+	}
+
+	// The index is rebuilt once the file can be read again.
+	writeActiveFile(t, path, ezproxy.UserSessions{
+		{SessionID: "DDDDDDDDDDDDDDD", IPAddress: "192.168.2.3", Username: "jsmith"},
+	})
+	w.check()
+	if got := lookup(); len(got) != 1 || got[0] != "DDDDDDDDDDDDDDD" {
+		t.Errorf("Lookup() after recovery = %q; want %q", got, "DDDDDDDDDDDDDDD")
+	}
+}
+
+func TestActiveFileWatcherWait(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "ezproxy.hst")
+	writeActiveFile(t, path, nil)
+
+	w := NewActiveFileWatcher(path, time.Second)
+	w.check()
+
+	// no matching sessions are written before the deadline
+	sessions, err := w.Wait(time.Now().Add(50*time.Millisecond), events.TerminateScopeUsername, "jsmith", "")
+	if err != nil || len(sessions) != 0 {
+		t.Errorf("Wait() = %v, %v; want no sessions", sessions, err)
+	}
+
+	// matching sessions are written while waiting
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		content := activeFileContent(ezproxy.UserSessions{
+			{SessionID: "AAAAAAAAAAAAAAA", IPAddress: "192.168.2.3", Username: "jsmith"},
+		})
+		if err := ioutil.WriteFile(path, content, 0600); err != nil {
+			t.Errorf("failed to write active file: %v", err)
+		}
+		w.check()
+	}()
+
+	sessions, err = w.Wait(time.Now().Add(5*time.Second), events.TerminateScopeUsername, "jsmith", "")
+	if err != nil {
+		t.Fatalf("Wait() returned error: %v", err)
+	}
+	if got := sessionIDs(sessions); len(got) != 1 || got[0] != "AAAAAAAAAAAAAAA" {
+		t.Errorf("Wait() = %q; want %q", got, "AAAAAAAAAAAAAAA")
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/events"
+)
+
+// ApprovalsEndpointPath is the path of the endpoint used to approve or
+// reject pending approval requests. This is used to build the links included
+// in approval request notifications.
+const ApprovalsEndpointPath string = "/api/v1/approvals"
+
+// approvalsFilePermissions is applied to the pending approvals state file
+// when it is first created.
+const approvalsFilePermissions os.FileMode = 0600
+
+// ErrApprovalNotFound indicates that the specified approval request is not
+// pending; it may have already been decided or timed out.
+var ErrApprovalNotFound = errors.New("no pending approval request found; it may have already been decided or timed out")
+
+// ErrApprovalInvalidSignature indicates that the signature provided with an
+// approval decision does not match the approval request ID and decision.
+var ErrApprovalInvalidSignature = errors.New("invalid approval request signature")
+
+// Approvals tracks disable requests held for operator approval. Pending
+// requests are persisted to a state file so that they survive application
+// restarts. Each request is approved or rejected via signed, single-use
+// links or by the configured timeout action once the request expires.
+type Approvals struct {
+
+	// FilePath is the fully-qualified path to the JSON state file used to
+	// persist pending approval requests.
+	FilePath string
+
+	// BaseURL is the externally reachable URL of this application (e.g.,
+	// https://brick.example.org:8000) used to build approval links.
+	BaseURL string
+
+	// Timeout is how long approval requests remain pending before the
+	// timeout action is applied.
+	Timeout time.Duration
+
+	// TimeoutAction is the decision applied to approval requests which time
+	// out; one of the events.ApprovalDecision values.
+	TimeoutAction string
+
+	secret                []byte
+	reportedUserEventsLog *ReportedUserEventsLog
+	notifyWorkQueue       chan<- events.Record
+	resume                func(events.SplunkAlertEvent)
+
+	mutex   *sync.Mutex
+	pending map[string]events.SplunkAlertEvent
+	timers  map[string]*time.Timer
+}
+
+// NewApprovals constructs a new Approvals value using the provided settings.
+// Approval requests and decisions are recorded in the provided reported user
+// events log of the tenant. The Start method must be called before approval
+// requests are accepted.
+func NewApprovals(
+	path string,
+	baseURL string,
+	secret string,
+	timeout time.Duration,
+	timeoutAction string,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	notifyWorkQueue chan<- events.Record,
+) *Approvals {
+	return &Approvals{
+		FilePath:              path,
+		BaseURL:               strings.TrimRight(baseURL, "/"),
+		Timeout:               timeout,
+		TimeoutAction:         timeoutAction,
+		secret:                []byte(secret),
+		reportedUserEventsLog: reportedUserEventsLog,
+		notifyWorkQueue:       notifyWorkQueue,
+		mutex:                 &sync.Mutex{},
+		pending:               make(map[string]events.SplunkAlertEvent),
+		timers:                make(map[string]*time.Timer),
+	}
+}
+
+// Start loads pending approval requests from the state file and schedules
+// their timeouts. The provided function is called (in a new goroutine) with
+// the alert for each approved request in order to complete processing.
+// Requests which expired while the application was stopped are timed out
+// immediately.
+func (a *Approvals) Start(resume func(events.SplunkAlertEvent)) error {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.resume = resume
+
+	pending, err := a.read()
+	if err != nil {
+		return err
+	}
+
+	for id, alert := range pending {
+		if alert.Approval == nil {
+			log.Warnf("discarding invalid pending approval request %q", id)
+			continue
+		}
+		a.pending[id] = alert
+		a.schedule(id, alert.Approval.Expires)
+	}
+
+	log.Debugf("%d pending approval requests loaded from %q", len(a.pending), a.FilePath)
+
+	return nil
+}
+
+// Request creates a new approval request for the provided alert, persists it
+// and returns the event record announcing the request. The record includes
+// the approve and reject links.
+func (a *Approvals) Request(alert events.SplunkAlertEvent) events.Record {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	id, err := newApprovalID()
+	if err != nil {
+		return events.NewRecord(
+			alert,
+			fmt.Errorf("error creating approval request for user %q: %w", alert.Username, err),
+			"",
+			events.ActionFailureApprovalRequest,
+			nil,
+		)
+	}
+
+	now := time.Now()
+	alert.Approval = &events.Approval{
+		ID:         id,
+		Requested:  now,
+		Expires:    now.Add(a.Timeout),
+		ApproveURL: a.link(id, events.ApprovalDecisionApprove),
+		RejectURL:  a.link(id, events.ApprovalDecisionReject),
+	}
+
+	a.pending[id] = alert
+	if err := a.write(); err != nil {
+		delete(a.pending, id)
+		return events.NewRecord(
+			alert,
+			fmt.Errorf("error saving approval request for user %q: %w", alert.Username, err),
+			"",
+			events.ActionFailureApprovalRequest,
+			nil,
+		)
+	}
+
+	a.schedule(id, alert.Approval.Expires)
+
+	return logEventPendingApproval(alert, a.reportedUserEventsLog)
+}
+
+// Lookup returns the alert associated with the specified pending approval
+// request.
+func (a *Approvals) Lookup(id string) (events.SplunkAlertEvent, bool) {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	alert, ok := a.pending[id]
+
+	return alert, ok
+}
+
+// VerifySignature confirms that the provided signature was generated by this
+// application for the specified approval request ID and decision.
+func (a *Approvals) VerifySignature(id string, decision string, signature string) error {
+
+	expected := a.sign(id, decision)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrApprovalInvalidSignature
+	}
+
+	return nil
+}
+
+// Decide applies the provided decision to the specified pending approval
+// request on behalf of the named operator. The request is removed so that
+// the approval links cannot be used again. The decision is logged and sent
+// as a notification. If approved, processing of the original alert resumes.
+func (a *Approvals) Decide(id string, decision string, operator string) (events.SplunkAlertEvent, error) {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.decide(id, decision, operator)
+}
+
+// decide implements Decide; the caller is expected to hold the mutex.
+func (a *Approvals) decide(id string, decision string, operator string) (events.SplunkAlertEvent, error) {
+
+	alert, ok := a.pending[id]
+	if !ok {
+		return events.SplunkAlertEvent{}, ErrApprovalNotFound
+	}
+
+	delete(a.pending, id)
+	if timer, ok := a.timers[id]; ok {
+		timer.Stop()
+		delete(a.timers, id)
+	}
+
+	if err := a.write(); err != nil {
+		// restore the request so that the decision can be retried
+		a.pending[id] = alert
+		a.schedule(id, alert.Approval.Expires)
+		return alert, err
+	}
+
+	// copy so that the persisted (pending) request is not modified
+	approval := *alert.Approval
+	approval.Decision = decision
+	approval.Approver = operator
+	approval.Decided = time.Now()
+	alert.Approval = &approval
+
+	decisionResult := logEventApprovalDecision(alert, a.reportedUserEventsLog)
+	processRecord(decisionResult, a.notifyWorkQueue)
+
+	if approval.Approved() && a.resume != nil {
+		go a.resume(alert)
+	}
+
+	return alert, nil
+}
+
+// schedule applies the timeout action to the specified approval request once
+// it expires; the caller is expected to hold the mutex.
+func (a *Approvals) schedule(id string, expires time.Time) {
+
+	a.timers[id] = time.AfterFunc(time.Until(expires), func() {
+
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+
+		log.Infof(
+			"Approval request %q timed out; applying timeout action %q",
+			id,
+			a.TimeoutAction,
+		)
+
+		if _, err := a.decide(id, a.TimeoutAction, events.ApprovalTimeoutApprover); err != nil &&
+			!errors.Is(err, ErrApprovalNotFound) {
+			log.Errorf("failed to apply timeout action to approval request %q: %v", id, err)
+		}
+	})
+}
+
+// link builds the signed link used to make the specified decision for an
+// approval request.
+func (a *Approvals) link(id string, decision string) string {
+
+	query := url.Values{}
+	query.Set("id", id)
+	query.Set("decision", decision)
+	query.Set("sig", a.sign(id, decision))
+
+	return a.BaseURL + ApprovalsEndpointPath + "?" + query.Encode()
+}
+
+// sign generates the signature for the specified approval request ID and
+// decision.
+func (a *Approvals) sign(id string, decision string) string {
+
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(id + "|" + decision))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// read loads pending approval requests from the state file. A missing state
+// file is treated as having no pending requests.
+func (a *Approvals) read() (map[string]events.SplunkAlertEvent, error) {
+	pending := make(map[string]events.SplunkAlertEvent)
+	if err := loadJSONState(a.FilePath, "pending approvals", &pending); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// write replaces the state file with the current pending approval requests;
+// the caller is expected to hold the mutex.
+func (a *Approvals) write() error {
+	return saveJSONState(a.FilePath, "pending approvals", a.pending, approvalsFilePermissions)
+}
+
+// newApprovalID generates a random identifier for an approval request.
+func newApprovalID() (string, error) {
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
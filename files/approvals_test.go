@@ -0,0 +1,327 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"errors"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/atc0005/brick/events"
+)
+
+// newTestApprovals returns Approvals using a state file in a temporary
+// directory along with the channels receiving notification records and the
+// alerts of approved requests.
+func newTestApprovals(
+	t *testing.T,
+	timeout time.Duration,
+	timeoutAction string,
+) (*Approvals, chan events.Record, chan events.SplunkAlertEvent) {
+
+	t.Helper()
+
+	dir := t.TempDir()
+	notifyWorkQueue := make(chan events.Record, 10)
+	resumed := make(chan events.SplunkAlertEvent, 10)
+
+	approvals := NewApprovals(
+		filepath.Join(dir, "pending-approvals.json"),
+		"https://brick.example.org:8000/",
+		"approvals-secret",
+		timeout,
+		timeoutAction,
+		NewReportedUserEventsLog(filepath.Join(dir, "users.brick-reported.log"), 0600),
+		notifyWorkQueue,
+	)
+
+	if err := approvals.Start(func(alert events.SplunkAlertEvent) { resumed <- alert }); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+
+	return approvals, notifyWorkQueue, resumed
+}
+
+// requestApproval creates an approval request for a test alert and returns
+// the pending approval.
+func requestApproval(t *testing.T, approvals *Approvals) events.Approval {
+
+	t.Helper()
+
+	record := approvals.Request(events.SplunkAlertEvent{Username: "jsmith", UserIP: "192.0.2.10"})
+	if record.Error != nil {
+		t.Fatalf("Request() returned error record: %v", record.Error)
+	}
+	if record.Alert.Approval == nil {
+		t.Fatal("Request() returned record without approval")
+	}
+
+	return *record.Alert.Approval
+}
+
+// linkQuery returns the query parameters of the provided approval link.
+func linkQuery(t *testing.T, link string) url.Values {
+
+	t.Helper()
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("failed to parse approval link %q: %v", link, err)
+	}
+	if u.Path != ApprovalsEndpointPath {
+		t.Errorf("approval link path = %q; want %q", u.Path, ApprovalsEndpointPath)
+	}
+
+	return u.Query()
+}
+
+func TestApprovalsVerifySignature(t *testing.T) {
+
+	approvals, _, _ := newTestApprovals(t, time.Hour, events.ApprovalDecisionReject)
+	approval := requestApproval(t, approvals)
+
+	approveQuery := linkQuery(t, approval.ApproveURL)
+	rejectQuery := linkQuery(t, approval.RejectURL)
+
+	otherApprovals, _, _ := newTestApprovals(t, time.Hour, events.ApprovalDecisionReject)
+	otherApprovals.secret = []byte("other-secret")
+
+	tests := []struct {
+		name      string
+		approvals *Approvals
+		id        string
+		decision  string
+		signature string
+		wantErr   error
+	}{
+		{
+			name:      "approve link",
+			approvals: approvals,
+			id:        approveQuery.Get("id"),
+			decision:  approveQuery.Get("decision"),
+			signature: approveQuery.Get("sig"),
+		},
+		{
+			name:      "reject link",
+			approvals: approvals,
+			id:        rejectQuery.Get("id"),
+			decision:  rejectQuery.Get("decision"),
+			signature: rejectQuery.Get("sig"),
+		},
+		{
+			name:      "decision changed",
+			approvals: approvals,
+			id:        rejectQuery.Get("id"),
+			decision:  events.ApprovalDecisionApprove,
+			signature: rejectQuery.Get("sig"),
+			wantErr:   ErrApprovalInvalidSignature,
+		},
+		{
+			name:      "id changed",
+			approvals: approvals,
+			id:        "0123456789abcdef0123456789abcdef",
+			decision:  approveQuery.Get("decision"),
+			signature: approveQuery.Get("sig"),
+			wantErr:   ErrApprovalInvalidSignature,
+		},
+		{
+			name:      "signature tampered",
+			approvals: approvals,
+			id:        approveQuery.Get("id"),
+			decision:  approveQuery.Get("decision"),
+			signature: approveQuery.Get("sig")[1:] + "0",
+			wantErr:   ErrApprovalInvalidSignature,
+		},
+		{
+			name:      "signature missing",
+			approvals: approvals,
+			id:        approveQuery.Get("id"),
+			decision:  approveQuery.Get("decision"),
+			wantErr:   ErrApprovalInvalidSignature,
+		},
+		{
+			name:      "signed with another secret",
+			approvals: otherApprovals,
+			id:        approveQuery.Get("id"),
+			decision:  approveQuery.Get("decision"),
+			signature: approveQuery.Get("sig"),
+			wantErr:   ErrApprovalInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.approvals.VerifySignature(tt.id, tt.decision, tt.signature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifySignature() = %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApprovalsDecide(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		decisions   []string
+		wantErrs    []error
+		wantResumed bool
+	}{
+		{
+			name:        "approved",
+			decisions:   []string{events.ApprovalDecisionApprove},
+			wantErrs:    []error{nil},
+			wantResumed: true,
+		},
+		{
+			name:      "rejected",
+			decisions: []string{events.ApprovalDecisionReject},
+			wantErrs:  []error{nil},
+		},
+		{
+			name:        "approve link replayed",
+			decisions:   []string{events.ApprovalDecisionApprove, events.ApprovalDecisionApprove},
+			wantErrs:    []error{nil, ErrApprovalNotFound},
+			wantResumed: true,
+		},
+		{
+			name:      "approve link used after rejection",
+			decisions: []string{events.ApprovalDecisionReject, events.ApprovalDecisionApprove},
+			wantErrs:  []error{nil, ErrApprovalNotFound},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			approvals, notifyWorkQueue, resumed := newTestApprovals(t, time.Hour, events.ApprovalDecisionReject)
+			approval := requestApproval(t, approvals)
+
+			if _, ok := approvals.Lookup(approval.ID); !ok {
+				t.Fatalf("Lookup() did not find pending approval request %q", approval.ID)
+			}
+
+			for i, decision := range tt.decisions {
+				_, err := approvals.Decide(approval.ID, decision, "operator")
+				if !errors.Is(err, tt.wantErrs[i]) {
+					t.Errorf("Decide(%q) #%d = %v; want %v", decision, i+1, err, tt.wantErrs[i])
+				}
+			}
+
+			// only the first decision is applied
+			select {
+			case record := <-notifyWorkQueue:
+				if record.Alert.Approval.Decision != tt.decisions[0] {
+					t.Errorf("decision recorded = %q; want %q", record.Alert.Approval.Decision, tt.decisions[0])
+				}
+				if record.Alert.Approval.Approver != "operator" {
+					t.Errorf("approver recorded = %q; want %q", record.Alert.Approval.Approver, "operator")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no decision record sent")
+			}
+
+			select {
+			case <-resumed:
+				if !tt.wantResumed {
+					t.Error("processing resumed for rejected approval request")
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.wantResumed {
+					t.Error("processing not resumed for approved approval request")
+				}
+			}
+
+			// decided requests are not restored after a restart
+			restarted := NewApprovals(
+				approvals.FilePath,
+				approvals.BaseURL,
+				"approvals-secret",
+				time.Hour,
+				events.ApprovalDecisionReject,
+				approvals.reportedUserEventsLog,
+				notifyWorkQueue,
+			)
+			if err := restarted.Start(nil); err != nil {
+				t.Fatalf("Start() returned error: %v", err)
+			}
+			if _, ok := restarted.Lookup(approval.ID); ok {
+				t.Errorf("decided approval request %q restored after restart", approval.ID)
+			}
+		})
+	}
+}
+
+func TestApprovalsTimeout(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		timeoutAction string
+		wantResumed   bool
+	}{
+		{
+			name:          "timeout rejects",
+			timeoutAction: events.ApprovalDecisionReject,
+		},
+		{
+			name:          "timeout approves",
+			timeoutAction: events.ApprovalDecisionApprove,
+			wantResumed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			approvals, notifyWorkQueue, resumed := newTestApprovals(t, 10*time.Millisecond, tt.timeoutAction)
+			approval := requestApproval(t, approvals)
+
+			select {
+			case record := <-notifyWorkQueue:
+				if record.Alert.Approval.Decision != tt.timeoutAction {
+					t.Errorf("decision recorded = %q; want %q", record.Alert.Approval.Decision, tt.timeoutAction)
+				}
+				if record.Alert.Approval.Approver != events.ApprovalTimeoutApprover {
+					t.Errorf("approver recorded = %q; want %q", record.Alert.Approval.Approver, events.ApprovalTimeoutApprover)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("approval request did not time out")
+			}
+
+			select {
+			case <-resumed:
+				if !tt.wantResumed {
+					t.Error("processing resumed for rejected approval request")
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.wantResumed {
+					t.Error("processing not resumed for approved approval request")
+				}
+			}
+
+			// links for expired requests can no longer be used
+			if _, err := approvals.Decide(approval.ID, events.ApprovalDecisionApprove, "operator"); !errors.Is(err, ErrApprovalNotFound) {
+				t.Errorf("Decide() for expired approval request = %v; want %v", err, ErrApprovalNotFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,554 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/internal/caller"
+	"github.com/atc0005/brick/internal/fileutils"
+)
+
+// blocklistFilePermissions is applied to the blocklist state file when it is
+// first created.
+const blocklistFilePermissions os.FileMode = 0644
+
+// blocklistCheckInterval is the longest time between checks for expired
+// blocklist entries.
+const blocklistCheckInterval time.Duration = time.Minute
+
+// blocklistHeader is written at the top of blocklist formats which support
+// comments.
+const blocklistHeader string = "# Generated by brick; do not edit. Changes are overwritten when the blocklist is updated.\n"
+
+// BlocklistRenderer renders the blocked usernames and IP Addresses in the
+// format expected by the consumer of a blocklist file.
+type BlocklistRenderer interface {
+
+	// Name returns the name of the blocklist format for use in log messages.
+	Name() string
+
+	// Render returns the content of the blocklist file for the provided
+	// entries.
+	Render(entries BlocklistEntries) ([]byte, error)
+}
+
+// BlocklistEntries is the current set of blocked usernames and IP Addresses.
+type BlocklistEntries struct {
+
+	// Usernames is the sorted list of usernames listed in the disabled users
+	// file of any EZproxy instance.
+	Usernames []string `json:"usernames"`
+
+	// IPAddresses is the list of blocked IP Addresses, sorted by IP Address.
+	IPAddresses []BlockedIPAddress `json:"ip_addresses"`
+}
+
+// BlockedIPAddress is a blocked IP Address along with the user account
+// reported with it and the time the block expires.
+type BlockedIPAddress struct {
+	IPAddress string    `json:"ip_address"`
+	Username  string    `json:"username"`
+	Tenant    string    `json:"tenant,omitempty"`
+	AlertName string    `json:"alert_name"`
+	Blocked   time.Time `json:"blocked"`
+	Expires   time.Time `json:"expires"`
+}
+
+// NginxBlocklist renders blocked IP Addresses as nginx deny directives
+// suitable for use with the include directive.
+type NginxBlocklist struct{}
+
+// ApacheBlocklist renders blocked IP Addresses as Apache HTTP Server
+// "Require not ip" directives suitable for use with the Include directive
+// within a RequireAll block.
+type ApacheBlocklist struct{}
+
+// IPListBlocklist renders blocked IP Addresses as a plain list with one IP
+// Address per line.
+type IPListBlocklist struct{}
+
+// JSONBlocklist renders blocked usernames and IP Addresses as a JSON
+// document. The same document is served by the blocklist feed endpoint.
+type JSONBlocklist struct{}
+
+// BlocklistFile is a file regenerated from the current set of blocked
+// usernames and IP Addresses whenever it changes.
+type BlocklistFile struct {
+
+	// FilePath is the fully-qualified path to the blocklist file.
+	FilePath string
+
+	// Permissions is applied to the blocklist file when it is written.
+	Permissions os.FileMode
+
+	// Renderer generates the content of the blocklist file.
+	Renderer BlocklistRenderer
+}
+
+// Blocklist keeps the configured blocklist files (and the blocklist feed)
+// in sync with the usernames listed in the disabled users files of the
+// provided EZproxy instances and the IP Addresses reported for disabled
+// user accounts. Reported IP Addresses are listed for the configured
+// duration and persisted to a state file independently of the Firewall, so
+// the blocklist is maintained whether or not a firewall backend is used.
+type Blocklist struct {
+
+	// Files is the collection of blocklist files to regenerate.
+	Files []BlocklistFile
+
+	// Feed indicates whether the blocklist is served by the blocklist feed
+	// endpoint.
+	Feed bool
+
+	// FilePath is the fully-qualified path to the JSON state file used to
+	// persist listed IP Addresses.
+	FilePath string
+
+	// Duration is how long each IP Address remains listed.
+	Duration time.Duration
+
+	instances      EZproxyInstances
+	ignoredSources IgnoredSources
+
+	mutex   *sync.Mutex
+	changes chan struct{}
+	entries BlocklistEntries
+	written map[string][]byte
+}
+
+// Name returns the name of the blocklist format.
+func (NginxBlocklist) Name() string {
+	return "nginx"
+}
+
+// Render returns nginx deny directives for the blocked IP Addresses.
+func (NginxBlocklist) Render(entries BlocklistEntries) ([]byte, error) {
+
+	var buf bytes.Buffer
+	buf.WriteString(blocklistHeader)
+	for _, blocked := range entries.IPAddresses {
+		fmt.Fprintf(&buf, "deny %s;\n", blocked.IPAddress)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Name returns the name of the blocklist format.
+func (ApacheBlocklist) Name() string {
+	return "apache"
+}
+
+// Render returns Apache HTTP Server "Require not ip" directives for the
+// blocked IP Addresses.
+func (ApacheBlocklist) Render(entries BlocklistEntries) ([]byte, error) {
+
+	var buf bytes.Buffer
+	buf.WriteString(blocklistHeader)
+	for _, blocked := range entries.IPAddresses {
+		fmt.Fprintf(&buf, "Require not ip %s\n", blocked.IPAddress)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Name returns the name of the blocklist format.
+func (IPListBlocklist) Name() string {
+	return "ip-list"
+}
+
+// Render returns the blocked IP Addresses, one per line.
+func (IPListBlocklist) Render(entries BlocklistEntries) ([]byte, error) {
+
+	var buf bytes.Buffer
+	for _, blocked := range entries.IPAddresses {
+		buf.WriteString(blocked.IPAddress + "\n")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Name returns the name of the blocklist format.
+func (JSONBlocklist) Name() string {
+	return "json"
+}
+
+// Render returns the blocked usernames and IP Addresses as a JSON document.
+func (JSONBlocklist) Render(entries BlocklistEntries) ([]byte, error) {
+
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%s: error encoding blocklist: %w",
+			caller.GetFuncName(),
+			err,
+		)
+	}
+
+	return append(content, '\n'), nil
+}
+
+// NewBlocklist constructs a new Blocklist value using the provided state
+// file, listing duration and blocklist files. Usernames are read from the
+// disabled users files of the provided EZproxy instances and the ignored
+// sources of the tenant are used to honor ignored IP Addresses. The Run
+// method must be called to keep the blocklist files up to date.
+func NewBlocklist(
+	path string,
+	duration time.Duration,
+	blocklistFiles []BlocklistFile,
+	feed bool,
+	instances EZproxyInstances,
+	ignoredSources IgnoredSources,
+) *Blocklist {
+	return &Blocklist{
+		Files:          blocklistFiles,
+		Feed:           feed,
+		FilePath:       path,
+		Duration:       duration,
+		instances:      instances,
+		ignoredSources: ignoredSources,
+		mutex:          &sync.Mutex{},
+		changes:        make(chan struct{}, 1),
+		entries: BlocklistEntries{
+			Usernames:   []string{},
+			IPAddresses: []BlockedIPAddress{},
+		},
+		written: make(map[string][]byte),
+	}
+}
+
+// Enabled indicates whether any blocklist files or the blocklist feed have
+// been configured.
+func (b *Blocklist) Enabled() bool {
+	return b != nil && (len(b.Files) > 0 || b.Feed)
+}
+
+// Entries returns the blocked usernames and IP Addresses as of the last
+// update.
+func (b *Blocklist) Entries() BlocklistEntries {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.entries
+}
+
+// Run regenerates the blocklist at startup, whenever a change is signaled
+// via Block or Changed and at each check for expired IP Addresses until the
+// provided context is cancelled. The periodic check also picks up changes
+// made to the disabled users files by other means. This is intended to be
+// run as a goroutine.
+func (b *Blocklist) Run(ctx context.Context) {
+
+	if !b.Enabled() {
+		log.Debug("Blocklist: blocklist files and feed not enabled")
+		return
+	}
+
+	interval := blocklistCheckInterval
+	if b.Duration > 0 && b.Duration < interval {
+		interval = b.Duration
+	}
+
+	log.Debugf(
+		"%s: checking for expired blocklist entries every %v",
+		caller.GetFuncName(),
+		interval,
+	)
+
+	// entries may have expired while the application was not running; this
+	// also generates the blocklist for the first time
+	b.update()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("Blocklist: context cancelled; stopping")
+			return
+		case <-b.changes:
+			b.update()
+		case <-ticker.C:
+			b.update()
+		}
+	}
+}
+
+// Changed signals that the blocked usernames or IP Addresses have changed
+// (e.g., a user account was just disabled) and that the blocklist should be
+// regenerated. Signals received while an update is already pending are
+// combined.
+func (b *Blocklist) Changed() {
+
+	if !b.Enabled() {
+		return
+	}
+
+	select {
+	case b.changes <- struct{}{}:
+	default:
+	}
+}
+
+// Block lists the user IP Address from the provided alert for the
+// configured duration. If the IP Address is already listed, the entry is
+// extended. IP Addresses listed in the ignored IP Addresses file for the
+// tenant are not listed.
+func (b *Blocklist) Block(alert events.SplunkAlertEvent) {
+
+	if !b.Enabled() {
+		return
+	}
+
+	if net.ParseIP(alert.UserIP) == nil {
+		log.Errorf("%q is not a valid IP Address; not adding IP Address to blocklist", alert.UserIP)
+		return
+	}
+
+	ignored, err := isIgnoredIPAddress(alert, b.ignoredSources)
+	switch {
+	case err != nil:
+		log.Errorf(
+			"error while checking ignored status for IP %q; not adding IP Address to blocklist: %v",
+			alert.UserIP,
+			err,
+		)
+
+		return
+
+	case ignored:
+		log.Infof(
+			"IP %q associated with user %q is ignored; not adding IP Address to blocklist",
+			alert.UserIP,
+			alert.Username,
+		)
+
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state, err := b.read()
+	if err != nil {
+		log.Errorf("failed to add IP %q to blocklist: %v", alert.UserIP, err)
+		return
+	}
+
+	now := time.Now()
+	block := ipAddressBlock{
+		Alert:   alert,
+		Blocked: now,
+		Expires: now.Add(b.Duration),
+	}
+
+	// keep the original block time when extending an existing entry
+	if existing, ok := state[alert.UserIP]; ok {
+		block.Blocked = existing.Blocked
+	}
+
+	state[alert.UserIP] = block
+
+	if err := b.write(state); err != nil {
+		log.Errorf("failed to add IP %q to blocklist: %v", alert.UserIP, err)
+		return
+	}
+
+	log.Infof(
+		"IP %q associated with username %q added to blocklist until %s",
+		alert.UserIP,
+		alert.Username,
+		block.Expires.Format(time.RFC3339),
+	)
+
+	b.Changed()
+}
+
+// update removes IP Addresses whose entry has expired (or which have since
+// been added to the ignored IP Addresses list) and regenerates the
+// blocklist from the disabled users files and the remaining IP Addresses.
+// Each blocklist file is replaced atomically, and only if its content has
+// changed. The blocklist is left as-is if the disabled users files cannot be
+// read so that usernames are not dropped from it.
+func (b *Blocklist) update() {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state, err := b.read()
+	if err != nil {
+		log.Errorf("failed to update blocklist: %v", err)
+		return
+	}
+
+	b.removeExpired(state)
+
+	entries, err := b.collect(state)
+	if err != nil {
+		log.Errorf("failed to update blocklist: %v", err)
+		return
+	}
+
+	b.entries = entries
+
+	for _, blocklistFile := range b.Files {
+
+		content, err := blocklistFile.Renderer.Render(entries)
+		if err != nil {
+			log.Errorf(
+				"failed to render %s blocklist %q: %v",
+				blocklistFile.Renderer.Name(),
+				blocklistFile.FilePath,
+				err,
+			)
+			continue
+		}
+
+		if previous, ok := b.written[blocklistFile.FilePath]; ok && bytes.Equal(previous, content) {
+			continue
+		}
+
+		if err := fileutils.WriteFileAtomic(blocklistFile.FilePath, content, blocklistFile.Permissions); err != nil {
+			log.Errorf(
+				"failed to write %s blocklist %q: %v",
+				blocklistFile.Renderer.Name(),
+				blocklistFile.FilePath,
+				err,
+			)
+			continue
+		}
+
+		log.Debugf(
+			"%s: updated %s blocklist %q (%d usernames, %d IP Addresses)",
+			caller.GetFuncName(),
+			blocklistFile.Renderer.Name(),
+			blocklistFile.FilePath,
+			len(entries.Usernames),
+			len(entries.IPAddresses),
+		)
+
+		b.written[blocklistFile.FilePath] = content
+	}
+}
+
+// removeExpired removes IP Addresses whose entry has expired or which have
+// been added to the ignored IP Addresses list since they were listed from
+// the provided state and updates the state file if any were removed. The
+// caller is expected to hold the mutex.
+func (b *Blocklist) removeExpired(state map[string]ipAddressBlock) {
+
+	now := time.Now()
+
+	var changed bool
+	for ipAddress, block := range state {
+
+		reason := "entry expired"
+		if now.Before(block.Expires) {
+			ignored, err := isIgnoredIPAddress(block.Alert, b.ignoredSources)
+			if err != nil || !ignored {
+				continue
+			}
+			reason = "IP Address is now ignored"
+		}
+
+		log.Infof(
+			"IP %q associated with username %q removed from blocklist: %s",
+			ipAddress,
+			block.Alert.Username,
+			reason,
+		)
+
+		delete(state, ipAddress)
+		changed = true
+	}
+
+	if changed {
+		if err := b.write(state); err != nil {
+			log.Errorf("failed to update blocklist state file: %v", err)
+		}
+	}
+}
+
+// collect gathers the usernames listed in the disabled users file of each
+// EZproxy instance along with the provided IP Address blocks.
+func (b *Blocklist) collect(blocks map[string]ipAddressBlock) (BlocklistEntries, error) {
+
+	usernames := make(map[string]struct{})
+	for _, instance := range b.instances {
+		disabled, err := readDisabledUsernames(instance.DisabledUsers)
+		if err != nil {
+			return BlocklistEntries{}, err
+		}
+		for username := range disabled {
+			usernames[username] = struct{}{}
+		}
+	}
+
+	entries := BlocklistEntries{
+		Usernames:   make([]string, 0, len(usernames)),
+		IPAddresses: make([]BlockedIPAddress, 0, len(blocks)),
+	}
+
+	for username := range usernames {
+		entries.Usernames = append(entries.Usernames, username)
+	}
+	sort.Strings(entries.Usernames)
+
+	for ipAddress, block := range blocks {
+		entries.IPAddresses = append(entries.IPAddresses, BlockedIPAddress{
+			IPAddress: ipAddress,
+			Username:  block.Alert.Username,
+			Tenant:    block.Alert.Tenant,
+			AlertName: block.Alert.AlertName,
+			Blocked:   block.Blocked,
+			Expires:   block.Expires,
+		})
+	}
+	sort.Slice(entries.IPAddresses, func(i, j int) bool {
+		return entries.IPAddresses[i].IPAddress < entries.IPAddresses[j].IPAddress
+	})
+
+	return entries, nil
+}
+
+// read loads the blocklist state file. A missing state file is treated as no
+// listed IP Addresses.
+func (b *Blocklist) read() (map[string]ipAddressBlock, error) {
+	state := make(map[string]ipAddressBlock)
+	err := loadJSONState(b.FilePath, "blocklist state", &state)
+
+	return state, err
+}
+
+// write replaces the blocklist state file with the provided state.
+func (b *Blocklist) write(state map[string]ipAddressBlock) error {
+	return saveJSONState(b.FilePath, "blocklist state", state, blocklistFilePermissions)
+}
@@ -0,0 +1,183 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/atc0005/brick/events"
+)
+
+func TestBlocklistRender(t *testing.T) {
+
+	blocked := time.Date(2020, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := BlocklistEntries{
+		Usernames: []string{"jdoe", "jsmith"},
+		IPAddresses: []BlockedIPAddress{
+			{
+				IPAddress: "192.168.1.10",
+				Username:  "jsmith",
+				AlertName: "Brute force",
+				Blocked:   blocked,
+				Expires:   blocked.Add(time.Hour),
+			},
+			{
+				IPAddress: "2001:db8::1",
+				Username:  "jdoe",
+				Tenant:    "library",
+				AlertName: "Brute force",
+				Blocked:   blocked,
+				Expires:   blocked.Add(time.Hour),
+			},
+		},
+	}
+
+	tests := []struct {
+		renderer BlocklistRenderer
+		want     string
+	}{
+		{
+			renderer: NginxBlocklist{},
+			want:     blocklistHeader + "deny 192.168.1.10;\ndeny 2001:db8::1;\n",
+		},
+		{
+			renderer: ApacheBlocklist{},
+			want:     blocklistHeader + "Require not ip 192.168.1.10\nRequire not ip 2001:db8::1\n",
+		},
+		{
+			renderer: IPListBlocklist{},
+			want:     "192.168.1.10\n2001:db8::1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.renderer.Name(), func(t *testing.T) {
+
+			got, err := tt.renderer.Render(entries)
+			if err != nil {
+				t.Fatalf("Render() returned error: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("Render() = %q; want %q", got, tt.want)
+			}
+
+			// empty blocklists only contain the header (if any)
+			empty, err := tt.renderer.Render(BlocklistEntries{})
+			if err != nil {
+				t.Fatalf("Render() returned error: %v", err)
+			}
+
+			wantEmpty := ""
+			if tt.renderer.Name() != (IPListBlocklist{}).Name() {
+				wantEmpty = blocklistHeader
+			}
+
+			if string(empty) != wantEmpty {
+				t.Errorf("Render() of empty blocklist = %q; want %q", empty, wantEmpty)
+			}
+		})
+	}
+
+	t.Run(JSONBlocklist{}.Name(), func(t *testing.T) {
+
+		got, err := JSONBlocklist{}.Render(entries)
+		if err != nil {
+			t.Fatalf("Render() returned error: %v", err)
+		}
+
+		var decoded BlocklistEntries
+		if err := json.Unmarshal(got, &decoded); err != nil {
+			t.Fatalf("Render() returned invalid JSON: %v", err)
+		}
+
+		if !reflect.DeepEqual(decoded, entries) {
+			t.Errorf("Render() decoded = %+v; want %+v", decoded, entries)
+		}
+	})
+}
+
+func TestBlocklistBlock(t *testing.T) {
+
+	dir := t.TempDir()
+
+	ignoredIPAddressesFile := filepath.Join(dir, "ips.brick-ignored.txt")
+	if err := ioutil.WriteFile(ignoredIPAddressesFile, []byte("10.0.0.5\n"), 0600); err != nil {
+		t.Fatalf("failed to write ignored IP Addresses file: %v", err)
+	}
+
+	nginxFile := filepath.Join(dir, "nginx.inc")
+
+	blocklist := NewBlocklist(
+		filepath.Join(dir, "blocklist-blocks.json"),
+		time.Hour,
+		[]BlocklistFile{{FilePath: nginxFile, Permissions: 0600, Renderer: NginxBlocklist{}}},
+		false,
+		nil,
+		IgnoredSources{IgnoredIPAddressesFile: ignoredIPAddressesFile},
+	)
+
+	for _, ipAddress := range []string{"192.168.1.10", "10.0.0.5", "not-an-ip", "192.168.1.10"} {
+		blocklist.Block(events.SplunkAlertEvent{Username: "jsmith", UserIP: ipAddress})
+	}
+
+	// entries which have already expired are dropped at the next update
+	state, err := blocklist.read()
+	if err != nil {
+		t.Fatalf("failed to read state: %v", err)
+	}
+	state["172.16.0.1"] = ipAddressBlock{
+		Alert:   events.SplunkAlertEvent{Username: "jdoe", UserIP: "172.16.0.1"},
+		Blocked: time.Now().Add(-2 * time.Hour),
+		Expires: time.Now().Add(-time.Hour),
+	}
+	if err := blocklist.write(state); err != nil {
+		t.Fatalf("failed to write state: %v", err)
+	}
+
+	blocklist.update()
+
+	content, err := ioutil.ReadFile(nginxFile)
+	if err != nil {
+		t.Fatalf("failed to read blocklist file: %v", err)
+	}
+
+	want := blocklistHeader + "deny 192.168.1.10;\n"
+	if string(content) != want {
+		t.Errorf("blocklist file = %q; want %q", content, want)
+	}
+
+	state, err = blocklist.read()
+	if err != nil {
+		t.Fatalf("failed to read state: %v", err)
+	}
+	if _, ok := state["172.16.0.1"]; ok || len(state) != 1 {
+		t.Errorf("state after update = %v; want only %q", state, "192.168.1.10")
+	}
+
+	entries := blocklist.Entries()
+	if len(entries.IPAddresses) != 1 || entries.IPAddresses[0].Username != "jsmith" {
+		t.Errorf("Entries() = %+v; want one IP Address for %q", entries, "jsmith")
+	}
+}
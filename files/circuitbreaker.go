@@ -0,0 +1,267 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/events"
+)
+
+// circuitBreakerFilePermissions is applied to the circuit breaker state file
+// when it is first created.
+const circuitBreakerFilePermissions os.FileMode = 0644
+
+// CircuitBreaker limits the number of distinct user accounts disabled within
+// a period of time in order to protect against runaway alerts (e.g., a
+// broken Splunk search). Once the limit is exceeded the circuit breaker
+// trips and no further user accounts are disabled until an operator resets
+// it. The state is persisted to a state file and read for each check so
+// that the circuit breaker remains tripped across application restarts and
+// may be reset by a separate invocation of this application.
+type CircuitBreaker struct {
+
+	// FilePath is the fully-qualified path to the JSON state file used to
+	// persist the circuit breaker state.
+	FilePath string
+
+	// MaxDisables is the number of distinct user accounts which may be
+	// disabled within the window. A value of zero disables the circuit
+	// breaker.
+	MaxDisables int
+
+	// Window is the period of time in which disabled user accounts are
+	// counted.
+	Window time.Duration
+
+	mutex *sync.Mutex
+}
+
+// circuitBreakerEntry is a single disabled user account recorded by the
+// circuit breaker.
+type circuitBreakerEntry struct {
+	Time     time.Time `json:"time"`
+	Username string    `json:"username"`
+}
+
+// circuitBreakerState is the content of the circuit breaker state file.
+type circuitBreakerState struct {
+	Disables   []circuitBreakerEntry `json:"disables"`
+	Open       bool                  `json:"open"`
+	TrippedAt  time.Time             `json:"tripped_at"`
+	TrippedBy  string                `json:"tripped_by,omitempty"`
+	Suppressed int                   `json:"suppressed"`
+}
+
+// NewCircuitBreaker constructs a new CircuitBreaker value using the provided
+// state file and limits.
+func NewCircuitBreaker(path string, maxDisables int, window time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		FilePath:    path,
+		MaxDisables: maxDisables,
+		Window:      window,
+		mutex:       &sync.Mutex{},
+	}
+}
+
+// Enabled indicates whether a mass-disable limit has been set.
+func (cb *CircuitBreaker) Enabled() bool {
+	return cb.MaxDisables > 0
+}
+
+// Allow is called before disabling the user account for the provided alert
+// and checks whether doing so would exceed the limit. If not, the user
+// account is counted toward the limit right away so that concurrent disable
+// requests cannot all pass the check before any of them is counted; Release
+// is called to give the slot back if the user account could not be
+// disabled. If the limit would be exceeded, the circuit breaker trips (if
+// not already open) and the disable request is counted as suppressed. The
+// returned status indicates whether the user account may be disabled (i.e.,
+// the circuit breaker is not open).
+func (cb *CircuitBreaker) Allow(alert events.SplunkAlertEvent) (events.CircuitBreakerStatus, error) {
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if !cb.Enabled() {
+		return cb.status(circuitBreakerState{}), nil
+	}
+
+	state, err := cb.read()
+	if err != nil {
+		return cb.status(circuitBreakerState{}), err
+	}
+
+	now := time.Now()
+	cb.prune(&state, now)
+
+	if !state.Open {
+		usernames := cb.usernames(state)
+		usernames[strings.ToLower(alert.Username)] = struct{}{}
+
+		if len(usernames) <= cb.MaxDisables {
+			state.Disables = append(state.Disables, circuitBreakerEntry{
+				Time:     now,
+				Username: strings.ToLower(alert.Username),
+			})
+
+			return cb.status(state), cb.write(state)
+		}
+
+		state.Open = true
+		state.TrippedAt = now
+		state.TrippedBy = alert.Username
+		state.Suppressed = 0
+
+		log.Warnf(
+			"Circuit breaker tripped by user %q; more than %d user accounts disabled within %s",
+			alert.Username,
+			cb.MaxDisables,
+			cb.Window,
+		)
+	}
+
+	state.Suppressed++
+
+	return cb.status(state), cb.write(state)
+}
+
+// Release removes the user account for the provided alert from the limit.
+// This is called if the user account could not be disabled after a prior
+// call to Allow counted it.
+func (cb *CircuitBreaker) Release(alert events.SplunkAlertEvent) error {
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if !cb.Enabled() {
+		return nil
+	}
+
+	state, err := cb.read()
+	if err != nil {
+		return err
+	}
+
+	cb.prune(&state, time.Now())
+
+	// remove the most recent entry; earlier entries for the same user
+	// account were counted by other (successful) disable requests
+	username := strings.ToLower(alert.Username)
+	for i := len(state.Disables) - 1; i >= 0; i-- {
+		if state.Disables[i].Username == username {
+			state.Disables = append(state.Disables[:i], state.Disables[i+1:]...)
+			break
+		}
+	}
+
+	return cb.write(state)
+}
+
+// Status returns the current state of the circuit breaker.
+func (cb *CircuitBreaker) Status() (events.CircuitBreakerStatus, error) {
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if !cb.Enabled() {
+		return cb.status(circuitBreakerState{}), nil
+	}
+
+	state, err := cb.read()
+	if err != nil {
+		return cb.status(circuitBreakerState{}), err
+	}
+
+	cb.prune(&state, time.Now())
+
+	return cb.status(state), nil
+}
+
+// Reset closes the circuit breaker and clears the disabled user accounts
+// counted toward the limit. The state prior to the reset is returned.
+func (cb *CircuitBreaker) Reset() (events.CircuitBreakerStatus, error) {
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	state, err := cb.read()
+	if err != nil {
+		return cb.status(circuitBreakerState{}), err
+	}
+
+	cb.prune(&state, time.Now())
+
+	return cb.status(state), cb.write(circuitBreakerState{})
+}
+
+// status converts the provided state to a status value.
+func (cb *CircuitBreaker) status(state circuitBreakerState) events.CircuitBreakerStatus {
+
+	return events.CircuitBreakerStatus{
+		MaxDisables: cb.MaxDisables,
+		Window:      cb.Window,
+		Disables:    len(cb.usernames(state)),
+		Open:        state.Open,
+		TrippedAt:   state.TrippedAt,
+		TrippedBy:   state.TrippedBy,
+		Suppressed:  state.Suppressed,
+	}
+}
+
+// usernames returns the distinct user accounts counted toward the limit.
+func (cb *CircuitBreaker) usernames(state circuitBreakerState) map[string]struct{} {
+
+	usernames := make(map[string]struct{}, len(state.Disables))
+	for _, entry := range state.Disables {
+		usernames[entry.Username] = struct{}{}
+	}
+
+	return usernames
+}
+
+// prune removes disabled user accounts recorded outside of the window.
+func (cb *CircuitBreaker) prune(state *circuitBreakerState, now time.Time) {
+
+	keep := state.Disables[:0]
+	for _, entry := range state.Disables {
+		if now.Sub(entry.Time) <= cb.Window {
+			keep = append(keep, entry)
+		}
+	}
+
+	state.Disables = keep
+}
+
+// read loads the circuit breaker state file. A missing state file is
+// treated as a closed circuit breaker.
+func (cb *CircuitBreaker) read() (circuitBreakerState, error) {
+	var state circuitBreakerState
+	err := loadJSONState(cb.FilePath, "circuit breaker state", &state)
+
+	return state, err
+}
+
+// write replaces the circuit breaker state file with the provided state.
+func (cb *CircuitBreaker) write(state circuitBreakerState) error {
+	return saveJSONState(cb.FilePath, "circuit breaker state", state, circuitBreakerFilePermissions)
+}
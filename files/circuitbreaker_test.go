@@ -0,0 +1,212 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/atc0005/brick/events"
+)
+
+func TestCircuitBreakerAllow(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		maxDisables int
+		// recorded user accounts and how long ago they were disabled
+		recorded   map[string]time.Duration
+		username   string
+		wantOpen   bool
+		wantCounts int
+	}{
+		{
+			name:        "disabled",
+			maxDisables: 0,
+			recorded:    map[string]time.Duration{"a": 0, "b": 0},
+			username:    "c",
+			wantOpen:    false,
+			wantCounts:  0,
+		},
+		{
+			name:        "below limit",
+			maxDisables: 2,
+			recorded:    map[string]time.Duration{"a": 0},
+			username:    "b",
+			wantOpen:    false,
+			wantCounts:  2,
+		},
+		{
+			name:        "exceeds limit",
+			maxDisables: 2,
+			recorded:    map[string]time.Duration{"a": 0, "b": 0},
+			username:    "c",
+			wantOpen:    true,
+			wantCounts:  2,
+		},
+		{
+			name:        "repeat user does not exceed limit",
+			maxDisables: 2,
+			recorded:    map[string]time.Duration{"a": 0, "b": 0},
+			username:    "B",
+			wantOpen:    false,
+			wantCounts:  2,
+		},
+		{
+			name:        "entries outside window are pruned",
+			maxDisables: 2,
+			recorded:    map[string]time.Duration{"a": 2 * time.Hour, "b": 0},
+			username:    "c",
+			wantOpen:    false,
+			wantCounts:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			cb := NewCircuitBreaker(
+				filepath.Join(t.TempDir(), "circuit-breaker.json"),
+				tt.maxDisables,
+				time.Hour,
+			)
+
+			// record directly to control the time of each entry
+			state := circuitBreakerState{}
+			for username, age := range tt.recorded {
+				state.Disables = append(state.Disables, circuitBreakerEntry{
+					Time:     time.Now().Add(-age),
+					Username: username,
+				})
+			}
+			if err := cb.write(state); err != nil {
+				t.Fatalf("failed to write state: %v", err)
+			}
+
+			status, err := cb.Allow(events.SplunkAlertEvent{Username: tt.username})
+			if err != nil {
+				t.Fatalf("Allow() returned error: %v", err)
+			}
+
+			if status.Open != tt.wantOpen {
+				t.Errorf("Allow() status.Open = %t; want %t", status.Open, tt.wantOpen)
+			}
+
+			// Allow counts the user account if permitted to disable it
+			if status.Disables != tt.wantCounts {
+				t.Errorf("Allow() status.Disables = %d; want %d", status.Disables, tt.wantCounts)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerRelease(t *testing.T) {
+
+	cb := NewCircuitBreaker(filepath.Join(t.TempDir(), "circuit-breaker.json"), 2, time.Hour)
+
+	for _, username := range []string{"a", "b"} {
+		status, err := cb.Allow(events.SplunkAlertEvent{Username: username})
+		if err != nil || status.Open {
+			t.Fatalf("Allow(%q) = %v, %v; want closed circuit breaker", username, status, err)
+		}
+	}
+
+	// the slot for a user account which could not be disabled is given back
+	if err := cb.Release(events.SplunkAlertEvent{Username: "B"}); err != nil {
+		t.Fatalf("Release() returned error: %v", err)
+	}
+
+	status, err := cb.Allow(events.SplunkAlertEvent{Username: "c"})
+	if err != nil || status.Open || status.Disables != 2 {
+		t.Fatalf("Allow() after Release() = %+v, %v; want closed circuit breaker with 2 disables", status, err)
+	}
+
+	status, err = cb.Allow(events.SplunkAlertEvent{Username: "d"})
+	if err != nil {
+		t.Fatalf("Allow() returned error: %v", err)
+	}
+
+	if !status.Open || status.TrippedBy != "d" || status.Suppressed != 1 {
+		t.Errorf("Allow() = %+v; want open circuit breaker tripped by %q with 1 suppressed", status, "d")
+	}
+
+	// once open, further requests are suppressed until reset
+	status, err = cb.Allow(events.SplunkAlertEvent{Username: "a"})
+	if err != nil || !status.Open || status.Suppressed != 2 {
+		t.Errorf("Allow() = %+v, %v; want open circuit breaker with 2 suppressed", status, err)
+	}
+
+	if _, err := cb.Reset(); err != nil {
+		t.Fatalf("Reset() returned error: %v", err)
+	}
+
+	status, err = cb.Status()
+	if err != nil || status.Open || status.Disables != 0 {
+		t.Errorf("Status() after Reset() = %+v, %v; want closed circuit breaker without disables", status, err)
+	}
+}
+
+func TestCircuitBreakerAllowConcurrent(t *testing.T) {
+
+	const maxDisables = 3
+	const requests = 20
+
+	cb := NewCircuitBreaker(filepath.Join(t.TempDir(), "circuit-breaker.json"), maxDisables, time.Hour)
+
+	var wg sync.WaitGroup
+	allowed := make(chan string, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(username string) {
+			defer wg.Done()
+
+			status, err := cb.Allow(events.SplunkAlertEvent{Username: username})
+			if err != nil {
+				t.Errorf("Allow(%q) returned error: %v", username, err)
+				return
+			}
+
+			if !status.Open {
+				allowed <- username
+			}
+		}(fmt.Sprintf("user%d", i))
+	}
+	wg.Wait()
+	close(allowed)
+
+	if len(allowed) != maxDisables {
+		t.Errorf("%d of %d concurrent requests allowed; want %d", len(allowed), requests, maxDisables)
+	}
+
+	status, err := cb.Status()
+	if err != nil {
+		t.Fatalf("Status() returned error: %v", err)
+	}
+
+	if !status.Open || status.Disables != maxDisables || status.Suppressed != requests-maxDisables {
+		t.Errorf(
+			"Status() = %+v; want open circuit breaker with %d disables and %d suppressed",
+			status,
+			maxDisables,
+			requests-maxDisables,
+		)
+	}
+}
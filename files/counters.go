@@ -0,0 +1,193 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atc0005/brick/events"
+)
+
+// reportCountersFilePermissions is applied to the report counters state file
+// when it is first created.
+const reportCountersFilePermissions os.FileMode = 0644
+
+// ReportCounters tracks reports received for each username so that user
+// accounts are only disabled once a report threshold has been reached. The
+// counters are persisted to a state file so that they survive application
+// restarts.
+type ReportCounters struct {
+
+	// FilePath is the fully-qualified path to the JSON state file used to
+	// persist report counters.
+	FilePath string
+
+	// Retention is how long reports are kept in the state file. This should
+	// be at least as long as the largest threshold window in use.
+	Retention time.Duration
+
+	mutex *sync.Mutex
+}
+
+// reportCounterEntry is a single report recorded for a username.
+type reportCounterEntry struct {
+	Time      time.Time `json:"time"`
+	AlertName string    `json:"alert_name"`
+	UserIP    string    `json:"user_ip"`
+}
+
+// reportCountersState is the content of the report counters state file,
+// indexed by lowercase username (see reportCounterKey).
+type reportCountersState map[string][]reportCounterEntry
+
+// dryRunReportCounterPrefix is prepended to the username for reports of
+// alerts handled by a dry-run alert policy. These reports are counted
+// separately so that a dry-run trial does not change later decisions for
+// alerts which are not handled in dry-run mode.
+const dryRunReportCounterPrefix string = "[dry-run] "
+
+// NewReportCounters constructs a new ReportCounters value using the
+// provided state file and retention period.
+func NewReportCounters(path string, retention time.Duration) *ReportCounters {
+	return &ReportCounters{
+		FilePath:  path,
+		Retention: retention,
+		mutex:     &sync.Mutex{},
+	}
+}
+
+// Record adds the provided alert to the report counters for the reported
+// username and returns the number of reports (and distinct alert names)
+// recorded within the window of the provided threshold. Reports older than
+// the retention period are discarded.
+func (rc *ReportCounters) Record(
+	alert events.SplunkAlertEvent,
+	threshold events.ReportThreshold,
+) (events.ReportThresholdStatus, error) {
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	status := events.ReportThresholdStatus{
+		Threshold: threshold,
+	}
+
+	state, err := rc.read()
+	if err != nil {
+		return status, err
+	}
+
+	now := time.Now()
+	key := reportCounterKey(alert)
+
+	state[key] = append(state[key], reportCounterEntry{
+		Time:      now,
+		AlertName: alert.AlertName,
+		UserIP:    alert.UserIP,
+	})
+
+	rc.prune(state, now)
+
+	alertNames := make(map[string]struct{})
+	for _, entry := range state[key] {
+		if now.Sub(entry.Time) > threshold.Window {
+			continue
+		}
+		status.Reports++
+		alertNames[strings.ToLower(entry.AlertName)] = struct{}{}
+	}
+	status.AlertNames = len(alertNames)
+
+	return status, rc.write(state)
+}
+
+// Reset removes all recorded reports for the username reported by the
+// provided alert. This is called once the user account has been disabled (or
+// would have been in dry-run mode) so that the threshold applies anew if the
+// account is later re-enabled.
+func (rc *ReportCounters) Reset(alert events.SplunkAlertEvent) error {
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	state, err := rc.read()
+	if err != nil {
+		return err
+	}
+
+	key := reportCounterKey(alert)
+	if _, ok := state[key]; !ok {
+		return nil
+	}
+
+	delete(state, key)
+
+	return rc.write(state)
+}
+
+// reportCounterKey returns the key under which reports for the username
+// reported by the provided alert are recorded. Reports of alerts handled by
+// a dry-run alert policy are kept apart from all other reports.
+func reportCounterKey(alert events.SplunkAlertEvent) string {
+
+	key := strings.ToLower(alert.Username)
+	if alert.Policy != nil && alert.Policy.DryRunEnabled() {
+		key = dryRunReportCounterPrefix + key
+	}
+
+	return key
+}
+
+// prune removes reports older than the retention period along with any
+// usernames left without reports.
+func (rc *ReportCounters) prune(state reportCountersState, now time.Time) {
+
+	for username, entries := range state {
+		keep := entries[:0]
+		for _, entry := range entries {
+			if now.Sub(entry.Time) <= rc.Retention {
+				keep = append(keep, entry)
+			}
+		}
+
+		if len(keep) == 0 {
+			delete(state, username)
+			continue
+		}
+
+		state[username] = keep
+	}
+}
+
+// read loads the report counters state file. A missing state file is
+// treated as an empty state.
+func (rc *ReportCounters) read() (reportCountersState, error) {
+	state := make(reportCountersState)
+	if err := loadJSONState(rc.FilePath, "report counters", &state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// write replaces the report counters state file with the provided state.
+func (rc *ReportCounters) write(state reportCountersState) error {
+	return saveJSONState(rc.FilePath, "report counters", state, reportCountersFilePermissions)
+}
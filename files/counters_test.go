@@ -0,0 +1,278 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/atc0005/brick/events"
+)
+
+func TestReportCountersRecord(t *testing.T) {
+
+	threshold := events.ReportThreshold{Reports: 3, AlertNames: 2, Window: time.Hour}
+
+	// reportAge is a report recorded for a username some time ago
+	type reportAge struct {
+		alertName string
+		age       time.Duration
+	}
+
+	tests := []struct {
+		name           string
+		recorded       map[string][]reportAge
+		username       string
+		alertName      string
+		wantReports    int
+		wantAlertNames int
+		wantMet        bool
+	}{
+		{
+			name:           "first report",
+			username:       "jsmith",
+			alertName:      "Brute force",
+			wantReports:    1,
+			wantAlertNames: 1,
+		},
+		{
+			name: "report count met",
+			recorded: map[string][]reportAge{
+				"jsmith": {{"Brute force", 0}, {"Brute force", 10 * time.Minute}},
+			},
+			username:       "jsmith",
+			alertName:      "brute FORCE",
+			wantReports:    3,
+			wantAlertNames: 1,
+			wantMet:        true,
+		},
+		{
+			name: "distinct alert names met",
+			recorded: map[string][]reportAge{
+				"jsmith": {{"Brute force", 0}},
+			},
+			username:       "JSmith",
+			alertName:      "Impossible travel",
+			wantReports:    2,
+			wantAlertNames: 2,
+			wantMet:        true,
+		},
+		{
+			name: "reports outside window are not counted",
+			recorded: map[string][]reportAge{
+				"jsmith": {{"Brute force", 90 * time.Minute}, {"Impossible travel", 2 * time.Hour}},
+			},
+			username:       "jsmith",
+			alertName:      "Brute force",
+			wantReports:    1,
+			wantAlertNames: 1,
+		},
+		{
+			name: "reports for other usernames are not counted",
+			recorded: map[string][]reportAge{
+				"adoe": {{"Brute force", 0}, {"Impossible travel", 0}},
+			},
+			username:       "jsmith",
+			alertName:      "Brute force",
+			wantReports:    1,
+			wantAlertNames: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			rc := NewReportCounters(
+				filepath.Join(t.TempDir(), "report-counters.json"),
+				24*time.Hour,
+			)
+
+			// record directly to control the time of each report
+			now := time.Now()
+			state := reportCountersState{}
+			for username, reports := range tt.recorded {
+				for _, report := range reports {
+					state[username] = append(state[username], reportCounterEntry{
+						Time:      now.Add(-report.age),
+						AlertName: report.alertName,
+					})
+				}
+			}
+			if err := rc.write(state); err != nil {
+				t.Fatalf("failed to write state: %v", err)
+			}
+
+			status, err := rc.Record(
+				events.SplunkAlertEvent{Username: tt.username, AlertName: tt.alertName},
+				threshold,
+			)
+			if err != nil {
+				t.Fatalf("Record() returned error: %v", err)
+			}
+
+			if status.Reports != tt.wantReports || status.AlertNames != tt.wantAlertNames {
+				t.Errorf(
+					"Record() = %d reports, %d alert names; want %d reports, %d alert names",
+					status.Reports,
+					status.AlertNames,
+					tt.wantReports,
+					tt.wantAlertNames,
+				)
+			}
+
+			if status.Met() != tt.wantMet {
+				t.Errorf("Record() status.Met() = %t; want %t", status.Met(), tt.wantMet)
+			}
+		})
+	}
+}
+
+func TestReportCountersRetentionAndReset(t *testing.T) {
+
+	rc := NewReportCounters(filepath.Join(t.TempDir(), "report-counters.json"), time.Hour)
+
+	state := reportCountersState{
+		"expired": {{Time: time.Now().Add(-2 * time.Hour), AlertName: "Brute force"}},
+		"jsmith":  {{Time: time.Now(), AlertName: "Brute force"}},
+	}
+	if err := rc.write(state); err != nil {
+		t.Fatalf("failed to write state: %v", err)
+	}
+
+	threshold := events.ReportThreshold{Reports: 2, Window: 24 * time.Hour}
+
+	// reports older than the retention period are discarded even if they
+	// fall within the threshold window
+	if _, err := rc.Record(events.SplunkAlertEvent{Username: "adoe"}, threshold); err != nil {
+		t.Fatalf("Record() returned error: %v", err)
+	}
+
+	got, err := rc.read()
+	if err != nil {
+		t.Fatalf("failed to read state: %v", err)
+	}
+	if _, ok := got["expired"]; ok {
+		t.Errorf("state contains reports for %q after the retention period", "expired")
+	}
+
+	if err := rc.Reset(events.SplunkAlertEvent{Username: "JSMITH"}); err != nil {
+		t.Fatalf("Reset() returned error: %v", err)
+	}
+
+	status, err := rc.Record(events.SplunkAlertEvent{Username: "jsmith"}, threshold)
+	if err != nil {
+		t.Fatalf("Record() returned error: %v", err)
+	}
+	if status.Reports != 1 || status.Met() {
+		t.Errorf("Record() after Reset() = %d reports (met: %t); want 1 report (met: false)", status.Reports, status.Met())
+	}
+
+	// resetting a username without reports is not an error
+	if err := rc.Reset(events.SplunkAlertEvent{Username: "missing"}); err != nil {
+		t.Errorf("Reset() returned error for username without reports: %v", err)
+	}
+}
+
+func TestReportCountersDryRun(t *testing.T) {
+
+	threshold := events.ReportThreshold{Reports: 2, Window: time.Hour}
+
+	dryRun := true
+	live := false
+	livePolicy := &events.AlertPolicy{Name: "live", DryRun: &live}
+	dryRunPolicy := &events.AlertPolicy{Name: "trial", DryRun: &dryRun}
+
+	tests := []struct {
+		name        string
+		recorded    []*events.AlertPolicy
+		reset       *events.AlertPolicy
+		policy      *events.AlertPolicy
+		wantReports int
+	}{
+		{
+			name:        "dry-run reports do not count toward live threshold",
+			recorded:    []*events.AlertPolicy{dryRunPolicy},
+			policy:      livePolicy,
+			wantReports: 1,
+		},
+		{
+			name:        "live reports do not count toward dry-run threshold",
+			recorded:    []*events.AlertPolicy{livePolicy, nil},
+			policy:      dryRunPolicy,
+			wantReports: 1,
+		},
+		{
+			name:        "dry-run reports count toward dry-run threshold",
+			recorded:    []*events.AlertPolicy{dryRunPolicy},
+			policy:      dryRunPolicy,
+			wantReports: 2,
+		},
+		{
+			name:        "dry-run reset keeps live reports",
+			recorded:    []*events.AlertPolicy{livePolicy, dryRunPolicy},
+			reset:       dryRunPolicy,
+			policy:      livePolicy,
+			wantReports: 2,
+		},
+		{
+			name:        "live reset keeps dry-run reports",
+			recorded:    []*events.AlertPolicy{livePolicy, dryRunPolicy},
+			reset:       livePolicy,
+			policy:      dryRunPolicy,
+			wantReports: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			rc := NewReportCounters(
+				filepath.Join(t.TempDir(), "report-counters.json"),
+				24*time.Hour,
+			)
+
+			for _, policy := range tt.recorded {
+				alert := events.SplunkAlertEvent{Username: "jsmith", Policy: policy}
+				if _, err := rc.Record(alert, threshold); err != nil {
+					t.Fatalf("Record() returned error: %v", err)
+				}
+			}
+
+			if tt.reset != nil {
+				alert := events.SplunkAlertEvent{Username: "JSMITH", Policy: tt.reset}
+				if err := rc.Reset(alert); err != nil {
+					t.Fatalf("Reset() returned error: %v", err)
+				}
+			}
+
+			status, err := rc.Record(
+				events.SplunkAlertEvent{Username: "jsmith", Policy: tt.policy},
+				threshold,
+			)
+			if err != nil {
+				t.Fatalf("Record() returned error: %v", err)
+			}
+
+			if status.Reports != tt.wantReports {
+				t.Errorf("Record() = %d reports; want %d reports", status.Reports, tt.wantReports)
+			}
+		})
+	}
+}
@@ -19,14 +19,23 @@
 package files
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/atc0005/brick/events"
 	"github.com/atc0005/go-ezproxy"
 )
 
+// sampleAlertUsername is the username used when rendering disabled users file
+// templates for validation and to determine the format of rendered entries.
+const sampleAlertUsername string = "brick-sample-user"
+
 // fileEntry represents the values that are used when generating entries via
 // templates for flat-files associated with disabling user accounts,
 // terminating active user sessions and logging actions taken.
@@ -35,6 +44,13 @@ type fileEntry struct {
 	UserSession        ezproxy.UserSession
 	EntrySuffix        string
 	IgnoredEntriesFile string
+	IgnoredEntry       IgnoredEntry
+	Operator           string
+	Verification       string
+	Note               string
+	Quarantine         bool
+	FirewallBackend    string
+	BlockExpires       time.Time
 }
 
 // FlatFile represents a text file that this application is responsible for
@@ -80,6 +96,23 @@ type DisabledUsers struct {
 	// Template is a parsed template representing the line written to this
 	// file when a user account is disabled.
 	Template *template.Template
+
+	// QuarantineTemplate is a parsed template representing the line written
+	// to this file when a user account is quarantined (e.g., moved to a
+	// restricted EZproxy group) instead of disabled. Nil if not configured.
+	QuarantineTemplate *template.Template
+}
+
+// DisabledUsersTemplates holds the parsed templates used to generate entries
+// for the disabled users file of each EZproxy instance.
+type DisabledUsersTemplates struct {
+
+	// Entry is used when a user account is disabled.
+	Entry *template.Template
+
+	// Quarantine is used when a user account is quarantined. Nil if not
+	// configured.
+	Quarantine *template.Template
 }
 
 // ReportedUserEventsLog represents a log file where this application
@@ -109,6 +142,11 @@ type ReportedUserEventsLog struct {
 	// the user account is disabled.
 	DisableFirstEventTemplate *template.Template
 
+	// QuarantineEventTemplate is a parsed template representing the log line
+	// written when a user account is reported via alert payload and the
+	// user account is quarantined instead of disabled.
+	QuarantineEventTemplate *template.Template
+
 	// DisableRepeatEventTemplate is a parsed template representing the log
 	// line written when a user account is reported via alert payload again
 	// after the user account is already disabled.
@@ -125,6 +163,45 @@ type ReportedUserEventsLog struct {
 	// an associated user session is terminated. There may be multiple log
 	// lines, one for each active user session associated with the username.
 	TerminateUserSessionEventTemplate *template.Template
+
+	// AuditTemplate is a parsed template representing the log line written
+	// when an operator makes a change via the management API (e.g., adding
+	// or removing an ignored user account or IP Address entry).
+	AuditTemplate *template.Template
+
+	// DryRunDisableEventTemplate is a parsed template representing the log
+	// line written when a user account would have been disabled, but the
+	// alert policy is in dry-run mode.
+	DryRunDisableEventTemplate *template.Template
+
+	// DryRunTerminateUserSessionEventTemplate is a parsed template
+	// representing the log line written for each user session which would
+	// have been terminated, but the alert policy is in dry-run mode.
+	DryRunTerminateUserSessionEventTemplate *template.Template
+
+	// PendingApprovalEventTemplate is a parsed template representing the log
+	// line written when disabling a user account is held for operator
+	// approval.
+	PendingApprovalEventTemplate *template.Template
+
+	// ApprovalDecisionEventTemplate is a parsed template representing the
+	// log line written when a pending approval request is approved,
+	// rejected or times out.
+	ApprovalDecisionEventTemplate *template.Template
+
+	// SuspendedEventTemplate is a parsed template representing the log line
+	// written when a user account is not disabled because the mass-disable
+	// circuit breaker is open.
+	SuspendedEventTemplate *template.Template
+
+	// BlockedIPEventTemplate is a parsed template representing the log line
+	// written when the reported user IP Address is blocked using the
+	// firewall backend.
+	BlockedIPEventTemplate *template.Template
+
+	// UnblockedIPEventTemplate is a parsed template representing the log
+	// line written when a blocked IP Address is unblocked.
+	UnblockedIPEventTemplate *template.Template
 }
 
 // IgnoredSources represents the various sources of "safe" or "ignore" entries
@@ -147,6 +224,9 @@ func NewReportedUserEventsLog(path string, permissions os.FileMode) *ReportedUse
 	disabledUserFirstEventTemplate := template.Must(template.New(
 		"disabledUserFirstEventTemplate").Parse(disabledUserFirstEventTemplateText))
 
+	quarantinedUserEventTemplate := template.Must(template.New(
+		"quarantinedUserEventTemplate").Parse(quarantinedUserEventTemplateText))
+
 	disabledUserRepeatEventTemplate := template.Must(template.New(
 		"disabledUserRepeatEventTemplate").Parse(disabledUserRepeatEventTemplateText))
 
@@ -156,54 +236,230 @@ func NewReportedUserEventsLog(path string, permissions os.FileMode) *ReportedUse
 	terminatedUserSessionEventTemplate := template.Must(template.New(
 		"terminatedUserSessionEventTemplate").Parse(terminatedUserEventTemplateText))
 
+	auditEventTemplate := template.Must(template.New(
+		"auditEventTemplate").Parse(auditEventTemplateText))
+
+	dryRunDisabledUserEventTemplate := template.Must(template.New(
+		"dryRunDisabledUserEventTemplate").Parse(dryRunDisabledUserEventTemplateText))
+
+	dryRunTerminatedUserSessionEventTemplate := template.Must(template.New(
+		"dryRunTerminatedUserSessionEventTemplate").Parse(dryRunTerminatedUserEventTemplateText))
+
+	pendingApprovalEventTemplate := template.Must(template.New(
+		"pendingApprovalEventTemplate").Parse(pendingApprovalEventTemplateText))
+
+	approvalDecisionEventTemplate := template.Must(template.New(
+		"approvalDecisionEventTemplate").Parse(approvalDecisionEventTemplateText))
+
+	suspendedUserEventTemplate := template.Must(template.New(
+		"suspendedUserEventTemplate").Parse(suspendedUserEventTemplateText))
+
+	blockedIPEventTemplate := template.Must(template.New(
+		"blockedIPEventTemplate").Parse(blockedIPEventTemplateText))
+
+	unblockedIPEventTemplate := template.Must(template.New(
+		"unblockedIPEventTemplate").Parse(unblockedIPEventTemplateText))
+
 	ruel := ReportedUserEventsLog{
 		FlatFile: FlatFile{
 			FilePath:        path,
 			FilePermissions: permissions,
 		},
-		ReportTemplate:                    reportedUserEventTemplate,
-		DisableFirstEventTemplate:         disabledUserFirstEventTemplate,
-		DisableRepeatEventTemplate:        disabledUserRepeatEventTemplate,
-		IgnoreTemplate:                    ignoredUserEventTemplate,
-		TerminateUserSessionEventTemplate: terminatedUserSessionEventTemplate,
+		ReportTemplate:                          reportedUserEventTemplate,
+		DisableFirstEventTemplate:               disabledUserFirstEventTemplate,
+		QuarantineEventTemplate:                 quarantinedUserEventTemplate,
+		DisableRepeatEventTemplate:              disabledUserRepeatEventTemplate,
+		IgnoreTemplate:                          ignoredUserEventTemplate,
+		TerminateUserSessionEventTemplate:       terminatedUserSessionEventTemplate,
+		AuditTemplate:                           auditEventTemplate,
+		DryRunDisableEventTemplate:              dryRunDisabledUserEventTemplate,
+		DryRunTerminateUserSessionEventTemplate: dryRunTerminatedUserSessionEventTemplate,
+		PendingApprovalEventTemplate:            pendingApprovalEventTemplate,
+		ApprovalDecisionEventTemplate:           approvalDecisionEventTemplate,
+		SuspendedEventTemplate:                  suspendedUserEventTemplate,
+		BlockedIPEventTemplate:                  blockedIPEventTemplate,
+		UnblockedIPEventTemplate:                unblockedIPEventTemplate,
 	}
 
 	return &ruel
 
 }
 
-// NewDisabledUsers constructs a DisabledUsers type with parsed template
-// already set.
-func NewDisabledUsers(path string, entrySuffix string, permissions os.FileMode) *DisabledUsers {
-
-	// parse template for disabled users file, provide a ToLower template
-	// function that can be used to case-fold values written to the disabled
-	// users file
-	disabledUsersFileTemplate := template.Must(
-		template.New(
-			"disabledUsersFileTemplate",
-		).Funcs(
-			template.FuncMap{
-				"ToLower": strings.ToLower,
-			},
-		).Parse(
-			disabledUsersFileTemplateText,
-		),
-	)
+// NewDisabledUsers constructs a DisabledUsers type with the provided parsed
+// templates already set.
+func NewDisabledUsers(
+	path string,
+	entrySuffix string,
+	templates DisabledUsersTemplates,
+	permissions os.FileMode,
+) *DisabledUsers {
 
 	du := DisabledUsers{
 		FlatFile: FlatFile{
 			FilePath:        path,
 			FilePermissions: permissions,
 		},
-		Template:    disabledUsersFileTemplate,
-		EntrySuffix: entrySuffix,
+		Template:           templates.Entry,
+		QuarantineTemplate: templates.Quarantine,
+		EntrySuffix:        entrySuffix,
 	}
 
 	return &du
 
 }
 
+// NewDisabledUsersTemplates parses the user-provided template files used to
+// generate disabled users file entries. The built-in template is used if the
+// entry template file is not provided; the quarantine template is left unset
+// if its file is not provided. Each template is rendered using a sample alert
+// to confirm that it produces at least one entry for the username.
+func NewDisabledUsersTemplates(
+	entryTemplateFile string,
+	quarantineTemplateFile string,
+	entrySuffix string,
+) (DisabledUsersTemplates, error) {
+
+	var templates DisabledUsersTemplates
+
+	entryTemplateText := disabledUsersFileTemplateText
+	if entryTemplateFile != "" {
+		text, err := readTemplateFile(entryTemplateFile)
+		if err != nil {
+			return DisabledUsersTemplates{}, err
+		}
+		entryTemplateText = text
+	}
+
+	entryTemplate, err := parseDisabledUsersTemplate(
+		"disabledUsersFileTemplate",
+		entryTemplateText,
+		entrySuffix,
+	)
+	if err != nil {
+		return DisabledUsersTemplates{}, fmt.Errorf(
+			"invalid disabled users entry template: %w",
+			err,
+		)
+	}
+	templates.Entry = entryTemplate
+
+	if quarantineTemplateFile != "" {
+		text, err := readTemplateFile(quarantineTemplateFile)
+		if err != nil {
+			return DisabledUsersTemplates{}, err
+		}
+
+		quarantineTemplate, err := parseDisabledUsersTemplate(
+			"disabledUsersQuarantineTemplate",
+			text,
+			entrySuffix,
+		)
+		if err != nil {
+			return DisabledUsersTemplates{}, fmt.Errorf(
+				"invalid disabled users quarantine template: %w",
+				err,
+			)
+		}
+		templates.Quarantine = quarantineTemplate
+	}
+
+	return templates, nil
+
+}
+
+// readTemplateFile returns the contents of the specified template file.
+func readTemplateFile(filename string) (string, error) {
+
+	// Reading this file via variable is intentional; sysadmins provide
+	// site-specific templates.
+	//
+	// #nosec G304
+	content, err := ioutil.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return "", fmt.Errorf(
+			"error reading template file %q: %w",
+			filename,
+			err,
+		)
+	}
+
+	return string(content), nil
+}
+
+// parseDisabledUsersTemplate parses the provided disabled users file template
+// text and renders it using a sample alert to confirm that it is usable. A
+// ToLower template function is provided so that values written to the
+// disabled users file can be case-folded.
+func parseDisabledUsersTemplate(name string, text string, entrySuffix string) (*template.Template, error) {
+
+	tmpl, err := template.New(name).Funcs(
+		template.FuncMap{
+			"ToLower": strings.ToLower,
+		},
+	).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := disabledUsersEntries(sampleAlert(), entrySuffix, tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf(
+			"template does not render an entry containing the username",
+		)
+	}
+
+	return tmpl, nil
+}
+
+// sampleAlert returns an alert used to render disabled users file templates
+// for validation and to determine the format of rendered entries. The
+// username is already lowercase so that it is unaffected by case-folding.
+func sampleAlert() events.SplunkAlertEvent {
+	return events.SplunkAlertEvent{
+		Username:        sampleAlertUsername,
+		UserIP:          "192.0.2.1",
+		AlertName:       "sample alert",
+		SearchID:        "sample",
+		PayloadSenderIP: "192.0.2.2",
+		ArrivalTime:     "2006-01-02T15:04:05Z",
+		LocalTime:       "2006-01-02 15:04:05",
+	}
+}
+
+// disabledUsersEntries renders the provided disabled users file template for
+// the alert and returns the entries which refer to the reported username.
+// Blank lines, comments and lines which do not contain the username (e.g.,
+// EZproxy group directives) are skipped.
+func disabledUsersEntries(alert events.SplunkAlertEvent, entrySuffix string, tmpl *template.Template) ([]string, error) {
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, fileEntry{
+		Alert:       alert,
+		EntrySuffix: entrySuffix,
+	}); err != nil {
+		return nil, fmt.Errorf("error rendering template: %w", err)
+	}
+
+	username := strings.ToLower(alert.Username)
+
+	var entries []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.Contains(strings.ToLower(line), username) {
+			entries = append(entries, line)
+		}
+	}
+
+	return entries, nil
+}
+
 // NewIgnoredSources constructs an IgnoredSources type
 func NewIgnoredSources(
 	ignoredUsersFile string,
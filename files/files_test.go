@@ -0,0 +1,226 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atc0005/brick/events"
+)
+
+// writeTemplateFile writes the provided template text to a file in a
+// temporary directory and returns its path. An empty path is returned for
+// empty text so that the built-in (or no) template is used.
+func writeTemplateFile(t *testing.T, text string) string {
+
+	t.Helper()
+
+	if text == "" {
+		return ""
+	}
+
+	path := filepath.Join(t.TempDir(), "disabled-users.tmpl")
+	if err := ioutil.WriteFile(path, []byte(text), 0600); err != nil {
+		t.Fatalf("failed to write template file: %v", err)
+	}
+
+	return path
+}
+
+func TestNewDisabledUsersTemplates(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		entry          string
+		quarantine     string
+		wantErr        bool
+		wantEntry      string
+		wantQuarantine string
+	}{
+		{
+			name:      "built-in entry template",
+			wantEntry: "brick-sample-user::deny",
+		},
+		{
+			name:           "custom entry and quarantine templates",
+			entry:          "{{ ToLower .Alert.Username }}::Expired\n",
+			quarantine:     "# quarantined\n{{ .Alert.Username }}{{ .EntrySuffix }}::Group=Quarantine\n",
+			wantEntry:      "brick-sample-user::Expired",
+			wantQuarantine: "brick-sample-user::deny::Group=Quarantine",
+		},
+		{
+			name:    "entry template without username",
+			entry:   "# disabled at {{ .Alert.ArrivalTime }}\n::DENY\n",
+			wantErr: true,
+		},
+		{
+			name:       "quarantine template without username",
+			quarantine: "Group Quarantine\n",
+			wantErr:    true,
+		},
+		{
+			name:    "entry template syntax error",
+			entry:   "{{ .Alert.Username \n",
+			wantErr: true,
+		},
+		{
+			name:    "entry template unknown field",
+			entry:   "{{ .Alert.Email }}\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			templates, err := NewDisabledUsersTemplates(
+				writeTemplateFile(t, tt.entry),
+				writeTemplateFile(t, tt.quarantine),
+				"::deny",
+			)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewDisabledUsersTemplates() returned error %v; want error: %t", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			entries, err := disabledUsersEntries(sampleAlert(), "::deny", templates.Entry)
+			if err != nil {
+				t.Fatalf("failed to render entry template: %v", err)
+			}
+			if got := strings.Join(entries, "\n"); got != tt.wantEntry {
+				t.Errorf("entry template rendered %q; want %q", got, tt.wantEntry)
+			}
+
+			if (templates.Quarantine != nil) != (tt.wantQuarantine != "") {
+				t.Fatalf("quarantine template set: %t; want %t", templates.Quarantine != nil, tt.wantQuarantine != "")
+			}
+
+			if templates.Quarantine != nil {
+				entries, err := disabledUsersEntries(sampleAlert(), "::deny", templates.Quarantine)
+				if err != nil {
+					t.Fatalf("failed to render quarantine template: %v", err)
+				}
+				if got := strings.Join(entries, "\n"); got != tt.wantQuarantine {
+					t.Errorf("quarantine template rendered %q; want %q", got, tt.wantQuarantine)
+				}
+			}
+		})
+	}
+
+	if _, err := NewDisabledUsersTemplates(filepath.Join(t.TempDir(), "missing.tmpl"), "", ""); err == nil {
+		t.Error("NewDisabledUsersTemplates() returned no error for missing template file")
+	}
+}
+
+func TestDisableUserQuarantine(t *testing.T) {
+
+	templates, err := NewDisabledUsersTemplates(
+		"",
+		writeTemplateFile(t, "{{ ToLower .Alert.Username }}::Group=Quarantine\n"),
+		"::DENY",
+	)
+	if err != nil {
+		t.Fatalf("NewDisabledUsersTemplates() returned error: %v", err)
+	}
+
+	disablePolicy := &events.AlertPolicy{Name: "disable", Action: events.PolicyActionDisable}
+	quarantinePolicy := &events.AlertPolicy{Name: "quarantine", Action: events.PolicyActionQuarantine}
+
+	tests := []struct {
+		name                 string
+		policy               *events.AlertPolicy
+		quarantineTemplate   bool
+		wantErr              bool
+		wantLine             string
+		wantDisabled         bool
+		wantQuarantineStatus bool
+	}{
+		{
+			name:                 "disable",
+			policy:               disablePolicy,
+			wantLine:             "jsmith::DENY",
+			wantDisabled:         true,
+			wantQuarantineStatus: true,
+		},
+		{
+			name:                 "quarantine",
+			policy:               quarantinePolicy,
+			quarantineTemplate:   true,
+			wantLine:             "jsmith::Group=Quarantine",
+			wantDisabled:         false,
+			wantQuarantineStatus: true,
+		},
+		{
+			name:    "quarantine without quarantine template",
+			policy:  quarantinePolicy,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			path := filepath.Join(t.TempDir(), "ezproxy.users.disabled")
+			instanceTemplates := DisabledUsersTemplates{Entry: templates.Entry}
+			if tt.quarantineTemplate {
+				instanceTemplates.Quarantine = templates.Quarantine
+			}
+			disabledUsers := NewDisabledUsers(path, "::DENY", instanceTemplates, 0600)
+
+			alert := events.SplunkAlertEvent{Username: "JSmith", UserIP: "192.0.2.10", Policy: tt.policy}
+			if err := disableUser(alert, disabledUsers); (err != nil) != tt.wantErr {
+				t.Fatalf("disableUser() returned error %v; want error: %t", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read disabled users file: %v", err)
+			}
+			if !strings.Contains("\n"+string(content), "\n"+tt.wantLine+"\n") {
+				t.Errorf("disabled users file = %q; want it to contain line %q", content, tt.wantLine)
+			}
+
+			// an existing disable entry also satisfies quarantine policies
+			for policy, want := range map[*events.AlertPolicy]bool{
+				disablePolicy:    tt.wantDisabled,
+				quarantinePolicy: tt.wantQuarantineStatus,
+			} {
+				alert.Policy = policy
+				disabledUsers.QuarantineTemplate = templates.Quarantine
+				got, err := isDisabled(alert, disabledUsers)
+				if err != nil {
+					t.Fatalf("isDisabled() returned error: %v", err)
+				}
+				if got != want {
+					t.Errorf("isDisabled() for policy %q = %t; want %t", policy.Name, got, want)
+				}
+			}
+		})
+	}
+}
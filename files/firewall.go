@@ -0,0 +1,399 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/internal/caller"
+)
+
+// firewallFilePermissions is applied to the firewall state file when it is
+// first created.
+const firewallFilePermissions os.FileMode = 0644
+
+// firewallCheckInterval is the longest time between checks for expired
+// blocks.
+const firewallCheckInterval time.Duration = time.Minute
+
+// Firewall blocks the IP Address reported for disabled user accounts using a
+// host firewall backend and unblocks it once the block duration has passed. Blocks are persisted to a state file so that IP
+// Addresses are unblocked even if the application is restarted in the
+// meantime.
+type Firewall struct {
+
+	// FilePath is the fully-qualified path to the JSON state file used to
+	// persist active blocks.
+	FilePath string
+
+	// Duration is how long each IP Address remains blocked.
+	Duration time.Duration
+
+	// Backend blocks and unblocks IP Addresses. The Firewall is disabled if
+	// nil.
+	Backend FirewallBackend
+
+	reportedUserEventsLog *ReportedUserEventsLog
+	ignoredSources        IgnoredSources
+	notifyWorkQueue       chan<- events.Record
+
+	mutex           *sync.Mutex
+	unblockFailures map[string]struct{}
+
+	// peers is the set of firewalls (including this one) which use the same
+	// firewall backend; see ShareFirewallBackend.
+	peers []*Firewall
+}
+
+// ipAddressBlock is a single blocked IP Address along with the alert which
+// caused the block. It is persisted by both the Firewall and the Blocklist.
+type ipAddressBlock struct {
+	Alert   events.SplunkAlertEvent `json:"alert"`
+	Blocked time.Time               `json:"blocked"`
+	Expires time.Time               `json:"expires"`
+}
+
+// NewFirewall constructs a new Firewall value using the provided state file,
+// block duration and backend. The reported users log and ignored sources of
+// the tenant are used to record block events and to honor ignored IP
+// Addresses. The Run method must be called to unblock expired blocks.
+func NewFirewall(
+	path string,
+	duration time.Duration,
+	backend FirewallBackend,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	ignoredSources IgnoredSources,
+	notifyWorkQueue chan<- events.Record,
+) *Firewall {
+	return &Firewall{
+		FilePath:              path,
+		Duration:              duration,
+		Backend:               backend,
+		reportedUserEventsLog: reportedUserEventsLog,
+		ignoredSources:        ignoredSources,
+		notifyWorkQueue:       notifyWorkQueue,
+		mutex:                 &sync.Mutex{},
+		unblockFailures:       make(map[string]struct{}),
+	}
+}
+
+// ShareFirewallBackend links the provided firewalls (e.g., those of each
+// tenant) which block IP Addresses using the same firewall backend, such as
+// the same ipset set or fail2ban jail. An IP Address blocked by more than one
+// of them is only removed from the firewall backend once none of them blocks
+// it any longer. The firewalls share a single lock so that the state files of
+// the others may be checked while unblocking. This must be called before any
+// of the firewalls are used.
+func ShareFirewallBackend(firewalls ...*Firewall) {
+
+	mutex := &sync.Mutex{}
+	for _, fw := range firewalls {
+		fw.mutex = mutex
+		fw.peers = firewalls
+	}
+}
+
+// Enabled indicates whether a firewall backend has been configured.
+func (fw *Firewall) Enabled() bool {
+	return fw != nil && fw.Backend != nil
+}
+
+// Run unblocks IP Addresses whose block has expired (or which have since
+// been added to the ignored IP Addresses list) until the provided context is
+// cancelled. This is intended to be run as a goroutine.
+func (fw *Firewall) Run(ctx context.Context) {
+
+	if !fw.Enabled() {
+		log.Debug("Firewall: firewall backend not enabled")
+		return
+	}
+
+	interval := firewallCheckInterval
+	if fw.Duration > 0 && fw.Duration < interval {
+		interval = fw.Duration
+	}
+
+	log.Debugf(
+		"%s: checking for expired %s blocks every %v",
+		caller.GetFuncName(),
+		fw.Backend.Name(),
+		interval,
+	)
+
+	// blocks may have expired while the application was not running
+	fw.unblockExpired()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("Firewall: context cancelled; stopping")
+			return
+		case <-ticker.C:
+			fw.unblockExpired()
+		}
+	}
+}
+
+// Block blocks the user IP Address from the provided alert for the
+// configured duration. If the IP Address is already blocked, the block is
+// extended. IP Addresses listed in the ignored IP Addresses file for the
+// tenant are not blocked. The result is logged, recorded in the reported
+// users log and sent as a notification.
+func (fw *Firewall) Block(alert events.SplunkAlertEvent) {
+
+	if !fw.Enabled() {
+		return
+	}
+
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+
+	if net.ParseIP(alert.UserIP) == nil {
+		processRecord(events.NewRecord(
+			alert,
+			fmt.Errorf("%q is not a valid IP Address", alert.UserIP),
+			"",
+			events.ActionFailureBlockedIPAddress,
+			nil,
+		), fw.notifyWorkQueue)
+
+		return
+	}
+
+	ignored, err := isIgnoredIPAddress(alert, fw.ignoredSources)
+	switch {
+	case err != nil:
+		processRecord(events.NewRecord(
+			alert,
+			fmt.Errorf(
+				"error while checking ignored status for IP %q; not blocking IP Address: %w",
+				alert.UserIP,
+				err,
+			),
+			"",
+			events.ActionFailureBlockedIPAddress,
+			nil,
+		), fw.notifyWorkQueue)
+
+		return
+
+	case ignored:
+		log.Infof(
+			"IP %q associated with user %q is ignored; not blocking IP Address",
+			alert.UserIP,
+			alert.Username,
+		)
+
+		return
+	}
+
+	state, err := fw.read()
+	if err != nil {
+		processRecord(events.NewRecord(
+			alert,
+			err,
+			"",
+			events.ActionFailureBlockedIPAddress,
+			nil,
+		), fw.notifyWorkQueue)
+
+		return
+	}
+
+	now := time.Now()
+	block := ipAddressBlock{
+		Alert:   alert,
+		Blocked: now,
+		Expires: now.Add(fw.Duration),
+	}
+
+	// keep the original block time when extending an existing block
+	if existing, ok := state[alert.UserIP]; ok {
+		block.Blocked = existing.Blocked
+	}
+
+	if err := fw.Backend.Block(alert.UserIP); err != nil {
+		log.Errorf(
+			"Failed to block IP %q associated with username %q via %s: %v",
+			alert.UserIP,
+			alert.Username,
+			fw.Backend.Name(),
+			err,
+		)
+
+		processRecord(events.NewRecord(
+			alert,
+			err,
+			fmt.Sprintf("Failed to block IP %q via %s", alert.UserIP, fw.Backend.Name()),
+			events.ActionFailureBlockedIPAddress,
+			nil,
+		), fw.notifyWorkQueue)
+
+		return
+	}
+
+	state[alert.UserIP] = block
+
+	var stateErr error
+	if err := fw.write(state); err != nil {
+		stateErr = fmt.Errorf(
+			"IP Address blocked, but will not be unblocked automatically: %w",
+			err,
+		)
+	}
+
+	processRecord(
+		logEventBlockedIPAddress(alert, fw.reportedUserEventsLog, fw.Backend.Name(), block.Expires, stateErr),
+		fw.notifyWorkQueue,
+	)
+}
+
+// unblockExpired unblocks IP Addresses whose block has expired or which
+// have been added to the ignored IP Addresses list since they were blocked.
+// Blocks which fail to be removed are retried at the next check; only the
+// first failure for each IP Address is sent as a notification.
+func (fw *Firewall) unblockExpired() {
+
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+
+	state, err := fw.read()
+	if err != nil {
+		log.Errorf("failed to check for expired blocks: %v", err)
+		return
+	}
+
+	now := time.Now()
+
+	ipAddresses := make([]string, 0, len(state))
+	for ipAddress := range state {
+		ipAddresses = append(ipAddresses, ipAddress)
+	}
+	sort.Strings(ipAddresses)
+
+	var changed bool
+	for _, ipAddress := range ipAddresses {
+
+		block := state[ipAddress]
+
+		reason := "block expired"
+		if now.Before(block.Expires) {
+			ignored, err := isIgnoredIPAddress(block.Alert, fw.ignoredSources)
+			if err != nil || !ignored {
+				continue
+			}
+			reason = "IP Address is now ignored"
+		}
+
+		alert := block.Alert
+		alert.ArrivalTime = now.Format(time.RFC3339)
+		alert.LocalTime = now.Format("2006-01-02 15:04:05")
+
+		// leave the IP Address in the shared firewall backend while
+		// another firewall still blocks it; that firewall removes it
+		blockedByPeer, peerErr := fw.blockedByPeer(ipAddress)
+
+		var unblockErr error
+		switch {
+		case peerErr != nil:
+			unblockErr = peerErr
+		case blockedByPeer:
+			reason += "; IP Address remains blocked for another tenant"
+		default:
+			unblockErr = fw.Backend.Unblock(ipAddress)
+		}
+
+		if unblockErr != nil {
+			if _, reported := fw.unblockFailures[ipAddress]; reported {
+				log.Warnf("Failed to unblock IP %q (retrying): %v", ipAddress, unblockErr)
+				continue
+			}
+			fw.unblockFailures[ipAddress] = struct{}{}
+		}
+
+		processRecord(
+			logEventUnblockedIPAddress(alert, fw.reportedUserEventsLog, fw.Backend.Name(), reason, unblockErr),
+			fw.notifyWorkQueue,
+		)
+
+		if unblockErr != nil {
+			continue
+		}
+
+		delete(fw.unblockFailures, ipAddress)
+		delete(state, ipAddress)
+		changed = true
+	}
+
+	if changed {
+		if err := fw.write(state); err != nil {
+			log.Errorf("failed to update firewall state file: %v", err)
+		}
+	}
+}
+
+// blockedByPeer indicates whether the provided IP Address is still blocked
+// by another firewall using the same firewall backend. The lock shared by the
+// firewalls is expected to be held.
+func (fw *Firewall) blockedByPeer(ipAddress string) (bool, error) {
+
+	for _, peer := range fw.peers {
+		if peer == fw {
+			continue
+		}
+
+		state, err := peer.read()
+		if err != nil {
+			return false, fmt.Errorf(
+				"failed to check whether IP Address is blocked for another tenant: %w",
+				err,
+			)
+		}
+
+		if _, ok := state[ipAddress]; ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// read loads the firewall state file. A missing state file is treated as no
+// active blocks.
+func (fw *Firewall) read() (map[string]ipAddressBlock, error) {
+	state := make(map[string]ipAddressBlock)
+	err := loadJSONState(fw.FilePath, "firewall state", &state)
+
+	return state, err
+}
+
+// write replaces the firewall state file with the provided state.
+func (fw *Firewall) write(state map[string]ipAddressBlock) error {
+	return saveJSONState(fw.FilePath, "firewall state", state, firewallFilePermissions)
+}
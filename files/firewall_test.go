@@ -0,0 +1,141 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/atc0005/brick/events"
+)
+
+// recordingFirewallBackend is a FirewallBackend which records the IP
+// Addresses it is asked to block and unblock.
+type recordingFirewallBackend struct {
+	mutex     sync.Mutex
+	blocked   []string
+	unblocked []string
+}
+
+func (fw *recordingFirewallBackend) Name() string {
+	return "recording"
+}
+
+func (fw *recordingFirewallBackend) Block(ipAddress string) error {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	fw.blocked = append(fw.blocked, ipAddress)
+
+	return nil
+}
+
+func (fw *recordingFirewallBackend) Unblock(ipAddress string) error {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	fw.unblocked = append(fw.unblocked, ipAddress)
+
+	return nil
+}
+
+func (fw *recordingFirewallBackend) unblockedIPAddresses() []string {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+
+	return append([]string(nil), fw.unblocked...)
+}
+
+// expireFirewallBlock marks the block for the provided IP Address as expired.
+func expireFirewallBlock(t *testing.T, fw *Firewall, ipAddress string) {
+	t.Helper()
+
+	state, err := fw.read()
+	if err != nil {
+		t.Fatalf("failed to read state: %v", err)
+	}
+
+	block, ok := state[ipAddress]
+	if !ok {
+		t.Fatalf("IP Address %q not blocked in %s", ipAddress, fw.FilePath)
+	}
+	block.Expires = time.Now().Add(-time.Minute)
+	state[ipAddress] = block
+
+	if err := fw.write(state); err != nil {
+		t.Fatalf("failed to write state: %v", err)
+	}
+}
+
+func TestShareFirewallBackend(t *testing.T) {
+
+	dir := t.TempDir()
+	backend := &recordingFirewallBackend{}
+	notifyWorkQueue := make(chan events.Record, 10)
+
+	newFirewall := func(tenant string) *Firewall {
+		return NewFirewall(
+			filepath.Join(dir, "firewall-blocks-"+tenant+".json"),
+			time.Hour,
+			backend,
+			NewReportedUserEventsLog(filepath.Join(dir, "users-"+tenant+".log"), 0600),
+			IgnoredSources{},
+			notifyWorkQueue,
+		)
+	}
+
+	fwA := newFirewall("library-a")
+	fwB := newFirewall("library-b")
+	ShareFirewallBackend(fwA, fwB)
+
+	fwA.Block(events.SplunkAlertEvent{Username: "jsmith", UserIP: "192.168.1.10", Tenant: "library-a"})
+	fwB.Block(events.SplunkAlertEvent{Username: "jdoe", UserIP: "192.168.1.10", Tenant: "library-b"})
+	fwB.Block(events.SplunkAlertEvent{Username: "jdoe", UserIP: "192.168.1.20", Tenant: "library-b"})
+
+	// the block of the other tenant keeps the IP Address in the backend
+	expireFirewallBlock(t, fwA, "192.168.1.10")
+	fwA.unblockExpired()
+
+	if got := backend.unblockedIPAddresses(); len(got) != 0 {
+		t.Errorf("unblocked %v while blocked for another tenant; want none", got)
+	}
+
+	state, err := fwA.read()
+	if err != nil {
+		t.Fatalf("failed to read state: %v", err)
+	}
+	if len(state) != 0 {
+		t.Errorf("state of expired tenant = %v; want no blocks", state)
+	}
+
+	// blocks of unrelated IP Addresses are unaffected
+	expireFirewallBlock(t, fwB, "192.168.1.20")
+	fwB.unblockExpired()
+
+	if got, want := backend.unblockedIPAddresses(), []string{"192.168.1.20"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unblocked %v; want %v", got, want)
+	}
+
+	// the last tenant blocking the IP Address removes it
+	expireFirewallBlock(t, fwB, "192.168.1.10")
+	fwB.unblockExpired()
+
+	if got, want := backend.unblockedIPAddresses(), []string{"192.168.1.20", "192.168.1.10"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unblocked %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/internal/caller"
+)
+
+// FirewallBackend blocks and unblocks IP Addresses using a host firewall.
+type FirewallBackend interface {
+
+	// Name returns a short name for the backend used in log messages and
+	// notifications.
+	Name() string
+
+	// Block adds the IP Address to the set of blocked IP Addresses.
+	Block(ipAddress string) error
+
+	// Unblock removes the IP Address from the set of blocked IP Addresses.
+	Unblock(ipAddress string) error
+}
+
+// IPSetFirewall blocks IP Addresses by adding them to an existing ipset set.
+// The set is expected to be referenced by an iptables rule which drops
+// traffic from its members.
+type IPSetFirewall struct {
+
+	// Executable is the path to the ipset executable.
+	Executable string
+
+	// Set is the name of the ipset set.
+	Set string
+
+	// Timeout is the time limit for each run of the executable.
+	Timeout time.Duration
+}
+
+// Name returns a short name for the backend.
+func (fw IPSetFirewall) Name() string {
+	return "ipset"
+}
+
+// Block adds the IP Address to the set. IP Addresses already in the set are
+// not treated as an error.
+func (fw IPSetFirewall) Block(ipAddress string) error {
+	return runFirewallCommand(fw.Executable, fw.Timeout, "add", fw.Set, ipAddress, "-exist")
+}
+
+// Unblock removes the IP Address from the set. IP Addresses not in the set
+// are not treated as an error.
+func (fw IPSetFirewall) Unblock(ipAddress string) error {
+	return runFirewallCommand(fw.Executable, fw.Timeout, "del", fw.Set, ipAddress, "-exist")
+}
+
+// NftablesFirewall blocks IP Addresses by adding them to an existing
+// nftables set. The set is expected to be referenced by a rule which drops
+// traffic from its elements.
+type NftablesFirewall struct {
+
+	// Executable is the path to the nft executable.
+	Executable string
+
+	// Table is the address family and name of the table containing the set
+	// (e.g., "inet brick").
+	Table string
+
+	// Set is the name of the set.
+	Set string
+
+	// Timeout is the time limit for each run of the executable.
+	Timeout time.Duration
+}
+
+// Name returns a short name for the backend.
+func (fw NftablesFirewall) Name() string {
+	return "nftables"
+}
+
+// Block adds the IP Address to the set.
+func (fw NftablesFirewall) Block(ipAddress string) error {
+	return runFirewallCommand(fw.Executable, fw.Timeout, fw.elementArgs("add", ipAddress)...)
+}
+
+// Unblock removes the IP Address from the set.
+func (fw NftablesFirewall) Unblock(ipAddress string) error {
+	return runFirewallCommand(fw.Executable, fw.Timeout, fw.elementArgs("delete", ipAddress)...)
+}
+
+// elementArgs returns the nft arguments used to add or delete the IP Address
+// as an element of the set.
+func (fw NftablesFirewall) elementArgs(command string, ipAddress string) []string {
+
+	args := []string{command, "element"}
+	args = append(args, strings.Fields(fw.Table)...)
+	args = append(args, fw.Set, "{ "+ipAddress+" }")
+
+	return args
+}
+
+// Fail2banFirewall blocks IP Addresses by banning them in an existing
+// fail2ban jail using fail2ban-client. The jail determines how IP Addresses
+// are blocked.
+type Fail2banFirewall struct {
+
+	// Executable is the path to the fail2ban-client executable.
+	Executable string
+
+	// Jail is the name of the fail2ban jail.
+	Jail string
+
+	// Timeout is the time limit for each run of the executable.
+	Timeout time.Duration
+}
+
+// Name returns a short name for the backend.
+func (fw Fail2banFirewall) Name() string {
+	return "fail2ban"
+}
+
+// Block bans the IP Address in the jail.
+func (fw Fail2banFirewall) Block(ipAddress string) error {
+	return runFirewallCommand(fw.Executable, fw.Timeout, "set", fw.Jail, "banip", ipAddress)
+}
+
+// Unblock unbans the IP Address in the jail.
+func (fw Fail2banFirewall) Unblock(ipAddress string) error {
+	return runFirewallCommand(fw.Executable, fw.Timeout, "set", fw.Jail, "unbanip", ipAddress)
+}
+
+// runFirewallCommand runs the provided executable with the provided
+// arguments, stopping it if it does not exit within the timeout. Any output
+// is included in the returned error if the executable fails.
+func runFirewallCommand(executable string, timeout time.Duration, args ...string) error {
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Accepting a variable here is intentional; the path to the firewall
+	// executable is site-specific.
+	//
+	// nolint:gosec
+	cmd := exec.CommandContext(ctx, executable, args...)
+
+	log.Debugf("%s: Executing: %s", caller.GetFuncName(), strings.Join(cmd.Args, " "))
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	switch {
+	case ctx.Err() != nil:
+		return fmt.Errorf(
+			"%s did not exit within %v: %w",
+			executable,
+			timeout,
+			ctx.Err(),
+		)
+	case err != nil && strings.TrimSpace(output.String()) != "":
+		return fmt.Errorf(
+			"%s %s failed: %w (output: %s)",
+			executable,
+			strings.Join(args, " "),
+			err,
+			strings.TrimSpace(output.String()),
+		)
+	case err != nil:
+		return fmt.Errorf(
+			"%s %s failed: %w",
+			executable,
+			strings.Join(args, " "),
+			err,
+		)
+	}
+
+	return nil
+}
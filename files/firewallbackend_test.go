@@ -0,0 +1,184 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeFirewallExecutable writes a shell script standing in for a firewall
+// executable and returns its path. The script records its arguments, one
+// per line, and then runs the provided shell commands.
+func fakeFirewallExecutable(t *testing.T, commands string) string {
+
+	t.Helper()
+
+	return writeTestExecutable(t, "firewall", "printf '%s\\n' \"$@\" > \"$0.args\"\n"+commands+"\n")
+}
+
+// fakeFirewallArgs returns the arguments recorded by the fake firewall
+// executable at the specified path.
+func fakeFirewallArgs(t *testing.T, path string) []string {
+
+	t.Helper()
+
+	content, err := ioutil.ReadFile(path + ".args")
+	if err != nil {
+		t.Fatalf("failed to read arguments recorded by fake firewall executable: %v", err)
+	}
+
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+func TestFirewallBackendArgs(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		backend func(executable string) FirewallBackend
+		block   bool
+		want    []string
+	}{
+		{
+			name: "ipset block",
+			backend: func(executable string) FirewallBackend {
+				return IPSetFirewall{Executable: executable, Set: "brick-blocked"}
+			},
+			block: true,
+			want:  []string{"add", "brick-blocked", "192.168.2.3", "-exist"},
+		},
+		{
+			name: "ipset unblock",
+			backend: func(executable string) FirewallBackend {
+				return IPSetFirewall{Executable: executable, Set: "brick-blocked"}
+			},
+			want: []string{"del", "brick-blocked", "192.168.2.3", "-exist"},
+		},
+		{
+			name: "nftables block",
+			backend: func(executable string) FirewallBackend {
+				return NftablesFirewall{Executable: executable, Table: "inet  brick", Set: "blocked"}
+			},
+			block: true,
+			want:  []string{"add", "element", "inet", "brick", "blocked", "{ 192.168.2.3 }"},
+		},
+		{
+			name: "nftables unblock",
+			backend: func(executable string) FirewallBackend {
+				return NftablesFirewall{Executable: executable, Table: "inet brick", Set: "blocked"}
+			},
+			want: []string{"delete", "element", "inet", "brick", "blocked", "{ 192.168.2.3 }"},
+		},
+		{
+			name: "fail2ban block",
+			backend: func(executable string) FirewallBackend {
+				return Fail2banFirewall{Executable: executable, Jail: "ezproxy"}
+			},
+			block: true,
+			want:  []string{"set", "ezproxy", "banip", "192.168.2.3"},
+		},
+		{
+			name: "fail2ban unblock",
+			backend: func(executable string) FirewallBackend {
+				return Fail2banFirewall{Executable: executable, Jail: "ezproxy"}
+			},
+			want: []string{"set", "ezproxy", "unbanip", "192.168.2.3"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			executable := fakeFirewallExecutable(t, "exit 0")
+			backend := tt.backend(executable)
+
+			run := backend.Unblock
+			if tt.block {
+				run = backend.Block
+			}
+
+			if err := run("192.168.2.3"); err != nil {
+				t.Fatalf("%s returned error: %v", tt.name, err)
+			}
+
+			got := fakeFirewallArgs(t, executable)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("%s ran executable with arguments %q; want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunFirewallCommandErrors(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		commands    string
+		missing     bool
+		wantOutput  string
+		wantTimeout bool
+	}{
+		{
+			name:       "failure with output",
+			commands:   "echo 'ipset v7.1: The set with the given name does not exist' >&2; exit 1",
+			wantOutput: "(output: ipset v7.1: The set with the given name does not exist)",
+		},
+		{
+			name:     "failure without output",
+			commands: "exit 2",
+		},
+		{
+			name:        "timeout",
+			commands:    "exec sleep 5",
+			wantTimeout: true,
+		},
+		{
+			name:    "missing executable",
+			missing: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			executable := filepath.Join(t.TempDir(), "missing")
+			if !tt.missing {
+				executable = fakeFirewallExecutable(t, tt.commands)
+			}
+
+			err := runFirewallCommand(executable, 200*time.Millisecond, "add", "brick-blocked", "192.168.2.3")
+			if err == nil {
+				t.Fatal("runFirewallCommand() returned nil error")
+			}
+
+			if !strings.Contains(err.Error(), tt.wantOutput) {
+				t.Errorf("runFirewallCommand() error = %q; want output %q", err, tt.wantOutput)
+			}
+
+			if tt.wantTimeout != errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("runFirewallCommand() error = %v; want timeout: %t", err, tt.wantTimeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestExecutable writes the provided shell commands as a script named
+// after the external command it stands in for and returns its path. Each
+// call uses a new temporary directory, so scripts may record state next to
+// themselves (e.g., "$0.args").
+func writeTestExecutable(t *testing.T, name string, commands string) string {
+
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+
+	// The script is run in place of the external command.
+	//
+	// #nosec G306
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+commands), 0700); err != nil {
+		t.Fatalf("failed to write fake %s executable: %v", name, err)
+	}
+
+	return path
+}
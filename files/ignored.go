@@ -0,0 +1,670 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/internal/caller"
+	"github.com/atc0005/brick/internal/fileutils"
+)
+
+// Ignored entries may optionally carry metadata in addition to the username
+// or IP Address value. Fields are separated by a pipe character and metadata
+// fields are provided as key=value pairs. For example:
+//
+//	zzzlok | expires=2020-10-31 | owner=jsmith | reason=stanza testing
+const (
+	ignoredEntryFieldDelimiter    string = "|"
+	ignoredEntryKeyValueDelimiter string = "="
+	ignoredEntryCommentPrefix     string = "#"
+
+	ignoredEntryKeyExpires string = "expires"
+	ignoredEntryKeyOwner   string = "owner"
+	ignoredEntryKeyReason  string = "reason"
+)
+
+// Entry values are compared literally unless they begin with one of these
+// markers. The marker is required so that existing entries containing
+// pattern characters or a slash keep their literal meaning. For example:
+//
+//	glob:zzz*
+//	cidr:192.168.10.0/24
+const (
+	ignoredEntryGlobPrefix string = "glob:"
+	ignoredEntryCIDRPrefix string = "cidr:"
+)
+
+// ignoredEntriesFilePermissions is applied to ignored entries files created
+// by this application. Existing files retain their current permissions.
+const ignoredEntriesFilePermissions os.FileMode = 0644
+
+// ignoredEntriesFileMutex serializes updates to the ignored entries files so
+// that concurrent API requests do not overwrite each other's changes.
+var ignoredEntriesFileMutex = &sync.Mutex{}
+
+// ignoredEntryDateLayout is the date-only layout accepted for the expires
+// field. Entries using this layout remain valid through the end of the
+// specified day (local time).
+const ignoredEntryDateLayout string = "2006-01-02"
+
+// ignoredEntryTimeLayouts is the list of additional layouts accepted for the
+// expires field, tried in order.
+var ignoredEntryTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+}
+
+// IgnoredEntry represents a single entry from either of the ignored user
+// accounts or ignored IP Addresses files. Aside from the username or IP
+// Address value, all fields are optional.
+type IgnoredEntry struct {
+
+	// Value is the username or IP Address that should be ignored.
+	Value string
+
+	// Expires is the time after which this entry no longer matches. The zero
+	// value indicates that the entry does not expire.
+	Expires time.Time
+
+	// Owner is the person or team responsible for the entry.
+	Owner string
+
+	// Reason briefly explains why the entry was added.
+	Reason string
+}
+
+// ParseIgnoredEntry parses a single (non-comment) line from an ignored
+// entries file into an IgnoredEntry value. Leading and trailing whitespace
+// for each field is ignored. An error is returned if the line is empty,
+// contains an unknown metadata field or if the expires field cannot be
+// parsed.
+func ParseIgnoredEntry(line string) (IgnoredEntry, error) {
+
+	fields := strings.Split(line, ignoredEntryFieldDelimiter)
+
+	entry := IgnoredEntry{
+		Value: strings.TrimSpace(fields[0]),
+	}
+
+	if entry.Value == "" {
+		return IgnoredEntry{}, fmt.Errorf(
+			"missing value in ignored entry %q",
+			line,
+		)
+	}
+
+	for _, field := range fields[1:] {
+
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		kv := strings.SplitN(field, ignoredEntryKeyValueDelimiter, 2)
+		if len(kv) != 2 {
+			return IgnoredEntry{}, fmt.Errorf(
+				"invalid field %q in ignored entry %q; expected key=value",
+				field,
+				line,
+			)
+		}
+
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		value := strings.TrimSpace(kv[1])
+
+		switch key {
+		case ignoredEntryKeyExpires:
+			expires, err := ParseIgnoredEntryExpiration(value)
+			if err != nil {
+				return IgnoredEntry{}, fmt.Errorf(
+					"invalid expires field in ignored entry %q: %w",
+					line,
+					err,
+				)
+			}
+			entry.Expires = expires
+
+		case ignoredEntryKeyOwner:
+			entry.Owner = value
+
+		case ignoredEntryKeyReason:
+			entry.Reason = value
+
+		default:
+			return IgnoredEntry{}, fmt.Errorf(
+				"unknown field %q in ignored entry %q",
+				key,
+				line,
+			)
+		}
+	}
+
+	return entry, nil
+}
+
+// ParseIgnoredEntryExpiration converts the provided expires field value into
+// a time.Time value. A date-only value is treated as valid through the end
+// of that day.
+func ParseIgnoredEntryExpiration(value string) (time.Time, error) {
+
+	if t, err := time.ParseInLocation(ignoredEntryDateLayout, value, time.Local); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+
+	for _, layout := range ignoredEntryTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf(
+		"unable to parse %q as a date (%s) or timestamp (%s)",
+		value,
+		ignoredEntryDateLayout,
+		strings.Join(ignoredEntryTimeLayouts, ", "),
+	)
+}
+
+// Expired indicates whether the entry has an expiration time and whether
+// that time has passed as of the provided time.
+func (ie IgnoredEntry) Expired(now time.Time) bool {
+	return !ie.Expires.IsZero() && !now.Before(ie.Expires)
+}
+
+// HasMetadata indicates whether any of the optional fields are set for the
+// entry.
+func (ie IgnoredEntry) HasMetadata() bool {
+	return ie.Owner != "" || ie.Reason != "" || !ie.Expires.IsZero()
+}
+
+// Details returns a brief, human-readable summary of the optional fields set
+// for the entry. An empty string is returned if none are set.
+func (ie IgnoredEntry) Details() string {
+
+	var details []string
+
+	if ie.Owner != "" {
+		details = append(details, fmt.Sprintf("owner: %q", ie.Owner))
+	}
+
+	if ie.Reason != "" {
+		details = append(details, fmt.Sprintf("reason: %q", ie.Reason))
+	}
+
+	if !ie.Expires.IsZero() {
+		details = append(details, fmt.Sprintf("expires: %q", ie.Expires.Format(time.RFC3339)))
+	}
+
+	return strings.Join(details, ", ")
+}
+
+// String returns the entry in the line format used by the ignored entries
+// files.
+func (ie IgnoredEntry) String() string {
+
+	fields := []string{ie.Value}
+
+	if !ie.Expires.IsZero() {
+		fields = append(fields, ignoredEntryKeyExpires+ignoredEntryKeyValueDelimiter+ie.Expires.Format(time.RFC3339))
+	}
+
+	if ie.Owner != "" {
+		fields = append(fields, ignoredEntryKeyOwner+ignoredEntryKeyValueDelimiter+ie.Owner)
+	}
+
+	if ie.Reason != "" {
+		fields = append(fields, ignoredEntryKeyReason+ignoredEntryKeyValueDelimiter+ie.Reason)
+	}
+
+	return strings.Join(fields, " "+ignoredEntryFieldDelimiter+" ")
+}
+
+// findIgnoredEntry searches the specified ignored entries file for an
+// unexpired entry matching the provided search term. Matching is
+// case-insensitive; see ignoredValueMatches for CIDR and pattern support.
+// Lines beginning with a `#` character are ignored, as are expired entries.
+// Metadata is only parsed for entries whose value matches the search term;
+// malformed metadata for a matching entry is reported as an error.
+func findIgnoredEntry(searchTerm string, filename string) (IgnoredEntry, bool, error) {
+
+	myFuncName := caller.GetFuncName()
+
+	log.Debugf("%s: Attempting to open sanitized version of file %q",
+		myFuncName, filepath.Clean(filename))
+
+	f, err := os.Open(filepath.Clean(filename))
+	if err != nil {
+		return IgnoredEntry{}, false, fmt.Errorf(
+			"%s: error encountered opening file %q: %w",
+			myFuncName,
+			filename,
+			err,
+		)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			// Ignore "file already closed" errors
+			if !errors.Is(err, os.ErrClosed) {
+				log.Errorf(
+					"%s: failed to close file %q: %s",
+					myFuncName,
+					filename,
+					err.Error(),
+				)
+			}
+		}
+	}()
+
+	log.Debugf("%s: Searching for: %q", myFuncName, searchTerm)
+
+	now := time.Now()
+
+	s := bufio.NewScanner(f)
+	var lineno int
+	for s.Scan() {
+		lineno++
+
+		currentLine := strings.TrimSpace(s.Text())
+
+		if currentLine == "" || strings.HasPrefix(currentLine, ignoredEntryCommentPrefix) {
+			continue
+		}
+
+		value := strings.TrimSpace(
+			strings.SplitN(currentLine, ignoredEntryFieldDelimiter, 2)[0],
+		)
+
+		if !ignoredValueMatches(value, searchTerm) {
+			continue
+		}
+
+		entry, parseErr := ParseIgnoredEntry(currentLine)
+		if parseErr != nil {
+			return IgnoredEntry{}, false, fmt.Errorf(
+				"%s: error parsing line %d of file %q: %w",
+				myFuncName,
+				lineno,
+				filename,
+				parseErr,
+			)
+		}
+
+		if entry.Expired(now) {
+			log.Infof(
+				"%s: Skipping expired entry for %q on line %d of %q (%s)",
+				myFuncName,
+				searchTerm,
+				lineno,
+				filename,
+				entry.Details(),
+			)
+			continue
+		}
+
+		log.Debugf(
+			"%s: Match found on line %d, returning true to indicate this",
+			myFuncName,
+			lineno,
+		)
+
+		return entry, true, nil
+	}
+
+	// report any errors encountered while scanning the input file
+	if err := s.Err(); err != nil {
+		return IgnoredEntry{}, false, err
+	}
+
+	// explicitly close file, bail if failure occurs
+	if err := f.Close(); err != nil {
+		return IgnoredEntry{}, false, fmt.Errorf(
+			"%s: failed to close file %q: %w",
+			myFuncName,
+			filepath.Clean(filename),
+			err,
+		)
+	}
+
+	return IgnoredEntry{}, false, nil
+}
+
+// ignoredValueMatches indicates whether the value from an ignored entry
+// matches the provided search term. Values with the cidr: marker (e.g.,
+// cidr:192.168.10.0/24) match IP Addresses within that network, values with
+// the glob: marker (e.g., glob:zzz*) are matched as shell-style patterns and
+// all other values are compared literally. All comparisons are
+// case-insensitive.
+func ignoredValueMatches(value string, searchTerm string) bool {
+
+	if network, ok := cutIgnoredEntryPrefix(value, ignoredEntryCIDRPrefix); ok {
+		_, ipNet, err := net.ParseCIDR(network)
+		ip := net.ParseIP(searchTerm)
+		return err == nil && ip != nil && ipNet.Contains(ip)
+	}
+
+	if pattern, ok := cutIgnoredEntryPrefix(value, ignoredEntryGlobPrefix); ok {
+		matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(searchTerm))
+		return err == nil && matched
+	}
+
+	return strings.EqualFold(value, searchTerm)
+}
+
+// cutIgnoredEntryPrefix returns the provided value without the specified
+// (case-insensitive) marker and whether the value began with the marker.
+func cutIgnoredEntryPrefix(value string, prefix string) (string, bool) {
+
+	if len(value) < len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+		return value, false
+	}
+
+	return value[len(prefix):], true
+}
+
+// ValidateIgnoredUsername confirms that the provided value is suitable for
+// use as an ignored user accounts entry. Shell-style patterns are permitted
+// using the glob: marker (e.g., glob:zzz*).
+func ValidateIgnoredUsername(value string) error {
+
+	if err := validateIgnoredValue(value); err != nil {
+		return err
+	}
+
+	if pattern, ok := cutIgnoredEntryPrefix(value, ignoredEntryGlobPrefix); ok {
+		if pattern == "" {
+			return fmt.Errorf("empty username pattern provided")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid username pattern %q: %w", value, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateIgnoredIPAddress confirms that the provided value is suitable for
+// use as an ignored IP Addresses entry. Individual IP Addresses and networks
+// in CIDR notation using the cidr: marker (e.g., cidr:10.0.0.0/8) are
+// permitted.
+func ValidateIgnoredIPAddress(value string) error {
+
+	if err := validateIgnoredValue(value); err != nil {
+		return err
+	}
+
+	if net.ParseIP(value) != nil {
+		return nil
+	}
+
+	if network, ok := cutIgnoredEntryPrefix(value, ignoredEntryCIDRPrefix); ok {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			return fmt.Errorf("invalid CIDR network %q: %w", value, err)
+		}
+		return nil
+	}
+
+	if _, _, err := net.ParseCIDR(value); err == nil {
+		return fmt.Errorf(
+			"%q is not a valid IP Address; use %s%s to ignore a CIDR network",
+			value,
+			ignoredEntryCIDRPrefix,
+			value,
+		)
+	}
+
+	return fmt.Errorf("%q is not a valid IP Address or CIDR network", value)
+}
+
+// validateIgnoredValue applies the checks common to all ignored entry values
+// in order to ensure that the value can be safely written to, and read back
+// from, an ignored entries file.
+func validateIgnoredValue(value string) error {
+
+	switch {
+	case value == "":
+		return fmt.Errorf("empty value provided")
+	case strings.TrimSpace(value) != value || strings.ContainsAny(value, " \t\r\n"):
+		return fmt.Errorf("value %q contains whitespace", value)
+	case strings.Contains(value, ignoredEntryFieldDelimiter):
+		return fmt.Errorf(
+			"value %q contains reserved character %q",
+			value,
+			ignoredEntryFieldDelimiter,
+		)
+	case strings.HasPrefix(value, ignoredEntryCommentPrefix):
+		return fmt.Errorf(
+			"value %q begins with comment character %q",
+			value,
+			ignoredEntryCommentPrefix,
+		)
+	}
+
+	return nil
+}
+
+// Validate confirms that the optional fields for the entry can be safely
+// written to an ignored entries file. The Value field is validated separately
+// via ValidateIgnoredUsername or ValidateIgnoredIPAddress as the rules
+// differ between the two files.
+func (ie IgnoredEntry) Validate() error {
+
+	for name, field := range map[string]string{
+		ignoredEntryKeyOwner:  ie.Owner,
+		ignoredEntryKeyReason: ie.Reason,
+	} {
+		if strings.Contains(field, ignoredEntryFieldDelimiter) ||
+			strings.ContainsAny(field, "\r\n") {
+			return fmt.Errorf(
+				"%s field %q contains a line break or reserved character %q",
+				name,
+				field,
+				ignoredEntryFieldDelimiter,
+			)
+		}
+	}
+
+	return nil
+}
+
+// ReadIgnoredEntries returns all entries from the specified ignored entries
+// file, including expired entries. Comments and blank lines are skipped, as
+// are malformed entries, which are logged so that a single bad line does not
+// hide the rest of the list. A missing file is treated as an empty list.
+func ReadIgnoredEntries(filename string) ([]IgnoredEntry, error) {
+
+	myFuncName := caller.GetFuncName()
+
+	lines, err := readIgnoredEntriesFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]IgnoredEntry, 0, len(lines))
+	for idx, line := range lines {
+
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ignoredEntryCommentPrefix) {
+			continue
+		}
+
+		entry, parseErr := ParseIgnoredEntry(line)
+		if parseErr != nil {
+			log.Warnf(
+				"%s: skipping malformed entry on line %d of file %q: %v",
+				myFuncName,
+				idx+1,
+				filename,
+				parseErr,
+			)
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// AddIgnoredEntry adds the provided entry to the specified ignored entries
+// file. If an entry with the same value (case-insensitive) is already
+// present, it is replaced in-place and true is returned to indicate this.
+// Comments and other entries are preserved. The file is created if it does
+// not already exist.
+func AddIgnoredEntry(filename string, entry IgnoredEntry) (bool, error) {
+
+	ignoredEntriesFileMutex.Lock()
+	defer ignoredEntriesFileMutex.Unlock()
+
+	lines, err := readIgnoredEntriesFile(filename)
+	if err != nil {
+		return false, err
+	}
+
+	var replaced bool
+	for idx, line := range lines {
+		if ignoredEntryLineValueIs(line, entry.Value) {
+			lines[idx] = entry.String()
+			replaced = true
+			break
+		}
+	}
+
+	if !replaced {
+		lines = append(lines, entry.String())
+	}
+
+	return replaced, writeIgnoredEntriesFile(filename, lines)
+}
+
+// RemoveIgnoredEntry removes all entries from the specified ignored entries
+// file whose value matches (case-insensitive) the provided value. Comments
+// and other entries are preserved. False is returned if no matching entry
+// was found, in which case the file is left untouched.
+func RemoveIgnoredEntry(filename string, value string) (bool, error) {
+
+	ignoredEntriesFileMutex.Lock()
+	defer ignoredEntriesFileMutex.Unlock()
+
+	lines, err := readIgnoredEntriesFile(filename)
+	if err != nil {
+		return false, err
+	}
+
+	var removed bool
+	keep := lines[:0]
+	for _, line := range lines {
+		if ignoredEntryLineValueIs(line, value) {
+			removed = true
+			continue
+		}
+		keep = append(keep, line)
+	}
+
+	if !removed {
+		return false, nil
+	}
+
+	return true, writeIgnoredEntriesFile(filename, keep)
+}
+
+// ignoredEntryLineValueIs indicates whether the provided (non-comment) line
+// from an ignored entries file is an entry for the specified value. Unlike
+// ignoredValueMatches, CIDR and pattern entries are compared literally.
+func ignoredEntryLineValueIs(line string, value string) bool {
+
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, ignoredEntryCommentPrefix) {
+		return false
+	}
+
+	lineValue := strings.TrimSpace(
+		strings.SplitN(line, ignoredEntryFieldDelimiter, 2)[0],
+	)
+
+	return strings.EqualFold(lineValue, value)
+}
+
+// readIgnoredEntriesFile returns all lines from the specified ignored entries
+// file as-is. A missing file is treated as an empty file.
+func readIgnoredEntriesFile(filename string) ([]string, error) {
+
+	myFuncName := caller.GetFuncName()
+
+	content, err := ioutil.ReadFile(filepath.Clean(filename))
+	switch {
+	case os.IsNotExist(err):
+		log.Debugf("%s: %q does not exist, treating as empty", myFuncName, filename)
+		return []string{}, nil
+	case err != nil:
+		return nil, fmt.Errorf(
+			"%s: error encountered reading file %q: %w",
+			myFuncName,
+			filename,
+			err,
+		)
+	}
+
+	text := strings.TrimRight(string(content), "\n")
+	if text == "" {
+		return []string{}, nil
+	}
+
+	return strings.Split(text, "\n"), nil
+}
+
+// writeIgnoredEntriesFile replaces the content of the specified ignored
+// entries file with the provided lines. The file is replaced atomically so
+// that lookups performed while processing alerts never observe a partially
+// written file.
+func writeIgnoredEntriesFile(filename string, lines []string) error {
+
+	content := strings.Join(lines, "\n") + "\n"
+
+	return fileutils.WriteFileAtomic(
+		filename,
+		[]byte(content),
+		ignoredEntriesFilePermissions,
+	)
+}
+
+// isIgnoredIPAddress indicates whether the user IP Address from the provided
+// alert is listed in the provided ignored IP Addresses file.
+func isIgnoredIPAddress(alert events.SplunkAlertEvent, ignoredSources IgnoredSources) (bool, error) {
+
+	if ignoredSources.IgnoredIPAddressesFile == "" {
+		return false, nil
+	}
+
+	_, found, err := findIgnoredEntry(alert.UserIP, ignoredSources.IgnoredIPAddressesFile)
+
+	return found, err
+}
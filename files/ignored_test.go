@@ -0,0 +1,327 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIgnoredValueMatches(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		value      string
+		searchTerm string
+		want       bool
+	}{
+		{"literal username", "zzzlok", "ZZZLOK", true},
+		{"literal username mismatch", "zzzlok", "zzzlok2", false},
+		{"literal value with pattern characters", "zzz*", "zzzlok", false},
+		{"literal value with pattern characters exact", "zzz*", "zzz*", true},
+		{"glob pattern", "glob:zzz*", "ZZZlok", true},
+		{"glob pattern uppercase marker", "GLOB:zzz?", "zzz1", true},
+		{"glob pattern mismatch", "glob:zzz*", "abc", false},
+		{"invalid glob pattern", "glob:zzz[", "zzz[", false},
+		{"literal CIDR network", "10.0.0.0/8", "10.1.2.3", false},
+		{"cidr network", "cidr:10.0.0.0/8", "10.1.2.3", true},
+		{"cidr network mismatch", "cidr:10.0.0.0/8", "192.168.1.1", false},
+		{"cidr network non-IP search term", "cidr:10.0.0.0/8", "jsmith", false},
+		{"invalid cidr network", "cidr:10.0.0.0/33", "10.1.2.3", false},
+		{"literal IP Address", "192.168.2.2", "192.168.2.2", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ignoredValueMatches(tt.value, tt.searchTerm); got != tt.want {
+				t.Errorf("ignoredValueMatches(%q, %q) = %t; want %t", tt.value, tt.searchTerm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateIgnoredValues(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		validate func(string) error
+		value    string
+		wantErr  bool
+	}{
+		{"username", ValidateIgnoredUsername, "zzzlok", false},
+		{"literal username with pattern characters", ValidateIgnoredUsername, "zzz[", false},
+		{"username pattern", ValidateIgnoredUsername, "glob:zzz*", false},
+		{"invalid username pattern", ValidateIgnoredUsername, "glob:zzz[", true},
+		{"empty username pattern", ValidateIgnoredUsername, "glob:", true},
+		{"username with whitespace", ValidateIgnoredUsername, "zzz lok", true},
+		{"username with delimiter", ValidateIgnoredUsername, "zzz|lok", true},
+		{"username with comment prefix", ValidateIgnoredUsername, "#zzzlok", true},
+		{"IP Address", ValidateIgnoredIPAddress, "192.168.2.2", false},
+		{"cidr network", ValidateIgnoredIPAddress, "cidr:10.0.0.0/8", false},
+		{"CIDR network without marker", ValidateIgnoredIPAddress, "10.0.0.0/8", true},
+		{"invalid cidr network", ValidateIgnoredIPAddress, "cidr:10.0.0.0/33", true},
+		{"hostname", ValidateIgnoredIPAddress, "example.com", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validating %q returned error %v; want error: %t", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseIgnoredEntry(t *testing.T) {
+
+	endOfDay := time.Date(2020, time.November, 1, 0, 0, 0, 0, time.Local)
+	timestamp := time.Date(2020, time.October, 31, 13, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name    string
+		line    string
+		want    IgnoredEntry
+		wantErr bool
+	}{
+		{
+			name: "value only",
+			line: "zzzlok",
+			want: IgnoredEntry{Value: "zzzlok"},
+		},
+		{
+			name: "all fields",
+			line: "zzzlok | expires=2020-10-31 | owner=jsmith | reason=stanza testing",
+			want: IgnoredEntry{Value: "zzzlok", Expires: endOfDay, Owner: "jsmith", Reason: "stanza testing"},
+		},
+		{
+			name: "uppercase keys without whitespace",
+			line: "192.168.2.2|OWNER=jsmith|Reason=scanner",
+			want: IgnoredEntry{Value: "192.168.2.2", Owner: "jsmith", Reason: "scanner"},
+		},
+		{
+			name: "reason containing delimiter",
+			line: "zzzlok | reason=a=b",
+			want: IgnoredEntry{Value: "zzzlok", Reason: "a=b"},
+		},
+		{
+			name: "empty fields are skipped",
+			line: "zzzlok | | owner=jsmith |",
+			want: IgnoredEntry{Value: "zzzlok", Owner: "jsmith"},
+		},
+		{
+			name: "timestamp expiration",
+			line: "zzzlok | expires=2020-10-31 13:30",
+			want: IgnoredEntry{Value: "zzzlok", Expires: timestamp},
+		},
+		{
+			name: "RFC3339 expiration",
+			line: "zzzlok | expires=2020-10-31T13:30:00Z",
+			want: IgnoredEntry{Value: "zzzlok", Expires: time.Date(2020, time.October, 31, 13, 30, 0, 0, time.UTC)},
+		},
+		{name: "missing value", line: " | owner=jsmith", wantErr: true},
+		{name: "field without key", line: "zzzlok | jsmith", wantErr: true},
+		{name: "unknown field", line: "zzzlok | ticket=123", wantErr: true},
+		{name: "invalid expiration", line: "zzzlok | expires=next week", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, err := ParseIgnoredEntry(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIgnoredEntry(%q) returned error %v; want error: %t", tt.line, err, tt.wantErr)
+			}
+
+			if got.Value != tt.want.Value ||
+				!got.Expires.Equal(tt.want.Expires) ||
+				got.Owner != tt.want.Owner ||
+				got.Reason != tt.want.Reason {
+				t.Errorf("ParseIgnoredEntry(%q) = %+v; want %+v", tt.line, got, tt.want)
+			}
+
+			if got.HasMetadata() != (tt.want.Owner != "" || tt.want.Reason != "" || !tt.want.Expires.IsZero()) {
+				t.Errorf("HasMetadata() = %t for %+v", got.HasMetadata(), got)
+			}
+		})
+	}
+}
+
+func TestIgnoredEntryExpired(t *testing.T) {
+
+	expires := time.Date(2020, time.November, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		entry IgnoredEntry
+		now   time.Time
+		want  bool
+	}{
+		{"no expiration", IgnoredEntry{Value: "zzzlok"}, expires.AddDate(10, 0, 0), false},
+		{"before expiration", IgnoredEntry{Value: "zzzlok", Expires: expires}, expires.Add(-time.Second), false},
+		{"at expiration", IgnoredEntry{Value: "zzzlok", Expires: expires}, expires, true},
+		{"after expiration", IgnoredEntry{Value: "zzzlok", Expires: expires}, expires.Add(time.Second), true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.Expired(tt.now); got != tt.want {
+				t.Errorf("Expired(%v) = %t; want %t", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoredEntryStringRoundTrip(t *testing.T) {
+
+	entry := IgnoredEntry{
+		Value:   "zzzlok",
+		Expires: time.Date(2020, time.October, 31, 13, 30, 0, 0, time.UTC),
+		Owner:   "jsmith",
+		Reason:  "stanza testing",
+	}
+
+	got, err := ParseIgnoredEntry(entry.String())
+	if err != nil {
+		t.Fatalf("ParseIgnoredEntry(%q) returned error: %v", entry.String(), err)
+	}
+
+	if got.Value != entry.Value || !got.Expires.Equal(entry.Expires) ||
+		got.Owner != entry.Owner || got.Reason != entry.Reason {
+		t.Errorf("ParseIgnoredEntry(%q) = %+v; want %+v", entry.String(), got, entry)
+	}
+}
+
+func TestFindIgnoredEntry(t *testing.T) {
+
+	lines := []string{
+		"# comment | expires=garbage",
+		"expired | expires=2000-01-01",
+		"expired | owner=later entry",
+		"current | expires=2999-01-01 | owner=jsmith",
+		"broken | expires=someday",
+		"glob:zzz*",
+	}
+
+	filename := filepath.Join(t.TempDir(), "ignored.txt")
+	if err := ioutil.WriteFile(filename, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatalf("failed to write ignored entries file: %v", err)
+	}
+
+	tests := []struct {
+		searchTerm string
+		wantFound  bool
+		wantOwner  string
+		wantErr    bool
+	}{
+		{searchTerm: "expired", wantFound: true, wantOwner: "later entry"},
+		{searchTerm: "CURRENT", wantFound: true, wantOwner: "jsmith"},
+		{searchTerm: "zzzlok", wantFound: true},
+		{searchTerm: "comment", wantFound: false},
+		{searchTerm: "missing", wantFound: false},
+		{searchTerm: "broken", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.searchTerm, func(t *testing.T) {
+
+			entry, found, err := findIgnoredEntry(tt.searchTerm, filename)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findIgnoredEntry(%q) returned error %v; want error: %t", tt.searchTerm, err, tt.wantErr)
+			}
+
+			if found != tt.wantFound || entry.Owner != tt.wantOwner {
+				t.Errorf(
+					"findIgnoredEntry(%q) = %+v, %t; want owner %q, %t",
+					tt.searchTerm,
+					entry,
+					found,
+					tt.wantOwner,
+					tt.wantFound,
+				)
+			}
+		})
+	}
+}
+
+func TestReadIgnoredEntries(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		lines      []string
+		wantValues []string
+	}{
+		{
+			name: "valid entries",
+			lines: []string{
+				"# comment",
+				"",
+				"jsmith | expires=2999-01-01 | owner=adoe",
+				"expired | expires=2000-01-01",
+			},
+			wantValues: []string{"jsmith", "expired"},
+		},
+		{
+			name: "malformed entries skipped",
+			lines: []string{
+				"broken | expires=someday",
+				"jsmith | owner=adoe",
+				"unknown | color=blue",
+				"glob:zzz*",
+			},
+			wantValues: []string{"jsmith", "glob:zzz*"},
+		},
+		{
+			name:       "only malformed entries",
+			lines:      []string{"broken | expires=someday"},
+			wantValues: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			filename := filepath.Join(t.TempDir(), "ignored.txt")
+			if err := ioutil.WriteFile(filename, []byte(strings.Join(tt.lines, "\n")+"\n"), 0600); err != nil {
+				t.Fatalf("failed to write ignored entries file: %v", err)
+			}
+
+			entries, err := ReadIgnoredEntries(filename)
+			if err != nil {
+				t.Fatalf("ReadIgnoredEntries() returned error: %v", err)
+			}
+
+			values := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				values = append(values, entry.Value)
+			}
+
+			if strings.Join(values, ",") != strings.Join(tt.wantValues, ",") {
+				t.Errorf("ReadIgnoredEntries() = %q; want %q", values, tt.wantValues)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/atc0005/go-ezproxy"
+
+	"github.com/atc0005/brick/events"
+)
+
+// EZproxyInstance represents one of the EZproxy servers managed by this
+// application. Each instance has its own disabled users file, active file,
+// audit file directory and means of terminating sessions.
+type EZproxyInstance struct {
+
+	// Name is the unique name of the instance used in alert policies, log
+	// messages and notifications.
+	Name string
+
+	// DisabledUsers is the file where user accounts disabled on this
+	// instance are written.
+	DisabledUsers *DisabledUsers
+
+	// ActiveFileWatcher optionally keeps an index of the sessions recorded
+	// in the active file for this instance.
+	ActiveFileWatcher *ActiveFileWatcher
+
+	// ActiveFilePath is the fully-qualified path to the active file for this
+	// instance.
+	ActiveFilePath string
+
+	// AuditFileDirPath is the path to the directory containing the audit
+	// files for this instance. Audit file lookups are skipped if empty.
+	AuditFileDirPath string
+
+	// Terminator terminates sessions on this instance.
+	Terminator SessionTerminator
+}
+
+// EZproxyInstances is a collection of EZproxyInstance values.
+type EZproxyInstances []EZproxyInstance
+
+// Select returns the instances with the provided names in the order
+// configured. All instances are returned if no names are provided.
+func (eis EZproxyInstances) Select(names []string) EZproxyInstances {
+
+	if len(names) == 0 {
+		return eis
+	}
+
+	selected := make(EZproxyInstances, 0, len(names))
+	for _, instance := range eis {
+		for _, name := range names {
+			if instance.Name == name {
+				selected = append(selected, instance)
+				break
+			}
+		}
+	}
+
+	return selected
+}
+
+// Names returns the names of the instances.
+func (eis EZproxyInstances) Names() []string {
+
+	names := make([]string, 0, len(eis))
+	for _, instance := range eis {
+		names = append(names, instance.Name)
+	}
+
+	return names
+}
+
+// selectInstances returns the instances selected by the alert policy for the
+// provided alert. If more than one instance is configured, the names of the
+// selected instances are recorded in the alert so that notifications and log
+// messages reflect them.
+func selectInstances(alert *events.SplunkAlertEvent, instances EZproxyInstances) EZproxyInstances {
+
+	selected := instances
+	if alert.Policy != nil {
+		selected = instances.Select(alert.Policy.Instances)
+	}
+
+	if len(instances) > 1 {
+		alert.Instances = selected.Names()
+	}
+
+	return selected
+}
+
+// instanceError adds the name of the instance to the provided error if the
+// alert is acted on by more than one instance.
+func instanceError(alert events.SplunkAlertEvent, instance EZproxyInstance, err error) error {
+
+	if err == nil || len(alert.Instances) == 0 {
+		return err
+	}
+
+	return fmt.Errorf("EZproxy instance %q: %w", instance.Name, err)
+}
+
+// mergeInstanceRecords combines the session termination records for each of
+// the provided instances into a single record so that one notification
+// summarizes the results for all instances. Notes and errors are prefixed
+// with the instance name; the record is a failure if any of the records are.
+func mergeInstanceRecords(
+	alert events.SplunkAlertEvent,
+	instances EZproxyInstances,
+	records []events.Record,
+) events.Record {
+
+	if len(records) == 1 {
+		return records[0]
+	}
+
+	action := records[0].Action
+	var results ezproxy.TerminateUserSessionResults
+	var notes []string
+	var errs []string
+	var verification *events.TerminationVerification
+
+	for i, record := range records {
+
+		name := instances[i].Name
+
+		results = append(results, record.SessionTerminationResults...)
+		notes = append(notes, fmt.Sprintf("%s: %s", name, record.Note))
+
+		if record.Error != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, record.Error))
+		}
+
+		if record.Action == events.ActionFailureTerminatedUserSession {
+			action = events.ActionFailureTerminatedUserSession
+		}
+
+		if record.Verification != nil {
+			if verification == nil {
+				verification = &events.TerminationVerification{
+					Delay: record.Verification.Delay,
+				}
+			}
+			verification.Remaining = append(verification.Remaining, record.Verification.Remaining...)
+			verification.NewSessions = append(verification.NewSessions, record.Verification.NewSessions...)
+			if record.Verification.Error != nil && verification.Error == nil {
+				verification.Error = fmt.Errorf("%s: %w", name, record.Verification.Error)
+			}
+		}
+	}
+
+	var err error
+	if len(errs) > 0 {
+		err = errors.New(strings.Join(errs, "; "))
+	}
+
+	merged := events.NewRecord(
+		alert,
+		err,
+		strings.Join(notes, "; "),
+		action,
+		results,
+	)
+	merged.Operator = records[0].Operator
+	merged.Verification = verification
+
+	return merged
+}
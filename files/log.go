@@ -19,6 +19,7 @@ package files
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/apex/log"
 
@@ -41,6 +42,10 @@ func logEventDisableRequestReceived(alert events.SplunkAlertEvent, reportedUserE
 		alert.UserIP,
 	)
 
+	if alert.Policy != nil {
+		requestReceivedMessage += fmt.Sprintf(" (alert policy: %s)", alert.Policy)
+	}
+
 	log.Debug(caller.GetFuncFileLineInfo())
 	log.Infof(requestReceivedMessage)
 
@@ -79,6 +84,74 @@ func logEventDisableRequestReceived(alert events.SplunkAlertEvent, reportedUserE
 
 }
 
+// logEventSkippedDisableUsername handles logging the event where a username
+// is not disabled because the alert policy selected for the alert does not
+// call for it (e.g., report-only or terminate-only policies). This function
+// emits the output to stdout for the init system to catch. The reported user
+// events log already contains a [REPORTED] entry for this alert, so no
+// additional entry is written.
+func logEventSkippedDisableUsername(alert events.SplunkAlertEvent) events.Record {
+
+	skippedDisableMsg := fmt.Sprintf(
+		"Skipped disabling username %q from IP %q per report from %q",
+		alert.Username,
+		alert.UserIP,
+		alert.PayloadSenderIP,
+	)
+
+	if alert.Policy != nil {
+		skippedDisableMsg += fmt.Sprintf(" due to alert policy %s", alert.Policy)
+	}
+
+	log.Debug(caller.GetFuncFileLineInfo())
+
+	log.Info(skippedDisableMsg)
+
+	return events.NewRecord(
+		alert,
+		nil,
+		skippedDisableMsg,
+		events.ActionSkippedDisableUsername,
+		nil,
+	)
+
+}
+
+// logEventBelowReportThreshold handles logging the event where a username is
+// not (yet) disabled because the report threshold for the alert policy has
+// not been reached. This function emits the output to stdout for the init
+// system to catch. The reported user events log already contains a
+// [REPORTED] entry for this alert, so no additional entry is written.
+func logEventBelowReportThreshold(alert events.SplunkAlertEvent) events.Record {
+
+	belowThresholdMsg := fmt.Sprintf(
+		"Skipped disabling username %q from IP %q per report from %q",
+		alert.Username,
+		alert.UserIP,
+		alert.PayloadSenderIP,
+	)
+
+	if alert.ReportThreshold != nil {
+		belowThresholdMsg += fmt.Sprintf(
+			"; report threshold not reached (%s)",
+			alert.ReportThreshold,
+		)
+	}
+
+	log.Debug(caller.GetFuncFileLineInfo())
+
+	log.Info(belowThresholdMsg)
+
+	return events.NewRecord(
+		alert,
+		nil,
+		belowThresholdMsg,
+		events.ActionSkippedDisableUsernameThreshold,
+		nil,
+	)
+
+}
+
 // logEventDisablingUsername handles logging the event where a username is
 // being disabled. This function emits the output to stdout for the init
 // system to catch. This function does NOT report the intent via
@@ -86,6 +159,9 @@ func logEventDisableRequestReceived(alert events.SplunkAlertEvent, reportedUserE
 func logEventDisablingUsername(alert events.SplunkAlertEvent, reportedUserEventsLog *ReportedUserEventsLog) {
 
 	msgTemplate := "Disabling username %q from IP %q per report from %q"
+	if quarantineEnabled(alert) {
+		msgTemplate = "Quarantining username %q from IP %q per report from %q"
+	}
 
 	log.Debug(caller.GetFuncFileLineInfo())
 
@@ -94,18 +170,33 @@ func logEventDisablingUsername(alert events.SplunkAlertEvent, reportedUserEvents
 }
 
 // logEventDisabledUsername handles logging the event where a username
-// has been successfully disabled. This function is responsible for emitting
-// the success message to stdout for the init system to catch, write a
-// templated message to the reported user events log for potential automation.
+// has been successfully disabled (or quarantined). This function is
+// responsible for emitting the success message to stdout for the init system
+// to catch, write a templated message to the reported user events log for
+// potential automation.
 func logEventDisabledUsername(alert events.SplunkAlertEvent, reportedUserEventsLog *ReportedUserEventsLog) events.Record {
 
+	msgTemplate := "Disabled username %q from IP %q per report from %q"
+	eventTemplate := reportedUserEventsLog.DisableFirstEventTemplate
+	if quarantineEnabled(alert) {
+		msgTemplate = "Quarantined username %q from IP %q per report from %q"
+		eventTemplate = reportedUserEventsLog.QuarantineEventTemplate
+	}
+
 	disableSuccessMsg := fmt.Sprintf(
-		"Disabled username %q from IP %q per report from %q",
+		msgTemplate,
 		alert.Username,
 		alert.UserIP,
 		alert.PayloadSenderIP,
 	)
 
+	if alert.ReportThreshold != nil {
+		disableSuccessMsg += fmt.Sprintf(
+			"; report threshold reached (%s)",
+			alert.ReportThreshold,
+		)
+	}
+
 	log.Debug(caller.GetFuncFileLineInfo())
 
 	// emit to stdout right away in case we have problems recording this event
@@ -116,7 +207,7 @@ func logEventDisabledUsername(alert events.SplunkAlertEvent, reportedUserEventsL
 		fileEntry{
 			Alert: alert,
 		},
-		reportedUserEventsLog.DisableFirstEventTemplate,
+		eventTemplate,
 		reportedUserEventsLog.FilePath,
 		reportedUserEventsLog.FilePermissions,
 	); err != nil {
@@ -146,6 +237,129 @@ func logEventDisabledUsername(alert events.SplunkAlertEvent, reportedUserEventsL
 
 }
 
+// logEventSuspendedUsername handles logging the event where a username was
+// not disabled because the mass-disable circuit breaker is open. This
+// function emits the output to stdout for the init system to catch and also
+// writes a templated [SUSPENDED] message to the reported user events log.
+// The returned Record is flagged as high priority if this event tripped the
+// circuit breaker.
+func logEventSuspendedUsername(alert events.SplunkAlertEvent, reportedUserEventsLog *ReportedUserEventsLog) events.Record {
+
+	action := events.ActionSkippedDisableUsernameSuspended
+	if alert.CircuitBreaker.Tripped() {
+		action = events.ActionSkippedCircuitBreakerTripped
+	}
+
+	suspendedMsg := fmt.Sprintf(
+		"Not disabling username %q from IP %q per report from %q; circuit breaker %s",
+		alert.Username,
+		alert.UserIP,
+		alert.PayloadSenderIP,
+		alert.CircuitBreaker,
+	)
+
+	log.Debug(caller.GetFuncFileLineInfo())
+
+	log.Warn(suspendedMsg)
+
+	if err := appendToFile(
+		fileEntry{
+			Alert: alert,
+		},
+		reportedUserEventsLog.SuspendedEventTemplate,
+		reportedUserEventsLog.FilePath,
+		reportedUserEventsLog.FilePermissions,
+	); err != nil {
+		recordEventErr := fmt.Errorf(
+			"func %s: error updating events log file %q: %w",
+			caller.GetFuncName(),
+			reportedUserEventsLog.FilePath,
+			err,
+		)
+
+		return events.NewRecord(
+			alert,
+			recordEventErr,
+			suspendedMsg,
+			action,
+			nil,
+		)
+	}
+
+	return events.NewRecord(
+		alert,
+		nil,
+		suspendedMsg,
+		action,
+		nil,
+	)
+
+}
+
+// logEventDryRunDisabledUsername handles logging the event where a username
+// would have been disabled, but the alert policy is in dry-run mode. This
+// function emits the output to stdout for the init system to catch and also
+// writes a templated [DRY-RUN] message to the reported user events log.
+func logEventDryRunDisabledUsername(alert events.SplunkAlertEvent, reportedUserEventsLog *ReportedUserEventsLog) events.Record {
+
+	msgTemplate := "[DRY-RUN] Would have disabled username %q from IP %q per report from %q"
+	if quarantineEnabled(alert) {
+		msgTemplate = "[DRY-RUN] Would have quarantined username %q from IP %q per report from %q"
+	}
+
+	dryRunMsg := fmt.Sprintf(
+		msgTemplate,
+		alert.Username,
+		alert.UserIP,
+		alert.PayloadSenderIP,
+	)
+
+	if alert.ReportThreshold != nil {
+		dryRunMsg += fmt.Sprintf(
+			"; report threshold reached (%s)",
+			alert.ReportThreshold,
+		)
+	}
+
+	log.Debug(caller.GetFuncFileLineInfo())
+
+	log.Info(dryRunMsg)
+
+	if err := appendToFile(
+		fileEntry{
+			Alert:      alert,
+			Quarantine: quarantineEnabled(alert),
+		},
+		reportedUserEventsLog.DryRunDisableEventTemplate,
+		reportedUserEventsLog.FilePath,
+		reportedUserEventsLog.FilePermissions,
+	); err != nil {
+		recordEventErr := fmt.Errorf(
+			"func %s: error updating events log file %q: %w",
+			caller.GetFuncName(),
+			reportedUserEventsLog.FilePath,
+			err,
+		)
+
+		return events.NewRecord(
+			alert,
+			recordEventErr,
+			dryRunMsg,
+			events.ActionDryRunDisabledUsername,
+			nil,
+		)
+	}
+
+	return events.NewRecord(
+		alert,
+		nil,
+		dryRunMsg,
+		events.ActionDryRunDisabledUsername,
+		nil,
+	)
+
+}
+
 // logEventUsernameAlreadyDisabled handles logging the event where a username
 // is already disabled, but another request has arrived to disable it, usually
 // as a result of account compromise/sharing. This function emits the output
@@ -161,8 +375,13 @@ func logEventUsernameAlreadyDisabled(alert events.SplunkAlertEvent, reportedUser
 	// 	alert.UserIP,
 	// )
 
+	msgTemplate := "Username %q already disabled (current IP %q per report from %q)"
+	if quarantineEnabled(alert) {
+		msgTemplate = "Username %q already quarantined or disabled (current IP %q per report from %q)"
+	}
+
 	alreadyDisabledMsg := fmt.Sprintf(
-		"Username %q already disabled (current IP %q per report from %q)",
+		msgTemplate,
 		alert.Username,
 		alert.UserIP,
 		alert.PayloadSenderIP,
@@ -173,7 +392,8 @@ func logEventUsernameAlreadyDisabled(alert events.SplunkAlertEvent, reportedUser
 
 	if err := appendToFile(
 		fileEntry{
-			Alert: alert,
+			Alert:      alert,
+			Quarantine: quarantineEnabled(alert),
 		},
 		reportedUserEventsLog.DisableRepeatEventTemplate,
 		reportedUserEventsLog.FilePath,
@@ -211,7 +431,12 @@ func logEventUsernameAlreadyDisabled(alert events.SplunkAlertEvent, reportedUser
 // Addresses. This function emits the output to stdout for the init system to
 // catch and also writes a templated message to the reported user events log
 // for potential automation.
-func logEventIgnoredIPAddress(alert events.SplunkAlertEvent, reportedUserEventsLog *ReportedUserEventsLog, ignoredEntriesFile string) events.Record {
+func logEventIgnoredIPAddress(
+	alert events.SplunkAlertEvent,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	ignoredEntriesFile string,
+	ignoredEntry IgnoredEntry,
+) events.Record {
 
 	ignoreIPAddressMsg := fmt.Sprintf(
 		"Ignored disable request from %q for user %q from IP %q due to presence in %q file.",
@@ -221,6 +446,13 @@ func logEventIgnoredIPAddress(alert events.SplunkAlertEvent, reportedUserEventsL
 		ignoredEntriesFile,
 	)
 
+	// Include the owner, reason and expiration for the matching entry (if
+	// set) so that those reviewing the notification know who to ask about
+	// the exemption.
+	if ignoredEntry.HasMetadata() {
+		ignoreIPAddressMsg += fmt.Sprintf(" Entry details: %s.", ignoredEntry.Details())
+	}
+
 	log.Debug(caller.GetFuncFileLineInfo())
 
 	log.Info(ignoreIPAddressMsg)
@@ -229,6 +461,7 @@ func logEventIgnoredIPAddress(alert events.SplunkAlertEvent, reportedUserEventsL
 		fileEntry{
 			Alert:              alert,
 			IgnoredEntriesFile: ignoredEntriesFile,
+			IgnoredEntry:       ignoredEntry,
 		},
 		reportedUserEventsLog.IgnoreTemplate,
 		reportedUserEventsLog.FilePath,
@@ -265,7 +498,12 @@ func logEventIgnoredIPAddress(alert events.SplunkAlertEvent, reportedUserEventsL
 // usernames. This function emits the output to stdout for the init system to
 // catch and also writes a templated message to the reported user events log
 // for potential automation.
-func logEventIgnoredUsername(alert events.SplunkAlertEvent, reportedUserEventsLog *ReportedUserEventsLog, ignoredEntriesFile string) events.Record {
+func logEventIgnoredUsername(
+	alert events.SplunkAlertEvent,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	ignoredEntriesFile string,
+	ignoredEntry IgnoredEntry,
+) events.Record {
 
 	ignoreUsernameMsg := fmt.Sprintf(
 		"Ignored disable request from %q for user %q from IP %q due to presence in %q file.",
@@ -275,6 +513,13 @@ func logEventIgnoredUsername(alert events.SplunkAlertEvent, reportedUserEventsLo
 		ignoredEntriesFile,
 	)
 
+	// Include the owner, reason and expiration for the matching entry (if
+	// set) so that those reviewing the notification know who to ask about
+	// the exemption.
+	if ignoredEntry.HasMetadata() {
+		ignoreUsernameMsg += fmt.Sprintf(" Entry details: %s.", ignoredEntry.Details())
+	}
+
 	log.Debug(caller.GetFuncFileLineInfo())
 
 	log.Info(ignoreUsernameMsg)
@@ -283,6 +528,7 @@ func logEventIgnoredUsername(alert events.SplunkAlertEvent, reportedUserEventsLo
 		fileEntry{
 			Alert:              alert,
 			IgnoredEntriesFile: ignoredEntriesFile,
+			IgnoredEntry:       ignoredEntry,
 		},
 		reportedUserEventsLog.IgnoreTemplate,
 		reportedUserEventsLog.FilePath,
@@ -333,7 +579,7 @@ func logEventTerminatingUserSession(
 		msgTemplate,
 		userSession.SessionID,
 		userSession.IPAddress,
-		alert.Username,
+		userSession.Username,
 		alert.UserIP,
 		alert.PayloadSenderIP,
 	)
@@ -344,11 +590,16 @@ func logEventTerminatingUserSession(
 // a username have been terminated. This function is called once for a
 // collection of termination results associated with a username. This function
 // emits the output to stdout for the init system to catch and also sends a
-// summary of the termination results as a notification.
+// summary of the termination results as a notification. The operator name is
+// recorded if the sessions were terminated at the request of an operator.
+// If provided, the verification status of each session is recorded and
+// sessions still active after termination are reported as failures.
 func logEventTerminatedUserSessions(
 	alert events.SplunkAlertEvent,
 	reportedUserEventsLog *ReportedUserEventsLog,
 	terminationResults ezproxy.TerminateUserSessionResults,
+	operator string,
+	verification *events.TerminationVerification,
 ) events.Record {
 
 	// Record origin *before* we start processing via loop
@@ -357,20 +608,26 @@ func logEventTerminatedUserSessions(
 	// emit each termination result to stdout, write to the reported user
 	// events log file
 	successfulTerminationTmplPrefix := "Successfully terminated"
+	alreadyGoneTerminationTmplPrefix := "Already gone; skipped"
 	failedTerminationTmplPrefix := "Failed to terminate"
 	terminatedMsgTmpl := "%s session %q (associated with IP %q) for username %q (from IP %q) per report from %q [ExitCode: %d, StdOut: %q, StdErr: %q, Error: %q]"
 
 	var failedTerminationsNum int
+	var alreadyGoneTerminationsNum int
 	var failedTerminationsSessionIDs []string
 	for _, result := range terminationResults {
 
 		// be optimistic!
 		terminatedMsgPrefix := successfulTerminationTmplPrefix
 
-		if result.Error != nil {
+		switch events.TerminationOutcome(result) {
+		case events.TerminationOutcomeFailed:
 			terminatedMsgPrefix = failedTerminationTmplPrefix
 			failedTerminationsSessionIDs = append(failedTerminationsSessionIDs, result.SessionID)
 			failedTerminationsNum++
+		case events.TerminationOutcomeAlreadyGone:
+			terminatedMsgPrefix = alreadyGoneTerminationTmplPrefix
+			alreadyGoneTerminationsNum++
 		}
 
 		// guard against (nil) lack of error in results slice entry
@@ -384,7 +641,7 @@ func logEventTerminatedUserSessions(
 			terminatedMsgPrefix,
 			result.SessionID,
 			result.IPAddress,
-			alert.Username,
+			result.Username,
 			alert.UserIP,
 			alert.PayloadSenderIP,
 			result.ExitCode,
@@ -395,15 +652,24 @@ func logEventTerminatedUserSessions(
 
 		log.Info(terminatedMsg)
 
-		// only record successful terminations in the reported user events log
-		if result.Error == nil {
+		// only record successful terminations in the reported user events
+		// log; sessions which were already gone were not terminated by this
+		// application
+		if events.TerminationOutcome(result) == events.TerminationOutcomeTerminated {
 
 			var recordEventErr error
+			entry := fileEntry{
+				Alert:       alert,
+				UserSession: result.UserSession,
+				Operator:    operator,
+			}
+
+			if verification != nil && verification.Error == nil {
+				entry.Verification = verification.SessionStatus(result.SessionID)
+			}
+
 			if err := appendToFile(
-				fileEntry{
-					Alert:       alert,
-					UserSession: result.UserSession,
-				},
+				entry,
 				reportedUserEventsLog.TerminateUserSessionEventTemplate,
 				reportedUserEventsLog.FilePath,
 				reportedUserEventsLog.FilePermissions,
@@ -416,13 +682,17 @@ func logEventTerminatedUserSessions(
 					recordEventErr,
 				)
 
-				return events.NewRecord(
+				record := events.NewRecord(
 					alert,
 					recordEventErr,
 					terminatedMsg,
 					events.ActionFailureTerminatedUserSession,
 					terminationResults,
 				)
+				record.Operator = operator
+				record.Verification = verification
+
+				return record
 
 			}
 
@@ -430,13 +700,14 @@ func logEventTerminatedUserSessions(
 
 	}
 
-	successfulTerminations := len(terminationResults) - failedTerminationsNum
+	successfulTerminations := len(terminationResults) - failedTerminationsNum - alreadyGoneTerminationsNum
 
 	// emit via stdout (for systemd/syslog)
 	log.Infof(
-		"Session termination summary for %q: [success: %d, failure: %d]",
+		"Session termination summary for %q: [success: %d, already gone: %d, failure: %d]",
 		alert.Username,
 		successfulTerminations,
+		alreadyGoneTerminationsNum,
 		failedTerminationsNum,
 	)
 
@@ -454,13 +725,38 @@ func logEventTerminatedUserSessions(
 			strings.Join(failedTerminationsSessionIDs, ", "),
 		)
 
-		return events.NewRecord(
+		record := events.NewRecord(
 			alert,
 			terminationResultsError,
 			terminationResultsFailureMsg,
 			events.ActionFailureTerminatedUserSession,
 			terminationResults,
 		)
+		record.Operator = operator
+		record.Verification = verification
+
+		return record
+	}
+
+	if verification != nil && len(verification.Remaining) > 0 {
+
+		record := events.NewRecord(
+			alert,
+			fmt.Errorf("terminated sessions still active: %s", strings.Join(verification.Remaining, ", ")),
+			fmt.Sprintf(
+				"%d of %d terminated sessions for username %s still active after %v",
+				len(verification.Remaining),
+				len(terminationResults),
+				alert.Username,
+				verification.Delay,
+			),
+			events.ActionFailureTerminatedUserSession,
+			terminationResults,
+		)
+		record.Operator = operator
+		record.Verification = verification
+
+		return record
 	}
 
 	// TODO: We need to determine our % of success and convey that at a
@@ -474,12 +770,409 @@ func logEventTerminatedUserSessions(
 		alert.Username,
 	)
 
-	return events.NewRecord(
+	if alreadyGoneTerminationsNum > 0 {
+		sessionTerminationResultsSuccessMsg = fmt.Sprintf(
+			"Successfully terminated %d user sessions for %q; %d sessions were already gone",
+			successfulTerminations,
+			alert.Username,
+			alreadyGoneTerminationsNum,
+		)
+	}
+
+	record := events.NewRecord(
 		alert,
 		nil,
 		sessionTerminationResultsSuccessMsg,
 		events.ActionSuccessTerminatedUserSession,
 		terminationResults,
 	)
+	record.Operator = operator
+	record.Verification = verification
+
+	return record
+
+}
+
+// logEventOperatorChange handles logging the event where an operator has
+// made a change via the management API (e.g., added or removed an ignored
+// user account or IP Address entry). This function emits the output to
+// stdout for the init system to catch and also writes a templated audit
+// message to the reported user events log. Any error from the attempted
+// change is included in the returned Record and the failure action is used
+// in place of the provided action.
+func logEventOperatorChange(
+	alert events.SplunkAlertEvent,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	operator string,
+	action string,
+	failureAction string,
+	changeMsg string,
+	changeErr error,
+) events.Record {
+
+	log.Debug(caller.GetFuncFileLineInfo())
+
+	if changeErr != nil {
+		changeMsg = fmt.Sprintf("Failed: %s", changeMsg)
+		action = failureAction
+	}
+
+	log.Infof("Operator %q from IP %q: %s", operator, alert.PayloadSenderIP, changeMsg)
+
+	record := events.NewRecord(
+		alert,
+		changeErr,
+		changeMsg,
+		action,
+		nil,
+	)
+	record.Operator = operator
+
+	if err := appendToFile(
+		fileEntry{
+			Alert:    alert,
+			Operator: operator,
+			Note:     changeMsg,
+		},
+		reportedUserEventsLog.AuditTemplate,
+		reportedUserEventsLog.FilePath,
+		reportedUserEventsLog.FilePermissions,
+	); err != nil {
+		recordEventErr := fmt.Errorf(
+			"func %s: error updating events log file %q: %w",
+			caller.GetFuncName(),
+			reportedUserEventsLog.FilePath,
+			err,
+		)
+
+		// retain the original error if the change itself failed
+		if record.Error == nil {
+			record.Error = recordEventErr
+			record.Action = failureAction
+		} else {
+			log.Error(recordEventErr.Error())
+		}
+	}
+
+	return record
+
+}
+
+// logEventDryRunTerminatedUserSessions handles logging the event where user
+// sessions would have been terminated, but the alert policy is in dry-run
+// mode. This function emits the output to stdout for the init system to
+// catch and also writes a templated [DRY-RUN] message to the reported user
+// events log for each session.
+func logEventDryRunTerminatedUserSessions(
+	alert events.SplunkAlertEvent,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	activeSessions ezproxy.UserSessions,
+) events.Record {
+
+	log.Debug(caller.GetFuncFileLineInfo())
+
+	sessionIDs := make([]string, 0, len(activeSessions))
+	for _, session := range activeSessions {
+
+		sessionIDs = append(sessionIDs, session.SessionID)
+
+		log.Infof(
+			"[DRY-RUN] Would have terminated session %q (associated with IP %q) for username %q (from IP %q) per report from %q",
+			session.SessionID,
+			session.IPAddress,
+			session.Username,
+			alert.UserIP,
+			alert.PayloadSenderIP,
+		)
+
+		if err := appendToFile(
+			fileEntry{
+				Alert:       alert,
+				UserSession: session,
+			},
+			reportedUserEventsLog.DryRunTerminateUserSessionEventTemplate,
+			reportedUserEventsLog.FilePath,
+			reportedUserEventsLog.FilePermissions,
+		); err != nil {
+			recordEventErr := fmt.Errorf(
+				"func %s: error updating events log file %q: %w",
+				caller.GetFuncName(),
+				reportedUserEventsLog.FilePath,
+				err,
+			)
+
+			return events.NewRecord(
+				alert,
+				recordEventErr,
+				"",
+				events.ActionDryRunTerminatedUserSessions,
+				nil,
+			)
+		}
+	}
+
+	dryRunMsg := fmt.Sprintf(
+		`[DRY-RUN] Would have terminated all %d user sessions for %q: "%s"`,
+		len(activeSessions),
+		alert.Username,
+		strings.Join(sessionIDs, `", "`),
+	)
+
+	log.Info(dryRunMsg)
+
+	return events.NewRecord(
+		alert,
+		nil,
+		dryRunMsg,
+		events.ActionDryRunTerminatedUserSessions,
+		nil,
+	)
+
+}
+
+// logEventPendingApproval handles logging the event where disabling a
+// username is held for operator approval. This function emits the output to
+// stdout for the init system to catch and also writes a templated message to
+// the reported user events log for potential automation.
+func logEventPendingApproval(alert events.SplunkAlertEvent, reportedUserEventsLog *ReportedUserEventsLog) events.Record {
+
+	pendingApprovalMsg := fmt.Sprintf(
+		"Disabling username %q from IP %q per report from %q is pending approval (ID: %s, expires: %s)",
+		alert.Username,
+		alert.UserIP,
+		alert.PayloadSenderIP,
+		alert.Approval.ID,
+		alert.Approval.Expires.Format(time.RFC3339),
+	)
+
+	log.Debug(caller.GetFuncFileLineInfo())
 
+	log.Info(pendingApprovalMsg)
+
+	if err := appendToFile(
+		fileEntry{
+			Alert: alert,
+		},
+		reportedUserEventsLog.PendingApprovalEventTemplate,
+		reportedUserEventsLog.FilePath,
+		reportedUserEventsLog.FilePermissions,
+	); err != nil {
+		recordEventErr := fmt.Errorf(
+			"func %s: error updating events log file %q: %w",
+			caller.GetFuncName(),
+			reportedUserEventsLog.FilePath,
+			err,
+		)
+
+		return events.NewRecord(
+			alert,
+			recordEventErr,
+			pendingApprovalMsg,
+			events.ActionPendingApprovalDisableUsername,
+			nil,
+		)
+	}
+
+	return events.NewRecord(
+		alert,
+		nil,
+		pendingApprovalMsg,
+		events.ActionPendingApprovalDisableUsername,
+		nil,
+	)
+
+}
+
+// logEventApprovalDecision handles logging the event where a pending
+// approval request is approved, rejected or times out. This function emits
+// the output to stdout for the init system to catch and also writes a
+// templated message to the reported user events log for potential
+// automation. The approver is recorded as the operator for the event.
+func logEventApprovalDecision(alert events.SplunkAlertEvent, reportedUserEventsLog *ReportedUserEventsLog) events.Record {
+
+	action := events.ActionSuccessRejectedDisableUsername
+	if alert.Approval.Approved() {
+		action = events.ActionSuccessApprovedDisableUsername
+	}
+
+	decisionMsg := fmt.Sprintf(
+		"Disabling username %q from IP %q per report from %q %s",
+		alert.Username,
+		alert.UserIP,
+		alert.PayloadSenderIP,
+		alert.Approval,
+	)
+
+	log.Debug(caller.GetFuncFileLineInfo())
+
+	log.Info(decisionMsg)
+
+	record := events.NewRecord(
+		alert,
+		nil,
+		decisionMsg,
+		action,
+		nil,
+	)
+	record.Operator = alert.Approval.Approver
+
+	if err := appendToFile(
+		fileEntry{
+			Alert: alert,
+		},
+		reportedUserEventsLog.ApprovalDecisionEventTemplate,
+		reportedUserEventsLog.FilePath,
+		reportedUserEventsLog.FilePermissions,
+	); err != nil {
+		record.Error = fmt.Errorf(
+			"func %s: error updating events log file %q: %w",
+			caller.GetFuncName(),
+			reportedUserEventsLog.FilePath,
+			err,
+		)
+	}
+
+	return record
+
+}
+
+// logEventBlockedIPAddress handles logging the event where the reported user
+// IP Address was blocked using the firewall backend. This function emits the
+// output to stdout for the init system to catch and also writes a templated
+// [BLOCKED] message to the reported user events log. A non-nil stateErr
+// indicates that the block could not be recorded in the firewall state file.
+func logEventBlockedIPAddress(
+	alert events.SplunkAlertEvent,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	backend string,
+	expires time.Time,
+	stateErr error,
+) events.Record {
+
+	log.Debug(caller.GetFuncFileLineInfo())
+
+	blockedMsg := fmt.Sprintf(
+		"Blocked IP %q associated with username %q via %s until %s",
+		alert.UserIP,
+		alert.Username,
+		backend,
+		expires.Format(time.RFC3339),
+	)
+
+	log.Info(blockedMsg)
+
+	if err := appendToFile(
+		fileEntry{
+			Alert:           alert,
+			FirewallBackend: backend,
+			BlockExpires:    expires,
+		},
+		reportedUserEventsLog.BlockedIPEventTemplate,
+		reportedUserEventsLog.FilePath,
+		reportedUserEventsLog.FilePermissions,
+	); err != nil {
+		stateErr = fmt.Errorf(
+			"func %s: error updating events log file %q: %w",
+			caller.GetFuncName(),
+			reportedUserEventsLog.FilePath,
+			err,
+		)
+	}
+
+	if stateErr != nil {
+		return events.NewRecord(
+			alert,
+			stateErr,
+			blockedMsg,
+			events.ActionFailureBlockedIPAddress,
+			nil,
+		)
+	}
+
+	return events.NewRecord(
+		alert,
+		nil,
+		blockedMsg,
+		events.ActionSuccessBlockedIPAddress,
+		nil,
+	)
+}
+
+// logEventUnblockedIPAddress handles logging the event where a blocked IP
+// Address was unblocked (or failed to be unblocked) using the firewall
+// backend for the provided reason. This function emits the output to stdout
+// for the init system to catch and, if the IP Address was unblocked, also
+// writes a templated [UNBLOCKED] message to the reported user events log.
+func logEventUnblockedIPAddress(
+	alert events.SplunkAlertEvent,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	backend string,
+	reason string,
+	unblockErr error,
+) events.Record {
+
+	log.Debug(caller.GetFuncFileLineInfo())
+
+	if unblockErr != nil {
+		log.Errorf(
+			"Failed to unblock IP %q associated with username %q via %s (%s): %v",
+			alert.UserIP,
+			alert.Username,
+			backend,
+			reason,
+			unblockErr,
+		)
+
+		return events.NewRecord(
+			alert,
+			unblockErr,
+			fmt.Sprintf("Failed to unblock IP %q via %s (%s); retrying", alert.UserIP, backend, reason),
+			events.ActionFailureUnblockedIPAddress,
+			nil,
+		)
+	}
+
+	unblockedMsg := fmt.Sprintf(
+		"Unblocked IP %q associated with username %q via %s: %s",
+		alert.UserIP,
+		alert.Username,
+		backend,
+		reason,
+	)
+
+	log.Info(unblockedMsg)
+
+	if err := appendToFile(
+		fileEntry{
+			Alert:           alert,
+			FirewallBackend: backend,
+			Note:            reason,
+		},
+		reportedUserEventsLog.UnblockedIPEventTemplate,
+		reportedUserEventsLog.FilePath,
+		reportedUserEventsLog.FilePermissions,
+	); err != nil {
+		recordEventErr := fmt.Errorf(
+			"func %s: error updating events log file %q: %w",
+			caller.GetFuncName(),
+			reportedUserEventsLog.FilePath,
+			err,
+		)
+
+		return events.NewRecord(
+			alert,
+			recordEventErr,
+			unblockedMsg,
+			events.ActionFailureUnblockedIPAddress,
+			nil,
+		)
+	}
+
+	return events.NewRecord(
+		alert,
+		nil,
+		unblockedMsg,
+		events.ActionSuccessUnblockedIPAddress,
+		nil,
+	)
 }
@@ -19,11 +19,13 @@ package files
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/apex/log"
 
@@ -31,6 +33,7 @@ import (
 	"github.com/atc0005/go-ezproxy/activefile"
 
 	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/internal/auditlog"
 	"github.com/atc0005/brick/internal/caller"
 	"github.com/atc0005/brick/internal/fileutils"
 )
@@ -49,193 +52,599 @@ func processRecord(record events.Record, notifyWorkQueue chan<- events.Record) {
 
 }
 
-// ProcessDisableEvent receives a care-package of configuration settings, the
-// original alert, a channel to send event records on and values representing
-// the disabled users and reported user events log files. This function
-// handles orchestration of multiple actions taken in response to the received
-// alert and request to disable a user account (and disable the associated
-// sessions). This function returns a collection of
+// DisableContext is the collection of state files, settings and channels
+// used to process disable requests for a tenant. One value is created for
+// each tenant and shared by all disable requests received by that tenant.
+type DisableContext struct {
+
+	// ReportedUserEventsLog is the log where actions taken in response to
+	// received alerts are recorded.
+	ReportedUserEventsLog *ReportedUserEventsLog
+
+	// IgnoredSources is the collection of ignored user accounts and IP
+	// Addresses consulted before acting on received alerts.
+	IgnoredSources IgnoredSources
+
+	// ReportCounters tracks reports received toward the report threshold.
+	ReportCounters *ReportCounters
+
+	// Approvals holds disable requests pending operator approval.
+	Approvals *Approvals
+
+	// CircuitBreaker limits the number of user accounts disabled within a
+	// period of time.
+	CircuitBreaker *CircuitBreaker
+
+	// Firewall blocks the IP Addresses associated with disabled user
+	// accounts.
+	Firewall *Firewall
+
+	// Blocklist lists the usernames and IP Addresses of disabled user
+	// accounts for consumption by other systems.
+	Blocklist *Blocklist
+
+	// NotifyWorkQueue is the channel used to send event records for
+	// notification.
+	NotifyWorkQueue chan<- events.Record
+
+	// AlertPolicies is the ordered list of alert policies used to determine
+	// how received alerts are handled.
+	AlertPolicies events.AlertPolicies
+
+	// DefaultAlertPolicy is applied to alerts which do not match any of the
+	// alert policies.
+	DefaultAlertPolicy events.AlertPolicy
+
+	// Instances is the collection of EZproxy instances on which user
+	// accounts are disabled and sessions terminated.
+	Instances EZproxyInstances
+
+	// AuditFileLookback is the number of past audit files searched for user
+	// sessions.
+	AuditFileLookback int
+
+	// SessionsSearchDelay is the number of seconds to wait between session
+	// lookup attempts.
+	SessionsSearchDelay int
+
+	// SessionSearchRetries is the number of additional session lookup
+	// attempts made if no sessions are found.
+	SessionSearchRetries int
+
+	// VerifyDelay is the number of seconds to wait before verifying that
+	// terminated sessions are no longer listed in the active file.
+	VerifyDelay int
+}
+
+// ProcessDisableEvent receives the original alert along with the context
+// of the tenant which received it. This function handles orchestration of
+// multiple actions taken in response to the received alert and request to
+// disable a user account (and disable the associated sessions). The first
+// alert policy matching the alert (or the default policy) determines
+// whether the user account is disabled and whether associated sessions are
+// terminated. If the policy specifies a report threshold, the user account
+// is disabled (and sessions terminated) only once the threshold has been
+// reached. If the policy is in dry-run mode, all checks are performed, but
+// the disabled users file is not updated and sessions are not terminated.
+// If the policy requires approval, processing stops once the approval
+// request is sent and resumes via ProcessApprovedDisableEvent if approved.
+// If the mass-disable circuit breaker is open, the user account is not
+// disabled and sessions are not terminated. The user account is disabled
+// and sessions terminated on each of the EZproxy instances selected by the
+// policy; session termination results for all instances are sent as one
+// notification. If a firewall backend or blocklist is enabled, the reported
+// user IP Address is blocked and/or listed once the user account is
+// disabled.
+func ProcessDisableEvent(alert events.SplunkAlertEvent, dc DisableContext) {
+
+	// Select the policy for this alert before anything else so that all
+	// event records (and thus notifications) generated from this point
+	// forward reflect the chosen policy.
+	alertPolicy := dc.AlertPolicies.Match(alert, dc.DefaultAlertPolicy)
+	alert.Policy = &alertPolicy
+
+	processDisableEvent(alert, dc, false)
+}
+
+// ProcessApprovedDisableEvent completes processing of an alert whose disable
+// request was held for operator approval and has since been approved. The
+// alert policy selected when the alert was received determines whether
+// sessions are terminated and whether actions are only simulated. The
+// disabled status is checked again as the user account may have been
+// disabled while the request was pending. The EZproxy instances selected by
+// the alert policy are used.
+func ProcessApprovedDisableEvent(alert events.SplunkAlertEvent, dc DisableContext) {
+	processDisableEvent(alert, dc, true)
+}
+
+// processDisableEvent is the shared implementation of ProcessDisableEvent
+// and ProcessApprovedDisableEvent. The alert policy is expected to have
+// already been selected. For approved alerts, the checks performed before
+// the approval request was sent (ignored entries, report thresholds) are
+// skipped along with the approval itself.
 //
 // TODO: This function and those called within are *badly* in need of
 // refactoring.
-func ProcessDisableEvent(
-	alert events.SplunkAlertEvent,
-	disabledUsers *DisabledUsers,
-	reportedUserEventsLog *ReportedUserEventsLog,
-	ignoredSources IgnoredSources,
-	notifyWorkQueue chan<- events.Record,
-	terminateSessions bool,
-	ezproxyActiveFilePath string,
-	ezproxySessionsSearchDelay int,
-	ezproxySessionSearchRetries int,
-	ezproxyExecutable string,
-) {
+func processDisableEvent(alert events.SplunkAlertEvent, dc DisableContext, approved bool) {
 
-	// Record/log that a username was reported
-	//
-	// It so happens that we are going to try and disable a username. The
-	// assumption with this remark is that the remote monitoring system is
-	// just passing along specific information to a specific endpoint, but in
-	// reality we're setting up an alert in the monitoring system with a
-	// specific outcome in mind.
-	disableRequestReceivedResult := logEventDisableRequestReceived(
-		alert,
-		reportedUserEventsLog,
-	)
+	alertPolicy := events.AlertPolicy{}
+	if alert.Policy != nil {
+		alertPolicy = *alert.Policy
+	}
+
+	// Only the EZproxy instances selected by the policy are acted on.
+	instances := selectInstances(&alert, dc.Instances)
+
+	if !approved {
+
+		// Record/log that a username was reported
+		//
+		// It so happens that we are going to try and disable a username. The
+		// assumption with this remark is that the remote monitoring system is
+		// just passing along specific information to a specific endpoint, but
+		// in reality we're setting up an alert in the monitoring system with
+		// a specific outcome in mind.
+		disableRequestReceivedResult := logEventDisableRequestReceived(
+			alert,
+			dc.ReportedUserEventsLog,
+		)
+
+		processRecord(disableRequestReceivedResult, dc.NotifyWorkQueue)
+
+		// check whether username or IP Address is ignored, return early if
+		// true or if there is an error looking up the status which the
+		// sysadmin did not opt to disregard.
+		ignoredEntryFound, ignoredEntryResults := isIgnored(alert, dc.ReportedUserEventsLog, dc.IgnoredSources)
+		switch {
+		case ignoredEntryResults.Error != nil:
+
+			if dc.IgnoredSources.IgnoreLookupErrors {
+				// If sysadmin opted to ignore lookup errors then honor the
+				// request; emit complaint (to console, local logs, syslog
+				// via systemd, etc) and ignore the lookup error by
+				// proceeding.
+				//
+				// WARNING: See GH-62; this "feature" may be removed in a
+				// future release in order to avoid potentially unexpected
+				// logic bugs.
+				log.Warn(ignoredEntryResults.Error.Error())
+				break
+			}
+
+			// send record for notification
+			processRecord(ignoredEntryResults, dc.NotifyWorkQueue)
+
+			// exit after sending notification
+			return
 
-	processRecord(disableRequestReceivedResult, notifyWorkQueue)
+		// early exit to force desired ignore behavior
+		case ignoredEntryFound:
+
+			// Note: `logEventIgnoredUsername()` is called within
+			// `isIgnored()`, so we refrain from calling it again explicitly
+			// here.
+			processRecord(ignoredEntryResults, dc.NotifyWorkQueue)
+
+			// exit after sending notification
+			return
+
+		}
+	}
 
-	// check whether username or IP Address is ignored, return early if true
-	// or if there is an error looking up the status which the sysadmin did
-	// not opt to disregard.
-	ignoredEntryFound, ignoredEntryResults := isIgnored(alert, reportedUserEventsLog, ignoredSources)
 	switch {
-	case ignoredEntryResults.Error != nil:
 
-		if ignoredSources.IgnoreLookupErrors {
-			// If sysadmin opted to ignore lookup errors then honor the
-			// request; emit complaint (to console, local logs, syslog via
-			// systemd, etc) and ignore the lookup error by proceeding.
-			//
-			// WARNING: See GH-62; this "feature" may be removed in a future
-			// release in order to avoid potentially unexpected logic bugs.
-			log.Warn(ignoredEntryResults.Error.Error())
-			break
+	// report-only and terminate-only policies skip disabling the account
+	case !alertPolicy.Disable():
+
+		skippedDisableResult := logEventSkippedDisableUsername(alert)
+		processRecord(skippedDisableResult, dc.NotifyWorkQueue)
+
+		// nothing further to do for report-only policies
+		if alertPolicy.Action == events.PolicyActionReportOnly {
+			return
 		}
 
-		// send record for notification
-		processRecord(ignoredEntryResults, notifyWorkQueue)
+	default:
 
-		// exit after sending notification
-		return
+		// check to see if username has already been disabled on all
+		// selected instances
+		pendingInstances, disableEntryLookupErr := notDisabledInstances(alert, instances)
 
-	// early exit to force desired ignore behavior
-	case ignoredEntryFound:
+		// Handle logic for disabling user account
+		switch {
 
-		// Note: `logEventIgnoredUsername()` is called within `isIgnored()`,
-		// so we refrain from calling it again explicitly here.
-		processRecord(ignoredEntryResults, notifyWorkQueue)
+		case disableEntryLookupErr != nil:
 
-		// exit after sending notification
-		return
+			if dc.IgnoredSources.IgnoreLookupErrors {
+				// If sysadmin opted to ignore lookup errors then honor the
+				// request; emit complaint (to console, local logs, syslog via
+				// systemd, etc) and ignore the lookup error by proceeding.
+				//
+				// WARNING: See GH-62; this "feature" may be removed in a future
+				// release in order to avoid potentially unexpected logic bugs.
+				log.Warn(disableEntryLookupErr.Error())
+
+				// NOTE: If the lookup error is being ignored, we skip all
+				// attempts to disable the user account.
+				break
+			}
+
+			result := events.NewRecord(
+				alert,
+				disableEntryLookupErr,
+				// FIXME: Not sure what Note or "summary" field value to use here
+				"",
+				events.ActionFailureDisabledUsername,
+				nil,
+			)
+
+			processRecord(result, dc.NotifyWorkQueue)
+
+			return
+
+		case len(pendingInstances) > 0:
+
+			if !approved {
+
+				// count this report toward the threshold (if any) and
+				// return early if the threshold has not been reached
+				if !reportThresholdMet(&alert, dc.ReportCounters, dc.IgnoredSources, dc.NotifyWorkQueue) {
+					return
+				}
+
+				// hold the request until an operator approves it (or it
+				// times out); processing resumes via
+				// ProcessApprovedDisableEvent. Operators are not asked to
+				// approve simulated actions.
+				switch {
+				case alertPolicy.RequireApproval && alertPolicy.DryRunEnabled():
+					log.Infof(
+						"[DRY-RUN] Would have requested approval to disable username %q",
+						alert.Username,
+					)
+				case alertPolicy.RequireApproval:
+					approvalRequestResult := dc.Approvals.Request(alert)
+					processRecord(approvalRequestResult, dc.NotifyWorkQueue)
+
+					return
+				}
+			}
+
+			if !disableUsername(alert, pendingInstances, dc) {
+				return
+			}
+
+		default:
+
+			usernameAlreadyDisabledResult := logEventUsernameAlreadyDisabled(alert, dc.ReportedUserEventsLog)
+			processRecord(usernameAlreadyDisabledResult, dc.NotifyWorkQueue)
+
+		}
 
 	}
 
-	// check to see if username has already been disabled
-	disabledUserEntry := alert.Username + disabledUsers.EntrySuffix
-	disableEntryFound, disableEntryLookupErr := fileutils.HasLine(
-		disabledUserEntry,
-		"#",
-		disabledUsers.FilePath,
-	)
+	// At this point the username has been disabled, either just now or as
+	// part of a previous report, unless the policy only calls for session
+	// termination.
+	blockUserIP(alert, dc.Firewall, dc.Blocklist)
 
-	// Handle logic for disabling user account
-	switch {
+	processUserSessions(alert, instances, dc)
 
-	case disableEntryLookupErr != nil:
+}
 
-		errMsg := fmt.Errorf(
-			"error while checking disabled status for user %q from IP %q: %w",
-			alert.Username,
+// isDisabled indicates whether the reported username is already listed in
+// the disabled users file. If the alert policy quarantines user accounts, an
+// existing quarantine entry also counts; an existing disable entry counts
+// either way as it is the more restrictive of the two.
+func isDisabled(alert events.SplunkAlertEvent, disabledUsers *DisabledUsers) (bool, error) {
+
+	templates := []*template.Template{disabledUsers.Template}
+	if quarantineEnabled(alert) {
+		templates = append(templates, disabledUsers.QuarantineTemplate)
+	}
+
+	for _, tmpl := range templates {
+		disableEntryFound, disableEntryLookupErr := hasDisabledUsersEntries(alert, disabledUsers, tmpl)
+		if disableEntryLookupErr != nil {
+			return false, fmt.Errorf(
+				"error while checking disabled status for user %q from IP %q: %w",
+				alert.Username,
+				alert.UserIP,
+				disableEntryLookupErr,
+			)
+		}
+
+		if disableEntryFound {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// hasDisabledUsersEntries indicates whether all of the entries rendered from
+// the provided template for the reported username are listed in the disabled
+// users file.
+func hasDisabledUsersEntries(
+	alert events.SplunkAlertEvent,
+	disabledUsers *DisabledUsers,
+	tmpl *template.Template,
+) (bool, error) {
+
+	if tmpl == nil {
+		return false, nil
+	}
+
+	entries, err := disabledUsersEntries(alert, disabledUsers.EntrySuffix, tmpl)
+	if err != nil {
+		return false, err
+	}
+
+	if len(entries) == 0 {
+		return false, nil
+	}
+
+	for _, entry := range entries {
+		found, err := fileutils.HasLine(entry, "#", disabledUsers.FilePath)
+		if err != nil {
+			return false, err
+		}
+		if !found {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// quarantineEnabled indicates whether the alert policy applied to the alert
+// quarantines user accounts instead of disabling them.
+func quarantineEnabled(alert events.SplunkAlertEvent) bool {
+	return alert.Policy != nil && alert.Policy.Quarantine()
+}
+
+// blockUserIP blocks the reported user IP Address using the firewall
+// backend and lists it in the blocklist if the alert policy disables (rather
+// than quarantines) user accounts. The IP Address is not blocked if the
+// alert policy is in dry-run mode.
+func blockUserIP(alert events.SplunkAlertEvent, firewall *Firewall, blocklist *Blocklist) {
+
+	if alert.Policy == nil || (!firewall.Enabled() && !blocklist.Enabled()) {
+		return
+	}
+
+	if !alert.Policy.Disable() || alert.Policy.Quarantine() {
+		return
+	}
+
+	if alert.Policy.DryRunEnabled() {
+		log.Infof(
+			"[DRY-RUN] Would have blocked IP %q associated with username %q",
 			alert.UserIP,
-			disableEntryLookupErr,
+			alert.Username,
 		)
+		return
+	}
 
-		if ignoredSources.IgnoreLookupErrors {
-			// If sysadmin opted to ignore lookup errors then honor the
-			// request; emit complaint (to console, local logs, syslog via
-			// systemd, etc) and ignore the lookup error by proceeding.
-			//
-			// WARNING: See GH-62; this "feature" may be removed in a future
-			// release in order to avoid potentially unexpected logic bugs.
-			log.Warn(disableEntryLookupErr.Error())
-
-			// NOTE: If the lookup error is being ignored, we skip all
-			// attempts to disable the user account.
-			break
+	firewall.Block(alert)
+	blocklist.Block(alert)
+}
+
+// notDisabledInstances returns the provided EZproxy instances on which the
+// reported username is not yet listed in the disabled users file. An error
+// is returned if the disabled status could not be checked for any instance.
+func notDisabledInstances(alert events.SplunkAlertEvent, instances EZproxyInstances) (EZproxyInstances, error) {
+
+	var pending EZproxyInstances
+	for _, instance := range instances {
+		disableEntryFound, err := isDisabled(alert, instance.DisabledUsers)
+		if err != nil {
+			return nil, instanceError(alert, instance, err)
 		}
 
-		result := events.NewRecord(
-			alert,
-			errMsg,
-			// FIXME: Not sure what Note or "summary" field value to use here
-			"",
-			events.ActionFailureDisabledUsername,
-			nil,
-		)
+		if !disableEntryFound {
+			pending = append(pending, instance)
+		}
+	}
 
-		processRecord(result, notifyWorkQueue)
+	return pending, nil
+}
 
-		return
+// disableUsername adds the reported username to the disabled users file for
+// each of the provided EZproxy instances (or records what would have been
+// done if the alert policy is in dry-run mode) and sends the results as
+// notifications. False is returned if the user account could not be disabled
+// on one or more instances or the mass-disable circuit breaker is open; the
+// caller should stop processing the alert.
+func disableUsername(
+	alert events.SplunkAlertEvent,
+	instances EZproxyInstances,
+	dc DisableContext,
+) bool {
 
-	case !disableEntryFound:
+	// record what would have happened, but leave the disabled users file
+	// untouched
+	if alert.Policy != nil && alert.Policy.DryRunEnabled() {
+		dryRunResult := logEventDryRunDisabledUsername(alert, dc.ReportedUserEventsLog)
+		processRecord(dryRunResult, dc.NotifyWorkQueue)
 
-		// log our intent to disable the username
-		logEventDisablingUsername(alert, reportedUserEventsLog)
+		resetReportCounters(alert, dc.ReportCounters)
+
+		return true
+	}
 
-		// disable usename
-		if err := disableUser(alert, disabledUsers); err != nil {
+	// stop disabling user accounts if too many have been disabled recently
+	if dc.CircuitBreaker.Enabled() {
+		status, err := dc.CircuitBreaker.Allow(alert)
+		if err != nil {
 			result := events.NewRecord(
 				alert,
-				err,
-				// FIXME: Unsure what note to use here
+				fmt.Errorf(
+					"error checking circuit breaker for user %q; not disabling user account: %w",
+					alert.Username,
+					err,
+				),
 				"",
-				events.ActionFailureDisabledUsername,
+				events.ActionFailureCircuitBreaker,
 				nil,
 			)
+			processRecord(result, dc.NotifyWorkQueue)
 
-			processRecord(result, notifyWorkQueue)
+			return false
+		}
 
-			return
+		if status.Open {
+			alert.CircuitBreaker = &status
+			suspendedResult := logEventSuspendedUsername(alert, dc.ReportedUserEventsLog)
+			processRecord(suspendedResult, dc.NotifyWorkQueue)
+
+			return false
 		}
+	}
 
-		// log success (file, notifications, etc.)
-		disableUsernameResult := logEventDisabledUsername(alert, reportedUserEventsLog)
-		processRecord(disableUsernameResult, notifyWorkQueue)
+	// log our intent to disable the username
+	logEventDisablingUsername(alert, dc.ReportedUserEventsLog)
 
-	case disableEntryFound:
+	// disable usename
+	var disableErrs []string
+	var disabled int
+	for _, instance := range instances {
+		if err := disableUser(alert, instance.DisabledUsers); err != nil {
+			disableErrs = append(disableErrs, instanceError(alert, instance, err).Error())
+			continue
+		}
+		disabled++
+	}
 
-		usernameAlreadyDisabledResult := logEventUsernameAlreadyDisabled(alert, reportedUserEventsLog)
-		processRecord(usernameAlreadyDisabledResult, notifyWorkQueue)
+	// only user accounts actually disabled count toward the limit
+	if disabled == 0 {
+		if err := dc.CircuitBreaker.Release(alert); err != nil {
+			log.Warnf(
+				"failed to release circuit breaker slot for user %q: %v",
+				alert.Username,
+				err,
+			)
+		}
+	}
 
+	if disabled > 0 {
+		dc.Blocklist.Changed()
 	}
 
-	// At this point the username has been disabled, either just now or as
-	// part of a previous report. We should proceed with session termination
-	// if enabled or note that the setting is not enabled for troubleshooting
-	// purposes later.
-	switch {
-	case !terminateSessions:
+	if len(disableErrs) > 0 {
+		result := events.NewRecord(
+			alert,
+			errors.New(strings.Join(disableErrs, "; ")),
+			// FIXME: Unsure what note to use here
+			"",
+			events.ActionFailureDisabledUsername,
+			nil,
+		)
+
+		processRecord(result, dc.NotifyWorkQueue)
+
+		return false
+	}
+
+	// log success (file, notifications, etc.)
+	disableUsernameResult := logEventDisabledUsername(alert, dc.ReportedUserEventsLog)
+	processRecord(disableUsernameResult, dc.NotifyWorkQueue)
+
+	resetReportCounters(alert, dc.ReportCounters)
+
+	return true
+}
+
+// resetReportCounters clears the reports counted toward the report threshold
+// (if any) for the reported username once the user account has been
+// disabled so that counting starts over if the user account is later
+// re-enabled. In dry-run mode only the separate dry-run reports are cleared.
+func resetReportCounters(alert events.SplunkAlertEvent, reportCounters *ReportCounters) {
+
+	if alert.Policy == nil || !alert.Policy.ReportThreshold().Enabled() {
+		return
+	}
+
+	if err := reportCounters.Reset(alert); err != nil {
+		log.Warnf(
+			"failed to reset report counters for user %q: %v",
+			alert.Username,
+			err,
+		)
+	}
+}
+
+// processUserSessions looks up the sessions associated with the reported
+// username on each of the provided EZproxy instances and terminates them if
+// enabled by the alert policy or notes that termination is not enabled for
+// troubleshooting purposes later. Results for all instances are sent as one
+// notification.
+func processUserSessions(
+	alert events.SplunkAlertEvent,
+	instances EZproxyInstances,
+	dc DisableContext,
+) {
+
+	alertPolicy := events.AlertPolicy{}
+	if alert.Policy != nil {
+		alertPolicy = *alert.Policy
+	}
+
+	if !alertPolicy.Terminate() {
+		log.Warnf(
+			"Sessions termination is not enabled by alert policy %s. Sessions will persist until they timeout.",
+			alertPolicy,
+		)
+	}
+
+	// Sessions are only looked up and terminated on the instances selected
+	// by the alert policy, regardless of the instances provided.
+	if alert.Policy != nil {
+		instances = instances.Select(alert.Policy.Instances)
+	}
 
-		log.Warn("Sessions termination is disabled via configuration setting. Sessions will persist until they timeout.")
+	var userSessionIDs []string
+	instanceSessions := make([]ezproxy.UserSessions, len(instances))
+
+	for i, instance := range instances {
 
 		userSessions, userSessionsLookupErr := getUserSessions(
 			alert,
-			reportedUserEventsLog,
-			ezproxyActiveFilePath,
-			ezproxySessionsSearchDelay,
-			ezproxySessionSearchRetries,
-			ezproxyExecutable,
+			dc.ReportedUserEventsLog,
+			instance.ActiveFileWatcher,
+			instance.ActiveFilePath,
+			instance.AuditFileDirPath,
+			dc.AuditFileLookback,
+			dc.SessionsSearchDelay,
+			dc.SessionSearchRetries,
+			instance.Terminator,
 		)
 
 		if userSessionsLookupErr != nil {
 			record := events.NewRecord(
 				alert,
-				userSessionsLookupErr,
+				instanceError(alert, instance, userSessionsLookupErr),
 				"",
 				events.ActionFailureUserSessionLookupFailure,
 				nil,
 			)
 
-			processRecord(record, notifyWorkQueue)
+			processRecord(record, dc.NotifyWorkQueue)
 
 		}
 
-		var userSessionIDs []string
 		for _, session := range userSessions {
 			userSessionIDs = append(userSessionIDs, session.SessionID)
 		}
 
+		instanceSessions[i] = userSessions
+	}
+
+	terminateInstances, terminateSessions := sessionTerminationTargets(instances, instanceSessions)
+
+	switch {
+	case !alertPolicy.Terminate():
+
 		sessionsSkipped := strings.Join(userSessionIDs, `", "`)
 
 		sessionsSkippedMsg := fmt.Sprintf(
@@ -253,48 +662,249 @@ func ProcessDisableEvent(
 			nil,
 		)
 
-		processRecord(record, notifyWorkQueue)
+		processRecord(record, dc.NotifyWorkQueue)
 
-	case terminateSessions:
+	default:
 
-		userSessions, userSessionsLookupErr := getUserSessions(
-			alert,
-			reportedUserEventsLog,
-			ezproxyActiveFilePath,
-			ezproxySessionsSearchDelay,
-			ezproxySessionSearchRetries,
-			ezproxyExecutable,
-		)
+		terminateUserSessionsResults := make([]events.Record, 0, len(terminateInstances))
+		for i, instance := range terminateInstances {
 
-		if userSessionsLookupErr != nil {
-			record := events.NewRecord(
+			// logEventTerminatingUserSession is called within this function
+			// for each session termination attempt (one or many) and
+			// logEventTerminatedUserSessions is called at the end of the
+			// function to provide a summary of the results.
+			terminateUserSessionsResult := terminateUserSessions(
 				alert,
-				userSessionsLookupErr,
+				dc.ReportedUserEventsLog,
+				terminateSessions[i],
+				instance,
+				dc.VerifyDelay,
+				alertPolicy.DryRunEnabled(),
 				"",
-				events.ActionFailureUserSessionLookupFailure,
-				nil,
 			)
 
-			processRecord(record, notifyWorkQueue)
+			terminateUserSessionsResults = append(terminateUserSessionsResults, terminateUserSessionsResult)
+		}
 
+		processRecord(
+			mergeInstanceRecords(alert, terminateInstances, terminateUserSessionsResults),
+			dc.NotifyWorkQueue,
+		)
+
+	}
+
+}
+
+// sessionTerminationTargets returns the instances on which sessions are
+// terminated along with the sessions found on each. The provided sessions
+// are those found on the instance at the same index. Instances without
+// sessions for the user are skipped; if no instance has any, all of the
+// provided instances are returned without sessions so that the results note
+// that no sessions were found. Only the provided instances are ever
+// returned.
+func sessionTerminationTargets(
+	instances EZproxyInstances,
+	sessions []ezproxy.UserSessions,
+) (EZproxyInstances, []ezproxy.UserSessions) {
+
+	var targetInstances EZproxyInstances
+	var targetSessions []ezproxy.UserSessions
+
+	for i, instance := range instances {
+		if i < len(sessions) && len(sessions[i]) > 0 {
+			targetInstances = append(targetInstances, instance)
+			targetSessions = append(targetSessions, sessions[i])
 		}
+	}
 
-		// logEventTerminatingUserSession is called within this function for
-		// each session termination attempt (one or many) and
-		// logEventTerminatedUserSessions is called at the end of the function
-		// to provide a summary of the results.
-		terminateUserSessionsResult := terminateUserSessions(
-			alert,
-			reportedUserEventsLog,
-			userSessions,
-			ezproxyActiveFilePath,
-			ezproxyExecutable,
+	if len(targetInstances) == 0 {
+		return instances, make([]ezproxy.UserSessions, len(instances))
+	}
+
+	return targetInstances, targetSessions
+}
+
+// ProcessAddIgnoredEntryEvent adds (or replaces) the provided entry in the
+// specified ignored entries file on behalf of the named operator. The change
+// (or failure to make it) is recorded in the reported user events log and
+// sent as a notification via the provided channel. Any error encountered
+// while updating the ignored entries file is returned to the caller.
+func ProcessAddIgnoredEntryEvent(
+	alert events.SplunkAlertEvent,
+	operator string,
+	ignoredEntriesFile string,
+	entry IgnoredEntry,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	notifyWorkQueue chan<- events.Record,
+) error {
+
+	replaced, err := AddIgnoredEntry(ignoredEntriesFile, entry)
+
+	verb := "Added"
+	if replaced {
+		verb = "Replaced"
+	}
+
+	changeMsg := fmt.Sprintf(
+		"%s entry %q in %q",
+		verb,
+		entry.Value,
+		ignoredEntriesFile,
+	)
+	if entry.HasMetadata() {
+		changeMsg += fmt.Sprintf(" (%s)", entry.Details())
+	}
+
+	result := logEventOperatorChange(
+		alert,
+		reportedUserEventsLog,
+		operator,
+		events.ActionSuccessIgnoredEntryAdded,
+		events.ActionFailureIgnoredEntryUpdate,
+		changeMsg,
+		err,
+	)
+
+	processRecord(result, notifyWorkQueue)
+
+	return err
+}
+
+// ProcessCircuitBreakerResetEvent resets the mass-disable circuit breaker on
+// behalf of the named operator so that user accounts are once again
+// disabled in response to received alerts. The change is recorded in the
+// reported user events log and sent as a notification. The circuit breaker
+// status prior to the reset is returned.
+func ProcessCircuitBreakerResetEvent(
+	alert events.SplunkAlertEvent,
+	operator string,
+	circuitBreaker *CircuitBreaker,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	notifyWorkQueue chan<- events.Record,
+) (events.CircuitBreakerStatus, error) {
+
+	status, err := circuitBreaker.Reset()
+
+	changeMsg := fmt.Sprintf(
+		"Reset circuit breaker in %q (previously %s)",
+		circuitBreaker.FilePath,
+		status,
+	)
+
+	result := logEventOperatorChange(
+		alert,
+		reportedUserEventsLog,
+		operator,
+		events.ActionSuccessCircuitBreakerReset,
+		events.ActionFailureCircuitBreakerReset,
+		changeMsg,
+		err,
+	)
+
+	processRecord(result, notifyWorkQueue)
+
+	return status, err
+}
+
+// ProcessRemoveIgnoredEntryEvent removes all entries for the provided value
+// from the specified ignored entries file on behalf of the named operator.
+// The change (or failure to make it) is recorded in the reported user events
+// log and sent as a notification via the provided channel. False is returned
+// if no matching entry was found; no event is recorded in that case.
+func ProcessRemoveIgnoredEntryEvent(
+	alert events.SplunkAlertEvent,
+	operator string,
+	ignoredEntriesFile string,
+	value string,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	notifyWorkQueue chan<- events.Record,
+) (bool, error) {
+
+	removed, err := RemoveIgnoredEntry(ignoredEntriesFile, value)
+	if err == nil && !removed {
+		return false, nil
+	}
+
+	changeMsg := fmt.Sprintf(
+		"Removed entry %q from %q",
+		value,
+		ignoredEntriesFile,
+	)
+
+	result := logEventOperatorChange(
+		alert,
+		reportedUserEventsLog,
+		operator,
+		events.ActionSuccessIgnoredEntryRemoved,
+		events.ActionFailureIgnoredEntryUpdate,
+		changeMsg,
+		err,
+	)
+
+	processRecord(result, notifyWorkQueue)
+
+	return removed, err
+}
+
+// reportThresholdMet records the alert in the report counters and indicates
+// whether the report threshold for the selected alert policy has been
+// reached. The threshold status is attached to the alert so that subsequent
+// notifications reflect it. A notification is sent if the threshold has not
+// been reached or if the report counters could not be updated; in the latter
+// case, true is returned only if the sysadmin opted to ignore lookup errors.
+func reportThresholdMet(
+	alert *events.SplunkAlertEvent,
+	reportCounters *ReportCounters,
+	ignoredSources IgnoredSources,
+	notifyWorkQueue chan<- events.Record,
+) bool {
+
+	threshold := alert.Policy.ReportThreshold()
+	if !threshold.Enabled() {
+		return true
+	}
+
+	status, err := reportCounters.Record(*alert, threshold)
+	if err != nil {
+
+		errMsg := fmt.Errorf(
+			"error while updating report counters for user %q from IP %q: %w",
+			alert.Username,
+			alert.UserIP,
+			err,
+		)
+
+		if ignoredSources.IgnoreLookupErrors {
+			// If sysadmin opted to ignore lookup errors then honor the
+			// request; emit complaint and proceed as though the
+			// threshold has been reached.
+			log.Warn(errMsg.Error())
+			return true
+		}
+
+		result := events.NewRecord(
+			*alert,
+			errMsg,
+			"",
+			events.ActionFailureReportThreshold,
+			nil,
 		)
 
-		processRecord(terminateUserSessionsResult, notifyWorkQueue)
+		processRecord(result, notifyWorkQueue)
+
+		return false
+	}
+
+	alert.ReportThreshold = &status
+
+	if !status.Met() {
+		belowThresholdResult := logEventBelowReportThreshold(*alert)
+		processRecord(belowThresholdResult, notifyWorkQueue)
 
+		return false
 	}
 
+	return true
 }
 
 // isIgnored is a wrapper function to help concentrate common ignored status
@@ -308,9 +918,8 @@ func isIgnored(
 	ignoredSources IgnoredSources,
 ) (bool, events.Record) {
 
-	ignoredUserEntryFound, ignoredUserLookupErr := fileutils.HasLine(
+	ignoredUserEntry, ignoredUserEntryFound, ignoredUserLookupErr := findIgnoredEntry(
 		alert.Username,
-		"#",
 		ignoredSources.IgnoredUsersFile,
 	)
 
@@ -342,6 +951,7 @@ func isIgnored(
 			alert,
 			reportedUserEventsLog,
 			ignoredSources.IgnoredUsersFile,
+			ignoredUserEntry,
 		)
 
 		return true, ignoredUsernameResult
@@ -349,9 +959,8 @@ func isIgnored(
 	}
 
 	// check to see if IP Address has been ignored
-	ipAddressIgnoreEntryFound, ipAddressIgnoreLookupErr := fileutils.HasLine(
+	ipAddressIgnoreEntry, ipAddressIgnoreEntryFound, ipAddressIgnoreLookupErr := findIgnoredEntry(
 		alert.UserIP,
-		"#",
 		ignoredSources.IgnoredIPAddressesFile,
 	)
 
@@ -383,6 +992,7 @@ func isIgnored(
 			alert,
 			reportedUserEventsLog,
 			ignoredSources.IgnoredIPAddressesFile,
+			ipAddressIgnoreEntry,
 		)
 
 		return true, ignoredIPAddressResult
@@ -401,13 +1011,122 @@ func isIgnored(
 
 }
 
+// getUserSessions retrieves the sessions associated with the reported
+// username from the EZproxy active file and, if enabled, the EZproxy audit
+// files. Sessions from both sources are merged. If the audit files record
+// sessions for the user, the active file is searched without retries as
+// there is no need to wait for EZproxy to write those sessions to the active
+// file. If the active file watcher is enabled, its index is used in place of
+// reading the active file. If the active file cannot be read, the sessions
+// found in the audit files are used instead.
 func getUserSessions(
 	alert events.SplunkAlertEvent,
 	reportedUserEventsLog *ReportedUserEventsLog,
+	activeFileWatcher *ActiveFileWatcher,
+	ezproxyActiveFilePath string,
+	ezproxyAuditFileDirPath string,
+	ezproxyAuditFileLookback int,
+	ezproxySessionsSearchDelay int,
+	ezproxySessionSearchRetries int,
+	terminator SessionTerminator,
+) (ezproxy.UserSessions, error) {
+
+	auditFileSessions, auditFileErr := getAuditFileUserSessions(
+		alert,
+		ezproxyAuditFileDirPath,
+		ezproxyAuditFileLookback,
+	)
+	if auditFileErr != nil {
+		log.Warn(auditFileErr.Error())
+	}
+
+	if len(auditFileSessions) > 0 {
+		ezproxySessionSearchRetries = 0
+	}
+
+	var activeFileSessions ezproxy.UserSessions
+	var activeFileErr error
+	switch {
+	case activeFileWatcher.Enabled():
+		activeFileSessions, activeFileErr = waitForActiveFileUserSessions(
+			alert,
+			activeFileWatcher,
+			ezproxySessionsSearchDelay,
+			ezproxySessionSearchRetries,
+		)
+	default:
+		activeFileSessions, activeFileErr = getActiveFileUserSessions(
+			alert,
+			ezproxyActiveFilePath,
+			ezproxySessionsSearchDelay,
+			ezproxySessionSearchRetries,
+		)
+	}
+
+	switch {
+	case activeFileErr != nil && len(auditFileSessions) > 0:
+		log.Warnf(
+			"%v; using %d sessions found in audit files within %q instead",
+			activeFileErr,
+			len(auditFileSessions),
+			ezproxyAuditFileDirPath,
+		)
+
+		return auditFileSessions, nil
+
+	case activeFileErr != nil:
+		return nil, activeFileErr
+	}
+
+	return mergeUserSessions(activeFileSessions, auditFileSessions), nil
+}
+
+// waitForActiveFileUserSessions retrieves the sessions associated with the
+// reported user from the active file watcher index. If no sessions are
+// indexed, this waits for EZproxy to write them to the active file for up to
+// the combined time of the configured search delay and retries.
+func waitForActiveFileUserSessions(
+	alert events.SplunkAlertEvent,
+	activeFileWatcher *ActiveFileWatcher,
+	ezproxySessionsSearchDelay int,
+	ezproxySessionSearchRetries int,
+) (ezproxy.UserSessions, error) {
+
+	scope := events.TerminateScopeUsername
+	if alert.Policy != nil {
+		scope = alert.Policy.TerminationScope()
+	}
+
+	wait := time.Duration(ezproxySessionsSearchDelay*(ezproxySessionSearchRetries+1)) * time.Second
+
+	log.Debugf(
+		"%s: Waiting up to %v for sessions within scope %q for %q in %q",
+		caller.GetFuncName(),
+		wait,
+		scope,
+		alert.Username,
+		activeFileWatcher.FilePath,
+	)
+
+	sessions, err := activeFileWatcher.Wait(time.Now().Add(wait), scope, alert.Username, alert.UserIP)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error retrieving matching user sessions associated with user %q from active file watcher: %w",
+			alert.Username,
+			err,
+		)
+	}
+
+	return sessions, nil
+}
+
+// getActiveFileUserSessions retrieves the sessions associated with the
+// reported username from the EZproxy active file.
+func getActiveFileUserSessions(
+	alert events.SplunkAlertEvent,
 	ezproxyActiveFilePath string,
 	ezproxySessionsSearchDelay int,
 	ezproxySessionSearchRetries int,
-	ezproxyExecutable string,
 ) (ezproxy.UserSessions, error) {
 
 	reader, readerErr := activefile.NewReader(alert.Username, ezproxyActiveFilePath)
@@ -451,7 +1170,12 @@ func getUserSessions(
 		alert.Username,
 	)
 
-	activeSessions, userSessionsLookupErr := reader.MatchingUserSessions()
+	activeSessions, userSessionsLookupErr := scopedUserSessions(
+		reader,
+		alert,
+		ezproxySessionsSearchDelay,
+		ezproxySessionSearchRetries,
+	)
 	if userSessionsLookupErr != nil {
 		userSessionsRetrievalErr := fmt.Errorf(
 			"error retrieving matching user sessions associated with user %q: %w",
@@ -465,12 +1189,161 @@ func getUserSessions(
 	return activeSessions, nil
 }
 
+// getAuditFileUserSessions retrieves the sessions associated with the
+// reported username from login events recorded in the EZproxy audit files
+// within the specified lookback window. No sessions are returned if the
+// lookback window is 0.
+func getAuditFileUserSessions(
+	alert events.SplunkAlertEvent,
+	ezproxyAuditFileDirPath string,
+	ezproxyAuditFileLookback int,
+) (ezproxy.UserSessions, error) {
+
+	if ezproxyAuditFileLookback == 0 {
+		return nil, nil
+	}
+
+	reader, readerErr := auditlog.NewReader(alert.Username, ezproxyAuditFileDirPath)
+	if readerErr != nil {
+		return nil, fmt.Errorf(
+			"error while creating audit file reader to retrieve sessions associated with user %q: %w",
+			alert.Username,
+			readerErr,
+		)
+	}
+
+	if err := reader.SetLookback(ezproxyAuditFileLookback); err != nil {
+		return nil, fmt.Errorf(
+			"error while setting lookback for audit file reader to retrieve sessions associated with user %q: %w",
+			alert.Username,
+			err,
+		)
+	}
+
+	log.Debugf(
+		"%s: Searching audit files within %q for %q",
+		caller.GetFuncName(),
+		ezproxyAuditFileDirPath,
+		alert.Username,
+	)
+
+	auditFileSessions, err := scopedUserSessions(reader, alert, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error retrieving matching user sessions from audit files associated with user %q: %w",
+			alert.Username,
+			err,
+		)
+	}
+
+	return auditFileSessions, nil
+}
+
+// scopedUserSessions returns the sessions found by the provided reader which
+// fall within the termination scope of the alert policy. Sessions selected by
+// username alone rely on the retry behavior of the reader. For other scopes,
+// all sessions are read after the search delay (in seconds) and filtered,
+// retrying up to the specified number of times if no sessions match.
+func scopedUserSessions(
+	reader ezproxy.SessionsReader,
+	alert events.SplunkAlertEvent,
+	searchDelay int,
+	searchRetries int,
+) (ezproxy.UserSessions, error) {
+
+	scope := events.TerminateScopeUsername
+	if alert.Policy != nil {
+		scope = alert.Policy.TerminationScope()
+	}
+
+	if scope == events.TerminateScopeUsername {
+		return reader.MatchingUserSessions()
+	}
+
+	userIP := net.ParseIP(alert.UserIP)
+	if userIP == nil {
+		return nil, fmt.Errorf(
+			"termination scope %q requires a valid user IP Address; received %q",
+			scope,
+			alert.UserIP,
+		)
+	}
+
+	matched := make(ezproxy.UserSessions, 0, ezproxy.SessionsLimit)
+	for attempt := 0; attempt <= searchRetries; attempt++ {
+
+		time.Sleep(time.Duration(searchDelay) * time.Second)
+
+		allUserSessions, err := reader.AllUserSessions()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, session := range allUserSessions {
+			if sessionInScope(session, scope, alert.Username, userIP) {
+				matched = append(matched, session)
+			}
+		}
+
+		if len(matched) > 0 {
+			break
+		}
+	}
+
+	return matched, nil
+}
+
+// sessionInScope indicates whether the provided session falls within the
+// specified termination scope for the reported username and user IP Address.
+func sessionInScope(session ezproxy.UserSession, scope string, username string, userIP net.IP) bool {
+
+	switch scope {
+	case events.TerminateScopeIP:
+		return userIP.Equal(net.ParseIP(session.IPAddress))
+	case events.TerminateScopeUsernameIP:
+		return userIP.Equal(net.ParseIP(session.IPAddress)) &&
+			strings.EqualFold(username, session.Username)
+	default:
+		return strings.EqualFold(username, session.Username)
+	}
+}
+
+// mergeUserSessions combines the sessions found in the active file with
+// those found in the audit files. Sessions found in both are only listed
+// once using the details from the active file.
+func mergeUserSessions(activeFileSessions ezproxy.UserSessions, auditFileSessions ezproxy.UserSessions) ezproxy.UserSessions {
+
+	merged := make(ezproxy.UserSessions, 0, len(activeFileSessions)+len(auditFileSessions))
+	seen := make(map[string]struct{}, cap(merged))
+
+	for _, sessions := range []ezproxy.UserSessions{activeFileSessions, auditFileSessions} {
+		for _, session := range sessions {
+			if _, ok := seen[session.SessionID]; ok {
+				continue
+			}
+			seen[session.SessionID] = struct{}{}
+			merged = append(merged, session)
+		}
+	}
+
+	if len(merged) > len(activeFileSessions) {
+		log.Infof(
+			"Found %d sessions in audit files not yet recorded in the active file",
+			len(merged)-len(activeFileSessions),
+		)
+	}
+
+	return merged
+}
+
 func terminateUserSessions(
 	alert events.SplunkAlertEvent,
 	reportedUserEventsLog *ReportedUserEventsLog,
 	activeSessions ezproxy.UserSessions,
-	ezproxyActiveFilePath string,
-	ezproxyExecutable string,
+	instance EZproxyInstance,
+	ezproxyVerifyDelay int,
+	dryRun bool,
+	operator string,
 ) events.Record {
 
 	// TODO: On the fence re emitting this output each time
@@ -492,10 +1365,15 @@ func terminateUserSessions(
 	// and our receiving the notification.
 	if len(activeSessions) == 0 {
 
+		alertPolicy := events.AlertPolicy{}
+		if alert.Policy != nil {
+			alertPolicy = *alert.Policy
+		}
+
 		activeSessionsCountErr := fmt.Errorf(
-			"0 active sessions found for username %q in file %q",
-			alert.Username,
-			ezproxyActiveFilePath,
+			"0 active sessions found in file %q for termination scope %s",
+			instance.ActiveFilePath,
+			alertPolicy.TerminationScopeDescription(alert),
 		)
 
 		return events.NewRecord(
@@ -516,39 +1394,178 @@ func terminateUserSessions(
 		alert.Username,
 	)
 
+	// report the sessions which would have been terminated without
+	// attempting to terminate them
+	if dryRun {
+		return logEventDryRunTerminatedUserSessions(
+			alert,
+			reportedUserEventsLog,
+			activeSessions,
+		)
+	}
+
 	for _, session := range activeSessions {
 		logEventTerminatingUserSession(alert, session)
 	}
 
-	terminationResults := activeSessions.Terminate(ezproxyExecutable)
+	terminationResults := instance.Terminator.Terminate(activeSessions)
 
-	// User sessions *should* now be terminated; results of the attempts
-	// are recorded for further review to confirm.
+	// User sessions *should* now be terminated; optionally confirm this
+	// using the active file. Sessions are only checked for the reported user
+	// (vs an operator terminating a specific session).
+	var verification *events.TerminationVerification
+	if ezproxyVerifyDelay > 0 {
+		verification, terminationResults = verifyTerminatedUserSessions(
+			alert,
+			instance.ActiveFileWatcher,
+			instance.ActiveFilePath,
+			instance.Terminator,
+			terminationResults,
+			time.Duration(ezproxyVerifyDelay)*time.Second,
+			operator == "",
+		)
+	}
 
+	// Results of the attempts are recorded for further review.
 	logTerminatedUserSessionsResult := logEventTerminatedUserSessions(
 		alert,
 		reportedUserEventsLog,
 		terminationResults,
+		operator,
+		verification,
 	)
 
 	return logTerminatedUserSessionsResult
 
 }
 
-// disableUser adds the specified username to the disabled users file. This
-// function is intended to be called from within another function that first
-// confirms that the specified user account has not already been disabled.
+// verifyTerminatedUserSessions checks the active file (or the active file
+// watcher index) after the specified delay for sessions within the
+// termination scope of the alert. Sessions reported as terminated (or
+// already gone) which are still recorded are listed as remaining. If
+// checkNewSessions is set, sessions which were not part of the original
+// termination attempt are terminated; their results are added to those
+// returned. This is done once; sessions which appear after this are not
+// checked for.
+func verifyTerminatedUserSessions(
+	alert events.SplunkAlertEvent,
+	activeFileWatcher *ActiveFileWatcher,
+	ezproxyActiveFilePath string,
+	terminator SessionTerminator,
+	terminationResults ezproxy.TerminateUserSessionResults,
+	verifyDelay time.Duration,
+	checkNewSessions bool,
+) (*events.TerminationVerification, ezproxy.TerminateUserSessionResults) {
+
+	verification := events.TerminationVerification{
+		Delay: verifyDelay,
+	}
+
+	log.Debugf(
+		"%s: Waiting %v to verify termination of %d sessions for %q",
+		caller.GetFuncName(),
+		verifyDelay,
+		len(terminationResults),
+		alert.Username,
+	)
+
+	time.Sleep(verifyDelay)
+
+	var sessions ezproxy.UserSessions
+	var err error
+	switch {
+	case activeFileWatcher.Enabled():
+		scope := events.TerminateScopeUsername
+		if alert.Policy != nil {
+			scope = alert.Policy.TerminationScope()
+		}
+		sessions, err = activeFileWatcher.Lookup(scope, alert.Username, alert.UserIP)
+	default:
+		sessions, err = getActiveFileUserSessions(alert, ezproxyActiveFilePath, 0, 0)
+	}
+
+	if err != nil {
+		verification.Error = err
+		log.Warnf("Failed to verify termination of sessions for %q: %v", alert.Username, err)
+		return &verification, terminationResults
+	}
+
+	outcomes := make(map[string]string, len(terminationResults))
+	for _, result := range terminationResults {
+		outcomes[result.SessionID] = events.TerminationOutcome(result)
+	}
+
+	var newSessions ezproxy.UserSessions
+	for _, session := range sessions {
+
+		outcome, attempted := outcomes[session.SessionID]
+
+		switch {
+
+		// failed terminations are already reported as such
+		case attempted && outcome != events.TerminationOutcomeFailed:
+			log.Warnf(
+				"Session %q for username %q still active %v after termination",
+				session.SessionID,
+				session.Username,
+				verifyDelay,
+			)
+			verification.Remaining = append(verification.Remaining, session.SessionID)
+
+		case !attempted && checkNewSessions:
+			log.Warnf(
+				"New session %q for username %q found %v after termination",
+				session.SessionID,
+				session.Username,
+				verifyDelay,
+			)
+			verification.NewSessions = append(verification.NewSessions, session.SessionID)
+			newSessions = append(newSessions, session)
+		}
+	}
+
+	if len(newSessions) > 0 {
+		for _, session := range newSessions {
+			logEventTerminatingUserSession(alert, session)
+		}
+		terminationResults = append(terminationResults, terminator.Terminate(newSessions)...)
+	}
+
+	log.Infof("Termination verification for %q: %s", alert.Username, verification)
+
+	return &verification, terminationResults
+}
+
+// disableUser adds the specified username to the disabled users file. The
+// quarantine template is used in place of the disable template if the alert
+// policy quarantines user accounts. This function is intended to be called
+// from within another function that first confirms that the specified user
+// account has not already been disabled.
 func disableUser(alert events.SplunkAlertEvent, disabledUsers *DisabledUsers) error {
 
 	// NOTE: Notifications are handled by the caller
 
-	log.Debug("DisableUser: disabling user per alert")
+	tmpl := disabledUsers.Template
+	if quarantineEnabled(alert) {
+		log.Debug("DisableUser: quarantining user per alert")
+		tmpl = disabledUsers.QuarantineTemplate
+	} else {
+		log.Debug("DisableUser: disabling user per alert")
+	}
+
+	if tmpl == nil {
+		return fmt.Errorf(
+			"no template configured for disabled users file %q",
+			disabledUsers.FilePath,
+		)
+	}
+
 	if err := appendToFile(
 		fileEntry{
 			Alert:       alert,
 			EntrySuffix: disabledUsers.EntrySuffix,
 		},
-		disabledUsers.Template,
+		tmpl,
 		disabledUsers.FilePath,
 		disabledUsers.FilePermissions,
 	); err != nil {
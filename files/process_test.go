@@ -0,0 +1,291 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/atc0005/go-ezproxy"
+
+	"github.com/atc0005/brick/events"
+)
+
+// recordingTerminator records the sessions it is asked to terminate and
+// reports each of them as terminated.
+type recordingTerminator struct {
+	mu       sync.Mutex
+	sessions ezproxy.UserSessions
+}
+
+func (rt *recordingTerminator) Terminate(sessions ezproxy.UserSessions) ezproxy.TerminateUserSessionResults {
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.sessions = append(rt.sessions, sessions...)
+
+	results := make(ezproxy.TerminateUserSessionResults, 0, len(sessions))
+	for _, session := range sessions {
+		results = append(results, ezproxy.TerminateUserSessionResult{
+			UserSession: session,
+			ExitCode:    ezproxy.KillSubCmdExitCodeSessionTerminated,
+		})
+	}
+
+	return results
+}
+
+func (rt *recordingTerminator) terminated() int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	return len(rt.sessions)
+}
+
+func TestSessionTerminationTargets(t *testing.T) {
+
+	instances := EZproxyInstances{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	session := ezproxy.UserSession{SessionID: "ABCDEFGHIJKLMNOP", Username: "jsmith"}
+
+	tests := []struct {
+		name          string
+		sessions      []ezproxy.UserSessions
+		wantInstances []string
+	}{
+		{
+			name:          "sessions on one instance",
+			sessions:      []ezproxy.UserSessions{nil, {session}, nil},
+			wantInstances: []string{"b"},
+		},
+		{
+			name:          "sessions on several instances",
+			sessions:      []ezproxy.UserSessions{{session}, nil, {session}},
+			wantInstances: []string{"a", "c"},
+		},
+		{
+			name:          "no sessions on any instance",
+			sessions:      []ezproxy.UserSessions{nil, nil, nil},
+			wantInstances: []string{"a", "b", "c"},
+		},
+		{
+			name:          "lookup failures",
+			sessions:      nil,
+			wantInstances: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			gotInstances, gotSessions := sessionTerminationTargets(instances, tt.sessions)
+
+			names := make([]string, 0, len(gotInstances))
+			for _, instance := range gotInstances {
+				names = append(names, instance.Name)
+			}
+
+			if !reflect.DeepEqual(names, tt.wantInstances) {
+				t.Errorf("sessionTerminationTargets() instances = %v; want %v", names, tt.wantInstances)
+			}
+
+			if len(gotSessions) != len(gotInstances) {
+				t.Errorf(
+					"sessionTerminationTargets() returned %d session lists for %d instances",
+					len(gotSessions),
+					len(gotInstances),
+				)
+			}
+		})
+	}
+}
+
+func TestProcessUserSessionsSkipsExcludedInstances(t *testing.T) {
+
+	const username string = "jsmith"
+
+	dir := t.TempDir()
+
+	// every instance has an active session for the user, but the alert
+	// policy only selects instances "a" and "c"
+	terminators := make(map[string]*recordingTerminator)
+	var instances EZproxyInstances
+	for i, name := range []string{"a", "b", "c"} {
+
+		activeFile := filepath.Join(dir, name+"-ezproxy.hst")
+		content := fmt.Sprintf(
+			"S SESSIONID000000%d 1 2 3 4 192.168.1.%d\nL %s\n",
+			i,
+			i+10,
+			username,
+		)
+		if err := ioutil.WriteFile(activeFile, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write active file: %v", err)
+		}
+
+		terminators[name] = &recordingTerminator{}
+		instances = append(instances, EZproxyInstance{
+			Name:           name,
+			ActiveFilePath: activeFile,
+			Terminator:     terminators[name],
+		})
+	}
+
+	alert := events.SplunkAlertEvent{
+		Username: username,
+		Policy: &events.AlertPolicy{
+			Action:    events.PolicyActionTerminateOnly,
+			Instances: []string{"a", "c"},
+		},
+	}
+
+	notifyWorkQueue := make(chan events.Record, 10)
+
+	dc := DisableContext{
+		ReportedUserEventsLog: NewReportedUserEventsLog(filepath.Join(dir, "users.brick-reported.log"), 0600),
+		NotifyWorkQueue:       notifyWorkQueue,
+	}
+
+	processUserSessions(alert, instances, dc)
+
+	for name, want := range map[string]int{"a": 1, "b": 0, "c": 1} {
+		if got := terminators[name].terminated(); got != want {
+			t.Errorf("instance %q terminated %d sessions; want %d", name, got, want)
+		}
+	}
+}
+
+func TestVerifyTerminatedUserSessions(t *testing.T) {
+
+	// results of the original termination attempt
+	terminationResults := ezproxy.TerminateUserSessionResults{
+		{
+			UserSession: ezproxy.UserSession{SessionID: "s1", Username: "jsmith"},
+			ExitCode:    ezproxy.KillSubCmdExitCodeSessionTerminated,
+		},
+		{
+			UserSession: ezproxy.UserSession{SessionID: "s2", Username: "jsmith"},
+			ExitCode:    ezproxy.KillSubCmdExitCodeSessionDoesNotExist,
+		},
+		{
+			UserSession: ezproxy.UserSession{SessionID: "s3", Username: "jsmith"},
+			Error:       errors.New("kill failed"),
+		},
+	}
+
+	tests := []struct {
+		name             string
+		activeSessions   ezproxy.UserSessions
+		checkNewSessions bool
+		wantRemaining    []string
+		wantNewSessions  []string
+		wantResults      int
+		wantErr          bool
+	}{
+		{
+			name: "all sessions terminated",
+			activeSessions: ezproxy.UserSessions{
+				{SessionID: "s9", Username: "adoe", IPAddress: "192.0.2.90"},
+			},
+			checkNewSessions: true,
+			wantResults:      3,
+		},
+		{
+			name: "terminated session still active",
+			activeSessions: ezproxy.UserSessions{
+				{SessionID: "s1", Username: "jsmith", IPAddress: "192.0.2.10"},
+				{SessionID: "s2", Username: "jsmith", IPAddress: "192.0.2.10"},
+				{SessionID: "s3", Username: "jsmith", IPAddress: "192.0.2.10"},
+			},
+			checkNewSessions: true,
+			wantRemaining:    []string{"s1", "s2"},
+			wantResults:      3,
+		},
+		{
+			name: "new session terminated",
+			activeSessions: ezproxy.UserSessions{
+				{SessionID: "s4", Username: "JSMITH", IPAddress: "192.0.2.40"},
+				{SessionID: "s9", Username: "adoe", IPAddress: "192.0.2.90"},
+			},
+			checkNewSessions: true,
+			wantNewSessions:  []string{"s4"},
+			wantResults:      4,
+		},
+		{
+			name: "new sessions not checked",
+			activeSessions: ezproxy.UserSessions{
+				{SessionID: "s4", Username: "jsmith", IPAddress: "192.0.2.40"},
+			},
+			wantResults: 3,
+		},
+		{
+			name:        "active file missing",
+			wantErr:     true,
+			wantResults: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			path := filepath.Join(t.TempDir(), "ezproxy.hst")
+			if tt.activeSessions != nil {
+				writeActiveFile(t, path, tt.activeSessions)
+			}
+
+			terminator := &recordingTerminator{}
+			verification, results := verifyTerminatedUserSessions(
+				events.SplunkAlertEvent{Username: "jsmith"},
+				nil,
+				path,
+				terminator,
+				terminationResults,
+				time.Millisecond,
+				tt.checkNewSessions,
+			)
+
+			if (verification.Error != nil) != tt.wantErr {
+				t.Fatalf("verification error = %v; want error: %t", verification.Error, tt.wantErr)
+			}
+
+			if got := strings.Join(verification.Remaining, ","); got != strings.Join(tt.wantRemaining, ",") {
+				t.Errorf("remaining sessions = %q; want %q", verification.Remaining, tt.wantRemaining)
+			}
+
+			if got := strings.Join(verification.NewSessions, ","); got != strings.Join(tt.wantNewSessions, ",") {
+				t.Errorf("new sessions = %q; want %q", verification.NewSessions, tt.wantNewSessions)
+			}
+
+			if terminator.terminated() != len(tt.wantNewSessions) {
+				t.Errorf("%d sessions terminated during verification; want %d", terminator.terminated(), len(tt.wantNewSessions))
+			}
+
+			if len(results) != tt.wantResults {
+				t.Errorf("%d termination results returned; want %d", len(results), tt.wantResults)
+			}
+		})
+	}
+}
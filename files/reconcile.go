@@ -0,0 +1,519 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/atc0005/go-ezproxy"
+
+	"github.com/atc0005/brick/events"
+	"github.com/atc0005/brick/internal/caller"
+)
+
+// ReconcilerAlertName is used in place of the alert/search name for events
+// generated by the Reconciler. These events are not triggered by a remote
+// monitoring system.
+const ReconcilerAlertName string = "Disabled users reconciliation"
+
+// Reconciler periodically compares all sessions recorded in the active file
+// of each EZproxy instance against the users listed in the disabled users
+// file for that instance. Sessions belonging to disabled users (e.g.,
+// sessions which failed to terminate or users disabled by hand) are reported
+// and optionally terminated. A notification summarizing the sessions found is
+// sent for each check which finds them; if sessions are only reported (or
+// their termination is simulated by a dry-run alert policy), each session is
+// reported once.
+type Reconciler struct {
+
+	// Interval is how often the active files are checked. A value of 0
+	// disables the Reconciler.
+	Interval time.Duration
+
+	// Terminate indicates whether sessions found for disabled users are
+	// terminated or only reported.
+	Terminate bool
+
+	tenant                string
+	instances             EZproxyInstances
+	alertPolicies         events.AlertPolicies
+	defaultAlertPolicy    events.AlertPolicy
+	reportedUserEventsLog *ReportedUserEventsLog
+	notifyWorkQueue       chan<- events.Record
+
+	mutex    *sync.Mutex
+	reported map[string]struct{}
+}
+
+// NewReconciler constructs a new Reconciler for the EZproxy instances of the
+// named tenant (empty for the default endpoints) using the provided
+// settings. The alert policies determine whether terminating the sessions of
+// each user is only simulated (dry-run). The Run method must be called to
+// start the periodic checks.
+func NewReconciler(
+	interval time.Duration,
+	terminate bool,
+	tenant string,
+	instances EZproxyInstances,
+	alertPolicies events.AlertPolicies,
+	defaultAlertPolicy events.AlertPolicy,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	notifyWorkQueue chan<- events.Record,
+) *Reconciler {
+	return &Reconciler{
+		Interval:              interval,
+		Terminate:             terminate,
+		tenant:                tenant,
+		instances:             instances,
+		alertPolicies:         alertPolicies,
+		defaultAlertPolicy:    defaultAlertPolicy,
+		reportedUserEventsLog: reportedUserEventsLog,
+		notifyWorkQueue:       notifyWorkQueue,
+		mutex:                 &sync.Mutex{},
+		reported:              make(map[string]struct{}),
+	}
+}
+
+// Enabled indicates whether periodic reconciliation has been configured.
+func (rc *Reconciler) Enabled() bool {
+	return rc != nil && rc.Interval > 0
+}
+
+// Run checks for sessions belonging to disabled users at the configured
+// interval until the provided context is cancelled. This is intended to be
+// run as a goroutine.
+func (rc *Reconciler) Run(ctx context.Context) {
+
+	if !rc.Enabled() {
+		log.Debug("Reconciler: reconciliation not enabled")
+		return
+	}
+
+	log.Debugf(
+		"%s: checking %d EZproxy instances for sessions belonging to disabled users every %v",
+		caller.GetFuncName(),
+		len(rc.instances),
+		rc.Interval,
+	)
+
+	ticker := time.NewTicker(rc.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("Reconciler: context cancelled; stopping")
+			return
+		case <-ticker.C:
+			if record, found := rc.Reconcile(); found {
+				processRecord(record, rc.notifyWorkQueue)
+			}
+		}
+	}
+}
+
+// Reconcile checks the active file of each EZproxy instance once for
+// sessions belonging to disabled users and reports or terminates them. The
+// returned Record summarizes what was found and done; false is returned if
+// there is nothing to report.
+func (rc *Reconciler) Reconcile() (events.Record, bool) {
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	alert := events.SplunkAlertEvent{
+		ArrivalTime: time.Now().Format(time.RFC3339),
+		LocalTime:   time.Now().Format("2006-01-02 15:04:05"),
+		AlertName:   ReconcilerAlertName,
+		Tenant:      rc.tenant,
+	}
+
+	if len(rc.instances) > 1 {
+		alert.Instances = rc.instances.Names()
+	}
+
+	var lookupErrs []string
+	var found []reconciledSession
+	current := make(map[string]struct{})
+
+	for _, instance := range rc.instances {
+
+		sessions, err := disabledUserSessions(instance)
+		if err != nil {
+			lookupErrs = append(lookupErrs, instanceError(alert, instance, err).Error())
+			continue
+		}
+
+		for _, session := range sessions {
+			key := instance.Name + "/" + session.SessionID
+			current[key] = struct{}{}
+
+			// sessions are only reported once unless terminated
+			if _, reported := rc.reported[key]; reported {
+				continue
+			}
+
+			found = append(found, reconciledSession{instance: instance, session: session})
+		}
+	}
+
+	// forget reported sessions which have since ended
+	for key := range rc.reported {
+		if _, ok := current[key]; !ok {
+			delete(rc.reported, key)
+		}
+	}
+
+	log.Debugf(
+		"%s: %d new sessions found for disabled users on %d EZproxy instances",
+		caller.GetFuncName(),
+		len(found),
+		len(rc.instances),
+	)
+
+	var lookupErr error
+	if len(lookupErrs) > 0 {
+		lookupErr = errors.New(strings.Join(lookupErrs, "; "))
+	}
+
+	if len(found) == 0 {
+		if lookupErr != nil {
+			return events.NewRecord(
+				alert,
+				lookupErr,
+				"Failed to check for sessions belonging to disabled users",
+				events.ActionFailureReconciledSessions,
+				nil,
+			), true
+		}
+
+		return events.Record{}, false
+	}
+
+	for _, item := range found {
+		log.Warnf(
+			"Session %q (associated with IP %q) found for disabled username %q",
+			item.session.SessionID,
+			item.session.IPAddress,
+			item.session.Username,
+		)
+	}
+
+	summary := fmt.Sprintf(
+		"Found %d active sessions for disabled users: %s",
+		len(found),
+		reconciledSessionsList(found, len(rc.instances) > 1),
+	)
+
+	if !rc.Terminate {
+		for _, item := range found {
+			rc.reported[item.instance.Name+"/"+item.session.SessionID] = struct{}{}
+		}
+
+		log.Warn(summary)
+
+		action := events.ActionSkippedReconciledSessions
+		if lookupErr != nil {
+			action = events.ActionFailureReconciledSessions
+		}
+
+		return events.NewRecord(
+			alert,
+			lookupErr,
+			summary+"; sessions were not terminated (reconcile action: report)",
+			action,
+			nil,
+		), true
+	}
+
+	// group sessions by instance, username and IP Address so that log
+	// entries for each record the associated user IP Address
+	var keys []string
+	groups := make(map[string][]reconciledSession)
+	for _, item := range found {
+		key := item.instance.Name + " " + strings.ToLower(item.session.Username) + " " + item.session.IPAddress
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], item)
+	}
+
+	var terminationResults ezproxy.TerminateUserSessionResults
+	var dryRunPolicy *events.AlertPolicy
+	var dryRunSessions int
+	failures := lookupErrs
+	for _, key := range keys {
+
+		instance := groups[key][0].instance
+
+		userAlert := alert
+		userAlert.Username = groups[key][0].session.Username
+		userAlert.UserIP = groups[key][0].session.IPAddress
+
+		alertPolicy := rc.alertPolicies.Match(userAlert, rc.defaultAlertPolicy)
+		userAlert.Policy = &alertPolicy
+
+		sessions := make(ezproxy.UserSessions, 0, len(groups[key]))
+		for _, item := range groups[key] {
+			sessions = append(sessions, item.session)
+		}
+
+		// record the sessions which would have been terminated; like
+		// reported sessions, these are only recorded once
+		if alertPolicy.DryRunEnabled() {
+			record := logEventDryRunTerminatedUserSessions(userAlert, rc.reportedUserEventsLog, sessions)
+			if record.Error != nil {
+				failures = append(failures, instanceError(alert, instance, record.Error).Error())
+			}
+
+			for _, item := range groups[key] {
+				rc.reported[item.instance.Name+"/"+item.session.SessionID] = struct{}{}
+			}
+
+			dryRunPolicy = userAlert.Policy
+			dryRunSessions += len(sessions)
+
+			continue
+		}
+
+		for _, session := range sessions {
+			logEventTerminatingUserSession(userAlert, session)
+		}
+
+		results := instance.Terminator.Terminate(sessions)
+		record := logEventTerminatedUserSessions(
+			userAlert,
+			rc.reportedUserEventsLog,
+			results,
+			"",
+			nil,
+		)
+		if record.Error != nil {
+			failures = append(failures, instanceError(alert, instance, record.Error).Error())
+		}
+
+		terminationResults = append(terminationResults, results...)
+	}
+
+	outcome := "sessions were terminated"
+	switch {
+	case dryRunSessions == len(found):
+		outcome = "[DRY-RUN] sessions would have been terminated"
+	case dryRunSessions > 0:
+		outcome = fmt.Sprintf(
+			"%d sessions were terminated; [DRY-RUN] %d sessions would have been terminated",
+			len(found)-dryRunSessions,
+			dryRunSessions,
+		)
+	}
+
+	if len(failures) > 0 {
+		return events.NewRecord(
+			alert,
+			errors.New(strings.Join(failures, "; ")),
+			summary+"; "+outcome+"; one or more sessions could not be checked or terminated",
+			events.ActionFailureReconciledSessions,
+			terminationResults,
+		), true
+	}
+
+	// only flag the notification as a dry-run if no sessions were terminated
+	if dryRunSessions == len(found) {
+		alert.Policy = dryRunPolicy
+
+		return events.NewRecord(
+			alert,
+			nil,
+			summary+"; "+outcome,
+			events.ActionDryRunReconciledSessions,
+			nil,
+		), true
+	}
+
+	return events.NewRecord(
+		alert,
+		nil,
+		summary+"; "+outcome,
+		events.ActionSuccessReconciledSessions,
+		terminationResults,
+	), true
+}
+
+// reconciledSession is a session found for a disabled user along with the
+// EZproxy instance on which it was found.
+type reconciledSession struct {
+	instance EZproxyInstance
+	session  ezproxy.UserSession
+}
+
+// disabledUserSessions returns the sessions recorded in the active file of
+// the provided EZproxy instance for users listed in its disabled users file.
+func disabledUserSessions(instance EZproxyInstance) (ezproxy.UserSessions, error) {
+
+	disabledUsernames, err := readDisabledUsernames(instance.DisabledUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	allUserSessions, err := ListUserSessions(instance.ActiveFilePath, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions ezproxy.UserSessions
+	for _, session := range allUserSessions {
+		if _, disabled := disabledUsernames[strings.ToLower(session.Username)]; disabled {
+			sessions = append(sessions, session)
+		}
+	}
+
+	log.Debugf(
+		"%s: %d sessions checked against %d disabled users for EZproxy instance %q",
+		caller.GetFuncName(),
+		len(allUserSessions),
+		len(disabledUsernames),
+		instance.Name,
+	)
+
+	return sessions, nil
+}
+
+// reconciledSessionsList provides a brief description of each of the
+// provided sessions for use in log messages and notifications. The instance
+// name is included if requested.
+func reconciledSessionsList(found []reconciledSession, includeInstance bool) string {
+
+	items := make([]string, 0, len(found))
+	for _, item := range found {
+		where := item.session.IPAddress
+		if includeInstance {
+			where += " on " + item.instance.Name
+		}
+		items = append(items, fmt.Sprintf(
+			"%s (session %s from %s)",
+			item.session.Username,
+			item.session.SessionID,
+			where,
+		))
+	}
+
+	sort.Strings(items)
+
+	return strings.Join(items, ", ")
+}
+
+// readDisabledUsernames returns the set of usernames (lowercased) listed in
+// the disabled users file. Blank lines, comments and entries which do not
+// match the format of the disable template (e.g., quarantine entries) are
+// skipped. A missing file is treated as an empty list.
+func readDisabledUsernames(disabledUsers *DisabledUsers) (map[string]struct{}, error) {
+
+	usernames := make(map[string]struct{})
+
+	prefix, suffix, err := disabledUsersEntryFormat(disabledUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Clean(disabledUsers.FilePath))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return usernames, nil
+	case err != nil:
+		return nil, fmt.Errorf(
+			"error opening disabled users file %q: %w",
+			disabledUsers.FilePath,
+			err,
+		)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Errorf(
+				"%s: failed to close file %q: %s",
+				caller.GetFuncName(),
+				disabledUsers.FilePath,
+				err.Error(),
+			)
+		}
+	}()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if len(line) <= len(prefix)+len(suffix) ||
+			!strings.HasPrefix(line, prefix) ||
+			!strings.HasSuffix(line, suffix) {
+			continue
+		}
+
+		username := strings.TrimSpace(line[len(prefix) : len(line)-len(suffix)])
+		if username != "" {
+			usernames[strings.ToLower(username)] = struct{}{}
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"error reading disabled users file %q: %w",
+			disabledUsers.FilePath,
+			err,
+		)
+	}
+
+	return usernames, nil
+}
+
+// disabledUsersEntryFormat returns the text before and after the username in
+// entries rendered from the disable template of the disabled users file. The
+// first rendered entry is used if the template renders more than one.
+func disabledUsersEntryFormat(disabledUsers *DisabledUsers) (string, string, error) {
+
+	entries, err := disabledUsersEntries(sampleAlert(), disabledUsers.EntrySuffix, disabledUsers.Template)
+	if err != nil {
+		return "", "", fmt.Errorf(
+			"error determining format of disabled users file %q entries: %w",
+			disabledUsers.FilePath,
+			err,
+		)
+	}
+
+	if len(entries) == 0 {
+		return "", "", fmt.Errorf(
+			"error determining format of disabled users file %q entries: template does not render an entry containing the username",
+			disabledUsers.FilePath,
+		)
+	}
+
+	entry := entries[0]
+	idx := strings.Index(strings.ToLower(entry), sampleAlertUsername)
+
+	return entry[:idx], entry[idx+len(sampleAlertUsername):], nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atc0005/go-ezproxy"
+
+	"github.com/atc0005/brick/events"
+)
+
+func TestReconcilerDryRun(t *testing.T) {
+
+	dryRun := true
+	live := false
+
+	tests := []struct {
+		name             string
+		alertPolicies    events.AlertPolicies
+		defaultDryRun    *bool
+		wantTerminated   int
+		wantAction       string
+		wantNote         string
+		wantDryRunPolicy bool
+		wantRepeat       bool
+	}{
+		{
+			name:           "live",
+			defaultDryRun:  &live,
+			wantTerminated: 2,
+			wantAction:     events.ActionSuccessReconciledSessions,
+			wantNote:       "sessions were terminated",
+			wantRepeat:     true,
+		},
+		{
+			name:             "global dry-run",
+			defaultDryRun:    &dryRun,
+			wantTerminated:   0,
+			wantAction:       events.ActionDryRunReconciledSessions,
+			wantNote:         "[DRY-RUN] sessions would have been terminated",
+			wantDryRunPolicy: true,
+		},
+		{
+			name: "dry-run alert policy",
+			alertPolicies: events.AlertPolicies{
+				{Name: "trial", Usernames: []string{"jsmith"}, DryRun: &dryRun},
+			},
+			defaultDryRun:  &live,
+			wantTerminated: 1,
+			wantAction:     events.ActionSuccessReconciledSessions,
+			wantNote:       "1 sessions were terminated; [DRY-RUN] 1 sessions would have been terminated",
+			wantRepeat:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			dir := t.TempDir()
+
+			templates, err := NewDisabledUsersTemplates("", "", "::DENY")
+			if err != nil {
+				t.Fatalf("NewDisabledUsersTemplates() returned error: %v", err)
+			}
+
+			terminator := &recordingTerminator{}
+			instance := EZproxyInstance{
+				Name:           "proxy",
+				DisabledUsers:  NewDisabledUsers(filepath.Join(dir, "users.brick-disabled.txt"), "::DENY", templates, 0600),
+				ActiveFilePath: filepath.Join(dir, "ezproxy.hst"),
+				Terminator:     terminator,
+			}
+
+			for _, username := range []string{"jsmith", "jdoe"} {
+				if err := disableUser(events.SplunkAlertEvent{Username: username}, instance.DisabledUsers); err != nil {
+					t.Fatalf("failed to disable user %q: %v", username, err)
+				}
+			}
+
+			writeActiveFile(t, instance.ActiveFilePath, ezproxy.UserSessions{
+				{SessionID: "AAAAAAAAAAAAAAA", Username: "jsmith", IPAddress: "192.168.1.10"},
+				{SessionID: "BBBBBBBBBBBBBBB", Username: "jdoe", IPAddress: "192.168.1.20"},
+				{SessionID: "CCCCCCCCCCCCCCC", Username: "active", IPAddress: "192.168.1.30"},
+			})
+
+			rc := NewReconciler(
+				0,
+				true,
+				"",
+				EZproxyInstances{instance},
+				tt.alertPolicies,
+				events.AlertPolicy{Name: events.DefaultAlertPolicyName, DryRun: tt.defaultDryRun},
+				NewReportedUserEventsLog(filepath.Join(dir, "users.brick-reported.log"), 0600),
+				make(chan events.Record, 10),
+			)
+
+			record, found := rc.Reconcile()
+			if !found {
+				t.Fatal("Reconcile() found nothing to report; want sessions for disabled users")
+			}
+
+			if got := terminator.terminated(); got != tt.wantTerminated {
+				t.Errorf("terminated %d sessions; want %d", got, tt.wantTerminated)
+			}
+
+			if record.Action != tt.wantAction {
+				t.Errorf("Reconcile() action = %q; want %q", record.Action, tt.wantAction)
+			}
+
+			if !strings.HasSuffix(record.Note, "; "+tt.wantNote) {
+				t.Errorf("Reconcile() note = %q; want suffix %q", record.Note, tt.wantNote)
+			}
+
+			dryRunPolicy := record.Alert.Policy != nil && record.Alert.Policy.DryRunEnabled()
+			if dryRunPolicy != tt.wantDryRunPolicy {
+				t.Errorf("Reconcile() record flagged as dry-run: %t; want %t", dryRunPolicy, tt.wantDryRunPolicy)
+			}
+
+			// sessions which would have been terminated are only reported
+			// once; sessions which were terminated are checked again
+			if _, found := rc.Reconcile(); found != tt.wantRepeat {
+				t.Errorf("second Reconcile() found sessions to report: %t; want %t", found, tt.wantRepeat)
+			}
+		})
+	}
+}
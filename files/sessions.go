@@ -0,0 +1,136 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/atc0005/go-ezproxy"
+	"github.com/atc0005/go-ezproxy/activefile"
+
+	"github.com/atc0005/brick/events"
+)
+
+// ErrSessionNotFound indicates that the specified session is not recorded in
+// the EZproxy active file.
+var ErrSessionNotFound = errors.New("session not found in active file")
+
+// ListUserSessions returns the sessions recorded in the EZproxy active file.
+// If a username or user IP Address is provided, only sessions matching all
+// provided values are returned. Usernames are matched case-insensitively.
+func ListUserSessions(ezproxyActiveFilePath string, username string, userIP string) (ezproxy.UserSessions, error) {
+
+	var ip net.IP
+	if userIP != "" {
+		ip = net.ParseIP(userIP)
+		if ip == nil {
+			return nil, fmt.Errorf("%q is not a valid IP Address", userIP)
+		}
+	}
+
+	reader, err := activefile.NewReader(allSessionsReaderUsername, ezproxyActiveFilePath)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error while creating activeFile reader to retrieve sessions from %q: %w",
+			ezproxyActiveFilePath,
+			err,
+		)
+	}
+
+	allUserSessions, err := reader.AllUserSessions()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error retrieving sessions from %q: %w",
+			ezproxyActiveFilePath,
+			err,
+		)
+	}
+
+	sessions := make(ezproxy.UserSessions, 0, len(allUserSessions))
+	for _, session := range allUserSessions {
+		if username != "" && !strings.EqualFold(username, session.Username) {
+			continue
+		}
+		if ip != nil && !ip.Equal(net.ParseIP(session.IPAddress)) {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// ProcessTerminateSessionEvent terminates the specified session at the
+// request of the named operator. The session must be recorded in the active
+// file of one of the provided EZproxy instances; its username and IP Address
+// are recorded in the provided alert. The session is terminated using the
+// same process as sessions for reported users and the result is logged and
+// sent as a notification along with the operator name. ErrSessionNotFound is
+// returned if the session is not recorded in any active file.
+func ProcessTerminateSessionEvent(
+	alert events.SplunkAlertEvent,
+	operator string,
+	sessionID string,
+	reportedUserEventsLog *ReportedUserEventsLog,
+	notifyWorkQueue chan<- events.Record,
+	instances EZproxyInstances,
+	ezproxyVerifyDelay int,
+) (events.Record, error) {
+
+	if len(instances) > 1 {
+		alert.Instances = instances.Names()
+	}
+
+	for _, instance := range instances {
+
+		sessions, err := ListUserSessions(instance.ActiveFilePath, "", "")
+		if err != nil {
+			return events.Record{}, instanceError(alert, instance, err)
+		}
+
+		for _, session := range sessions {
+			if session.SessionID != sessionID {
+				continue
+			}
+
+			alert.Username = session.Username
+			alert.UserIP = session.IPAddress
+			if len(instances) > 1 {
+				alert.Instances = []string{instance.Name}
+			}
+
+			result := terminateUserSessions(
+				alert,
+				reportedUserEventsLog,
+				ezproxy.UserSessions{session},
+				instance,
+				ezproxyVerifyDelay,
+				false,
+				operator,
+			)
+
+			processRecord(result, notifyWorkQueue)
+
+			return result, nil
+		}
+	}
+
+	return events.Record{}, ErrSessionNotFound
+}
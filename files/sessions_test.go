@@ -0,0 +1,126 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atc0005/go-ezproxy"
+
+	"github.com/atc0005/brick/events"
+)
+
+func TestListUserSessions(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "ezproxy.hst")
+	writeActiveFile(t, path, ezproxy.UserSessions{
+		{SessionID: "s1", Username: "jsmith", IPAddress: "192.0.2.10"},
+		{SessionID: "s2", Username: "JSmith", IPAddress: "192.0.2.20"},
+		{SessionID: "s3", Username: "adoe", IPAddress: "192.0.2.10"},
+		{SessionID: "s4", Username: "rjones", IPAddress: "2001:db8::1"},
+	})
+
+	tests := []struct {
+		name     string
+		username string
+		userIP   string
+		wantIDs  []string
+		wantErr  bool
+	}{
+		{
+			name:    "all sessions",
+			wantIDs: []string{"s1", "s2", "s3", "s4"},
+		},
+		{
+			name:     "username is case-insensitive",
+			username: "JSMITH",
+			wantIDs:  []string{"s1", "s2"},
+		},
+		{
+			name:    "user IP Address",
+			userIP:  "192.0.2.10",
+			wantIDs: []string{"s1", "s3"},
+		},
+		{
+			name:    "equivalent IPv6 Address",
+			userIP:  "2001:db8:0:0:0:0:0:1",
+			wantIDs: []string{"s4"},
+		},
+		{
+			name:     "username and user IP Address",
+			username: "jsmith",
+			userIP:   "192.0.2.20",
+			wantIDs:  []string{"s2"},
+		},
+		{
+			name:     "no matching sessions",
+			username: "adoe",
+			userIP:   "192.0.2.20",
+			wantIDs:  []string{},
+		},
+		{
+			name:    "invalid user IP Address",
+			userIP:  "192.0.2",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			sessions, err := ListUserSessions(path, tt.username, tt.userIP)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ListUserSessions() returned error %v; want error: %t", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if got := sessionIDs(sessions); strings.Join(got, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("ListUserSessions() = %q; want %q", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestProcessTerminateSessionEventNotFound(t *testing.T) {
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ezproxy.hst")
+	writeActiveFile(t, path, ezproxy.UserSessions{
+		{SessionID: "s1", Username: "jsmith", IPAddress: "192.0.2.10"},
+	})
+
+	_, err := ProcessTerminateSessionEvent(
+		events.SplunkAlertEvent{},
+		"operator",
+		"missing",
+		NewReportedUserEventsLog(filepath.Join(dir, "users.brick-reported.log"), 0600),
+		make(chan events.Record, 10),
+		EZproxyInstances{{Name: "proxy-a", ActiveFilePath: path, Terminator: &recordingTerminator{}}},
+		0,
+	)
+
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("ProcessTerminateSessionEvent() = %v; want %v", err, ErrSessionNotFound)
+	}
+}
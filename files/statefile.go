@@ -0,0 +1,94 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apex/log"
+
+	"github.com/atc0005/brick/internal/caller"
+	"github.com/atc0005/brick/internal/fileutils"
+)
+
+// loadJSONState decodes the JSON state file at the specified path into
+// state, which is expected to be a pointer. A missing or empty state file
+// leaves state unchanged. The description (e.g., "circuit breaker state") is
+// used in log and error messages. The caller is expected to hold the mutex
+// guarding the state file.
+func loadJSONState(path string, description string, state interface{}) error {
+
+	myFuncName := caller.GetParentFuncName()
+
+	log.Debugf("%s: Reading %s from %q", myFuncName, description, path)
+
+	// Reading this file via variable is intentional; sysadmins may place the
+	// state file in a non-default location.
+	//
+	// #nosec G304
+	content, err := ioutil.ReadFile(filepath.Clean(path))
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return fmt.Errorf(
+			"%s: error reading %s file %q: %w",
+			myFuncName,
+			description,
+			path,
+			err,
+		)
+	case len(strings.TrimSpace(string(content))) == 0:
+		return nil
+	}
+
+	if err := json.Unmarshal(content, state); err != nil {
+		return fmt.Errorf(
+			"%s: error parsing %s file %q: %w",
+			myFuncName,
+			description,
+			path,
+			err,
+		)
+	}
+
+	return nil
+}
+
+// saveJSONState replaces the state file at the specified path with the JSON
+// encoding of state. The provided permissions are applied when the state
+// file is first created. The caller is expected to hold the mutex guarding
+// the state file.
+func saveJSONState(path string, description string, state interface{}, perms os.FileMode) error {
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf(
+			"%s: error encoding %s: %w",
+			caller.GetParentFuncName(),
+			description,
+			err,
+		)
+	}
+
+	return fileutils.WriteFileAtomic(path, append(content, '\n'), perms)
+}
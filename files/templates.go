@@ -33,16 +33,67 @@ const reportedUserEventTemplateText string = `{{ .Alert.ArrivalTime }} [REPORTED
 const disabledUserFirstEventTemplateText string = `{{ .Alert.ArrivalTime }} [DISABLED] Username "{{ .Alert.Username }}" from source IP "{{ .Alert.UserIP }}" disabled due to alert "{{ .Alert.AlertName }}" received from "{{ .Alert.PayloadSenderIP }}" (SearchID: "{{ .Alert.SearchID }}")
 `
 
-const disabledUserRepeatEventTemplateText string = `{{ .Alert.ArrivalTime }} [DISABLED] Username "{{ .Alert.Username }}" from source IP "{{ .Alert.UserIP }}" already disabled, but would be again due to alert "{{ .Alert.AlertName }}" received from "{{ .Alert.PayloadSenderIP }}" (SearchID: "{{ .Alert.SearchID }}")
+// This template is used in place of the disabled user template when the
+// alert policy quarantines user accounts. The user account retains limited
+// access; fail2ban rules matching [DISABLED] entries are not affected.
+const quarantinedUserEventTemplateText string = `{{ .Alert.ArrivalTime }} [QUARANTINED] Username "{{ .Alert.Username }}" from source IP "{{ .Alert.UserIP }}" quarantined due to alert "{{ .Alert.AlertName }}" received from "{{ .Alert.PayloadSenderIP }}" (SearchID: "{{ .Alert.SearchID }}")
+`
+
+// The repeat template is also used for quarantine policies; those entries
+// are recorded as [QUARANTINED] so that fail2ban rules matching [DISABLED]
+// entries are not affected.
+const disabledUserRepeatEventTemplateText string = `{{ .Alert.ArrivalTime }} [{{ if .Quarantine }}QUARANTINED{{ else }}DISABLED{{ end }}] Username "{{ .Alert.Username }}" from source IP "{{ .Alert.UserIP }}" already {{ if .Quarantine }}quarantined or disabled{{ else }}disabled{{ end }}, but would be again due to alert "{{ .Alert.AlertName }}" received from "{{ .Alert.PayloadSenderIP }}" (SearchID: "{{ .Alert.SearchID }}")
 `
 
 // NOTE: This template is used for ignored users and IP Addresses based on
-// presence in the ignored users list and the ignored IP Addresses list.
-const ignoredUserEventTemplateText string = `{{ .Alert.ArrivalTime }} [IGNORED] Username "{{ .Alert.Username }}" from source IP "{{ .Alert.UserIP }}" ignored per entry in "{{ .IgnoredEntriesFile }}" (SearchID: "{{ .Alert.SearchID }}")
+// presence in the ignored users list and the ignored IP Addresses list. The
+// owner, reason and expiration details are only included if set for the
+// matching entry.
+const ignoredUserEventTemplateText string = `{{ .Alert.ArrivalTime }} [IGNORED] Username "{{ .Alert.Username }}" from source IP "{{ .Alert.UserIP }}" ignored per entry in "{{ .IgnoredEntriesFile }}"{{ with .IgnoredEntry.Owner }} (Owner: "{{ . }}"){{ end }}{{ with .IgnoredEntry.Reason }} (Reason: "{{ . }}"){{ end }}{{ if not .IgnoredEntry.Expires.IsZero }} (Expires: "{{ .IgnoredEntry.Expires.Format "2006-01-02T15:04:05Z07:00" }}"){{ end }} (SearchID: "{{ .Alert.SearchID }}")
 `
 
 // This template is used to write out the results of each session termination
 // attempt; this template is not used to generate a bulk summary for multiple
 // sessions
-const terminatedUserEventTemplateText string = `{{ .Alert.ArrivalTime }} [TERMINATED] Session "{{ .UserSession.SessionID }}" associated with {{ .UserSession.IPAddress }} for username "{{ .Alert.Username }}" from source IP "{{ .Alert.UserIP }}" terminated due to alert "{{ .Alert.AlertName }}" received from "{{ .Alert.PayloadSenderIP }}" (SearchID: "{{ .Alert.SearchID }}")
+const terminatedUserEventTemplateText string = `{{ .Alert.ArrivalTime }} [TERMINATED] Session "{{ .UserSession.SessionID }}" associated with {{ .UserSession.IPAddress }} for username "{{ .UserSession.Username }}" from source IP "{{ .Alert.UserIP }}" terminated due to alert "{{ .Alert.AlertName }}" received from "{{ .Alert.PayloadSenderIP }}"{{ with .Operator }} (Operator: "{{ . }}"){{ end }}{{ with .Verification }} (Verification: "{{ . }}"){{ end }} (SearchID: "{{ .Alert.SearchID }}")
+`
+
+// These templates are used in place of the disabled user and terminated
+// session templates when the alert policy is in dry-run mode. They record the
+// action which would have been taken without affecting fail2ban rules
+// matching the [DISABLED] and [TERMINATED] entries.
+const dryRunDisabledUserEventTemplateText string = `{{ .Alert.ArrivalTime }} [DRY-RUN] Username "{{ .Alert.Username }}" from source IP "{{ .Alert.UserIP }}" would be {{ if .Quarantine }}quarantined{{ else }}disabled{{ end }} due to alert "{{ .Alert.AlertName }}" received from "{{ .Alert.PayloadSenderIP }}" (SearchID: "{{ .Alert.SearchID }}")
+`
+
+const dryRunTerminatedUserEventTemplateText string = `{{ .Alert.ArrivalTime }} [DRY-RUN] Session "{{ .UserSession.SessionID }}" associated with {{ .UserSession.IPAddress }} for username "{{ .UserSession.Username }}" from source IP "{{ .Alert.UserIP }}" would be terminated due to alert "{{ .Alert.AlertName }}" received from "{{ .Alert.PayloadSenderIP }}" (SearchID: "{{ .Alert.SearchID }}")
+`
+
+// These templates are used to record disable requests held for operator
+// approval and the decision made for each request. The decision entry is
+// timestamped when the decision is made, not when the alert was received.
+const pendingApprovalEventTemplateText string = `{{ .Alert.ArrivalTime }} [PENDING] Username "{{ .Alert.Username }}" from source IP "{{ .Alert.UserIP }}" disable pending approval due to alert "{{ .Alert.AlertName }}" received from "{{ .Alert.PayloadSenderIP }}" (Approval ID: "{{ .Alert.Approval.ID }}") (Expires: "{{ .Alert.Approval.Expires.Format "2006-01-02T15:04:05Z07:00" }}") (SearchID: "{{ .Alert.SearchID }}")
+`
+
+const approvalDecisionEventTemplateText string = `{{ .Alert.Approval.Decided.Format "2006-01-02T15:04:05Z07:00" }} [{{ if .Alert.Approval.Approved }}APPROVED{{ else }}REJECTED{{ end }}] Username "{{ .Alert.Username }}" from source IP "{{ .Alert.UserIP }}" disable {{ if .Alert.Approval.Approved }}approved{{ else }}rejected{{ end }} by "{{ .Alert.Approval.Approver }}" (Approval ID: "{{ .Alert.Approval.ID }}") (SearchID: "{{ .Alert.SearchID }}")
+`
+
+// This template is used in place of the disabled user template when the
+// mass-disable circuit breaker is open. The user account is not disabled;
+// fail2ban rules matching [DISABLED] entries are not affected.
+const suspendedUserEventTemplateText string = `{{ .Alert.ArrivalTime }} [SUSPENDED] Username "{{ .Alert.Username }}" from source IP "{{ .Alert.UserIP }}" not disabled due to alert "{{ .Alert.AlertName }}" received from "{{ .Alert.PayloadSenderIP }}"; circuit breaker open (Tripped by: "{{ .Alert.CircuitBreaker.TrippedBy }}") (Tripped at: "{{ .Alert.CircuitBreaker.TrippedAt.Format "2006-01-02T15:04:05Z07:00" }}") (SearchID: "{{ .Alert.SearchID }}")
+`
+
+// This template is used to record changes made by an operator via the
+// management API. The Note field describes the change; the source IP is the
+// address of the client which submitted the request.
+const auditEventTemplateText string = `{{ .Alert.ArrivalTime }} [AUDIT] Operator "{{ .Operator }}" from source IP "{{ .Alert.PayloadSenderIP }}" via "{{ .Alert.HTTPMethod }} {{ .Alert.EndpointPath }}": {{ .Note }}
+`
+
+// These templates are used to record IP Addresses blocked and unblocked
+// using the firewall backend. The unblock entry is timestamped when the IP
+// Address is unblocked; the Note field gives the reason.
+const blockedIPEventTemplateText string = `{{ .Alert.ArrivalTime }} [BLOCKED] IP "{{ .Alert.UserIP }}" associated with username "{{ .Alert.Username }}" blocked via {{ .FirewallBackend }} due to alert "{{ .Alert.AlertName }}" received from "{{ .Alert.PayloadSenderIP }}" (Expires: "{{ .BlockExpires.Format "2006-01-02T15:04:05Z07:00" }}") (SearchID: "{{ .Alert.SearchID }}")
+`
+
+const unblockedIPEventTemplateText string = `{{ .Alert.ArrivalTime }} [UNBLOCKED] IP "{{ .Alert.UserIP }}" associated with username "{{ .Alert.Username }}" unblocked via {{ .FirewallBackend }}: {{ .Note }} (SearchID: "{{ .Alert.SearchID }}")
 `
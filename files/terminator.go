@@ -0,0 +1,228 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/atc0005/go-ezproxy"
+
+	"github.com/atc0005/brick/internal/caller"
+)
+
+// resultExitCodeNotRun is recorded as the ExitCode for termination attempts
+// where the EZproxy executable did not exit on its own (e.g., it could not
+// be run or was stopped after the timeout).
+const resultExitCodeNotRun int = -1
+
+// SessionTerminator terminates EZproxy user sessions, returning the result
+// of each termination attempt.
+type SessionTerminator interface {
+	Terminate(sessions ezproxy.UserSessions) ezproxy.TerminateUserSessionResults
+}
+
+// ExecTerminator terminates user sessions by running the `kill` subcommand
+// of the EZproxy executable. This requires running on the EZproxy server.
+type ExecTerminator struct {
+
+	// Executable is the fully-qualified path to the EZproxy executable.
+	Executable string
+
+	// Timeout is the time limit for each run of the EZproxy executable. The
+	// executable is stopped if it does not exit within this limit.
+	Timeout time.Duration
+
+	// Concurrency is the maximum number of sessions terminated at the same
+	// time. Values less than 1 are treated as 1.
+	Concurrency int
+
+	// Retries is the number of additional attempts made for sessions which
+	// fail to terminate due to a transient error (e.g., a timeout or an
+	// unexpected exit code).
+	Retries int
+
+	// RetryDelay is the delay before the first retry. The delay is doubled
+	// for each retry which follows.
+	RetryDelay time.Duration
+}
+
+// Terminate runs the `kill` subcommand of the EZproxy executable for each of
+// the provided sessions, up to the configured concurrency limit at a time.
+// Results are returned in the same order as the provided sessions. Sessions
+// which no longer exist are recorded without an error; see
+// events.TerminationOutcome.
+func (t ExecTerminator) Terminate(sessions ezproxy.UserSessions) ezproxy.TerminateUserSessionResults {
+
+	concurrency := t.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make(ezproxy.TerminateUserSessionResults, len(sessions))
+	semaphore := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i := range sessions {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i] = t.terminateWithRetries(sessions[i])
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// terminateWithRetries attempts to terminate the provided session, retrying
+// with an increasing delay for as long as transient errors occur and retries
+// remain.
+func (t ExecTerminator) terminateWithRetries(session ezproxy.UserSession) ezproxy.TerminateUserSessionResult {
+
+	delay := t.RetryDelay
+	attemptsAllowed := t.Retries + 1
+
+	var result ezproxy.TerminateUserSessionResult
+	for attempt := 1; attempt <= attemptsAllowed; attempt++ {
+
+		result = t.terminate(session)
+
+		if !transientTerminationFailure(result) || attempt == attemptsAllowed {
+			break
+		}
+
+		log.Warnf(
+			"Attempt %d of %d to terminate session %q for username %q failed (retrying in %v): %v",
+			attempt,
+			attemptsAllowed,
+			session.SessionID,
+			session.Username,
+			delay,
+			result.Error,
+		)
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	return result
+}
+
+// terminate runs the `kill` subcommand of the EZproxy executable once for
+// the provided session, stopping the executable if it does not exit within
+// the configured timeout.
+func (t ExecTerminator) terminate(session ezproxy.UserSession) ezproxy.TerminateUserSessionResult {
+
+	ctx := context.Background()
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	// Accepting a variable here is intentional; the path to the EZproxy
+	// executable is site-specific.
+	//
+	// nolint:gosec
+	cmd := exec.CommandContext(
+		ctx,
+		t.Executable,
+		ezproxy.SubCmdNameSessionTerminate,
+		session.SessionID,
+	)
+
+	log.Debugf("%s: Executing: %s", caller.GetFuncName(), strings.Join(cmd.Args, " "))
+
+	var cmdStdOut bytes.Buffer
+	var cmdStdErr bytes.Buffer
+	cmd.Stdout = &cmdStdOut
+	cmd.Stderr = &cmdStdErr
+
+	result := ezproxy.TerminateUserSessionResult{
+		UserSession: session,
+		ExitCode:    resultExitCodeNotRun,
+	}
+
+	if err := cmd.Start(); err != nil {
+		result.Error = err
+		return result
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+
+	case cmdErr := <-done:
+		result.ExitCode = cmd.ProcessState.ExitCode()
+		result.StdOut = strings.TrimSpace(cmdStdOut.String())
+		result.StdErr = strings.TrimSpace(cmdStdErr.String())
+		result.Error = cmdErr
+
+		// the session was already terminated or has expired; nothing left
+		// to do
+		if result.ExitCode == ezproxy.KillSubCmdExitCodeSessionDoesNotExist {
+			result.Error = nil
+		}
+
+	// The executable is killed once the timeout is reached, but Wait does
+	// not return until its output is closed, which any child processes may
+	// keep open. The output is discarded instead of waiting for them.
+	case <-ctx.Done():
+		result.Error = fmt.Errorf(
+			"%s %s did not exit within %v: %w",
+			t.Executable,
+			ezproxy.SubCmdNameSessionTerminate,
+			t.Timeout,
+			ctx.Err(),
+		)
+	}
+
+	return result
+}
+
+// transientTerminationFailure indicates whether the termination attempt
+// failed for a reason which may not occur again. Failures to run the EZproxy
+// executable or rejections of the session ID are not expected to succeed if
+// retried.
+func transientTerminationFailure(result ezproxy.TerminateUserSessionResult) bool {
+
+	if result.Error == nil {
+		return false
+	}
+
+	var execErr *exec.Error
+	var pathErr *os.PathError
+	if errors.As(result.Error, &execErr) || errors.As(result.Error, &pathErr) {
+		return false
+	}
+
+	return result.ExitCode != ezproxy.KillSubCmdExitCodeSessionNotSpecified
+}
@@ -0,0 +1,263 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atc0005/go-ezproxy"
+)
+
+// fakeEZproxyExecutable writes a shell script standing in for the EZproxy
+// executable and returns its path. Each run prints the session ID, records
+// the attempt and exits with the exit code for that attempt; the last exit
+// code is used for all attempts which follow. If sleep is set the script
+// sleeps for that many seconds before exiting.
+func fakeEZproxyExecutable(t *testing.T, exitCodes []int, sleep int) string {
+
+	t.Helper()
+
+	var cases strings.Builder
+	for i, code := range exitCodes {
+		pattern := strconv.Itoa(i + 1)
+		if i == len(exitCodes)-1 {
+			pattern = "*"
+		}
+		fmt.Fprintf(&cases, "  %s) exit %d ;;\n", pattern, code)
+	}
+
+	return writeTestExecutable(t, "ezproxy", fmt.Sprintf(`n=$(cat "$0.count" 2>/dev/null || echo 0)
+n=$((n+1))
+echo "$n" > "$0.count"
+echo "$2"
+[ %d -gt 0 ] && sleep %d
+case "$n" in
+%sesac
+`, sleep, sleep, cases.String()))
+}
+
+// fakeEZproxyAttempts returns the number of times the fake EZproxy
+// executable at the specified path was run.
+func fakeEZproxyAttempts(t *testing.T, path string) int {
+
+	t.Helper()
+
+	content, err := ioutil.ReadFile(path + ".count")
+	if err != nil {
+		return 0
+	}
+
+	attempts, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		t.Fatalf("failed to parse attempts recorded by fake EZproxy executable: %v", err)
+	}
+
+	return attempts
+}
+
+func TestExecTerminatorRetries(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		exitCodes    []int
+		sleep        int
+		missing      bool
+		retries      int
+		wantAttempts int
+		wantExitCode int
+		wantErr      bool
+		wantTimeout  bool
+	}{
+		{
+			name:         "terminated",
+			exitCodes:    []int{ezproxy.KillSubCmdExitCodeSessionTerminated},
+			retries:      2,
+			wantAttempts: 1,
+			wantExitCode: ezproxy.KillSubCmdExitCodeSessionTerminated,
+		},
+		{
+			name:         "session does not exist",
+			exitCodes:    []int{ezproxy.KillSubCmdExitCodeSessionDoesNotExist},
+			retries:      2,
+			wantAttempts: 1,
+			wantExitCode: ezproxy.KillSubCmdExitCodeSessionDoesNotExist,
+		},
+		{
+			name:         "transient failure then terminated",
+			exitCodes:    []int{2, ezproxy.KillSubCmdExitCodeSessionTerminated},
+			retries:      2,
+			wantAttempts: 2,
+			wantExitCode: ezproxy.KillSubCmdExitCodeSessionTerminated,
+		},
+		{
+			name:         "retries exhausted",
+			exitCodes:    []int{2},
+			retries:      2,
+			wantAttempts: 3,
+			wantExitCode: 2,
+			wantErr:      true,
+		},
+		{
+			name:         "retries disabled",
+			exitCodes:    []int{2},
+			retries:      0,
+			wantAttempts: 1,
+			wantExitCode: 2,
+			wantErr:      true,
+		},
+		{
+			name:         "session not specified is not retried",
+			exitCodes:    []int{ezproxy.KillSubCmdExitCodeSessionNotSpecified},
+			retries:      2,
+			wantAttempts: 1,
+			wantExitCode: ezproxy.KillSubCmdExitCodeSessionNotSpecified,
+			wantErr:      true,
+		},
+		{
+			name:         "timeout is retried",
+			exitCodes:    []int{ezproxy.KillSubCmdExitCodeSessionTerminated},
+			sleep:        5,
+			retries:      1,
+			wantAttempts: 2,
+			wantExitCode: resultExitCodeNotRun,
+			wantErr:      true,
+			wantTimeout:  true,
+		},
+		{
+			name:         "missing executable is not retried",
+			missing:      true,
+			retries:      2,
+			wantExitCode: resultExitCodeNotRun,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			executable := filepath.Join(t.TempDir(), "missing")
+			if !tt.missing {
+				executable = fakeEZproxyExecutable(t, tt.exitCodes, tt.sleep)
+			}
+
+			terminator := ExecTerminator{
+				Executable: executable,
+				Timeout:    500 * time.Millisecond,
+				Retries:    tt.retries,
+				RetryDelay: 10 * time.Millisecond,
+			}
+
+			session := ezproxy.UserSession{SessionID: "AAAAAAAAAAAAAAA", Username: "jsmith"}
+			results := terminator.Terminate(ezproxy.UserSessions{session})
+			if len(results) != 1 {
+				t.Fatalf("Terminate() returned %d results; want 1", len(results))
+			}
+			result := results[0]
+
+			if got := fakeEZproxyAttempts(t, executable); got != tt.wantAttempts {
+				t.Errorf("EZproxy executable run %d times; want %d", got, tt.wantAttempts)
+			}
+
+			if result.ExitCode != tt.wantExitCode {
+				t.Errorf("ExitCode = %d; want %d", result.ExitCode, tt.wantExitCode)
+			}
+
+			if (result.Error != nil) != tt.wantErr {
+				t.Errorf("Error = %v; want error: %t", result.Error, tt.wantErr)
+			}
+
+			if tt.wantTimeout && !errors.Is(result.Error, context.DeadlineExceeded) {
+				t.Errorf("Error = %v; want %v", result.Error, context.DeadlineExceeded)
+			}
+
+			if result.UserSession != session {
+				t.Errorf("UserSession = %+v; want %+v", result.UserSession, session)
+			}
+		})
+	}
+}
+
+func TestExecTerminatorRetryBackoff(t *testing.T) {
+
+	executable := fakeEZproxyExecutable(t, []int{2}, 0)
+
+	terminator := ExecTerminator{
+		Executable: executable,
+		Timeout:    5 * time.Second,
+		Retries:    3,
+		RetryDelay: 20 * time.Millisecond,
+	}
+
+	start := time.Now()
+	terminator.Terminate(ezproxy.UserSessions{{SessionID: "AAAAAAAAAAAAAAA"}})
+	elapsed := time.Since(start)
+
+	// the delay doubles for each retry: 20ms + 40ms + 80ms
+	if want := 140 * time.Millisecond; elapsed < want {
+		t.Errorf("Terminate() returned after %v; want at least %v", elapsed, want)
+	}
+
+	if got := fakeEZproxyAttempts(t, executable); got != 4 {
+		t.Errorf("EZproxy executable run %d times; want 4", got)
+	}
+}
+
+func TestExecTerminatorResultOrder(t *testing.T) {
+
+	terminator := ExecTerminator{
+		Executable:  fakeEZproxyExecutable(t, []int{ezproxy.KillSubCmdExitCodeSessionTerminated}, 0),
+		Timeout:     5 * time.Second,
+		Concurrency: 3,
+	}
+
+	sessions := ezproxy.UserSessions{
+		{SessionID: "AAAAAAAAAAAAAAA"},
+		{SessionID: "BBBBBBBBBBBBBBB"},
+		{SessionID: "CCCCCCCCCCCCCCC"},
+		{SessionID: "DDDDDDDDDDDDDDD"},
+		{SessionID: "EEEEEEEEEEEEEEE"},
+	}
+
+	results := terminator.Terminate(sessions)
+	if len(results) != len(sessions) {
+		t.Fatalf("Terminate() returned %d results; want %d", len(results), len(sessions))
+	}
+
+	for i, result := range results {
+		if result.SessionID != sessions[i].SessionID || result.StdOut != sessions[i].SessionID {
+			t.Errorf(
+				"result %d for session %q (output %q); want session %q",
+				i,
+				result.SessionID,
+				result.StdOut,
+				sessions[i].SessionID,
+			)
+		}
+		if result.Error != nil {
+			t.Errorf("result %d returned error: %v", i, result.Error)
+		}
+	}
+}
@@ -27,4 +27,5 @@ require (
 	github.com/atc0005/go-ezproxy v0.1.6
 	github.com/atc0005/go-teams-notify/v2 v2.3.0
 	github.com/pelletier/go-toml v1.8.0
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 )
@@ -0,0 +1,390 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditlog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/atc0005/go-ezproxy"
+
+	"github.com/atc0005/brick/internal/caller"
+)
+
+const (
+	// FileNameLayout is the time layout used by EZproxy when naming audit
+	// log files (e.g., 20201019.txt). Files not matching this layout are
+	// ignored.
+	FileNameLayout string = "20060102.txt"
+
+	// TimestampLayout is the time layout used for the first field of each
+	// audit log entry. EZproxy records these timestamps in local time.
+	TimestampLayout string = "2006-01-02 15:04:05"
+
+	// FieldDelimiter separates the fields of each audit log entry.
+	FieldDelimiter string = "\t"
+
+	// LoginSuccessEventPrefix is the prefix shared by audit log events which
+	// record a new session for a user (e.g., Login.Success,
+	// Login.Success.Relogin).
+	LoginSuccessEventPrefix string = "Login.Success"
+
+	// LogoutEventPrefix is the prefix shared by audit log events which
+	// record the end of a session.
+	LogoutEventPrefix string = "Logout"
+
+	// DefaultLookback is the default window in which login events are
+	// considered when searching for user sessions.
+	DefaultLookback time.Duration = 10 * time.Minute
+)
+
+// These are the positions of the audit log entry fields used to tie
+// usernames, IP Addresses and session IDs together. Entries have the fields
+// Date/Time, Event, IP, Username, Session and Other.
+const (
+	fieldTimestamp int = iota
+	fieldEvent
+	fieldIPAddress
+	fieldUsername
+	fieldSessionID
+
+	minimumFields
+)
+
+var sessionIDRegex = regexp.MustCompile("^" + ezproxy.SessionIDRegex + "$")
+
+// Entry reflects a session event recorded in an EZproxy audit log file.
+type Entry struct {
+	Timestamp time.Time
+	Event     string
+	IPAddress string
+	Username  string
+	SessionID string
+}
+
+// Reader represents a file reader specific to the EZproxy audit log files.
+// Only login events recorded within the lookback window are used; sessions
+// ended by a later logout event are excluded.
+type Reader struct {
+
+	// SearchDelay is the intentional delay before each attempt to search the
+	// audit log files for the specified username.
+	SearchDelay time.Duration
+
+	// SearchRetries is the number of additional search attempts that will be
+	// made whenever the initial search attempt returns zero results.
+	SearchRetries int
+
+	// Lookback is how far back login events are considered. Only audit log
+	// files for the days covered by this window are read.
+	Lookback time.Duration
+
+	// Username is the name of the user account to search for within the
+	// audit log files.
+	Username string
+
+	// DirPath is the path to the directory containing the audit log files.
+	DirPath string
+}
+
+// NewReader creates a new instance of a Reader that provides access to a
+// collection of user sessions for the specified username found in the audit
+// log files within the specified directory.
+func NewReader(username string, dirPath string) (*Reader, error) {
+
+	if username == "" {
+		return nil, errors.New(
+			"func NewReader: missing username",
+		)
+	}
+
+	if dirPath == "" {
+		return nil, errors.New(
+			"func NewReader: missing audit log directory path",
+		)
+	}
+
+	reader := Reader{
+		SearchDelay:   0,
+		SearchRetries: 0,
+		Lookback:      DefaultLookback,
+		Username:      username,
+		DirPath:       dirPath,
+	}
+
+	return &reader, nil
+}
+
+// SetSearchRetries is a helper method for setting the number of additional
+// retries allowed when receiving zero search results.
+func (r *Reader) SetSearchRetries(retries int) error {
+	if retries < 0 {
+		return fmt.Errorf("func SetSearchRetries: %d is not a valid number of search retries", retries)
+	}
+
+	r.SearchRetries = retries
+
+	return nil
+}
+
+// SetSearchDelay is a helper method for setting the delay in seconds between
+// search attempts.
+func (r *Reader) SetSearchDelay(delay int) error {
+	if delay < 0 {
+		return fmt.Errorf("func SetSearchDelay: %d is not a valid number of seconds for search delay", delay)
+	}
+
+	r.SearchDelay = time.Duration(delay) * time.Second
+
+	return nil
+}
+
+// SetLookback is a helper method for setting the number of minutes in which
+// login events are considered.
+func (r *Reader) SetLookback(minutes int) error {
+	if minutes <= 0 {
+		return fmt.Errorf("func SetLookback: %d is not a valid number of minutes for lookback", minutes)
+	}
+
+	r.Lookback = time.Duration(minutes) * time.Minute
+
+	return nil
+}
+
+// Files returns the paths to the audit log files covering the lookback
+// window which are present in the audit log directory, oldest first.
+func (r Reader) Files(now time.Time) ([]string, error) {
+
+	since := now.Add(-r.Lookback)
+
+	// walk backwards from today to the first day covered by the lookback
+	// window, then reverse so that entries are read in the order recorded
+	var files []string
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for !day.Before(time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, since.Location())) {
+		path := filepath.Join(r.DirPath, day.Format(FileNameLayout))
+		switch _, err := os.Stat(path); {
+		case err == nil:
+			files = append([]string{path}, files...)
+		case !os.IsNotExist(err):
+			return nil, fmt.Errorf(
+				"%s: error accessing audit log file %q: %w",
+				caller.GetFuncName(),
+				path,
+				err,
+			)
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return files, nil
+}
+
+// Entries returns all session entries recorded in the specified audit log
+// file. Header lines and entries without a valid session ID are skipped.
+func Entries(filename string) ([]Entry, error) {
+
+	myFuncName := caller.GetFuncName()
+
+	log.Debugf("%s: Attempting to open sanitized version of file %q",
+		myFuncName, filepath.Clean(filename))
+
+	f, err := os.Open(filepath.Clean(filename))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%s: error encountered opening file %q: %w",
+			myFuncName,
+			filename,
+			err,
+		)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			// Ignore "file already closed" errors
+			if !errors.Is(err, os.ErrClosed) {
+				log.Errorf(
+					"%s: failed to close file %q: %s",
+					myFuncName,
+					filename,
+					err.Error(),
+				)
+			}
+		}
+	}()
+
+	var entries []Entry
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+
+		fields := strings.Split(s.Text(), FieldDelimiter)
+		if len(fields) < minimumFields {
+			continue
+		}
+
+		timestamp, err := time.ParseInLocation(
+			TimestampLayout,
+			strings.TrimSpace(fields[fieldTimestamp]),
+			time.Local,
+		)
+		if err != nil {
+			// header line or otherwise unexpected content
+			continue
+		}
+
+		sessionID := strings.TrimSpace(fields[fieldSessionID])
+		if !sessionIDRegex.MatchString(sessionID) {
+			// e.g., failed login attempts
+			continue
+		}
+
+		entries = append(entries, Entry{
+			Timestamp: timestamp,
+			Event:     strings.TrimSpace(fields[fieldEvent]),
+			IPAddress: strings.TrimSpace(fields[fieldIPAddress]),
+			Username:  strings.TrimSpace(fields[fieldUsername]),
+			SessionID: sessionID,
+		})
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf(
+			"%s: errors encountered while scanning the audit log file %q: %w",
+			myFuncName,
+			filename,
+			err,
+		)
+	}
+
+	return entries, nil
+}
+
+// AllUserSessions returns a list of all sessions established by login events
+// within the lookback window (and not since ended by a logout event) in the
+// form of a slice of UserSession values.
+func (r Reader) AllUserSessions() (ezproxy.UserSessions, error) {
+
+	now := time.Now()
+	since := now.Add(-r.Lookback)
+
+	files, err := r.Files(now)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make(map[string]ezproxy.UserSession)
+	var order []string
+
+	for _, file := range files {
+
+		entries, err := Entries(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			switch {
+			case strings.HasPrefix(entry.Event, LogoutEventPrefix):
+				delete(sessions, entry.SessionID)
+
+			case entry.Timestamp.Before(since):
+				continue
+
+			case strings.HasPrefix(entry.Event, LoginSuccessEventPrefix):
+				if _, ok := sessions[entry.SessionID]; !ok {
+					order = append(order, entry.SessionID)
+				}
+				sessions[entry.SessionID] = ezproxy.UserSession{
+					SessionID: entry.SessionID,
+					IPAddress: entry.IPAddress,
+					Username:  entry.Username,
+				}
+			}
+		}
+	}
+
+	allUserSessions := make(ezproxy.UserSessions, 0, len(sessions))
+	for _, sessionID := range order {
+		if session, ok := sessions[sessionID]; ok {
+			allUserSessions = append(allUserSessions, session)
+		}
+	}
+
+	log.Debugf(
+		"%s: Found %d sessions in %d audit log files in %q",
+		caller.GetFuncName(),
+		len(allUserSessions),
+		len(files),
+		r.DirPath,
+	)
+
+	return allUserSessions, nil
+}
+
+// MatchingUserSessions uses the previously provided username to return a list
+// of all matching sessions found in the audit log files in the form of a
+// slice of UserSession values.
+func (r Reader) MatchingUserSessions() (ezproxy.UserSessions, error) {
+
+	requestedUserSessions := make(ezproxy.UserSessions, 0, ezproxy.SessionsLimit)
+
+	searchAttemptsAllowed := r.SearchRetries + 1
+
+	for searchAttempts := 1; searchAttempts <= searchAttemptsAllowed; searchAttempts++ {
+
+		log.Debugf(
+			"%s: Beginning search attempt %d of %d for %q",
+			caller.GetFuncName(),
+			searchAttempts,
+			searchAttemptsAllowed,
+			r.Username,
+		)
+
+		time.Sleep(r.SearchDelay)
+
+		allUserSessions, err := r.AllUserSessions()
+		if err != nil {
+			return nil, fmt.Errorf(
+				"func MatchingUserSessions: failed to retrieve all user sessions in order to filter to specific username: %w",
+				err,
+			)
+		}
+
+		for _, session := range allUserSessions {
+			if strings.EqualFold(r.Username, session.Username) {
+				requestedUserSessions = append(requestedUserSessions, session)
+			}
+		}
+
+		if len(requestedUserSessions) > 0 {
+			break
+		}
+	}
+
+	return requestedUserSessions, nil
+}
+
+// Verify that the Reader satisfies the same interface as the active file
+// reader.
+var _ ezproxy.SessionsReader = (*Reader)(nil)
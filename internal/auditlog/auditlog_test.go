@@ -0,0 +1,296 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditlog
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEntry is an audit log entry recorded some time ago.
+type testEntry struct {
+	age       time.Duration
+	event     string
+	ipAddress string
+	username  string
+	sessionID string
+}
+
+// writeAuditLogFiles writes the provided entries to the audit log file for
+// the day on which each was recorded, using the same layout as EZproxy.
+func writeAuditLogFiles(t *testing.T, dir string, now time.Time, entries []testEntry) {
+
+	t.Helper()
+
+	files := make(map[string][]string)
+	for _, entry := range entries {
+		timestamp := now.Add(-entry.age)
+		name := timestamp.Format(FileNameLayout)
+		if _, ok := files[name]; !ok {
+			files[name] = []string{strings.Join([]string{"%Date/Time", "Event", "IP", "Username", "Session", "Other"}, FieldDelimiter)}
+		}
+		files[name] = append(files[name], strings.Join(
+			[]string{timestamp.Format(TimestampLayout), entry.event, entry.ipAddress, entry.username, entry.sessionID, ""},
+			FieldDelimiter,
+		))
+	}
+
+	for name, lines := range files {
+		content := strings.Join(lines, "\n") + "\n"
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write audit log file: %v", err)
+		}
+	}
+}
+
+func TestEntries(t *testing.T) {
+
+	content := strings.Join([]string{
+		"%Date/Time\tEvent\tIP\tUsername\tSession\tOther",
+		"2020-10-19 08:15:00\tLogin.Success\t192.168.2.3\tjsmith\tABCDEFGHIJKLMNO\t",
+		"2020-10-19 08:16:00\tLogin.Failure\t192.168.2.4\tadoe\t\t",
+		"2020-10-19 08:17:00\tLogin.Success\t192.168.2.5\tadoe\tshort\t",
+		"2020-10-19 08:18:00\tLogout",
+		"not a timestamp\tLogin.Success\t192.168.2.6\tjdoe\tABCDEFGHIJKLMNP\t",
+		" 2020-10-19 08:19:00 \t Logout \t 192.168.2.3 \t jsmith \t ABCDEFGHIJKLMNO \t",
+	}, "\n")
+
+	filename := filepath.Join(t.TempDir(), "20201019.txt")
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write audit log file: %v", err)
+	}
+
+	entries, err := Entries(filename)
+	if err != nil {
+		t.Fatalf("Entries() returned error: %v", err)
+	}
+
+	want := []Entry{
+		{
+			Timestamp: time.Date(2020, time.October, 19, 8, 15, 0, 0, time.Local),
+			Event:     "Login.Success",
+			IPAddress: "192.168.2.3",
+			Username:  "jsmith",
+			SessionID: "ABCDEFGHIJKLMNO",
+		},
+		{
+			Timestamp: time.Date(2020, time.October, 19, 8, 19, 0, 0, time.Local),
+			Event:     "Logout",
+			IPAddress: "192.168.2.3",
+			Username:  "jsmith",
+			SessionID: "ABCDEFGHIJKLMNO",
+		},
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("Entries() returned %d entries; want %d: %+v", len(entries), len(want), entries)
+	}
+
+	for i := range want {
+		if !entries[i].Timestamp.Equal(want[i].Timestamp) ||
+			entries[i].Event != want[i].Event ||
+			entries[i].IPAddress != want[i].IPAddress ||
+			entries[i].Username != want[i].Username ||
+			entries[i].SessionID != want[i].SessionID {
+			t.Errorf("Entries()[%d] = %+v; want %+v", i, entries[i], want[i])
+		}
+	}
+
+	if _, err := Entries(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Entries() returned nil error for missing file")
+	}
+}
+
+func TestReaderFiles(t *testing.T) {
+
+	now := time.Date(2020, time.October, 19, 0, 5, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		lookback time.Duration
+		present  []string
+		want     []string
+	}{
+		{
+			name:     "lookback within today",
+			lookback: time.Minute,
+			present:  []string{"20201018.txt", "20201019.txt"},
+			want:     []string{"20201019.txt"},
+		},
+		{
+			name:     "lookback spans midnight",
+			lookback: 10 * time.Minute,
+			present:  []string{"20201017.txt", "20201018.txt", "20201019.txt"},
+			want:     []string{"20201018.txt", "20201019.txt"},
+		},
+		{
+			name:     "lookback spans several days",
+			lookback: 48 * time.Hour,
+			present:  []string{"20201016.txt", "20201017.txt", "20201019.txt", "notes.txt"},
+			want:     []string{"20201017.txt", "20201019.txt"},
+		},
+		{
+			name:     "no files present",
+			lookback: 10 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			dir := t.TempDir()
+			for _, name := range tt.present {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+					t.Fatalf("failed to write audit log file: %v", err)
+				}
+			}
+
+			reader, err := NewReader("jsmith", dir)
+			if err != nil {
+				t.Fatalf("NewReader() returned error: %v", err)
+			}
+			reader.Lookback = tt.lookback
+
+			files, err := reader.Files(now)
+			if err != nil {
+				t.Fatalf("Files() returned error: %v", err)
+			}
+
+			if len(files) != len(tt.want) {
+				t.Fatalf("Files() = %q; want %q", files, tt.want)
+			}
+			for i := range tt.want {
+				if files[i] != filepath.Join(dir, tt.want[i]) {
+					t.Errorf("Files()[%d] = %q; want %q", i, files[i], filepath.Join(dir, tt.want[i]))
+				}
+			}
+		})
+	}
+}
+
+func TestReaderMatchingUserSessions(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		lookback int
+		entries  []testEntry
+		want     []string
+	}{
+		{
+			name:     "recent login",
+			lookback: 10,
+			entries: []testEntry{
+				{time.Minute, "Login.Success", "192.168.2.3", "jsmith", "AAAAAAAAAAAAAAA"},
+			},
+			want: []string{"AAAAAAAAAAAAAAA"},
+		},
+		{
+			name:     "login outside lookback",
+			lookback: 10,
+			entries: []testEntry{
+				{20 * time.Minute, "Login.Success", "192.168.2.3", "jsmith", "AAAAAAAAAAAAAAA"},
+				{time.Minute, "Login.Success.Relogin", "192.168.2.3", "JSmith", "BBBBBBBBBBBBBBB"},
+			},
+			want: []string{"BBBBBBBBBBBBBBB"},
+		},
+		{
+			name:     "longer lookback",
+			lookback: 30,
+			entries: []testEntry{
+				{20 * time.Minute, "Login.Success", "192.168.2.3", "jsmith", "AAAAAAAAAAAAAAA"},
+				{time.Minute, "Login.Success", "192.168.2.3", "jsmith", "BBBBBBBBBBBBBBB"},
+			},
+			want: []string{"AAAAAAAAAAAAAAA", "BBBBBBBBBBBBBBB"},
+		},
+		{
+			name:     "session ended by logout",
+			lookback: 10,
+			entries: []testEntry{
+				{5 * time.Minute, "Login.Success", "192.168.2.3", "jsmith", "AAAAAAAAAAAAAAA"},
+				{4 * time.Minute, "Logout", "192.168.2.3", "jsmith", "AAAAAAAAAAAAAAA"},
+				{3 * time.Minute, "Login.Success", "192.168.2.3", "jsmith", "BBBBBBBBBBBBBBB"},
+			},
+			want: []string{"BBBBBBBBBBBBBBB"},
+		},
+		{
+			name:     "other users and events",
+			lookback: 10,
+			entries: []testEntry{
+				{time.Minute, "Login.Success", "192.168.2.4", "adoe", "AAAAAAAAAAAAAAA"},
+				{time.Minute, "Login.Failure", "192.168.2.3", "jsmith", ""},
+				{time.Minute, "Session.Limit", "192.168.2.3", "jsmith", "CCCCCCCCCCCCCCC"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			dir := t.TempDir()
+			writeAuditLogFiles(t, dir, time.Now(), tt.entries)
+
+			reader, err := NewReader("jsmith", dir)
+			if err != nil {
+				t.Fatalf("NewReader() returned error: %v", err)
+			}
+			if err := reader.SetLookback(tt.lookback); err != nil {
+				t.Fatalf("SetLookback() returned error: %v", err)
+			}
+
+			sessions, err := reader.MatchingUserSessions()
+			if err != nil {
+				t.Fatalf("MatchingUserSessions() returned error: %v", err)
+			}
+
+			var got []string
+			for _, session := range sessions {
+				got = append(got, session.SessionID)
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("MatchingUserSessions() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReaderSetLookback(t *testing.T) {
+
+	reader, err := NewReader("jsmith", t.TempDir())
+	if err != nil {
+		t.Fatalf("NewReader() returned error: %v", err)
+	}
+
+	if reader.Lookback != DefaultLookback {
+		t.Errorf("NewReader() Lookback = %v; want %v", reader.Lookback, DefaultLookback)
+	}
+
+	for _, minutes := range []int{0, -1} {
+		if err := reader.SetLookback(minutes); err == nil {
+			t.Errorf("SetLookback(%d) returned nil error", minutes)
+		}
+	}
+
+	if err := reader.SetLookback(30); err != nil || reader.Lookback != 30*time.Minute {
+		t.Errorf("SetLookback(30) = %v, Lookback = %v; want nil error, %v", err, reader.Lookback, 30*time.Minute)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ezadmin is an internal package that terminates EZproxy user
+// sessions using the EZproxy administrative web interface instead of running
+// the `kill` subcommand of the EZproxy executable. This allows session
+// termination from a host other than the EZproxy server.
+//
+// A login is performed using the configured administrator credentials for
+// each batch of sessions to terminate; the resulting session cookie is used
+// for the termination requests which follow. Redirects are not followed; a
+// redirect to the login page is treated as a failed login or termination
+// request, and terminations are only recorded as successful if EZproxy
+// confirms them in the response. The `serve` subcommand of the mock
+// `ezproxy` binary provided by this project implements the same endpoints
+// for testing purposes.
+package ezadmin
@@ -0,0 +1,352 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ezadmin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/atc0005/go-ezproxy"
+
+	"github.com/atc0005/brick/internal/caller"
+)
+
+const (
+	// LoginPath is the path, relative to the EZproxy base URL, used to log
+	// into the administrative web interface.
+	LoginPath string = "/login"
+
+	// KillPath is the path, relative to the EZproxy base URL, used to
+	// terminate a session.
+	KillPath string = "/kill"
+
+	// UsernameField is the name of the login form field for the username.
+	UsernameField string = "user"
+
+	// PasswordField is the name of the login form field for the password.
+	PasswordField string = "pass"
+
+	// SessionField is the name of the form field used to specify the session
+	// to terminate.
+	SessionField string = "session"
+
+	// DefaultTimeout is the default time limit for each request to the
+	// administrative web interface.
+	DefaultTimeout time.Duration = 10 * time.Second
+
+	// maxResponseSize limits how much of each response body is read.
+	maxResponseSize int64 = 1 << 20
+)
+
+// resultExitCodeNotRun is recorded as the ExitCode for termination attempts
+// which did not receive a response from EZproxy. This is the same value
+// recorded by the ezproxy package when the EZproxy executable is not run.
+const resultExitCodeNotRun int = -1
+
+// ErrLoginFailed indicates that the administrative web interface did not
+// accept the provided credentials.
+var ErrLoginFailed = errors.New("login to EZproxy administrative web interface failed")
+
+// ErrLoginRequired indicates that the administrative web interface did not
+// recognize the login session and responded with the login page instead of
+// processing the request.
+var ErrLoginRequired = errors.New("login to EZproxy administrative web interface required")
+
+// loginFormRegex matches the password field of the login form. Responses
+// including this field are treated as the login page.
+var loginFormRegex = regexp.MustCompile(
+	`(?i)<input[^>]+name\s*=\s*["']?` + regexp.QuoteMeta(PasswordField) + `["'\s/>]`,
+)
+
+// Client terminates user sessions using the EZproxy administrative web
+// interface.
+type Client struct {
+
+	// URL is the base URL of the EZproxy server (e.g.,
+	// https://ezproxy.example.edu:2443).
+	URL *url.URL
+
+	// Username is the name of an EZproxy administrator account.
+	Username string
+
+	// Password is the password for the EZproxy administrator account.
+	Password string
+
+	// HTTPClient is used for all requests. A separate cookie jar is used
+	// for each call to Terminate.
+	HTTPClient *http.Client
+}
+
+// NewClient creates a new Client for the EZproxy server at the specified
+// base URL using the provided administrator credentials.
+func NewClient(baseURL string, username string, password string) (*Client, error) {
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("func NewClient: invalid EZproxy URL %q: %w", baseURL, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf(
+			"func NewClient: invalid EZproxy URL %q; expected http or https URL",
+			baseURL,
+		)
+	}
+
+	if username == "" || password == "" {
+		return nil, errors.New("func NewClient: missing EZproxy administrator credentials")
+	}
+
+	client := Client{
+		URL:      u,
+		Username: username,
+		Password: password,
+		HTTPClient: &http.Client{
+			Timeout: DefaultTimeout,
+
+			// Redirects are not followed so that a redirect to the login
+			// page (e.g., for an expired login session) is not mistaken for
+			// a successful response.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	return &client, nil
+}
+
+// SetTimeout is a helper method for setting the time limit in seconds for
+// each request to the administrative web interface.
+func (c *Client) SetTimeout(timeout int) error {
+	if timeout < 1 {
+		return fmt.Errorf("func SetTimeout: %d is not a valid number of seconds for timeout", timeout)
+	}
+
+	c.HTTPClient.Timeout = time.Duration(timeout) * time.Second
+
+	return nil
+}
+
+// Terminate logs into the administrative web interface and then attempts to
+// terminate each of the provided sessions, returning the results in the
+// same format as the ezproxy package uses for the EZproxy executable. The
+// ExitCode for each result mirrors the exit code the `kill` subcommand would
+// have returned; failed requests are recorded with an ExitCode of -1.
+// Sessions which no longer exist are recorded without an error.
+func (c *Client) Terminate(sessions ezproxy.UserSessions) ezproxy.TerminateUserSessionResults {
+
+	results := make(ezproxy.TerminateUserSessionResults, 0, len(sessions))
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		err = fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+
+	client := *c.HTTPClient
+	client.Jar = jar
+
+	if err == nil {
+		err = c.login(&client)
+	}
+
+	for _, session := range sessions {
+
+		if err != nil {
+			results = append(results, ezproxy.TerminateUserSessionResult{
+				UserSession: session,
+				ExitCode:    resultExitCodeNotRun,
+				Error:       err,
+			})
+			continue
+		}
+
+		results = append(results, c.terminate(&client, session))
+	}
+
+	return results
+}
+
+// login submits the administrator credentials to the login form. The login
+// is considered successful if EZproxy responds with a session cookie and
+// either redirects away from the login page (e.g., to the administration
+// menu) or responds with a page other than the login form.
+func (c *Client) login(client *http.Client) error {
+
+	loginURL := c.URL.ResolveReference(&url.URL{Path: LoginPath})
+
+	log.Debugf("%s: logging into %s as %q", caller.GetFuncName(), loginURL, c.Username)
+
+	resp, err := client.PostForm(loginURL.String(), url.Values{
+		UsernameField: {c.Username},
+		PasswordField: {c.Password},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLoginFailed, err)
+	}
+
+	body, err := readBody(resp)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLoginFailed, err)
+	}
+
+	switch {
+	case resp.StatusCode >= http.StatusBadRequest:
+		return fmt.Errorf("%w: %s returned %s", ErrLoginFailed, loginURL, resp.Status)
+
+	case isRedirect(resp):
+		if redirectsToLogin(resp) {
+			return fmt.Errorf("%w: %s redirected back to the login page", ErrLoginFailed, loginURL)
+		}
+
+	case isLoginPage(body):
+		return fmt.Errorf("%w: %s responded with the login page", ErrLoginFailed, loginURL)
+	}
+
+	if len(client.Jar.Cookies(c.URL)) == 0 {
+		return fmt.Errorf("%w: no session cookie returned by %s", ErrLoginFailed, loginURL)
+	}
+
+	return nil
+}
+
+// terminate submits a request to terminate the specified session. The
+// session is only recorded as terminated if the response confirms the
+// termination using the same text as the `kill` subcommand.
+func (c *Client) terminate(client *http.Client, session ezproxy.UserSession) ezproxy.TerminateUserSessionResult {
+
+	killURL := c.URL.ResolveReference(&url.URL{Path: KillPath})
+
+	log.Debugf(
+		"%s: terminating session %q for username %q via %s",
+		caller.GetFuncName(),
+		session.SessionID,
+		session.Username,
+		killURL,
+	)
+
+	result := ezproxy.TerminateUserSessionResult{
+		UserSession: session,
+		ExitCode:    resultExitCodeNotRun,
+	}
+
+	resp, err := client.PostForm(killURL.String(), url.Values{
+		SessionField: {session.SessionID},
+	})
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	body, err := readBody(resp)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.StdOut = body
+
+	terminatedText := fmt.Sprintf(ezproxy.KillSubCmdExitTextTemplateSessionTerminated, session.SessionID)
+
+	switch {
+	case redirectsToLogin(resp) || isLoginPage(body):
+		result.Error = fmt.Errorf("%w: %s responded with the login page", ErrLoginRequired, killURL)
+
+	case isRedirect(resp):
+		result.Error = fmt.Errorf(
+			"%s returned unexpected redirect (%s) to %q",
+			killURL,
+			resp.Status,
+			resp.Header.Get("Location"),
+		)
+
+	// the session was already terminated or has expired; this is recorded
+	// the same way as for the EZproxy executable, without an error
+	case resp.StatusCode == http.StatusNotFound:
+		result.ExitCode = ezproxy.KillSubCmdExitCodeSessionDoesNotExist
+
+	case resp.StatusCode != http.StatusOK:
+		result.Error = fmt.Errorf("%s returned %s", killURL, resp.Status)
+
+	case !strings.Contains(body, terminatedText):
+		result.Error = fmt.Errorf(
+			"%s response did not confirm termination of session %q",
+			killURL,
+			session.SessionID,
+		)
+
+	default:
+		result.ExitCode = ezproxy.KillSubCmdExitCodeSessionTerminated
+	}
+
+	return result
+}
+
+// isRedirect indicates whether the response is a redirect. Redirects are not
+// followed by the HTTP client used by this package.
+func isRedirect(resp *http.Response) bool {
+	return resp.StatusCode >= http.StatusMultipleChoices &&
+		resp.StatusCode < http.StatusBadRequest
+}
+
+// redirectsToLogin indicates whether the response is a redirect to the login
+// page of the administrative web interface.
+func redirectsToLogin(resp *http.Response) bool {
+
+	if !isRedirect(resp) {
+		return false
+	}
+
+	location, err := resp.Location()
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(strings.TrimSuffix(location.Path, "/"), LoginPath)
+}
+
+// isLoginPage indicates whether the response body is the login page of the
+// administrative web interface.
+func isLoginPage(body string) bool {
+	return loginFormRegex.MatchString(body)
+}
+
+// readBody reads and closes the response body, returning the body as text
+// with surrounding whitespace removed.
+func readBody(resp *http.Response) (string, error) {
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Errorf("%s: failed to close response body: %v", caller.GetFuncName(), err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response from %s: %w", resp.Request.URL, err)
+	}
+
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,229 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ezadmin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atc0005/go-ezproxy"
+)
+
+const (
+	testUsername  string = "admin"
+	testPassword  string = "s3cr3t"
+	testCookie    string = "ezproxy"
+	testSessionID string = "ABCDEFGHIJKLMNOP"
+	testLoginForm string = `<form method="post" action="/login">` +
+		`<input type="text" name="user"><input type="password" name="pass">` +
+		`</form>`
+)
+
+// loginOK accepts the test credentials, setting a session cookie and
+// redirecting to the administration menu. Invalid credentials are answered
+// with the login form.
+func loginOK(w http.ResponseWriter, r *http.Request) {
+
+	if r.PostFormValue(UsernameField) != testUsername ||
+		r.PostFormValue(PasswordField) != testPassword {
+		fmt.Fprint(w, testLoginForm)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: testCookie, Value: "token", Path: "/"})
+	http.Redirect(w, r, "/admin", http.StatusFound)
+}
+
+// killOK terminates the submitted session for logged in administrators and
+// redirects all other requests to the login page.
+func killOK(w http.ResponseWriter, r *http.Request) {
+
+	if _, err := r.Cookie(testCookie); err != nil {
+		http.Redirect(w, r, LoginPath, http.StatusFound)
+		return
+	}
+
+	fmt.Fprintf(
+		w,
+		ezproxy.KillSubCmdExitTextTemplateSessionTerminated+"\n",
+		r.PostFormValue(SessionField),
+	)
+}
+
+func TestClientTerminate(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		password     string
+		login        http.HandlerFunc
+		kill         http.HandlerFunc
+		wantExitCode int
+		wantErr      error
+		wantAnyErr   bool
+	}{
+		{
+			name:         "login and kill succeed",
+			password:     testPassword,
+			login:        loginOK,
+			kill:         killOK,
+			wantExitCode: ezproxy.KillSubCmdExitCodeSessionTerminated,
+		},
+		{
+			name:         "login fails with login form",
+			password:     "wrong",
+			login:        loginOK,
+			kill:         killOK,
+			wantExitCode: resultExitCodeNotRun,
+			wantErr:      ErrLoginFailed,
+		},
+		{
+			name:     "login fails with redirect to login page",
+			password: testPassword,
+			login: func(w http.ResponseWriter, r *http.Request) {
+				http.SetCookie(w, &http.Cookie{Name: testCookie, Value: "token", Path: "/"})
+				http.Redirect(w, r, LoginPath+"?error=1", http.StatusFound)
+			},
+			kill:         killOK,
+			wantExitCode: resultExitCodeNotRun,
+			wantErr:      ErrLoginFailed,
+		},
+		{
+			name:     "login fails without session cookie",
+			password: testPassword,
+			login: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/admin", http.StatusFound)
+			},
+			kill:         killOK,
+			wantExitCode: resultExitCodeNotRun,
+			wantErr:      ErrLoginFailed,
+		},
+		{
+			name:     "kill redirected to login page",
+			password: testPassword,
+			login:    loginOK,
+			kill: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, LoginPath, http.StatusFound)
+			},
+			wantExitCode: resultExitCodeNotRun,
+			wantErr:      ErrLoginRequired,
+		},
+		{
+			name:     "kill responds with login page",
+			password: testPassword,
+			login:    loginOK,
+			kill: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, testLoginForm)
+			},
+			wantExitCode: resultExitCodeNotRun,
+			wantErr:      ErrLoginRequired,
+		},
+		{
+			name:     "kill response without confirmation",
+			password: testPassword,
+			login:    loginOK,
+			kill: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "<html><body>Administration</body></html>")
+			},
+			wantExitCode: resultExitCodeNotRun,
+			wantAnyErr:   true,
+		},
+		{
+			name:     "session does not exist",
+			password: testPassword,
+			login:    loginOK,
+			kill: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(
+					w,
+					fmt.Sprintf(ezproxy.KillSubCmdExitTextTemplateSessionDoesNotExist, testSessionID),
+					http.StatusNotFound,
+				)
+			},
+			wantExitCode: ezproxy.KillSubCmdExitCodeSessionDoesNotExist,
+		},
+		{
+			name:     "kill server error",
+			password: testPassword,
+			login:    loginOK,
+			kill: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+			},
+			wantExitCode: resultExitCodeNotRun,
+			wantAnyErr:   true,
+		},
+		{
+			name:     "login server error",
+			password: testPassword,
+			login: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+			},
+			kill:         killOK,
+			wantExitCode: resultExitCodeNotRun,
+			wantErr:      ErrLoginFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			mux := http.NewServeMux()
+			mux.HandleFunc(LoginPath, tt.login)
+			mux.HandleFunc(KillPath, tt.kill)
+			mux.HandleFunc("/admin", func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("unexpected request for %s; redirects should not be followed", r.URL.Path)
+			})
+
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			client, err := NewClient(server.URL, testUsername, tt.password)
+			if err != nil {
+				t.Fatalf("NewClient() returned error: %v", err)
+			}
+
+			results := client.Terminate(ezproxy.UserSessions{
+				{SessionID: testSessionID, Username: "jsmith"},
+			})
+			if len(results) != 1 {
+				t.Fatalf("Terminate() returned %d results; want 1", len(results))
+			}
+			result := results[0]
+
+			if result.ExitCode != tt.wantExitCode {
+				t.Errorf("ExitCode = %d; want %d", result.ExitCode, tt.wantExitCode)
+			}
+
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(result.Error, tt.wantErr) {
+					t.Errorf("Error = %v; want %v", result.Error, tt.wantErr)
+				}
+			case tt.wantAnyErr:
+				if result.Error == nil {
+					t.Error("Error = nil; want error")
+				}
+			default:
+				if result.Error != nil {
+					t.Errorf("Error = %v; want nil", result.Error)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 Adam Chalkley
+//
+// https://github.com/atc0005/brick
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/apex/log"
+	"github.com/atc0005/brick/internal/caller"
+)
+
+// WriteFileAtomic writes the provided content to a temporary file in the same
+// directory as the specified filename and then renames the temporary file
+// over the original. This prevents readers (e.g., EZproxy or this
+// application) from observing a partially written file. The provided
+// permissions are applied to the new file. If the file already exists, the
+// existing permissions are preserved instead.
+func WriteFileAtomic(filename string, content []byte, perms os.FileMode) error {
+
+	myFuncName := caller.GetFuncName()
+
+	filename = filepath.Clean(filename)
+
+	if fi, err := os.Stat(filename); err == nil {
+		perms = fi.Mode().Perm()
+	}
+
+	log.Debugf("%s: Creating temporary file for %q", myFuncName, filename)
+	tmpFile, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return fmt.Errorf(
+			"%s: error creating temporary file for %q: %w",
+			myFuncName,
+			filename,
+			err,
+		)
+	}
+
+	// Remove the temporary file if we bail out early. This is a no-op once
+	// the file has been renamed.
+	tmpFilename := tmpFile.Name()
+	defer func() {
+		if err := os.Remove(tmpFilename); err != nil && !os.IsNotExist(err) {
+			log.Errorf(
+				"%s: failed to remove temporary file %q: %s",
+				myFuncName,
+				tmpFilename,
+				err.Error(),
+			)
+		}
+	}()
+
+	if _, err := tmpFile.Write(content); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf(
+			"%s: error writing to temporary file %q: %w",
+			myFuncName,
+			tmpFilename,
+			err,
+		)
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf(
+			"%s: failed to explicitly sync temporary file %q after writing: %w",
+			myFuncName,
+			tmpFilename,
+			err,
+		)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf(
+			"%s: error closing temporary file %q: %w",
+			myFuncName,
+			tmpFilename,
+			err,
+		)
+	}
+
+	if err := os.Chmod(tmpFilename, perms); err != nil {
+		return fmt.Errorf(
+			"%s: error setting permissions on temporary file %q: %w",
+			myFuncName,
+			tmpFilename,
+			err,
+		)
+	}
+
+	log.Debugf("%s: Replacing %q with %q", myFuncName, filename, tmpFilename)
+	if err := os.Rename(tmpFilename, filename); err != nil {
+		return fmt.Errorf(
+			"%s: error replacing %q with temporary file %q: %w",
+			myFuncName,
+			filename,
+			tmpFilename,
+			err,
+		)
+	}
+
+	return nil
+}